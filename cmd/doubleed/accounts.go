@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"text/tabwriter"
+)
+
+const accountsUsage = `usage:
+  doubleed accounts list --ledger UUID --metadata KEY=VALUE [--metadata KEY=VALUE...]
+  doubleed accounts create --ledger UUID --name NAME --type TYPE [--metadata KEY=VALUE...]`
+
+// runAccounts manages the accounts of a ledger through the API of a server
+func runAccounts(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(accountsUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		return listAccounts(ctx, w, args[1:])
+	case "create":
+		return createAccount(ctx, w, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], accountsUsage)
+	}
+}
+
+type cliAccount struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+func listAccounts(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("accounts list", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	ledger := fs.String("ledger", "", "UUID of the ledger")
+	metadata := metadataFlag{}
+	fs.Var(metadata, "metadata", "filter by metadata, KEY=VALUE, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *ledger == "" {
+		return errors.New("--ledger is required")
+	}
+	// the API requires a metadata filter
+	if len(metadata) == 0 {
+		return errors.New("--metadata is required")
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"ledger_uuid": {*ledger}}
+	metadata.filter(query)
+	detail, err := client.do(ctx, "GET", "/accounts", query, nil)
+
+	// the API answers 404 when nothing matches the filter
+	if isNotFound(err) {
+		detail, err = json.RawMessage(`[]`), nil
+	}
+	if err != nil {
+		return fmt.Errorf("list accounts: %w", err)
+	}
+	if opts.output == outputJSON {
+		return printJSON(w, detail)
+	}
+
+	var accounts []cliAccount
+	if err := json.Unmarshal(detail, &accounts); err != nil {
+		return fmt.Errorf("decode accounts: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tNAME\tTYPE")
+	for _, account := range accounts {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", account.UUID, account.Name, account.Type)
+	}
+	return tw.Flush()
+}
+
+func createAccount(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("accounts create", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	ledger := fs.String("ledger", "", "UUID of the ledger")
+	name := fs.String("name", "", "name of the account")
+	accountType := fs.String("type", "", "type of the account, e.g., asset or expense")
+	metadata := metadataFlag{}
+	fs.Var(metadata, "metadata", "metadata of the account, KEY=VALUE, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *ledger == "":
+		return errors.New("--ledger is required")
+	case *name == "":
+		return errors.New("--name is required")
+	case *accountType == "":
+		return errors.New("--type is required")
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	detail, err := client.do(ctx, "POST", "/accounts", nil, map[string]any{
+		"ledger_uuid": *ledger,
+		"name":        *name,
+		"type":        *accountType,
+		"metadata":    metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("create account: %w", err)
+	}
+	if opts.output == outputJSON {
+		return printJSON(w, detail)
+	}
+
+	var account cliAccount
+	if err := json.Unmarshal(detail, &account); err != nil {
+		return fmt.Errorf("decode account: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "uuid: %s\nname: %s\n", account.UUID, account.Name)
+	return err
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/j0lvera/go-double-e/pkg/client"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+)
+
+// clientOptions are the flags of the commands talking to the API of a
+// server, instead of its database
+type clientOptions struct {
+	url    string
+	apiKey string
+	output string
+}
+
+func clientFlags(fs *flag.FlagSet) *clientOptions {
+	opts := &clientOptions{}
+
+	serverURL := os.Getenv("DOUBLEED_URL")
+	if serverURL == "" {
+		serverURL = "http://localhost:8080"
+	}
+	fs.StringVar(&opts.url, "url", serverURL, "URL of the server, or DOUBLEED_URL")
+	fs.StringVar(&opts.apiKey, "api-key", os.Getenv("DOUBLEED_API_KEY"), "API key, or DOUBLEED_API_KEY")
+	fs.StringVar(&opts.output, "output", outputTable, "output format, table or json")
+	return opts
+}
+
+func (o *clientOptions) client() (*apiClient, error) {
+	if o.apiKey == "" {
+		return nil, errors.New("--api-key or DOUBLEED_API_KEY is required")
+	}
+	if o.output != outputTable && o.output != outputJSON {
+		return nil, fmt.Errorf("unknown output %q, use %q or %q", o.output, outputTable, outputJSON)
+	}
+
+	sdk, err := client.New(o.url, o.apiKey, client.Options{UserAgent: "doubleed-cli"})
+	if err != nil {
+		return nil, err
+	}
+	return &apiClient{sdk: sdk}, nil
+}
+
+// apiClient sends the requests of the command line client, the commands
+// print the details of the responses as they are
+type apiClient struct {
+	sdk *client.Client
+}
+
+// do sends the request and returns the detail of the response
+func (c *apiClient) do(ctx context.Context, method, path string, query url.Values, body any) (json.RawMessage, error) {
+	var detail json.RawMessage
+	if err := c.sdk.Do(ctx, method, path, query, body, &detail); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// isNotFound tells apart the 404 the API answers when a list is empty
+func isNotFound(err error) bool {
+	return errors.Is(err, client.ErrNotFound)
+}
+
+// printJSON writes the detail of a response as is, indented
+func printJSON(w io.Writer, detail json.RawMessage) error {
+	var out bytes.Buffer
+	if err := json.Indent(&out, detail, "", "  "); err != nil {
+		return err
+	}
+	out.WriteByte('\n')
+	_, err := out.WriteTo(w)
+	return err
+}
+
+// metadataFlag collects `--metadata key=value` flags, numbers are kept as
+// numbers like the filters of the API
+type metadataFlag map[string]any
+
+func (m metadataFlag) String() string {
+	return ""
+}
+
+func (m metadataFlag) Set(value string) error {
+	key, v, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid metadata %q, use key=value", value)
+	}
+	if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+		m[key] = n
+	} else {
+		m[key] = v
+	}
+	return nil
+}
+
+// filter adds the metadata to the query as `metadata.key=value` params
+func (m metadataFlag) filter(query url.Values) {
+	for key, value := range m {
+		query.Set("metadata."+key, fmt.Sprint(value))
+	}
+}
+
+// parseDate reads a YYYY-MM-DD date, today when it's empty
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Now().UTC().Truncate(24 * time.Hour), nil
+	}
+	date, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD", s)
+	}
+	return date, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/testutils"
+	is_ "github.com/matryer/is"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestClientCommands(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	testDb, err := testutils.GetTestDB(ctx)
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+	key, err := testutils.CreateTestAPIKey(ctx, testDb.Pool, testTenant.Uuid, auth.ScopeAll)
+	is.NoErr(err)
+
+	// cli runs a command against the test server and returns its output,
+	// the flags of the server go before the arguments of the command
+	cli := func(command func(context.Context, io.Writer, []string) error, args ...string) (string, error) {
+		var out bytes.Buffer
+		args = append([]string{args[0], "--url", testServer.BaseURL, "--api-key", key}, args[1:]...)
+		err := command(ctx, &out, args)
+		return out.String(), err
+	}
+	// fields splits the rows of a table in their columns
+	fields := func(out string) [][]string {
+		var rows [][]string
+		for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+			rows = append(rows, strings.Fields(line))
+		}
+		return rows
+	}
+
+	out, err := cli(runLedgers, "create", "--name", "CLI Books", "--metadata", "source=cli")
+	is.NoErr(err)
+	is.True(strings.HasPrefix(out, "uuid: "))
+	ledgerUUID := strings.TrimPrefix(strings.Split(out, "\n")[0], "uuid: ")
+
+	out, err = cli(runLedgers, "create", "--name", "CLI Books", "--metadata", "source=cli", "--output", "json")
+	is.NoErr(err)
+	var ledger cliLedger
+	is.NoErr(json.Unmarshal([]byte(out), &ledger))
+	is.Equal(ledger.Name, "CLI Books")
+
+	t.Run("should print tables", func(t *testing.T) {
+		is := is_.New(t)
+
+		out, err := cli(runLedgers, "list", "--metadata", "source=cli")
+		is.NoErr(err)
+		rows := fields(out)
+		is.Equal(rows[0], []string{"UUID", "NAME", "DESCRIPTION"})
+		is.Equal(len(rows), 3) // header and both ledgers
+		is.Equal(rows[1][1:], []string{"CLI", "Books", "-"})
+
+		out, err = cli(runAccounts, "create", "--ledger", ledgerUUID, "--name", "Cash", "--type", "asset", "--metadata", "source=cli")
+		is.NoErr(err)
+		cashUUID := strings.TrimPrefix(strings.Split(out, "\n")[0], "uuid: ")
+		out, err = cli(runAccounts, "create", "--ledger", ledgerUUID, "--name", "Sales", "--type", "revenue", "--metadata", "source=cli")
+		is.NoErr(err)
+		salesUUID := strings.TrimPrefix(strings.Split(out, "\n")[0], "uuid: ")
+
+		out, err = cli(runAccounts, "list", "--ledger", ledgerUUID, "--metadata", "source=cli")
+		is.NoErr(err)
+		rows = fields(out)
+		is.Equal(rows[0], []string{"UUID", "NAME", "TYPE"})
+		is.Equal(len(rows), 3)
+
+		out, err = cli(runTx, "add", "--ledger", ledgerUUID, "--debit", cashUUID, "--credit", salesUUID,
+			"--amount", "1250", "--date", "2024-11-24", "--metadata", "source=cli")
+		is.NoErr(err)
+		is.True(strings.HasSuffix(out, "amount: 1250\n"))
+		txUUID := strings.TrimPrefix(strings.Split(out, "\n")[0], "uuid: ")
+
+		out, err = cli(runTx, "list", "--ledger", ledgerUUID, "--metadata", "source=cli")
+		is.NoErr(err)
+		rows = fields(out)
+		is.Equal(rows[0], []string{"UUID", "DATE", "AMOUNT", "DESCRIPTION"})
+		is.Equal(rows[1][0], txUUID)
+		is.Equal(rows[1][2:], []string{"1250", "-"})
+		is.Equal(rows[2], []string{"1", "of", "1", "transactions"})
+
+		out, err = cli(runTx, "reverse", "--date", "2024-11-25", txUUID)
+		is.NoErr(err)
+		is.True(!strings.HasPrefix(out, "uuid: "+txUUID)) // a new transaction
+
+		out, err = cli(runReport, "trial-balance", "--ledger", ledgerUUID, "--to", "2024-11-24")
+		is.NoErr(err)
+		rows = fields(out)
+		is.Equal(rows[0], []string{"ACCOUNT", "TYPE", "DEBIT", "CREDIT", "BALANCE"})
+		is.Equal(rows[len(rows)-1], []string{"TOTAL", "1250", "1250"})
+	})
+
+	t.Run("should print JSON", func(t *testing.T) {
+		is := is_.New(t)
+
+		out, err := cli(runLedgers, "list", "--metadata", "source=cli", "--output", "json")
+		is.NoErr(err)
+		var ledgers []cliLedger
+		is.NoErr(json.Unmarshal([]byte(out), &ledgers))
+		is.Equal(len(ledgers), 2)
+
+		out, err = cli(runTx, "list", "--ledger", ledgerUUID, "--metadata", "source=cli", "--output", "json")
+		is.NoErr(err)
+		var page struct {
+			Data []cliTransaction `json:"data"`
+		}
+		is.NoErr(json.Unmarshal([]byte(out), &page))
+		is.Equal(len(page.Data), 1)
+	})
+
+	t.Run("should print empty lists", func(t *testing.T) {
+		is := is_.New(t)
+
+		out, err := cli(runLedgers, "list", "--metadata", "source=nowhere")
+		is.NoErr(err)
+		is.Equal(fields(out), [][]string{{"UUID", "NAME", "DESCRIPTION"}}) // header only
+
+		out, err = cli(runLedgers, "list", "--metadata", "source=nowhere", "--output", "json")
+		is.NoErr(err)
+		is.Equal(out, "[]\n")
+
+		out, err = cli(runAccounts, "list", "--ledger", ledger.UUID, "--metadata", "source=cli", "--output", "json")
+		is.NoErr(err)
+		is.Equal(out, "[]\n") // ledger without accounts
+
+		out, err = cli(runTx, "list", "--ledger", ledger.UUID, "--metadata", "source=cli")
+		is.NoErr(err)
+		is.Equal(fields(out), [][]string{{"UUID", "DATE", "AMOUNT", "DESCRIPTION"}, {"0", "of", "0", "transactions"}})
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/config"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const keysUsage = `usage:
+  doubleed keys create --tenant UUID --name NAME --scopes SCOPE[,SCOPE...] [--user UUID]
+  doubleed keys revoke UUID|PREFIX
+  doubleed keys list`
+
+// runKeys manages the API keys from the command line
+func runKeys(ctx context.Context, w io.Writer, cfg config.Database, args []string) error {
+	if len(args) == 0 {
+		return errors.New(keysUsage)
+	}
+
+	pool, err := newPool(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	queries := dbGen.New(pool)
+
+	switch args[0] {
+	case "create":
+		return createKey(ctx, w, queries, args[1:])
+	case "revoke":
+		return revokeKey(ctx, w, queries, args[1:])
+	case "list":
+		return listKeys(ctx, w, queries)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], keysUsage)
+	}
+}
+
+func createKey(ctx context.Context, w io.Writer, q *dbGen.Queries, args []string) error {
+	fs := flag.NewFlagSet("keys create", flag.ContinueOnError)
+	tenantUUID := fs.String("tenant", "", "uuid of the tenant the key has access to")
+	name := fs.String("name", "", "name of the key, e.g., the service using it")
+	scopes := fs.String("scopes", "", "comma separated scopes, e.g., ledgers:read,reports:read")
+	userUUID := fs.String("user", "", "uuid of the user the key acts for, limited to the user's roles")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tenantUUID == "" || *name == "" || *scopes == "" {
+		return errors.New("--tenant, --name and --scopes are required")
+	}
+
+	tenant, err := q.GetTenant(ctx, *tenantUUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("tenant %q not found", *tenantUUID)
+		}
+		return fmt.Errorf("get tenant: %w", err)
+	}
+
+	// without a user the key has access to every ledger of the tenant
+	var user pgtype.Text
+	if *userUUID != "" {
+		u, err := q.GetUser(ctx, *userUUID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("get user: %w", err)
+		}
+		if err != nil || u.TenantID != tenant.ID {
+			return fmt.Errorf("user %q not found in tenant %q", *userUUID, tenant.Uuid)
+		}
+		user = pgtype.Text{String: u.Uuid, Valid: true}
+	}
+
+	var granted []string
+	for _, scope := range strings.Split(*scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if !auth.ValidScope(scope) {
+			return fmt.Errorf("invalid scope %q, valid scopes: %s", scope, strings.Join(auth.Scopes, ", "))
+		}
+		granted = append(granted, scope)
+	}
+
+	key, err := auth.GenerateKey()
+	if err != nil {
+		return fmt.Errorf("generate key: %w", err)
+	}
+
+	apiKey, err := q.CreateApiKey(ctx, dbGen.CreateApiKeyParams{
+		Name:       *name,
+		Prefix:     key.Prefix,
+		KeyHash:    key.Hash,
+		Scopes:     granted,
+		TenantUuid: tenant.Uuid,
+		UserUuid:   user,
+	})
+	if err != nil {
+		return fmt.Errorf("create key: %w", err)
+	}
+
+	// the plain key isn't stored, this is the only time it's shown
+	_, err = fmt.Fprintf(w, "uuid:   %s\ntenant: %s\nscopes: %s\nkey:    %s\n", apiKey.Uuid, tenant.Uuid, strings.Join(apiKey.Scopes, ","), key.Plain)
+	return err
+}
+
+func revokeKey(ctx context.Context, w io.Writer, q *dbGen.Queries, args []string) error {
+	if len(args) != 1 {
+		return errors.New(keysUsage)
+	}
+
+	n, err := q.RevokeApiKey(ctx, args[0])
+	if err != nil {
+		return fmt.Errorf("revoke key: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("no active key %q", args[0])
+	}
+
+	_, err = fmt.Fprintf(w, "revoked %s\n", args[0])
+	return err
+}
+
+func listKeys(ctx context.Context, w io.Writer, q *dbGen.Queries) error {
+	keys, err := q.ListApiKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("list keys: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tTENANT\tUSER\tPREFIX\tNAME\tSCOPES\tCREATED\tREVOKED")
+	for _, key := range keys {
+		revoked := "-"
+		if key.RevokedAt.Valid {
+			revoked = key.RevokedAt.Time.Format(time.RFC3339)
+		}
+		user := "-"
+		if key.UserUuid.Valid {
+			user = key.UserUuid.String
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			key.Uuid,
+			key.TenantUuid,
+			user,
+			key.Prefix,
+			key.Name,
+			strings.Join(key.Scopes, ","),
+			key.CreatedAt.Time.Format(time.RFC3339),
+			revoked,
+		)
+	}
+	return tw.Flush()
+}
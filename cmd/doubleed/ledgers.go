@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"text/tabwriter"
+)
+
+const ledgersUsage = `usage:
+  doubleed ledgers list --metadata KEY=VALUE [--metadata KEY=VALUE...]
+  doubleed ledgers create --name NAME [--description TEXT] [--metadata KEY=VALUE...]`
+
+// runLedgers manages the ledgers through the API of a server
+func runLedgers(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(ledgersUsage)
+	}
+
+	switch args[0] {
+	case "list":
+		return listLedgers(ctx, w, args[1:])
+	case "create":
+		return createLedger(ctx, w, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], ledgersUsage)
+	}
+}
+
+type cliLedger struct {
+	UUID        string  `json:"uuid"`
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func listLedgers(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("ledgers list", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	metadata := metadataFlag{}
+	fs.Var(metadata, "metadata", "filter by metadata, KEY=VALUE, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// the API filters the ledgers by metadata only
+	if len(metadata) == 0 {
+		return errors.New("--metadata is required")
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	metadata.filter(query)
+	detail, err := client.do(ctx, "GET", "/ledgers", query, nil)
+
+	// the API answers 404 when nothing matches the filter
+	if isNotFound(err) {
+		detail, err = json.RawMessage(`[]`), nil
+	}
+	if err != nil {
+		return fmt.Errorf("list ledgers: %w", err)
+	}
+	if opts.output == outputJSON {
+		return printJSON(w, detail)
+	}
+
+	var ledgers []cliLedger
+	if err := json.Unmarshal(detail, &ledgers); err != nil {
+		return fmt.Errorf("decode ledgers: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tNAME\tDESCRIPTION")
+	for _, ledger := range ledgers {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", ledger.UUID, ledger.Name, orDash(ledger.Description))
+	}
+	return tw.Flush()
+}
+
+func createLedger(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("ledgers create", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	name := fs.String("name", "", "name of the ledger")
+	description := fs.String("description", "", "description of the ledger")
+	metadata := metadataFlag{}
+	fs.Var(metadata, "metadata", "metadata of the ledger, KEY=VALUE, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return errors.New("--name is required")
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	detail, err := client.do(ctx, "POST", "/ledgers", nil, map[string]any{
+		"name":        *name,
+		"description": *description,
+		"metadata":    metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("create ledger: %w", err)
+	}
+	if opts.output == outputJSON {
+		return printJSON(w, detail)
+	}
+
+	var ledger cliLedger
+	if err := json.Unmarshal(detail, &ledger); err != nil {
+		return fmt.Errorf("decode ledger: %w", err)
+	}
+	_, err = fmt.Fprintf(w, "uuid: %s\nname: %s\n", ledger.UUID, ledger.Name)
+	return err
+}
+
+// orDash prints nullable columns
+func orDash(s *string) string {
+	if s == nil || *s == "" {
+		return "-"
+	}
+	return *s
+}
@@ -2,106 +2,386 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
-	"github.com/charmbracelet/log"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/config"
 	"github.com/j0lvera/go-double-e/internal/db"
+	"github.com/j0lvera/go-double-e/internal/db/migrations"
+	"github.com/j0lvera/go-double-e/internal/events"
+	"github.com/j0lvera/go-double-e/internal/grpcserver"
+	"github.com/j0lvera/go-double-e/internal/logging"
+	"github.com/j0lvera/go-double-e/internal/metrics"
 	"github.com/j0lvera/go-double-e/internal/server"
+	"github.com/j0lvera/go-double-e/internal/tracing"
+	"github.com/j0lvera/go-double-e/internal/webhook"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"time"
 )
 
-func run(ctx context.Context, w io.Writer, port int) error {
-	dbUrl := os.Getenv("DATABASE_URL")
-
+// newPool connects to the database of the config
+func newPool(ctx context.Context, cfg config.Database) (*pgxpool.Pool, error) {
 	// create a pool configuration
-	config, err := pgxpool.ParseConfig(dbUrl)
+	poolConfig, err := pgxpool.ParseConfig(cfg.URL)
 	if err != nil {
-		return fmt.Errorf("error parsing database url: %w", err)
+		return nil, fmt.Errorf("error parsing database url: %w", err)
 	}
 
 	// pool manual configuration
-	config.MaxConns = 25                      // Maximum number of connections
-	config.MinConns = 5                       // Minimum number of connections
-	config.MaxConnLifetime = time.Hour        // Maximum lifetime of a connection
-	config.MaxConnIdleTime = 30 * time.Minute // Maximum idle time for a connection
-	config.HealthCheckPeriod = time.Minute    // How often to check connection health
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+
+	// scope the queries of each request to its tenant
+	db.ScopeToTenant(poolConfig)
+
+	// a span per query, they are dropped unless tracing is enabled
+	poolConfig.ConnConfig.Tracer = tracing.QueryTracer{}
 
 	// create the connection pool
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		return fmt.Errorf("error creating connection pool: %w", err)
+		return nil, fmt.Errorf("error creating connection pool: %w", err)
 	}
-	defer pool.Close()
 
 	slog.Info("Database connection pool created")
 
 	// verify the connection
 	if err := pool.Ping(ctx); err != nil {
-		return fmt.Errorf("error pinging connection pool: %w", err)
+		pool.Close()
+		return nil, fmt.Errorf("error pinging connection pool: %w", err)
+	}
+
+	return pool, nil
+}
+
+// newVerifier verifies the JWTs of the identity provider with the keys of
+// the JWKS URL or the key file, it's nil when neither is set and only API
+// keys are accepted
+func newVerifier(cfg config.JWT) (auth.TokenVerifier, error) {
+	var keys auth.KeySet
+	switch {
+	case cfg.JWKSURL != "":
+		keys = auth.NewRemoteKeys(cfg.JWKSURL, nil)
+	case cfg.KeyFile != "":
+		fileKeys, err := auth.LoadKeyFile(cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = fileKeys
+	default:
+		return nil, nil
+	}
+
+	slog.Info("JWT authentication enabled", "jwks_url", cfg.JWKSURL, "key_file", cfg.KeyFile, "issuer", cfg.Issuer, "audience", cfg.Audience)
+
+	verifier, err := auth.NewJWTVerifier(keys, auth.JWTConfig{
+		Issuer:      cfg.Issuer,
+		Audience:    cfg.Audience,
+		TenantClaim: cfg.TenantClaim,
+		EmailClaim:  cfg.EmailClaim,
+		ScopesClaim: cfg.ScopesClaim,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return verifier, nil
+}
+
+func run(ctx context.Context, w io.Writer, cfg *config.Config) error {
+	// the otlp exporter sends the spans to OTEL_EXPORTER_OTLP_ENDPOINT,
+	// stdout writes them to w
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing.Exporter, w)
+	if err != nil {
+		return fmt.Errorf("error configuring tracing: %w", err)
+	}
+	defer func() {
+		// ctx is done by now, flush with a context of its own
+		flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("Could not flush the traces", "error", err)
+		}
+	}()
+
+	pool, err := newPool(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	// instances starting together wait for the one holding the lock
+	if cfg.MigrateOnStart {
+		db := stdlib.OpenDBFromPool(pool)
+		err := migrations.Up(ctx, db)
+		_ = db.Close()
+		if err != nil {
+			return err
+		}
 	}
 
 	// initialize the client
 	client := db.NewClient(pool)
 
+	// wake up the event streams when ledgers change, on any instance
+	broker := events.NewBroker(client)
+	go broker.Run(ctx)
+
+	verifier, err := newVerifier(cfg.JWT)
+	if err != nil {
+		return fmt.Errorf("error configuring jwt verification: %w", err)
+	}
+
+	limits := server.RateLimits{
+		Read:   cfg.RateLimits.Read,
+		Write:  cfg.RateLimits.Write,
+		Export: cfg.RateLimits.Export,
+		Auth:   cfg.RateLimits.Auth,
+	}
+	slog.Info("Rate limits", "read", limits.Read, "write", limits.Write, "export", limits.Export, "auth", limits.Auth)
+	// the HTTP and gRPC servers share the buckets
+	limiters := limits.Limiters()
+
+	// the HTTP and gRPC servers count transactions in the same registry
+	m := metrics.New(pool)
+
 	// initialize the server
-	srv := server.NewServer(client)
+	srv := server.NewServer(client, broker, server.Options{
+		Verifier:             verifier,
+		RateLimiters:         limiters,
+		Metrics:              m,
+		MaxReplicationLag:    cfg.Ready.MaxReplicationLag,
+		AllowPrivateWebhooks: cfg.Webhooks.AllowPrivateAddresses,
+	})
+
+	// deliver the outbox events to the webhooks until ctx is done
+	dispatcher := webhook.NewDispatcher(webhook.NewPostgresStore(client.Queries))
+	if cfg.Webhooks.AllowPrivateAddresses {
+		slog.Warn("Webhooks can reach private addresses")
+		dispatcher.Client = &http.Client{Timeout: dispatcher.Client.Timeout}
+	}
+	go dispatcher.Run(ctx)
 
 	// start HTTP server
 	httpServer := &http.Server{
-		Addr:    fmt.Sprintf(":%d", port),
+		Addr:    fmt.Sprintf(":%d", cfg.Port),
 		Handler: srv,
 	}
 
 	go func() {
-		slog.Info("Server is listening", "port", port)
+		slog.Info("Server is listening", "port", cfg.Port)
 		if err := httpServer.ListenAndServe(); err != nil {
 			slog.Error("Could not start the server", "error", err)
 		}
 	}()
 
+	// serve the metrics apart from the API, they are of every tenant
+	var metricsServer *http.Server
+	if cfg.MetricsPort != 0 {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", m.Handler())
+		metricsServer = &http.Server{
+			Addr:    fmt.Sprintf(":%d", cfg.MetricsPort),
+			Handler: mux,
+		}
+
+		go func() {
+			slog.Info("Metrics server is listening", "port", cfg.MetricsPort)
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				slog.Error("Could not start the metrics server", "error", err)
+			}
+		}()
+	}
+
+	// start gRPC server, sharing the ledger service of the HTTP API
+	var grpcServer *grpcserver.Server
+	if cfg.GRPCPort != 0 {
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.GRPCPort))
+		if err != nil {
+			return fmt.Errorf("error listening for grpc: %w", err)
+		}
+
+		grpcServer = grpcserver.NewServer(client, grpcserver.Options{
+			Verifier:     verifier,
+			RateLimiters: limiters,
+			Metrics:      m,
+		})
+		go func() {
+			slog.Info("gRPC server is listening", "port", cfg.GRPCPort)
+			if err := grpcServer.Serve(lis); err != nil {
+				slog.Error("Could not start the gRPC server", "error", err)
+			}
+		}()
+	}
+
 	// wait to interrupt
 	<-ctx.Done()
 
+	// fail the readiness probe first, so the load balancer stops routing
+	// requests here before the server stops accepting them
+	srv.Drain()
+	slog.Info("Draining", "delay", cfg.Shutdown.Delay)
+	time.Sleep(cfg.Shutdown.Delay)
+
 	// gracefully shutdown the server
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout)
 	defer cancel()
 
+	if grpcServer != nil {
+		// GracefulStop waits for the streams, which may outlive the timeout
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+		defer func() {
+			select {
+			case <-stopped:
+			case <-shutdownCtx.Done():
+				grpcServer.Stop()
+			}
+		}()
+	}
+
 	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		return fmt.Errorf("server shutdown: %w", err)
 	}
 
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			return fmt.Errorf("metrics server shutdown: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func main() {
-	// set up logging
-	handler := log.NewWithOptions(os.Stderr, log.Options{
-		ReportCaller:    true,
-		ReportTimestamp: true,
-	})
-	if os.Getenv("DEBUG") == "true" {
-		handler.SetLevel(log.DebugLevel)
-	} else {
-		handler.SetLevel(log.InfoLevel)
+const configUsage = `usage:
+  doubleed config print [FLAGS]`
+
+// runConfig prints the configuration the server would run with, the
+// flags are the ones of the server
+func runConfig(w io.Writer, args []string) error {
+	if len(args) == 0 || args[0] != "print" {
+		return errors.New(configUsage)
 	}
-	slog.SetDefault(slog.New(handler))
 
-	// ensure DATABASE_URL is set
-	if os.Getenv("DATABASE_URL") == "" {
-		slog.Error("DATABASE_URL environment variable is required", "DATABASE_URL", os.Getenv("DATABASE_URL"))
-		os.Exit(1)
+	cfg, err := config.Load("doubleed config print", args[1:])
+	if err != nil {
+		return err
+	}
+	return cfg.Print(w)
+}
+
+// clientCommands talk to the API of a server with an API key, they don't
+// need the config of the server
+var clientCommands = map[string]func(context.Context, io.Writer, []string) error{
+	"ledgers":  runLedgers,
+	"accounts": runAccounts,
+	"tx":       runTx,
+	"report":   runReport,
+	"tui":      runTUI,
+}
+
+func main() {
+	// `doubleed serve` runs the server, the default without a command,
+	// tenants, users, keys and migrate manage its database, e.g.,
+	// `doubleed tenants create --name acme`, and ledgers, accounts, tx,
+	// report and tui talk to its API, e.g., `doubleed ledgers list`
+	var command string
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
 	}
 
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	if err := run(ctx, os.Stdout, 8080); err != nil {
-		slog.Error("Error running server", "error", err)
+	if runClient, ok := clientCommands[command]; ok {
+		err := runClient(ctx, os.Stdout, args)
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if command == "config" {
+		if err := runConfig(os.Stdout, args); err != nil {
+			slog.Error("Error printing the config", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// the arguments of the other commands are their own
+	serverArgs := args
+	if command != "" && command != "serve" {
+		serverArgs = nil
+	}
+	cfg, err := config.Load("doubleed", serverArgs)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		slog.Error("Error loading the config", "error", err)
+		os.Exit(1)
+	}
+
+	// set up logging, json for log pipelines
+	level := slog.LevelInfo
+	if cfg.Debug {
+		level = slog.LevelDebug
+	}
+	handler, err := logging.NewHandler(os.Stderr, cfg.LogFormat, level)
+	if err != nil {
+		slog.Error("Error setting up logging", "error", err)
+		os.Exit(1)
+	}
+	slog.SetDefault(slog.New(handler))
+
+	switch command {
+	case "", "serve":
+		if err := run(ctx, os.Stdout, cfg); err != nil {
+			slog.Error("Error running server", "error", err)
+			os.Exit(1)
+		}
+	case "tenants":
+		if err := runTenants(ctx, os.Stdout, cfg.Database, args); err != nil {
+			slog.Error("Error managing tenants", "error", err)
+			os.Exit(1)
+		}
+	case "users":
+		if err := runUsers(ctx, os.Stdout, cfg.Database, args); err != nil {
+			slog.Error("Error managing users", "error", err)
+			os.Exit(1)
+		}
+	case "keys":
+		if err := runKeys(ctx, os.Stdout, cfg.Database, args); err != nil {
+			slog.Error("Error managing API keys", "error", err)
+			os.Exit(1)
+		}
+	case "migrate":
+		if err := runMigrate(ctx, os.Stdout, cfg.Database, args); err != nil {
+			slog.Error("Error migrating the database", "error", err)
+			os.Exit(1)
+		}
+	default:
+		slog.Error("Unknown command", "command", command)
 		os.Exit(1)
 	}
 }
@@ -1,24 +1,87 @@
 package main
 
 import (
+	"bytes"
 	"context"
+	"crypto"
 	"encoding/json"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/config"
+	"github.com/j0lvera/go-double-e/internal/db"
 	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/grpcserver"
+	"github.com/j0lvera/go-double-e/internal/ledger"
 	"github.com/j0lvera/go-double-e/internal/server"
 	"github.com/j0lvera/go-double-e/internal/testutils"
+	"github.com/j0lvera/go-double-e/internal/webhook"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	is_ "github.com/matryer/is"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"runtime"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
-var testServer *testutils.TestServer
+var (
+	testServer *testutils.TestServer
+	// testTenant owns the data created through testClient
+	testTenant *dbGen.Tenant
+	// testClient sends a key with every scope of testTenant
+	testClient *http.Client
+	// testSigningKey signs the JWTs of the fake identity provider
+	testSigningKey crypto.Signer
+)
+
+// testRun runs the server with the config of the environment on port
+func testRun(ctx context.Context, w io.Writer, port int) error {
+	cfg, err := config.Load("doubleed", nil)
+	if err != nil {
+		return err
+	}
+	cfg.Port = port
+	// TestGRPC serves the gRPC API in process
+	cfg.GRPCPort = 0
+	cfg.MetricsPort = 0
+	// TestWebhookDelivery receives the deliveries on loopback
+	cfg.Webhooks.AllowPrivateAddresses = true
+	return run(ctx, w, cfg)
+}
 
 func init() {
 	t := &testing.T{}
-	testServer = testutils.SetupTestServerWithRun(t, run)
+
+	var err error
+	testSigningKey, err = testutils.SetupTestSigningKey()
+	if err != nil {
+		t.Fatalf("unable to setup signing key: %v", err)
+	}
+
+	testServer = testutils.SetupTestServerWithRun(t, testRun)
+
+	// every endpoint but the health check requires an API key
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+	testTenant, testClient, err = testutils.NewTestClient(context.Background(), testDb.Pool)
+	if err != nil {
+		t.Fatalf("unable to authenticate test client: %v", err)
+	}
 
 	// register cleanup to run at the program exit
 	runtime.SetFinalizer(testServer, func(ts *testutils.TestServer) {
@@ -54,6 +117,28 @@ func TestHealthCheck(t *testing.T) {
 	is.Equal(body, expected) // invalid response body
 }
 
+func TestReadiness(t *testing.T) {
+	is := is_.New(t)
+
+	resp, err := testClient.Get(testServer.BaseURL + "/readyz")
+	is.NoErr(err)
+	defer resp.Body.Close()
+
+	var res struct {
+		Status string `json:"status"`
+		Checks map[string]struct {
+			Status string `json:"status"`
+			Detail string `json:"detail"`
+		} `json:"checks"`
+	}
+	is.NoErr(json.NewDecoder(resp.Body).Decode(&res))
+
+	is.Equal(resp.StatusCode, http.StatusOK) // the database is migrated
+	is.Equal(res.Status, "ok")
+	is.Equal(res.Checks["database"].Status, "ok")
+	is.Equal(res.Checks["migrations"].Status, "ok")
+}
+
 func TestCreateLedger(t *testing.T) {
 	is := is_.New(t)
 
@@ -68,7 +153,7 @@ func TestCreateLedger(t *testing.T) {
 	reqBody := `{"name": "Test Ledger", "description": "This is a test ledger", "metadata": ` + metadata + `}`
 
 	t.Run("should return 201 for valid request", func(t *testing.T) {
-		resp, err := http.Post(apiUrl, "application/json", strings.NewReader(reqBody))
+		resp, err := testClient.Post(apiUrl, "application/json", strings.NewReader(reqBody))
 		if err != nil {
 			t.Fatalf("unable to make POST request: %v", err)
 		}
@@ -87,7 +172,7 @@ func TestCreateLedger(t *testing.T) {
 		t.Run("should return 400 for invalid metadata JSON", func(t *testing.T) {
 			// create a request with invalid metadata JSON
 			invalidBody := `{"name": "Test Ledger", "description": "This is a test ledger", "metadata": "invalid metadata"}`
-			resp, err := http.Post(apiUrl, "application/json", strings.NewReader(invalidBody))
+			resp, err := testClient.Post(apiUrl, "application/json", strings.NewReader(invalidBody))
 			if err != nil {
 				t.Fatalf("unable to make POST request: %v", err)
 			}
@@ -105,7 +190,7 @@ func TestCreateLedger(t *testing.T) {
 		t.Run("should return 400 for invalid metadata JSON", func(t *testing.T) {
 			// create a request without name
 			invalidBody := `{"description": "This is a test ledger", "metadata": "{"user_id": 24}"}`
-			resp, err := http.Post(apiUrl, "application/json", strings.NewReader(invalidBody))
+			resp, err := testClient.Post(apiUrl, "application/json", strings.NewReader(invalidBody))
 			if err != nil {
 				t.Fatalf("unable to make POST request: %v", err)
 			}
@@ -124,7 +209,7 @@ func TestCreateLedger(t *testing.T) {
 	t.Run("should create ledger in database", func(t *testing.T) {
 		// check if ledger was created in the database
 		var ledgerExists bool
-		err = testDb.Pool.QueryRow(context.Background(), "SELECT EXISTS(SELECT 1 FROM ledgers WHERE name = $1)", "Test Ledger").Scan(&ledgerExists)
+		err = testDb.Pool.QueryRow(db.WithTenant(context.Background(), testTenant.ID), "SELECT EXISTS(SELECT 1 FROM ledgers WHERE name = $1)", "Test Ledger").Scan(&ledgerExists)
 		if err != nil {
 			t.Fatalf("unable to query database: %v", err)
 		}
@@ -144,7 +229,7 @@ func TestListLedgers(t *testing.T) {
 	}
 
 	// reset database before running tests
-	err = testutils.ResetTestData(context.Background(), testDb.Pool)
+	err = testutils.ResetTestData(context.Background(), testDb.AdminPool)
 	if err != nil {
 		t.Fatalf("unable to reset test data: %v", err)
 	}
@@ -162,11 +247,12 @@ func TestListLedgers(t *testing.T) {
 	for _, data := range testData {
 		var ledger dbGen.Ledger
 		err = testDb.Pool.QueryRow(
-			context.Background(),
-			"INSERT INTO ledgers (name, description, metadata) VALUES ($1, $2, $3) RETURNING id, uuid, created_at, updated_at, name, description, metadata",
+			db.WithTenant(context.Background(), testTenant.ID),
+			"INSERT INTO ledgers (name, description, metadata, tenant_id) VALUES ($1, $2, $3, $4) RETURNING id, uuid, created_at, updated_at, name, description, metadata",
 			data.name,
 			data.description,
 			data.metadata,
+			testTenant.ID,
 		).Scan(&ledger.ID, &ledger.Uuid, &ledger.CreatedAt, &ledger.UpdatedAt, &ledger.Name, &ledger.Description, &ledger.Metadata)
 		if err != nil {
 			t.Fatalf("unable to insert test ledger: %v", err)
@@ -180,7 +266,7 @@ func TestListLedgers(t *testing.T) {
 		validQueryParams.Add("metadata.user_id", "24")
 
 		requestURL := apiUrl + "?" + validQueryParams.Encode()
-		resp, err := http.Get(requestURL)
+		resp, err := testClient.Get(requestURL)
 		if err != nil {
 			t.Fatalf("unable to make GET request: %v", err)
 		}
@@ -209,7 +295,7 @@ func TestListLedgers(t *testing.T) {
 		emptyResultsQueryParams := url.Values{}
 		emptyResultsQueryParams.Add("metadata.user_id", "25")
 		requestURL := apiUrl + "?" + emptyResultsQueryParams.Encode()
-		resp, err := http.Get(requestURL)
+		resp, err := testClient.Get(requestURL)
 		if err != nil {
 			t.Fatalf("unable to make GET request: %v", err)
 		}
@@ -225,7 +311,7 @@ func TestListLedgers(t *testing.T) {
 	})
 
 	t.Run("should return bad request for missing param", func(t *testing.T) {
-		resp, err := http.Get(apiUrl)
+		resp, err := testClient.Get(apiUrl)
 		if err != nil {
 			t.Fatalf("unable to make GET request: %v", err)
 		}
@@ -239,3 +325,1200 @@ func TestListLedgers(t *testing.T) {
 		is.Equal(resp.StatusCode, http.StatusBadRequest) // invalid status code
 	})
 }
+
+func TestImportLedger(t *testing.T) {
+	is := is_.New(t)
+	apiUrl := testServer.BaseURL + "/ledgers/import"
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	journal := `option "title" "Imported Books"
+
+2024-01-01 open Assets:Checking USD
+2024-01-01 open Expenses:Food
+
+2024-01-05 * "Grocer" "Weekly groceries"
+  Expenses:Food      42.50 USD
+  Assets:Checking   -42.50 USD
+`
+
+	t.Run("should return 201 for a valid journal", func(t *testing.T) {
+		resp, err := testClient.Post(apiUrl+"?format=beancount", "text/plain", strings.NewReader(journal))
+		if err != nil {
+			t.Fatalf("unable to make POST request: %v", err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to close response body: %v", err)
+			}
+		}()
+
+		is.Equal(resp.StatusCode, http.StatusCreated) // invalid status code
+
+		var amount int64
+		err = testDb.Pool.QueryRow(
+			db.WithTenant(context.Background(), testTenant.ID),
+			`select t.amount
+			   from transactions t
+			   join ledgers l on l.id = t.ledger_id
+			  where l.name = $1`,
+			"Imported Books",
+		).Scan(&amount)
+		if err != nil {
+			t.Fatalf("unable to query database: %v", err)
+		}
+
+		is.Equal(amount, int64(4250)) // invalid imported amount
+
+		var scale int
+		var commodities string
+		err = testDb.Pool.QueryRow(
+			db.WithTenant(context.Background(), testTenant.ID),
+			`select (metadata->'journal'->>'scale')::int, metadata->'journal'->>'commodities'
+			   from ledgers
+			  where name = $1`,
+			"Imported Books",
+		).Scan(&scale, &commodities)
+		if err != nil {
+			t.Fatalf("unable to query database: %v", err)
+		}
+
+		is.Equal(scale, 2)               // scale not kept
+		is.Equal(commodities, `["USD"]`) // commodities not kept
+	})
+
+	t.Run("should return 400 for an unsupported directive", func(t *testing.T) {
+		invalidJournal := "2024-01-01 pad Assets:Checking Equity:Opening\n"
+		resp, err := testClient.Post(apiUrl+"?format=beancount", "text/plain", strings.NewReader(invalidJournal))
+		if err != nil {
+			t.Fatalf("unable to make POST request: %v", err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to close response body: %v", err)
+			}
+		}()
+
+		is.Equal(resp.StatusCode, http.StatusBadRequest) // invalid status code
+
+		body, err := testutils.ReadResponseBody(t, resp)
+		if err != nil {
+			t.Fatalf("unable to read response body: %v", err)
+		}
+		is.True(strings.Contains(body, `"Line":1`)) // missing line number
+	})
+}
+
+func TestAuditEvents(t *testing.T) {
+	is := is_.New(t)
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		testServer.BaseURL+"/ledgers",
+		strings.NewReader(`{"name": "Audited Ledger"}`),
+	)
+	if err != nil {
+		t.Fatalf("unable to create request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Request-ID", "req-audit-1")
+
+	resp, err := testClient.Do(req)
+	if err != nil {
+		t.Fatalf("unable to make POST request: %v", err)
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			t.Fatalf("unable to close response body: %v", err)
+		}
+	}()
+
+	is.Equal(resp.StatusCode, http.StatusCreated)            // invalid status code
+	is.Equal(resp.Header.Get("X-Request-ID"), "req-audit-1") // request id not propagated
+
+	t.Run("should record the creation", func(t *testing.T) {
+		var actor, action, requestID string
+		err = testDb.Pool.QueryRow(
+			db.WithTenant(context.Background(), testTenant.ID),
+			`select e.actor, e.action, e.request_id
+			   from audit_events e
+			   join ledgers l on l.uuid = e.entity_uuid
+			  where e.entity_type = 'ledger'
+			    and l.name = $1`,
+			"Audited Ledger",
+		).Scan(&actor, &action, &requestID)
+		if err != nil {
+			t.Fatalf("unable to query database: %v", err)
+		}
+
+		is.True(strings.HasPrefix(actor, "api_key:")) // invalid actor
+		is.Equal(action, "create")                    // invalid action
+		is.Equal(requestID, "req-audit-1")            // invalid request id
+	})
+
+	t.Run("should reject changes to audit events", func(t *testing.T) {
+		_, err := testDb.AdminPool.Exec(context.Background(), "delete from audit_events")
+		is.True(err != nil) // audit events must be append-only
+	})
+}
+
+func TestAuthentication(t *testing.T) {
+	is := is_.New(t)
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	readKey, err := testutils.CreateTestAPIKey(context.Background(), testDb.Pool, testTenant.Uuid, "ledgers:read")
+	if err != nil {
+		t.Fatalf("unable to create api key: %v", err)
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		authorization string
+		status        int
+	}{
+		{"should return 401 without a key", http.MethodGet, "", http.StatusUnauthorized},
+		{"should return 401 for an unknown key", http.MethodGet, "Bearer dde_00000000_nope", http.StatusUnauthorized},
+		{"should return 200 for a granted scope", http.MethodGet, "Bearer " + readKey, http.StatusOK},
+		{"should return 403 for a missing scope", http.MethodPost, "Bearer " + readKey, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, testServer.BaseURL+"/ledgers", strings.NewReader(`{"name": "Forbidden"}`))
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			// the default client doesn't send a key
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to make request: %v", err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatalf("unable to close response body: %v", err)
+				}
+			}()
+
+			is.Equal(resp.StatusCode, tt.status) // invalid status code
+		})
+	}
+}
+
+func TestJWTAuthentication(t *testing.T) {
+	is := is_.New(t)
+
+	sign := func(edit func(map[string]any)) string {
+		claims := map[string]any{
+			"sub":    "sso|staff",
+			"iss":    testutils.TestIssuer,
+			"aud":    testutils.TestAudience,
+			"exp":    time.Now().Add(time.Hour).Unix(),
+			"tenant": testTenant.Uuid,
+			"email":  "sso-staff@example.com",
+		}
+		edit(claims)
+		token, err := auth.SignToken(testSigningKey, "test", claims)
+		if err != nil {
+			t.Fatalf("unable to sign token: %v", err)
+		}
+		return "Bearer " + token
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		status        int
+	}{
+		{"should return 401 for an expired token", http.MethodGet, "/ledgers",
+			sign(func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }), http.StatusUnauthorized},
+		{"should return 401 for another issuer", http.MethodGet, "/ledgers",
+			sign(func(c map[string]any) { c["iss"] = "https://evil.example.com" }), http.StatusUnauthorized},
+		{"should return 401 for another audience", http.MethodGet, "/ledgers",
+			sign(func(c map[string]any) { c["aud"] = "other" }), http.StatusUnauthorized},
+		{"should return 401 for an unknown tenant", http.MethodGet, "/ledgers",
+			sign(func(c map[string]any) { c["tenant"] = "nope" }), http.StatusUnauthorized},
+		{"should return 401 for a forged token", http.MethodGet, "/ledgers",
+			sign(func(map[string]any) {}) + "x", http.StatusUnauthorized},
+		{"should sign in the user of a valid token", http.MethodGet, "/ledgers",
+			sign(func(map[string]any) {}), http.StatusOK},
+		{"should make the user owner of its ledgers", http.MethodPost, "/ledgers",
+			sign(func(map[string]any) {}), http.StatusCreated},
+		{"should limit the user to its scopes", http.MethodPost, "/ledgers",
+			sign(func(c map[string]any) { c["scope"] = "ledgers:read" }), http.StatusForbidden},
+		{"should keep the user out of tenant routes", http.MethodGet, "/webhooks",
+			sign(func(map[string]any) {}), http.StatusForbidden},
+		{"should not take over the user of another subject", http.MethodGet, "/ledgers",
+			sign(func(c map[string]any) { c["sub"] = "sso|impostor" }), http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, testServer.BaseURL+tt.path, strings.NewReader(`{"name": "SSO Books"}`))
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			req.Header.Set("Authorization", tt.authorization)
+
+			// the default client doesn't send a key
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to make request: %v", err)
+			}
+			defer func() {
+				err := resp.Body.Close()
+				if err != nil {
+					t.Fatalf("unable to close response body: %v", err)
+				}
+			}()
+
+			is.Equal(resp.StatusCode, tt.status) // invalid status code
+		})
+	}
+
+	t.Run("should keep the user when its email changes", func(t *testing.T) {
+		type createdDetail struct {
+			UUID string `json:"uuid"`
+		}
+
+		do := func(method, path, authorization string) (int, string) {
+			req, err := http.NewRequest(method, testServer.BaseURL+path, strings.NewReader(`{"name": "SSO Books"}`))
+			if err != nil {
+				t.Fatalf("unable to create request: %v", err)
+			}
+			req.Header.Set("Authorization", authorization)
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unable to make request: %v", err)
+			}
+			defer func() { _ = resp.Body.Close() }()
+
+			var detail createdDetail
+			_ = json.NewDecoder(resp.Body).Decode(&server.StandardResponse{Detail: &detail})
+			return resp.StatusCode, detail.UUID
+		}
+
+		status, ledger := do(http.MethodPost, "/ledgers", sign(func(map[string]any) {}))
+		is.Equal(status, http.StatusCreated)
+
+		status, _ = do(http.MethodGet, "/ledgers/"+ledger+"/grants", sign(func(c map[string]any) { c["email"] = "sso-staff@example.org" }))
+		is.Equal(status, http.StatusOK) // the user lost its ledgers
+	})
+}
+
+func TestTenantIsolation(t *testing.T) {
+	is := is_.New(t)
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	other, err := testutils.CreateTestTenant(context.Background(), testDb.Pool, "other")
+	if err != nil {
+		t.Fatalf("unable to create tenant: %v", err)
+	}
+	otherKey, err := testutils.CreateTestAPIKey(context.Background(), testDb.Pool, other.Uuid, "*")
+	if err != nil {
+		t.Fatalf("unable to create api key: %v", err)
+	}
+
+	type createdDetail struct {
+		UUID string `json:"uuid"`
+	}
+
+	// create makes the request with the key, or the test tenant's key when
+	// empty, and returns the status code and the uuid created
+	create := func(key, path, body string) (int, string) {
+		req, err := http.NewRequest(http.MethodPost, testServer.BaseURL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+
+		resp, err := testClient.Do(req)
+		if err != nil {
+			t.Fatalf("unable to make POST request: %v", err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to close response body: %v", err)
+			}
+		}()
+
+		var detail createdDetail
+		_ = json.NewDecoder(resp.Body).Decode(&server.StandardResponse{Detail: &detail})
+		return resp.StatusCode, detail.UUID
+	}
+
+	// the other tenant's books
+	status, otherLedger := create(otherKey, "/ledgers", `{"name": "Other Books"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+	status, otherAccount := create(otherKey, "/accounts", `{"name": "Other Cash", "type": "asset", "ledger_uuid": "`+otherLedger+`"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+
+	// the test tenant's books
+	status, ledger := create("", "/ledgers", `{"name": "Own Books"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+	status, account := create("", "/accounts", `{"name": "Own Cash", "type": "asset", "ledger_uuid": "`+ledger+`"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+
+	t.Run("should not list ledgers of other tenants", func(t *testing.T) {
+		resp, err := testClient.Get(testServer.BaseURL + "/ledgers")
+		if err != nil {
+			t.Fatalf("unable to make GET request: %v", err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to close response body: %v", err)
+			}
+		}()
+
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("unable to read response body: %v", err)
+		}
+		is.True(!strings.Contains(string(body), otherLedger)) // other tenant's ledger listed
+	})
+
+	t.Run("should not add accounts to ledgers of other tenants", func(t *testing.T) {
+		status, _ := create("", "/accounts", `{"name": "Intruder", "type": "asset", "ledger_uuid": "`+otherLedger+`"}`)
+		is.Equal(status, http.StatusBadRequest) // invalid status code
+	})
+
+	t.Run("should not link accounts of other tenants", func(t *testing.T) {
+		status, _ := create("", "/transactions", `{
+			"amount": 100,
+			"date": "2024-11-24T00:00:00Z",
+			"credit_account_uuid": "`+otherAccount+`",
+			"debit_account_uuid": "`+account+`",
+			"ledger_uuid": "`+ledger+`"
+		}`)
+		is.Equal(status, http.StatusBadRequest) // invalid status code
+	})
+
+	t.Run("should not read accounts of other tenants", func(t *testing.T) {
+		resp, err := testClient.Get(testServer.BaseURL + "/accounts/" + otherAccount + "/statement")
+		if err != nil {
+			t.Fatalf("unable to make GET request: %v", err)
+		}
+		_ = resp.Body.Close()
+		is.Equal(resp.StatusCode, http.StatusNotFound) // invalid status code
+	})
+
+	// the row level security policies hold even if a query forgets to
+	// filter by tenant
+	status, otherBank := create(otherKey, "/accounts", `{"name": "Other Bank", "type": "asset", "ledger_uuid": "`+otherLedger+`"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+	status, otherTransaction := create(otherKey, "/transactions", `{
+		"amount": 100,
+		"date": "2024-11-24T00:00:00Z",
+		"credit_account_uuid": "`+otherBank+`",
+		"debit_account_uuid": "`+otherAccount+`",
+		"ledger_uuid": "`+otherLedger+`"
+	}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+
+	ownCtx := db.WithTenant(context.Background(), testTenant.ID)
+	otherCtx := db.WithTenant(context.Background(), other.ID)
+
+	tables := []struct {
+		name  string
+		uuid  string
+		count string
+		edit  string
+	}{
+		{"ledgers", otherLedger, "select count(*) from ledgers where uuid = $1", "update ledgers set name = 'Taken' where uuid = $1"},
+		{"accounts", otherAccount, "select count(*) from accounts where uuid = $1", "update accounts set name = 'Taken' where uuid = $1"},
+		{"transactions", otherTransaction, "select count(*) from transactions where uuid = $1", "update transactions set amount = 1 where uuid = $1"},
+		{"audit events", otherLedger, "select count(*) from audit_events where entity_uuid = $1", ""},
+	}
+
+	for _, table := range tables {
+		t.Run("should not let the database role read the "+table.name+" of other tenants", func(t *testing.T) {
+			var count int
+			is.NoErr(testDb.Pool.QueryRow(otherCtx, table.count, table.uuid).Scan(&count))
+			is.True(count > 0) // the other tenant can't read its own rows
+
+			is.NoErr(testDb.Pool.QueryRow(ownCtx, table.count, table.uuid).Scan(&count))
+			is.Equal(count, 0) // other tenant's rows visible
+		})
+
+		if table.edit == "" {
+			continue
+		}
+		t.Run("should not let the database role update the "+table.name+" of other tenants", func(t *testing.T) {
+			tag, err := testDb.Pool.Exec(ownCtx, table.edit, table.uuid)
+			is.NoErr(err)
+			is.Equal(tag.RowsAffected(), int64(0)) // other tenant's rows updated
+		})
+	}
+
+	t.Run("should not let the database role list the ledgers of other tenants", func(t *testing.T) {
+		var count int
+		is.NoErr(testDb.Pool.QueryRow(ownCtx, "select count(*) from ledgers where tenant_id = $1", other.ID).Scan(&count))
+		is.Equal(count, 0) // other tenant's ledgers listed
+
+		is.NoErr(testDb.Pool.QueryRow(ownCtx, `
+			select count(*)
+			  from accounts a
+			  join ledgers l on l.id = a.ledger_id
+			 where l.tenant_id = $1`, other.ID).Scan(&count))
+		is.Equal(count, 0) // other tenant's accounts listed
+	})
+
+	t.Run("should not let the database role write to other tenants", func(t *testing.T) {
+		_, err := testDb.Pool.Exec(ownCtx, "insert into ledgers (name, tenant_id) values ('Planted', $1)", other.ID)
+		is.True(err != nil) // ledger planted in another tenant
+	})
+
+	t.Run("should not let the database role read anything without a tenant", func(t *testing.T) {
+		var count int
+		is.NoErr(testDb.Pool.QueryRow(context.Background(), "select count(*) from ledgers").Scan(&count))
+		is.Equal(count, 0) // ledgers visible without a tenant
+	})
+}
+
+func TestLedgerRoles(t *testing.T) {
+	is := is_.New(t)
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	user, err := testutils.CreateTestUser(context.Background(), testDb.Pool, testTenant.Uuid, "staff@example.com")
+	if err != nil {
+		t.Fatalf("unable to create user: %v", err)
+	}
+	userKey, err := testutils.CreateTestUserAPIKey(context.Background(), testDb.Pool, testTenant.Uuid, user.Uuid, "*")
+	if err != nil {
+		t.Fatalf("unable to create api key: %v", err)
+	}
+
+	type createdDetail struct {
+		UUID string `json:"uuid"`
+	}
+
+	// do makes the request with the key, or the test tenant's key when
+	// empty, and returns the status code and the uuid in the response
+	do := func(key, method, path, body string) (int, string) {
+		req, err := http.NewRequest(method, testServer.BaseURL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+
+		resp, err := testClient.Do(req)
+		if err != nil {
+			t.Fatalf("unable to make %s request: %v", method, err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to close response body: %v", err)
+			}
+		}()
+
+		var detail createdDetail
+		_ = json.NewDecoder(resp.Body).Decode(&server.StandardResponse{Detail: &detail})
+		return resp.StatusCode, detail.UUID
+	}
+
+	status, ledger := do("", http.MethodPost, "/ledgers", `{"name": "Client Books"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+	status, cash := do("", http.MethodPost, "/accounts", `{"name": "Cash", "type": "asset", "ledger_uuid": "`+ledger+`"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+	status, revenue := do("", http.MethodPost, "/accounts", `{"name": "Sales", "type": "revenue", "ledger_uuid": "`+ledger+`"}`)
+	is.Equal(status, http.StatusCreated) // invalid status code
+
+	transaction := `{
+		"amount": 100,
+		"date": "2024-11-26T00:00:00Z",
+		"credit_account_uuid": "` + revenue + `",
+		"debit_account_uuid": "` + cash + `",
+		"ledger_uuid": "` + ledger + `"
+	}`
+
+	t.Run("should hide ledgers without a role", func(t *testing.T) {
+		status, _ := do(userKey, http.MethodGet, "/ledgers/"+ledger+"/reports/trial-balance", "")
+		is.Equal(status, http.StatusNotFound) // invalid status code
+	})
+
+	t.Run("should let viewers only read reports", func(t *testing.T) {
+		status, _ := do("", http.MethodPut, "/ledgers/"+ledger+"/grants/"+user.Uuid, `{"role": "viewer"}`)
+		is.Equal(status, http.StatusOK) // invalid status code
+
+		status, _ = do(userKey, http.MethodGet, "/ledgers/"+ledger+"/reports/trial-balance", "")
+		is.Equal(status, http.StatusOK) // invalid status code
+		status, _ = do(userKey, http.MethodGet, "/transactions?ledger_uuid="+ledger, "")
+		is.Equal(status, http.StatusForbidden) // invalid status code
+		status, _ = do(userKey, http.MethodPost, "/transactions", transaction)
+		is.Equal(status, http.StatusForbidden) // invalid status code
+	})
+
+	t.Run("should let accountants create transactions", func(t *testing.T) {
+		status, _ := do("", http.MethodPut, "/ledgers/"+ledger+"/grants/"+user.Uuid, `{"role": "accountant"}`)
+		is.Equal(status, http.StatusOK) // invalid status code
+
+		status, _ = do(userKey, http.MethodPost, "/transactions", transaction)
+		is.Equal(status, http.StatusCreated) // invalid status code
+		status, _ = do(userKey, http.MethodGet, "/audit?ledger_uuid="+ledger, "")
+		is.Equal(status, http.StatusForbidden) // invalid status code
+		status, _ = do(userKey, http.MethodPut, "/ledgers/"+ledger+"/grants/"+user.Uuid, `{"role": "owner"}`)
+		is.Equal(status, http.StatusForbidden) // invalid status code
+	})
+
+	t.Run("should check the ledger a transaction moves to", func(t *testing.T) {
+		status, other := do("", http.MethodPost, "/ledgers", `{"name": "Other Books"}`)
+		is.Equal(status, http.StatusCreated) // invalid status code
+		status, posted := do(userKey, http.MethodPost, "/transactions", transaction)
+		is.Equal(status, http.StatusCreated) // invalid status code
+
+		// the body is decoded ignoring the case of the keys
+		status, _ = do(userKey, http.MethodPatch, "/transactions/"+posted, `{"LEDGER_UUID": "`+other+`"}`)
+		is.Equal(status, http.StatusNotFound) // no role on the target ledger
+		status, _ = do(userKey, http.MethodPatch, "/transactions/"+posted, `{"ledger_uuid": "`+other+`"}`)
+		is.Equal(status, http.StatusNotFound) // no role on the target ledger
+	})
+
+	t.Run("should let auditors read the audit trail", func(t *testing.T) {
+		status, _ := do("", http.MethodPut, "/ledgers/"+ledger+"/grants/"+user.Uuid, `{"role": "auditor"}`)
+		is.Equal(status, http.StatusOK) // invalid status code
+
+		status, _ = do(userKey, http.MethodGet, "/audit?ledger_uuid="+ledger, "")
+		is.Equal(status, http.StatusOK) // invalid status code
+		status, _ = do(userKey, http.MethodGet, "/audit", "")
+		is.Equal(status, http.StatusBadRequest) // invalid status code
+	})
+
+	t.Run("should make users owners of the ledgers they create", func(t *testing.T) {
+		status, own := do(userKey, http.MethodPost, "/ledgers", `{"name": "Own Books"}`)
+		is.Equal(status, http.StatusCreated) // invalid status code
+		status, _ = do(userKey, http.MethodGet, "/ledgers/"+own+"/grants", "")
+		is.Equal(status, http.StatusOK) // invalid status code
+
+		// the creator is the only owner
+		status, _ = do("", http.MethodPut, "/ledgers/"+own+"/grants/"+user.Uuid, `{"role": "viewer"}`)
+		is.Equal(status, http.StatusConflict) // the last owner was demoted
+		status, _ = do("", http.MethodDelete, "/ledgers/"+own+"/grants/"+user.Uuid, "")
+		is.Equal(status, http.StatusConflict) // the last owner was removed
+	})
+
+	t.Run("should record grant changes", func(t *testing.T) {
+		var actions []string
+		rows, err := testDb.Pool.Query(
+			db.WithTenant(context.Background(), testTenant.ID),
+			`select e.action
+			   from audit_events e
+			  where e.entity_type = 'grant'
+			    and e.after ->> 'ledger_uuid' = $1
+			  order by e.id`,
+			ledger,
+		)
+		is.NoErr(err)
+		for rows.Next() {
+			var action string
+			is.NoErr(rows.Scan(&action))
+			actions = append(actions, action)
+		}
+		is.NoErr(rows.Err())
+		is.Equal(actions, []string{"create", "update", "update"}) // invalid audit events
+
+		var published int
+		err = testDb.Pool.QueryRow(
+			db.WithTenant(context.Background(), testTenant.ID),
+			"select count(*) from outbox_events where ledger_uuid = $1 and entity_type = 'grant'",
+			ledger,
+		).Scan(&published)
+		is.NoErr(err)
+		is.Equal(published, 3) // invalid outbox events
+	})
+
+	t.Run("should keep users out of tenant routes", func(t *testing.T) {
+		status, _ := do(userKey, http.MethodGet, "/webhooks", "")
+		is.Equal(status, http.StatusForbidden) // invalid status code
+	})
+}
+
+func TestRestoreReplace(t *testing.T) {
+	is := is_.New(t)
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	user, err := testutils.CreateTestUser(context.Background(), testDb.Pool, testTenant.Uuid, "restore@example.com")
+	if err != nil {
+		t.Fatalf("unable to create user: %v", err)
+	}
+
+	// do makes the request and decodes the response into detail
+	do := func(method, path string, body io.Reader, detail any) int {
+		req, err := http.NewRequest(method, testServer.BaseURL+path, body)
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := testClient.Do(req)
+		if err != nil {
+			t.Fatalf("unable to make %s request: %v", method, err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to close response body: %v", err)
+			}
+		}()
+
+		if detail != nil {
+			_ = json.NewDecoder(resp.Body).Decode(detail)
+		}
+		return resp.StatusCode
+	}
+
+	var created server.CreateLedgerResponse
+	status := do(http.MethodPost, "/ledgers", strings.NewReader(`{"name": "Restored Books"}`), &server.StandardResponse{Detail: &created})
+	is.Equal(status, http.StatusCreated) // invalid status code
+	status = do(http.MethodPut, "/ledgers/"+created.UUID+"/grants/"+user.Uuid, strings.NewReader(`{"role": "accountant"}`), nil)
+	is.Equal(status, http.StatusOK) // invalid status code
+
+	var dump json.RawMessage
+	status = do(http.MethodGet, "/ledgers/"+created.UUID+"/dump", nil, &dump)
+	is.Equal(status, http.StatusOK) // invalid status code
+
+	status = do(http.MethodPost, "/ledgers/restore?replace=true", bytes.NewReader(dump), nil)
+	is.Equal(status, http.StatusCreated) // invalid status code
+
+	var grants []server.GrantResponse
+	status = do(http.MethodGet, "/ledgers/"+created.UUID+"/grants", nil, &server.StandardResponse{Detail: &grants})
+	is.Equal(status, http.StatusOK)                              // invalid status code
+	is.Equal(len(grants), 1)                                     // the grants were lost
+	is.Equal(grants[0].UserUUID, user.Uuid)                      // invalid grant
+	is.Equal(grants[0].Role, string(dbGen.LedgerRoleAccountant)) // invalid role
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	is := is_.New(t)
+
+	type delivery struct {
+		event     webhook.Event
+		signature string
+		body      []byte
+	}
+	received := make(chan delivery, 1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var event webhook.Event
+		_ = json.Unmarshal(body, &event)
+		received <- delivery{event: event, signature: r.Header.Get(webhook.SignatureHeader), body: body}
+	}))
+	defer receiver.Close()
+
+	// register the webhook
+	reqBody := `{"url": "` + receiver.URL + `", "event_types": ["ledger.created"]}`
+	resp, err := testClient.Post(testServer.BaseURL+"/webhooks", "application/json", strings.NewReader(reqBody))
+	if err != nil {
+		t.Fatalf("unable to make POST request: %v", err)
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			t.Fatalf("unable to close response body: %v", err)
+		}
+	}()
+	is.Equal(resp.StatusCode, http.StatusCreated) // invalid status code
+
+	var created server.StandardResponse
+	created.Detail = &server.WebhookResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("unable to decode response body: %v", err)
+	}
+	secret := created.Detail.(*server.WebhookResponse).Secret
+
+	// trigger an event
+	ledgerResp, err := testClient.Post(testServer.BaseURL+"/ledgers", "application/json", strings.NewReader(`{"name": "Webhook Ledger"}`))
+	if err != nil {
+		t.Fatalf("unable to make POST request: %v", err)
+	}
+	_ = ledgerResp.Body.Close()
+	is.Equal(ledgerResp.StatusCode, http.StatusCreated) // invalid status code
+
+	select {
+	case d := <-received:
+		is.Equal(d.event.Type, "ledger.created")                           // invalid event type
+		is.NoErr(webhook.Verify(secret, d.signature, d.body, time.Minute)) // invalid signature
+	case <-time.After(15 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+}
+
+func TestLedgerEventOrder(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	testDb, err := testutils.GetTestDB(ctx)
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	ledgerUUID := "event-order-" + time.Now().Format(time.RFC3339Nano)
+
+	// insert writes an event of the ledger in tx
+	insert := func(tx pgx.Tx) int64 {
+		var id int64
+		err := tx.QueryRow(ctx, `
+			insert into outbox_events (event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id)
+			values ('ledger.updated', 'ledger', $1, '{}', $1, $2)
+			returning id`, ledgerUUID, testTenant.ID).Scan(&id)
+		if err != nil {
+			t.Fatalf("unable to insert event: %v", err)
+		}
+		return id
+	}
+
+	// list returns the ids of the events the streams can send after afterID,
+	// and whether some are held back
+	list := func(afterID int64, includePending bool) ([]int64, bool) {
+		tx, err := testDb.Pool.Begin(ctx)
+		if err != nil {
+			t.Fatalf("unable to begin transaction: %v", err)
+		}
+		defer func() { _ = tx.Rollback(ctx) }()
+		if _, err := tx.Exec(ctx, "select set_config('app.tenant_id', $1, true)", strconv.FormatInt(testTenant.ID, 10)); err != nil {
+			t.Fatalf("unable to set tenant: %v", err)
+		}
+
+		q := dbGen.New(tx)
+		events, err := q.ListLedgerEvents(ctx, dbGen.ListLedgerEventsParams{
+			LedgerUuid:     ledgerUUID,
+			IncludePending: includePending,
+			AfterID:        afterID,
+			Limit:          10,
+		})
+		if err != nil {
+			t.Fatalf("unable to list events: %v", err)
+		}
+		pending, err := q.HasPendingLedgerEvents(ctx, dbGen.HasPendingLedgerEventsParams{LedgerUuid: ledgerUUID, AfterID: afterID})
+		if err != nil {
+			t.Fatalf("unable to check pending events: %v", err)
+		}
+
+		ids := make([]int64, 0, len(events))
+		for _, event := range events {
+			ids = append(ids, event.ID)
+		}
+		return ids, pending
+	}
+
+	// the first event is written by a transaction that commits last
+	slow, err := testDb.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("unable to begin transaction: %v", err)
+	}
+	defer func() { _ = slow.Rollback(ctx) }()
+	first := insert(slow)
+
+	fast, err := testDb.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("unable to begin transaction: %v", err)
+	}
+	second := insert(fast)
+	is.NoErr(fast.Commit(ctx))
+	is.True(second > first)
+
+	t.Run("should hold back events behind running transactions", func(t *testing.T) {
+		ids, pending := list(0, false)
+		is.Equal(len(ids), 0) // sent before the first event commits
+		is.True(pending)      // the second event isn't pending
+	})
+
+	t.Run("should send the held back events when asked", func(t *testing.T) {
+		ids, _ := list(0, true)
+		is.Equal(ids, []int64{second}) // invalid events, the first one isn't committed
+		_, pending := list(second, false)
+		is.True(!pending) // the events after the last one sent are pending
+	})
+
+	is.NoErr(slow.Commit(ctx))
+
+	t.Run("should send the events once the transactions before them finish", func(t *testing.T) {
+		ids, pending := list(0, false)
+		is.Equal(ids, []int64{first, second}) // invalid events
+		is.True(!pending)                     // events still pending
+	})
+
+	t.Run("should resume after the last event sent", func(t *testing.T) {
+		ids, _ := list(first, false)
+		is.Equal(ids, []int64{second}) // invalid events
+		ids, _ = list(second, false)
+		is.Equal(len(ids), 0) // events sent twice
+	})
+}
+
+func TestTransactionEventBalances(t *testing.T) {
+	is := is_.New(t)
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+	ctx := db.WithTenant(context.Background(), testTenant.ID)
+	client := db.NewClient(testDb.Pool)
+
+	l, err := client.Queries.CreateLedger(ctx, dbGen.CreateLedgerParams{Name: "Event Balances", Metadata: []byte("{}")})
+	is.NoErr(err)
+	cash, err := client.Queries.CreateAccount(ctx, dbGen.CreateAccountParams{
+		Name: "Cash", Type: dbGen.AccountTypeAsset, Metadata: []byte("{}"), LedgerUuid: l.Uuid,
+	})
+	is.NoErr(err)
+	sales, err := client.Queries.CreateAccount(ctx, dbGen.CreateAccountParams{
+		Name: "Sales", Type: dbGen.AccountTypeRevenue, Metadata: []byte("{}"), LedgerUuid: l.Uuid,
+	})
+	is.NoErr(err)
+
+	// create records a sale and returns the balances in its event
+	create := func(amount int64) map[string]int64 {
+		var txn *dbGen.Transaction
+		err := client.WithTx(ctx, func(q *dbGen.Queries) error {
+			var err error
+			txn, err = q.CreateTransaction(ctx, dbGen.CreateTransactionParams{
+				Amount:            amount,
+				Date:              pgtype.Date{Time: time.Now(), Valid: true},
+				Metadata:          []byte("{}"),
+				CreditAccountUuid: sales.Uuid,
+				DebitAccountUuid:  cash.Uuid,
+				LedgerUuid:        l.Uuid,
+			})
+			if err != nil {
+				return err
+			}
+			return ledger.RecordChange(ctx, q, ledger.Change{
+				Action:     ledger.AuditActionCreate,
+				EntityType: ledger.AuditEntityTransaction,
+				EntityUUID: txn.Uuid,
+			})
+		})
+		is.NoErr(err)
+
+		var payload []byte
+		err = testDb.Pool.QueryRow(ctx, "select payload from outbox_events where entity_uuid = $1", txn.Uuid).Scan(&payload)
+		is.NoErr(err)
+
+		var data ledger.EventData
+		is.NoErr(json.Unmarshal(payload, &data))
+		balances := map[string]int64{}
+		for _, line := range data.Balances {
+			balances[line.AccountUUID] = line.Balance
+		}
+		return balances
+	}
+
+	is.Equal(create(100), map[string]int64{cash.Uuid: 100, sales.Uuid: 100})
+	is.Equal(create(50), map[string]int64{cash.Uuid: 150, sales.Uuid: 150}) // not the balances after the change
+}
+
+func TestReadSnapshot(t *testing.T) {
+	is := is_.New(t)
+
+	testDb, err := testutils.GetTestDB(context.Background())
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+	ctx := db.WithTenant(context.Background(), testTenant.ID)
+	client := db.NewClient(testDb.Pool)
+
+	l, err := client.Queries.CreateLedger(ctx, dbGen.CreateLedgerParams{Name: "Snapshot", Metadata: []byte("{}")})
+	is.NoErr(err)
+
+	err = client.WithReadTx(ctx, func(q *dbGen.Queries) error {
+		before, err := q.GetLedger(ctx, l.Uuid)
+		is.NoErr(err)
+
+		// committed by another connection while the snapshot is open
+		_, err = testDb.AdminPool.Exec(context.Background(), "update ledgers set name = 'Renamed' where uuid = $1", l.Uuid)
+		is.NoErr(err)
+
+		after, err := q.GetLedger(ctx, l.Uuid)
+		is.NoErr(err)
+		is.Equal(after.Name, before.Name) // the snapshot saw a later write
+
+		_, err = q.CreateLedger(ctx, dbGen.CreateLedgerParams{Name: "Write", Metadata: []byte("{}")})
+		return err
+	})
+	is.True(err != nil) // the snapshot is read only
+}
+
+func TestConcurrentUpdate(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	testDb, err := testutils.GetTestDB(ctx)
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	client := db.NewClient(testDb.Pool)
+	l, err := client.Queries.CreateLedger(db.WithTenant(ctx, testTenant.ID), dbGen.CreateLedgerParams{Name: "Concurrent", Metadata: []byte("{}")})
+	is.NoErr(err)
+
+	// another update holds the row while the request reads it
+	tx, err := testDb.AdminPool.Begin(ctx)
+	is.NoErr(err)
+	defer func() { _ = tx.Rollback(ctx) }()
+	_, err = tx.Exec(ctx, "select 1 from ledgers where uuid = $1 for update", l.Uuid)
+	is.NoErr(err)
+
+	done := make(chan int)
+	go func() {
+		req, err := http.NewRequest(http.MethodPatch, testServer.BaseURL+"/ledgers/"+l.Uuid, strings.NewReader(`{"name": "Renamed"}`))
+		if err != nil {
+			t.Errorf("unable to create request: %v", err)
+			close(done)
+			return
+		}
+		resp, err := testClient.Do(req)
+		if err != nil {
+			t.Errorf("unable to make request: %v", err)
+			close(done)
+			return
+		}
+		_ = resp.Body.Close()
+		done <- resp.StatusCode
+	}()
+
+	// wait for the request to block on the row
+	for waiting := false; !waiting; {
+		err = testDb.AdminPool.QueryRow(ctx, "select exists(select 1 from pg_stat_activity where wait_event_type = 'Lock')").Scan(&waiting)
+		is.NoErr(err)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	_, err = tx.Exec(ctx, `update ledgers set metadata = '{"owner": "finance"}' where uuid = $1`, l.Uuid)
+	is.NoErr(err)
+	is.NoErr(tx.Commit(ctx))
+
+	is.Equal(<-done, http.StatusOK) // invalid status code
+
+	updated, err := client.Queries.GetLedger(db.WithTenant(ctx, testTenant.ID), l.Uuid)
+	is.NoErr(err)
+	is.Equal(updated.Name, "Renamed")                          // the request was lost
+	is.Equal(string(updated.Metadata), `{"owner": "finance"}`) // the concurrent update was overwritten
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	is := is_.New(t)
+
+	// postAs sends the request with the API key of another principal, the
+	// one of testClient when apiKey is empty
+	postAs := func(apiKey, key, body string) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodPost, testServer.BaseURL+"/ledgers", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("unable to create request: %v", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(server.IdempotencyKeyHeader, key)
+
+		client := testClient
+		if apiKey != "" {
+			client = http.DefaultClient
+			req.Header.Set("Authorization", "Bearer "+apiKey)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("unable to make POST request: %v", err)
+		}
+		defer func() {
+			err := resp.Body.Close()
+			if err != nil {
+				t.Fatalf("unable to close response body: %v", err)
+			}
+		}()
+
+		var detail struct {
+			UUID string `json:"uuid"`
+		}
+		_ = json.NewDecoder(resp.Body).Decode(&server.StandardResponse{Detail: &detail})
+		return resp, detail.UUID
+	}
+	post := func(key, body string) (*http.Response, string) {
+		return postAs("", key, body)
+	}
+
+	key := "idempotency-" + time.Now().Format(time.RFC3339Nano)
+
+	first, uuid := post(key, `{"name": "Idempotent Books"}`)
+	is.Equal(first.StatusCode, http.StatusCreated) // invalid status code
+	is.Equal(first.Header.Get(server.IdempotentReplayedHeader), "")
+
+	t.Run("should replay the response of a retry", func(t *testing.T) {
+		retry, retryUUID := post(key, `{"name": "Idempotent Books"}`)
+		is.Equal(retry.StatusCode, http.StatusCreated) // invalid status code
+		is.Equal(retry.Header.Get(server.IdempotentReplayedHeader), "true")
+		is.Equal(retryUUID, uuid) // created twice
+	})
+
+	t.Run("should reject the key of another request", func(t *testing.T) {
+		resp, _ := post(key, `{"name": "Other Books"}`)
+		is.Equal(resp.StatusCode, http.StatusUnprocessableEntity) // invalid status code
+	})
+
+	t.Run("should not replay the response of another principal", func(t *testing.T) {
+		is := is_.New(t)
+
+		testDb, err := testutils.GetTestDB(context.Background())
+		is.NoErr(err)
+
+		// a viewer of the tenant can't read the ledger created by the key
+		viewerKey, err := testutils.CreateTestAPIKey(context.Background(), testDb.Pool, testTenant.Uuid, auth.ScopeLedgersRead)
+		is.NoErr(err)
+		resp, _ := postAs(viewerKey, key, `{"name": "Idempotent Books"}`)
+		is.Equal(resp.StatusCode, http.StatusForbidden) // scope checked
+		is.Equal(resp.Header.Get(server.IdempotentReplayedHeader), "")
+
+		writerKey, err := testutils.CreateTestAPIKey(context.Background(), testDb.Pool, testTenant.Uuid, auth.ScopeAll)
+		is.NoErr(err)
+		resp, otherUUID := postAs(writerKey, key, `{"name": "Idempotent Books"}`)
+		is.Equal(resp.StatusCode, http.StatusCreated) // invalid status code
+		is.Equal(resp.Header.Get(server.IdempotentReplayedHeader), "")
+		is.True(otherUUID != uuid) // a request of its own
+	})
+}
+
+func TestGRPC(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	testDb, err := testutils.GetTestDB(ctx)
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	key, err := testutils.CreateTestAPIKey(ctx, testDb.Pool, testTenant.Uuid, auth.ScopeAll)
+	if err != nil {
+		t.Fatalf("unable to create api key: %v", err)
+	}
+
+	srv := grpcserver.NewServer(db.NewClient(testDb.Pool), grpcserver.Options{})
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("unable to dial grpc server: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+key)
+
+	l, err := doubleedv1.NewLedgerServiceClient(conn).CreateLedger(ctx, &doubleedv1.CreateLedgerRequest{Name: "gRPC"})
+	is.NoErr(err)
+
+	accounts := doubleedv1.NewAccountServiceClient(conn)
+	cash, err := accounts.CreateAccount(ctx, &doubleedv1.CreateAccountRequest{LedgerUuid: l.Uuid, Name: "Cash", Type: "asset"})
+	is.NoErr(err)
+	revenue, err := accounts.CreateAccount(ctx, &doubleedv1.CreateAccountRequest{LedgerUuid: l.Uuid, Name: "Sales", Type: "revenue"})
+	is.NoErr(err)
+
+	transactions := doubleedv1.NewTransactionServiceClient(conn)
+	md, err := structpb.NewStruct(map[string]any{"source": "grpc"})
+	is.NoErr(err)
+	posted, err := transactions.PostTransaction(ctx, &doubleedv1.PostTransactionRequest{
+		LedgerUuid:        l.Uuid,
+		Amount:            1000,
+		Date:              "2024-11-24",
+		Metadata:          md,
+		DebitAccountUuid:  cash.Uuid,
+		CreditAccountUuid: revenue.Uuid,
+	})
+	is.NoErr(err)
+	is.Equal(posted.LedgerUuid, l.Uuid) // invalid ledger
+	is.Equal(posted.Date, "2024-11-24") // invalid date
+
+	stream, err := transactions.ListTransactions(ctx, &doubleedv1.ListTransactionsRequest{LedgerUuid: l.Uuid})
+	is.NoErr(err)
+	var streamed []*doubleedv1.Transaction
+	for {
+		transaction, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		is.NoErr(err)
+		streamed = append(streamed, transaction)
+	}
+	is.Equal(len(streamed), 1)              // one transaction streamed
+	is.Equal(streamed[0].Uuid, posted.Uuid) // invalid transaction
+
+	tb, err := doubleedv1.NewReportServiceClient(conn).GetTrialBalance(ctx, &doubleedv1.GetTrialBalanceRequest{LedgerUuid: l.Uuid})
+	is.NoErr(err)
+	is.Equal(tb.TotalDebit, tb.TotalCredit) // unbalanced trial balance
+
+	_, err = transactions.PostTransaction(ctx, &doubleedv1.PostTransactionRequest{LedgerUuid: l.Uuid, Date: "2024-11-24"})
+	is.Equal(status.Code(err), codes.InvalidArgument) // missing fields
+
+	_, err = transactions.GetTransaction(ctx, &doubleedv1.GetTransactionRequest{Uuid: "tr4nsaction"})
+	is.Equal(status.Code(err), codes.NotFound) // unknown transaction
+
+	idempotentCtx := metadata.AppendToOutgoingContext(ctx, "idempotency-key", "grpc-post-1")
+	req := &doubleedv1.PostTransactionRequest{
+		LedgerUuid:        l.Uuid,
+		Amount:            500,
+		Date:              "2024-11-25",
+		DebitAccountUuid:  cash.Uuid,
+		CreditAccountUuid: revenue.Uuid,
+	}
+	first, err := transactions.PostTransaction(idempotentCtx, req)
+	is.NoErr(err)
+	var header metadata.MD
+	replayed, err := transactions.PostTransaction(idempotentCtx, req, grpc.Header(&header))
+	is.NoErr(err)
+	is.Equal(replayed.Uuid, first.Uuid)                           // retry posted again
+	is.Equal(header.Get("idempotent-replayed"), []string{"true"}) // retry not replayed
+	_, err = transactions.PostTransaction(idempotentCtx, &doubleedv1.PostTransactionRequest{
+		LedgerUuid:        l.Uuid,
+		Amount:            700,
+		Date:              "2024-11-25",
+		DebitAccountUuid:  cash.Uuid,
+		CreditAccountUuid: revenue.Uuid,
+	})
+	is.Equal(status.Code(err), codes.FailedPrecondition) // key reused for another call
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/config"
+	"github.com/j0lvera/go-double-e/internal/db/migrations"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = `usage:
+  doubleed migrate up
+  doubleed migrate down
+  doubleed migrate status
+  doubleed migrate version`
+
+// runMigrate migrates the database with the migrations embedded in the
+// binary, goose isn't needed on the hosts
+func runMigrate(ctx context.Context, w io.Writer, cfg config.Database, args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	pool, err := newPool(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	db := stdlib.OpenDBFromPool(pool)
+	defer db.Close()
+
+	provider, err := migrations.NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		results, err := provider.Up(ctx)
+		if err != nil {
+			return fmt.Errorf("apply migrations: %w", err)
+		}
+		if len(results) == 0 {
+			_, err = fmt.Fprintln(w, "no pending migrations")
+			return err
+		}
+		return printResults(w, results)
+	case "down":
+		result, err := provider.Down(ctx)
+		if err != nil {
+			return fmt.Errorf("roll back migration: %w", err)
+		}
+		return printResults(w, []*goose.MigrationResult{result})
+	case "status":
+		return printStatus(ctx, w, provider)
+	case "version":
+		current, target, err := provider.GetVersions(ctx)
+		if err != nil {
+			return fmt.Errorf("get version: %w", err)
+		}
+		_, err = fmt.Fprintf(w, "version: %d\nlatest: %d\n", current, target)
+		return err
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], migrateUsage)
+	}
+}
+
+func printResults(w io.Writer, results []*goose.MigrationResult) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintln(w, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func printStatus(ctx context.Context, w io.Writer, provider *goose.Provider) error {
+	statuses, err := provider.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("get status: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "MIGRATION\tSTATE\tAPPLIED")
+	for _, status := range statuses {
+		applied := "-"
+		if !status.AppliedAt.IsZero() {
+			applied = status.AppliedAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", status.Source.Path, status.State, applied)
+	}
+	return tw.Flush()
+}
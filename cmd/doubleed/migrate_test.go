@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/config"
+	"github.com/j0lvera/go-double-e/internal/db/migrations"
+	"github.com/j0lvera/go-double-e/internal/testutils"
+	is_ "github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	testDb, err := testutils.GetTestDB(ctx)
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+
+	cfg, err := config.Load("doubleed", nil)
+	is.NoErr(err)
+	// the migrations change the schema, the app role can't
+	cfg.Database.URL = testDb.AdminPool.Config().ConnString()
+
+	latest, err := migrations.Latest()
+	is.NoErr(err)
+
+	migrate := func(command string) string {
+		var out bytes.Buffer
+		is.NoErr(runMigrate(ctx, &out, cfg.Database, []string{command}))
+		return out.String()
+	}
+
+	is.Equal(migrate("up"), "no pending migrations\n") // migrated by the setup
+	is.Equal(migrate("version"), fmt.Sprintf("version: %d\nlatest: %d\n", latest, latest))
+
+	out := migrate("status")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	is.Equal(strings.Fields(lines[0]), []string{"MIGRATION", "STATE", "APPLIED"})
+	for _, line := range lines[1:] {
+		is.Equal(strings.Fields(line)[1], "applied")
+	}
+
+	// the latest migration is rolled back and applied again
+	is.True(strings.Contains(migrate("down"), fmt.Sprint(latest)))
+	version := migrate("version")
+	is.True(version != fmt.Sprintf("version: %d\nlatest: %d\n", latest, latest))
+
+	status := strings.Split(strings.TrimSpace(migrate("status")), "\n")
+	is.Equal(strings.Fields(status[len(status)-1])[1:], []string{"pending", "-"})
+
+	is.True(strings.Contains(migrate("up"), fmt.Sprint(latest)))
+	is.Equal(migrate("version"), fmt.Sprintf("version: %d\nlatest: %d\n", latest, latest))
+
+	var unknown bytes.Buffer
+	err = runMigrate(ctx, &unknown, cfg.Database, []string{"sideways"})
+	is.True(err != nil) // unknown command
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+)
+
+const reportUsage = `usage:
+  doubleed report trial-balance --ledger UUID [--from YYYY-MM-DD] [--to YYYY-MM-DD]`
+
+// runReport prints the reports of a ledger through the API of a server
+func runReport(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(reportUsage)
+	}
+
+	switch args[0] {
+	case "trial-balance":
+		return trialBalance(ctx, w, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], reportUsage)
+	}
+}
+
+// cliTrialBalance lists the accounts of a ledger, sub-accounts have a `:`
+// in their name, e.g., `Assets:Cash`, and a depth
+type cliTrialBalance struct {
+	Lines       []cliBalanceLine `json:"lines"`
+	TotalDebit  int64            `json:"total_debit"`
+	TotalCredit int64            `json:"total_credit"`
+}
+
+type cliBalanceLine struct {
+	AccountUUID string `json:"account_uuid"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Depth       int    `json:"depth"`
+	Debit       int64  `json:"debit"`
+	Credit      int64  `json:"credit"`
+	Balance     int64  `json:"balance"`
+}
+
+func trialBalance(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("report trial-balance", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	ledger := fs.String("ledger", "", "UUID of the ledger")
+	from := fs.String("from", "", "first day of the period, YYYY-MM-DD")
+	to := fs.String("to", "", "last day of the period, YYYY-MM-DD")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *ledger == "" {
+		return errors.New("--ledger is required")
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{}
+	if *from != "" {
+		query.Set("from", *from)
+	}
+	if *to != "" {
+		query.Set("to", *to)
+	}
+	path := "/ledgers/" + url.PathEscape(*ledger) + "/reports/trial-balance"
+	detail, err := client.do(ctx, "GET", path, query, nil)
+	if err != nil {
+		return fmt.Errorf("get trial balance: %w", err)
+	}
+	if opts.output == outputJSON {
+		return printJSON(w, detail)
+	}
+
+	var report cliTrialBalance
+	if err := json.Unmarshal(detail, &report); err != nil {
+		return fmt.Errorf("decode trial balance: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ACCOUNT\tTYPE\tDEBIT\tCREDIT\tBALANCE")
+	for _, line := range report.Lines {
+		// indent the sub-accounts under their parent
+		name := strings.Repeat("  ", line.Depth) + line.Name
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\n", name, line.Type, line.Debit, line.Credit, line.Balance)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t%d\t%d\t\n", report.TotalDebit, report.TotalCredit)
+	return tw.Flush()
+}
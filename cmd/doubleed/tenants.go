@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/config"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+const tenantsUsage = `usage:
+  doubleed tenants create --name NAME
+  doubleed tenants list`
+
+// runTenants manages the tenants from the command line, every ledger and
+// API key belongs to one
+func runTenants(ctx context.Context, w io.Writer, cfg config.Database, args []string) error {
+	if len(args) == 0 {
+		return errors.New(tenantsUsage)
+	}
+
+	pool, err := newPool(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	queries := dbGen.New(pool)
+
+	switch args[0] {
+	case "create":
+		return createTenant(ctx, w, queries, args[1:])
+	case "list":
+		return listTenants(ctx, w, queries)
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], tenantsUsage)
+	}
+}
+
+func createTenant(ctx context.Context, w io.Writer, q *dbGen.Queries, args []string) error {
+	fs := flag.NewFlagSet("tenants create", flag.ContinueOnError)
+	name := fs.String("name", "", "name of the tenant, e.g., the organization")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *name == "" {
+		return errors.New("--name is required")
+	}
+
+	tenant, err := q.CreateTenant(ctx, *name)
+	if err != nil {
+		return fmt.Errorf("create tenant: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "uuid: %s\nname: %s\n", tenant.Uuid, tenant.Name)
+	return err
+}
+
+func listTenants(ctx context.Context, w io.Writer, q *dbGen.Queries) error {
+	tenants, err := q.ListTenants(ctx)
+	if err != nil {
+		return fmt.Errorf("list tenants: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tNAME\tCREATED")
+	for _, tenant := range tenants {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", tenant.Uuid, tenant.Name, tenant.CreatedAt.Time.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
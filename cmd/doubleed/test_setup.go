@@ -48,7 +48,7 @@ func TestDBSetup(t *testing.T) {
 	}
 
 	// Test that we can reset the database
-	if err := testutils.ResetTestData(ctx, db.Pool); err != nil {
+	if err := testutils.ResetTestData(ctx, db.AdminPool); err != nil {
 		t.Fatalf("Failed to reset test data: %v", err)
 	}
 
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/charmbracelet/bubbles/table"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const tuiUsage = `usage:
+  doubleed tui --metadata KEY=VALUE [--metadata KEY=VALUE...]
+  doubleed tui --ledger UUID`
+
+// runTUI browses the ledgers of a server in the terminal: the ledgers
+// matching the metadata, the account tree of a ledger with its balances,
+// the entries of an account, and a form to post a transaction
+func runTUI(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("tui", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	ledger := fs.String("ledger", "", "UUID of the ledger to open instead of picking one")
+	metadata := metadataFlag{}
+	fs.Var(metadata, "metadata", "filter the ledgers to pick from by metadata, KEY=VALUE, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	// the API lists the ledgers matching a metadata filter only
+	if *ledger == "" && len(metadata) == 0 {
+		return errors.New(tuiUsage)
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	program := tea.NewProgram(
+		newTUIModel(ctx, client, metadata, *ledger),
+		tea.WithAltScreen(),
+		tea.WithContext(ctx),
+		tea.WithOutput(w),
+	)
+	_, err = program.Run()
+	if errors.Is(err, tea.ErrProgramKilled) {
+		// interrupted
+		return nil
+	}
+	return err
+}
+
+type screen int
+
+const (
+	screenLedgers screen = iota
+	screenAccounts
+	screenEntries
+	screenNewTx
+)
+
+// cliStatement is the account statement report
+type cliStatement struct {
+	Account        cliAccount `json:"account"`
+	OpeningBalance int64      `json:"opening_balance"`
+	Entries        []struct {
+		TransactionUUID string `json:"transaction_uuid"`
+		Date            string `json:"date"`
+		Description     string `json:"description"`
+		CounterpartName string `json:"counterpart_name"`
+		Debit           int64  `json:"debit"`
+		Credit          int64  `json:"credit"`
+		Balance         int64  `json:"balance"`
+	} `json:"entries"`
+	ClosingBalance int64 `json:"closing_balance"`
+}
+
+// messages of the commands calling the API
+type (
+	ledgersLoaded  []cliLedger
+	accountsLoaded cliTrialBalance
+	entriesLoaded  cliStatement
+	txPosted       cliTransaction
+	apiFailed      struct{ err error }
+)
+
+var (
+	titleStyle  = lipgloss.NewStyle().Bold(true).MarginBottom(1)
+	helpStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("241"))
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("196"))
+	statusStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("42"))
+)
+
+// tuiModel is the state of the terminal UI, every screen keeps its own
+// table so going back shows it as it was left
+type tuiModel struct {
+	ctx      context.Context
+	client   *apiClient
+	metadata metadataFlag
+
+	screen  screen
+	height  int
+	loading bool
+	status  string
+	err     error
+
+	ledgers      []cliLedger
+	ledger       cliLedger
+	ledgersTable table.Model
+
+	balances      cliTrialBalance
+	accountsTable table.Model
+
+	statement    cliStatement
+	entriesTable table.Model
+
+	form txForm
+}
+
+func newTUIModel(ctx context.Context, client *apiClient, metadata metadataFlag, ledgerUUID string) tuiModel {
+	m := tuiModel{
+		ctx:      ctx,
+		client:   client,
+		metadata: metadata,
+		screen:   screenLedgers,
+		loading:  true,
+		ledgersTable: newTable([]table.Column{
+			{Title: "Name", Width: 30},
+			{Title: "Description", Width: 40},
+			{Title: "UUID", Width: 36},
+		}),
+		accountsTable: newTable([]table.Column{
+			{Title: "Account", Width: 36},
+			{Title: "Type", Width: 10},
+			{Title: "Debit", Width: 14},
+			{Title: "Credit", Width: 14},
+			{Title: "Balance", Width: 14},
+		}),
+		entriesTable: newTable([]table.Column{
+			{Title: "Date", Width: 10},
+			{Title: "Description", Width: 30},
+			{Title: "Counterpart", Width: 24},
+			{Title: "Debit", Width: 12},
+			{Title: "Credit", Width: 12},
+			{Title: "Balance", Width: 12},
+		}),
+	}
+
+	if ledgerUUID != "" {
+		m.ledger = cliLedger{UUID: ledgerUUID, Name: ledgerUUID}
+		m.screen = screenAccounts
+	}
+	return m
+}
+
+func newTable(columns []table.Column) table.Model {
+	return table.New(
+		table.WithColumns(columns),
+		table.WithFocused(true),
+		table.WithHeight(10),
+	)
+}
+
+func (m tuiModel) Init() tea.Cmd {
+	if m.screen == screenAccounts {
+		return m.loadAccounts()
+	}
+	return m.loadLedgers()
+}
+
+func (m tuiModel) loadLedgers() tea.Cmd {
+	return func() tea.Msg {
+		query := url.Values{}
+		m.metadata.filter(query)
+		detail, err := m.client.do(m.ctx, "GET", "/ledgers", query, nil)
+		if isNotFound(err) {
+			return ledgersLoaded{}
+		}
+		if err != nil {
+			return apiFailed{fmt.Errorf("list ledgers: %w", err)}
+		}
+
+		var ledgers []cliLedger
+		if err := json.Unmarshal(detail, &ledgers); err != nil {
+			return apiFailed{fmt.Errorf("decode ledgers: %w", err)}
+		}
+		return ledgersLoaded(ledgers)
+	}
+}
+
+// loadAccounts reads the account tree from the trial balance, it has every
+// account of the ledger with its balance
+func (m tuiModel) loadAccounts() tea.Cmd {
+	path := "/ledgers/" + url.PathEscape(m.ledger.UUID) + "/reports/trial-balance"
+	return func() tea.Msg {
+		detail, err := m.client.do(m.ctx, "GET", path, nil, nil)
+		if err != nil {
+			return apiFailed{fmt.Errorf("get accounts: %w", err)}
+		}
+
+		var balances cliTrialBalance
+		if err := json.Unmarshal(detail, &balances); err != nil {
+			return apiFailed{fmt.Errorf("decode accounts: %w", err)}
+		}
+		return accountsLoaded(balances)
+	}
+}
+
+func (m tuiModel) loadEntries(accountUUID string) tea.Cmd {
+	path := "/accounts/" + url.PathEscape(accountUUID) + "/statement"
+	return func() tea.Msg {
+		detail, err := m.client.do(m.ctx, "GET", path, nil, nil)
+		if err != nil {
+			return apiFailed{fmt.Errorf("get entries: %w", err)}
+		}
+
+		var statement cliStatement
+		if err := json.Unmarshal(detail, &statement); err != nil {
+			return apiFailed{fmt.Errorf("decode entries: %w", err)}
+		}
+		return entriesLoaded(statement)
+	}
+}
+
+func (m tuiModel) postTx(body map[string]any) tea.Cmd {
+	return func() tea.Msg {
+		detail, err := m.client.do(m.ctx, "POST", "/transactions", nil, body)
+		if err != nil {
+			return apiFailed{fmt.Errorf("post transaction: %w", err)}
+		}
+
+		var tx cliTransaction
+		if err := json.Unmarshal(detail, &tx); err != nil {
+			return apiFailed{fmt.Errorf("decode transaction: %w", err)}
+		}
+		return txPosted(tx)
+	}
+}
+
+func (m tuiModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		// leave room for the title, the status and the help
+		m.height = max(msg.Height-8, 3)
+		m.ledgersTable.SetHeight(m.height)
+		m.accountsTable.SetHeight(m.height)
+		m.entriesTable.SetHeight(m.height)
+		return m, nil
+
+	case ledgersLoaded:
+		m.loading = false
+		m.ledgers = msg
+		rows := make([]table.Row, 0, len(msg))
+		for _, ledger := range msg {
+			description := ""
+			if ledger.Description != nil {
+				description = *ledger.Description
+			}
+			rows = append(rows, table.Row{ledger.Name, description, ledger.UUID})
+		}
+		m.ledgersTable.SetRows(rows)
+		m.ledgersTable.SetCursor(0)
+		if len(rows) == 0 {
+			m.status = "No ledger matches the metadata"
+		}
+		return m, nil
+
+	case accountsLoaded:
+		m.loading = false
+		m.balances = cliTrialBalance(msg)
+		rows := make([]table.Row, 0, len(msg.Lines))
+		for _, line := range msg.Lines {
+			rows = append(rows, table.Row{
+				accountLabel(line),
+				line.Type,
+				formatAmount(line.Debit),
+				formatAmount(line.Credit),
+				formatAmount(line.Balance),
+			})
+		}
+		cursor := m.accountsTable.Cursor()
+		m.accountsTable.SetRows(rows)
+		// stay on the account after a refresh
+		m.accountsTable.SetCursor(min(cursor, max(len(rows)-1, 0)))
+		return m, nil
+
+	case entriesLoaded:
+		m.loading = false
+		m.statement = cliStatement(msg)
+		rows := make([]table.Row, 0, len(msg.Entries))
+		for _, entry := range msg.Entries {
+			rows = append(rows, table.Row{
+				entry.Date,
+				entry.Description,
+				entry.CounterpartName,
+				formatAmount(entry.Debit),
+				formatAmount(entry.Credit),
+				formatAmount(entry.Balance),
+			})
+		}
+		m.entriesTable.SetRows(rows)
+		m.entriesTable.SetCursor(0)
+		m.screen = screenEntries
+		return m, nil
+
+	case txPosted:
+		m.form.submitting = false
+		m.status = fmt.Sprintf("Transaction %s posted", msg.UUID)
+		m.screen = screenAccounts
+		m.loading = true
+		return m, m.loadAccounts()
+
+	case apiFailed:
+		m.loading = false
+		m.form.submitting = false
+		m.err = msg.err
+		return m, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			return m, tea.Quit
+		}
+		// the messages are about the last action
+		m.status, m.err = "", nil
+
+		switch m.screen {
+		case screenLedgers:
+			return m.updateLedgers(msg)
+		case screenAccounts:
+			return m.updateAccounts(msg)
+		case screenEntries:
+			return m.updateEntries(msg)
+		case screenNewTx:
+			return m.updateNewTx(msg)
+		}
+	}
+
+	return m, nil
+}
+
+func (m tuiModel) updateLedgers(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q", "esc":
+		return m, tea.Quit
+	case "r":
+		m.loading = true
+		return m, m.loadLedgers()
+	case "enter":
+		if len(m.ledgers) == 0 {
+			return m, nil
+		}
+		m.ledger = m.ledgers[m.ledgersTable.Cursor()]
+		m.balances = cliTrialBalance{}
+		m.accountsTable.SetRows(nil)
+		m.accountsTable.SetCursor(0)
+		m.screen = screenAccounts
+		m.loading = true
+		return m, m.loadAccounts()
+	}
+
+	var cmd tea.Cmd
+	m.ledgersTable, cmd = m.ledgersTable.Update(msg)
+	return m, cmd
+}
+
+func (m tuiModel) updateAccounts(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "esc", "backspace":
+		// a ledger opened with --ledger has nothing to go back to
+		if len(m.metadata) == 0 {
+			return m, tea.Quit
+		}
+		m.screen = screenLedgers
+		return m, nil
+	case "r":
+		m.loading = true
+		return m, m.loadAccounts()
+	case "enter":
+		if len(m.balances.Lines) == 0 {
+			return m, nil
+		}
+		m.loading = true
+		return m, m.loadEntries(m.balances.Lines[m.accountsTable.Cursor()].AccountUUID)
+	case "n":
+		// debit the selected account by default
+		debit := ""
+		if len(m.balances.Lines) > 0 {
+			debit = m.balances.Lines[m.accountsTable.Cursor()].Name
+		}
+		m.form = newTxForm(m.balances.Lines, debit)
+		m.screen = screenNewTx
+		return m, m.form.init()
+	}
+
+	var cmd tea.Cmd
+	m.accountsTable, cmd = m.accountsTable.Update(msg)
+	return m, cmd
+}
+
+func (m tuiModel) updateEntries(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "q":
+		return m, tea.Quit
+	case "esc", "backspace":
+		m.screen = screenAccounts
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	m.entriesTable, cmd = m.entriesTable.Update(msg)
+	return m, cmd
+}
+
+func (m tuiModel) updateNewTx(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.form.submitting {
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.screen = screenAccounts
+		return m, nil
+	case "ctrl+s":
+		return m.submitTx()
+	case "enter":
+		if m.form.focus == fieldDescription {
+			return m.submitTx()
+		}
+		return m, m.form.next()
+	}
+
+	var cmd tea.Cmd
+	m.form, cmd = m.form.update(msg)
+	return m, cmd
+}
+
+func (m tuiModel) submitTx() (tea.Model, tea.Cmd) {
+	body, ok := m.form.submit(m.ledger.UUID)
+	if !ok {
+		return m, nil
+	}
+	return m, m.postTx(body)
+}
+
+func (m tuiModel) View() string {
+	var b strings.Builder
+
+	switch m.screen {
+	case screenLedgers:
+		b.WriteString(titleStyle.Render("Ledgers"))
+		b.WriteString("\n")
+		b.WriteString(m.ledgersTable.View())
+		b.WriteString("\n")
+		b.WriteString(m.footer("enter open • r refresh • q quit"))
+
+	case screenAccounts:
+		b.WriteString(titleStyle.Render("Accounts of " + m.ledger.Name))
+		b.WriteString("\n")
+		b.WriteString(m.accountsTable.View())
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "Total debit %s • Total credit %s\n",
+			formatAmount(m.balances.TotalDebit),
+			formatAmount(m.balances.TotalCredit),
+		)
+		b.WriteString(m.footer("enter entries • n new transaction • r refresh • esc back • q quit"))
+
+	case screenEntries:
+		b.WriteString(titleStyle.Render("Entries of " + m.statement.Account.Name))
+		b.WriteString("\n")
+		b.WriteString(m.entriesTable.View())
+		b.WriteString("\n")
+		fmt.Fprintf(&b, "Opening balance %s • Closing balance %s\n",
+			formatAmount(m.statement.OpeningBalance),
+			formatAmount(m.statement.ClosingBalance),
+		)
+		b.WriteString(m.footer("↑/↓ scroll • esc back • q quit"))
+
+	case screenNewTx:
+		b.WriteString(titleStyle.Render("New transaction in " + m.ledger.Name))
+		b.WriteString("\n")
+		b.WriteString(m.form.view())
+		b.WriteString("\n")
+		b.WriteString(m.footer("tab next • → accept suggestion • ctrl+s post • esc cancel"))
+	}
+
+	return b.String()
+}
+
+// footer shows the outcome of the last action above the help
+func (m tuiModel) footer(help string) string {
+	var b strings.Builder
+	switch {
+	case m.loading || m.form.submitting:
+		b.WriteString("Loading…")
+	case m.err != nil:
+		b.WriteString(errorStyle.Render(m.err.Error()))
+	case m.status != "":
+		b.WriteString(statusStyle.Render(m.status))
+	}
+	b.WriteString("\n")
+	b.WriteString(helpStyle.Render(help))
+	return b.String()
+}
+
+// accountLabel indents the last segment of the name of a sub-account,
+// e.g., `Assets:Cash` is `  Cash`
+func accountLabel(line cliBalanceLine) string {
+	name := line.Name
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		name = name[i+1:]
+	}
+	return strings.Repeat("  ", line.Depth) + name
+}
+
+// formatAmount prints minor units with thousands separators
+func formatAmount(amount int64) string {
+	s := strconv.FormatInt(amount, 10)
+	sign := ""
+	if amount < 0 {
+		sign, s = "-", s[1:]
+	}
+
+	var b strings.Builder
+	for i, digit := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
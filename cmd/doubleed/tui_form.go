@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// fields of the transaction form, in the order of the tab key
+const (
+	fieldDebit = iota
+	fieldCredit
+	fieldAmount
+	fieldDate
+	fieldDescription
+	fieldCount
+)
+
+var fieldLabels = [fieldCount]string{"Debit", "Credit", "Amount", "Date", "Description"}
+
+// txForm enters a transaction, the amount is debited to one account and
+// credited to the other so it's balanced by construction. The fields are
+// validated as they are typed, their errors show once they are left.
+type txForm struct {
+	accounts map[string]cliBalanceLine
+
+	inputs     [fieldCount]textinput.Model
+	errs       [fieldCount]string
+	touched    [fieldCount]bool
+	focus      int
+	submitting bool
+}
+
+func newTxForm(lines []cliBalanceLine, debit string) txForm {
+	f := txForm{accounts: make(map[string]cliBalanceLine, len(lines))}
+
+	names := make([]string, 0, len(lines))
+	for _, line := range lines {
+		f.accounts[line.Name] = line
+		names = append(names, line.Name)
+	}
+
+	for i := range f.inputs {
+		input := textinput.New()
+		input.Prompt = ""
+		input.Width = 40
+		f.inputs[i] = input
+	}
+
+	for _, i := range []int{fieldDebit, fieldCredit} {
+		f.inputs[i].Placeholder = "Assets:Cash"
+		f.inputs[i].ShowSuggestions = true
+		f.inputs[i].SetSuggestions(names)
+		// tab moves to the next field
+		f.inputs[i].KeyMap.AcceptSuggestion = key.NewBinding(key.WithKeys("right"))
+	}
+	f.inputs[fieldAmount].Placeholder = "in minor units, e.g., 1250"
+	f.inputs[fieldDescription].CharLimit = 255
+	f.inputs[fieldDate].SetValue(time.Now().Format(time.DateOnly))
+
+	f.inputs[fieldDebit].SetValue(debit)
+	if debit != "" {
+		f.touched[fieldDebit] = true
+		f.focus = fieldCredit
+	}
+	f.validate()
+
+	return f
+}
+
+func (f *txForm) init() tea.Cmd {
+	return f.inputs[f.focus].Focus()
+}
+
+func (f txForm) update(msg tea.KeyMsg) (txForm, tea.Cmd) {
+	switch msg.String() {
+	case "tab":
+		return f, f.next()
+	case "shift+tab":
+		return f, f.move(-1)
+	}
+
+	var cmd tea.Cmd
+	f.inputs[f.focus], cmd = f.inputs[f.focus].Update(msg)
+	f.validate()
+	return f, cmd
+}
+
+// next leaves the field, its error shows from now on
+func (f *txForm) next() tea.Cmd {
+	return f.move(1)
+}
+
+func (f *txForm) move(step int) tea.Cmd {
+	f.touched[f.focus] = true
+	f.inputs[f.focus].Blur()
+	f.focus = (f.focus + step + fieldCount) % fieldCount
+	return f.inputs[f.focus].Focus()
+}
+
+// validate checks every field, the API would reject the transaction
+// otherwise
+func (f *txForm) validate() bool {
+	value := func(i int) string {
+		return strings.TrimSpace(f.inputs[i].Value())
+	}
+
+	f.errs = [fieldCount]string{}
+	for _, i := range []int{fieldDebit, fieldCredit} {
+		name := value(i)
+		if name == "" {
+			f.errs[i] = "required"
+		} else if _, ok := f.accounts[name]; !ok {
+			f.errs[i] = fmt.Sprintf("no account named %q", name)
+		}
+	}
+	if f.errs[fieldCredit] == "" && value(fieldCredit) == value(fieldDebit) {
+		f.errs[fieldCredit] = "must differ from the debit account"
+	}
+
+	if amount, err := strconv.ParseInt(value(fieldAmount), 10, 64); err != nil || amount <= 0 {
+		f.errs[fieldAmount] = "a positive whole number of minor units"
+	}
+
+	if _, err := time.Parse(time.DateOnly, value(fieldDate)); err != nil {
+		f.errs[fieldDate] = "YYYY-MM-DD"
+	}
+
+	for _, err := range f.errs {
+		if err != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// submit returns the body of the request when every field is valid,
+// otherwise it shows all the errors
+func (f *txForm) submit(ledgerUUID string) (map[string]any, bool) {
+	for i := range f.touched {
+		f.touched[i] = true
+	}
+	if !f.validate() {
+		return nil, false
+	}
+
+	value := func(i int) string {
+		return strings.TrimSpace(f.inputs[i].Value())
+	}
+	amount, _ := strconv.ParseInt(value(fieldAmount), 10, 64)
+	date, _ := time.Parse(time.DateOnly, value(fieldDate))
+
+	f.submitting = true
+	return map[string]any{
+		"ledger_uuid":         ledgerUUID,
+		"debit_account_uuid":  f.accounts[value(fieldDebit)].AccountUUID,
+		"credit_account_uuid": f.accounts[value(fieldCredit)].AccountUUID,
+		"amount":              amount,
+		"date":                date,
+		"description":         value(fieldDescription),
+	}, true
+}
+
+func (f txForm) view() string {
+	var b strings.Builder
+	for i, input := range f.inputs {
+		fmt.Fprintf(&b, "%-12s %s\n", fieldLabels[i], input.View())
+		if f.touched[i] && f.errs[i] != "" {
+			fmt.Fprintf(&b, "%-12s %s\n", "", errorStyle.Render(f.errs[i]))
+		}
+	}
+	return b.String()
+}
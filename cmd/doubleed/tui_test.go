@@ -0,0 +1,50 @@
+package main
+
+import (
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestTxForm(t *testing.T) {
+	is := is_.New(t)
+
+	lines := []cliBalanceLine{
+		{AccountUUID: "cash", Name: "Assets:Cash", Type: "asset", Depth: 1},
+		{AccountUUID: "rent", Name: "Expenses:Rent", Type: "expense", Depth: 1},
+	}
+
+	form := newTxForm(lines, "Expenses:Rent")
+	is.Equal(form.focus, fieldCredit) // the debit is filled in
+
+	// nothing is sent until every field is valid
+	_, ok := form.submit("ledger")
+	is.True(!ok)
+	is.Equal(form.errs[fieldCredit], "required")
+	is.Equal(form.errs[fieldAmount], "a positive whole number of minor units")
+	is.Equal(form.errs[fieldDate], "")
+
+	form.inputs[fieldCredit].SetValue("Expenses:Rent")
+	form.validate()
+	is.Equal(form.errs[fieldCredit], "must differ from the debit account")
+
+	form.inputs[fieldCredit].SetValue("Assets:Bank")
+	form.validate()
+	is.Equal(form.errs[fieldCredit], `no account named "Assets:Bank"`)
+
+	form.inputs[fieldCredit].SetValue("Assets:Cash")
+	form.inputs[fieldAmount].SetValue("1250")
+	body, ok := form.submit("ledger")
+	is.True(ok)
+	is.Equal(body["debit_account_uuid"], "rent")
+	is.Equal(body["credit_account_uuid"], "cash")
+	is.Equal(body["amount"], int64(1250))
+}
+
+func TestFormatAmount(t *testing.T) {
+	is := is_.New(t)
+
+	is.Equal(formatAmount(0), "0")
+	is.Equal(formatAmount(999), "999")
+	is.Equal(formatAmount(1000), "1,000")
+	is.Equal(formatAmount(-1234567), "-1,234,567")
+}
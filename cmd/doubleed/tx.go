@@ -0,0 +1,227 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"text/tabwriter"
+)
+
+const txUsage = `usage:
+  doubleed tx add --ledger UUID --debit UUID --credit UUID --amount AMOUNT [--date YYYY-MM-DD] [--description TEXT] [--metadata KEY=VALUE...]
+  doubleed tx list --ledger UUID --metadata KEY=VALUE [--metadata KEY=VALUE...] [--limit N] [--offset N]
+  doubleed tx reverse [--date YYYY-MM-DD] UUID`
+
+// runTx posts and lists the transactions of a ledger through the API of a
+// server
+func runTx(ctx context.Context, w io.Writer, args []string) error {
+	if len(args) == 0 {
+		return errors.New(txUsage)
+	}
+
+	switch args[0] {
+	case "add":
+		return addTx(ctx, w, args[1:])
+	case "list":
+		return listTx(ctx, w, args[1:])
+	case "reverse":
+		return reverseTx(ctx, w, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], txUsage)
+	}
+}
+
+type cliTransaction struct {
+	UUID              string  `json:"uuid"`
+	Amount            int64   `json:"amount"`
+	Date              string  `json:"date"`
+	Description       *string `json:"description"`
+	CreditAccountUUID string  `json:"credit_account_uuid"`
+	DebitAccountUUID  string  `json:"debit_account_uuid"`
+	LedgerUUID        string  `json:"ledger_uuid"`
+}
+
+func addTx(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("tx add", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	ledger := fs.String("ledger", "", "UUID of the ledger")
+	debit := fs.String("debit", "", "UUID of the account debited")
+	credit := fs.String("credit", "", "UUID of the account credited")
+	amount := fs.Int64("amount", 0, "amount in minor units, e.g., cents")
+	date := fs.String("date", "", "date of the transaction, YYYY-MM-DD, today by default")
+	description := fs.String("description", "", "description of the transaction")
+	metadata := metadataFlag{}
+	fs.Var(metadata, "metadata", "metadata of the transaction, KEY=VALUE, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	switch {
+	case *ledger == "":
+		return errors.New("--ledger is required")
+	case *debit == "":
+		return errors.New("--debit is required")
+	case *credit == "":
+		return errors.New("--credit is required")
+	case *amount <= 0:
+		return errors.New("--amount must be positive")
+	}
+
+	txDate, err := parseDate(*date)
+	if err != nil {
+		return err
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	detail, err := client.do(ctx, "POST", "/transactions", nil, map[string]any{
+		"ledger_uuid":         *ledger,
+		"debit_account_uuid":  *debit,
+		"credit_account_uuid": *credit,
+		"amount":              *amount,
+		"date":                txDate,
+		"description":         *description,
+		"metadata":            metadata,
+	})
+	if err != nil {
+		return fmt.Errorf("add transaction: %w", err)
+	}
+	return printTx(w, opts.output, detail)
+}
+
+func listTx(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("tx list", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	ledger := fs.String("ledger", "", "UUID of the ledger")
+	limit := fs.Int("limit", 30, "maximum number of transactions")
+	offset := fs.Int("offset", 0, "number of transactions to skip")
+	metadata := metadataFlag{}
+	fs.Var(metadata, "metadata", "filter by metadata, KEY=VALUE, repeatable")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *ledger == "" {
+		return errors.New("--ledger is required")
+	}
+	// the API requires a metadata filter
+	if len(metadata) == 0 {
+		return errors.New("--metadata is required")
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{
+		"ledger_uuid": {*ledger},
+		"limit":       {strconv.Itoa(*limit)},
+		"offset":      {strconv.Itoa(*offset)},
+	}
+	metadata.filter(query)
+	detail, err := client.do(ctx, "GET", "/transactions", query, nil)
+
+	// the API answers 404 when nothing matches the filter
+	if isNotFound(err) {
+		detail, err = json.RawMessage(`{"data":[],"pagination":{"total":0}}`), nil
+	}
+	if err != nil {
+		return fmt.Errorf("list transactions: %w", err)
+	}
+	if opts.output == outputJSON {
+		return printJSON(w, detail)
+	}
+
+	var page struct {
+		Data       []cliTransaction `json:"data"`
+		Pagination struct {
+			Total int `json:"total"`
+		} `json:"pagination"`
+	}
+	if err := json.Unmarshal(detail, &page); err != nil {
+		return fmt.Errorf("decode transactions: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tDATE\tAMOUNT\tDESCRIPTION")
+	for _, tx := range page.Data {
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\n", tx.UUID, tx.Date, tx.Amount, orDash(tx.Description))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%d of %d transactions\n", len(page.Data), page.Pagination.Total)
+	return err
+}
+
+// reverseTx posts a transaction with the accounts of the original one
+// swapped, so the original stays in the history
+func reverseTx(ctx context.Context, w io.Writer, args []string) error {
+	fs := flag.NewFlagSet("tx reverse", flag.ContinueOnError)
+	opts := clientFlags(fs)
+	date := fs.String("date", "", "date of the reversal, YYYY-MM-DD, today by default")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 {
+		return errors.New(txUsage)
+	}
+	txUUID := fs.Arg(0)
+
+	txDate, err := parseDate(*date)
+	if err != nil {
+		return err
+	}
+
+	client, err := opts.client()
+	if err != nil {
+		return err
+	}
+
+	detail, err := client.do(ctx, "GET", "/transactions/"+url.PathEscape(txUUID), nil, nil)
+	if err != nil {
+		return fmt.Errorf("get transaction: %w", err)
+	}
+
+	var original cliTransaction
+	if err := json.Unmarshal(detail, &original); err != nil {
+		return fmt.Errorf("decode transaction: %w", err)
+	}
+
+	detail, err = client.do(ctx, "POST", "/transactions", nil, map[string]any{
+		"ledger_uuid":         original.LedgerUUID,
+		"debit_account_uuid":  original.CreditAccountUUID,
+		"credit_account_uuid": original.DebitAccountUUID,
+		"amount":              original.Amount,
+		"date":                txDate,
+		"description":         "Reversal of " + original.UUID,
+		"metadata":            map[string]any{"reverses": original.UUID},
+	})
+	if err != nil {
+		return fmt.Errorf("reverse transaction: %w", err)
+	}
+	return printTx(w, opts.output, detail)
+}
+
+func printTx(w io.Writer, output string, detail json.RawMessage) error {
+	if output == outputJSON {
+		return printJSON(w, detail)
+	}
+
+	var tx cliTransaction
+	if err := json.Unmarshal(detail, &tx); err != nil {
+		return fmt.Errorf("decode transaction: %w", err)
+	}
+	_, err := fmt.Fprintf(w, "uuid: %s\namount: %d\n", tx.UUID, tx.Amount)
+	return err
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/config"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"io"
+	"text/tabwriter"
+	"time"
+)
+
+const usersUsage = `usage:
+  doubleed users create --tenant UUID --email EMAIL [--name NAME]
+  doubleed users list --tenant UUID`
+
+// runUsers manages the users of a tenant from the command line, their
+// roles on each ledger are granted through the API
+func runUsers(ctx context.Context, w io.Writer, cfg config.Database, args []string) error {
+	if len(args) == 0 {
+		return errors.New(usersUsage)
+	}
+
+	pool, err := newPool(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer pool.Close()
+
+	queries := dbGen.New(pool)
+
+	switch args[0] {
+	case "create":
+		return createUser(ctx, w, queries, args[1:])
+	case "list":
+		return listUsers(ctx, w, queries, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q\n%s", args[0], usersUsage)
+	}
+}
+
+func createUser(ctx context.Context, w io.Writer, q *dbGen.Queries, args []string) error {
+	fs := flag.NewFlagSet("users create", flag.ContinueOnError)
+	tenantUUID := fs.String("tenant", "", "uuid of the tenant the user belongs to")
+	email := fs.String("email", "", "email of the user")
+	name := fs.String("name", "", "name of the user")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tenantUUID == "" || *email == "" {
+		return errors.New("--tenant and --email are required")
+	}
+
+	tenant, err := q.GetTenant(ctx, *tenantUUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("tenant %q not found", *tenantUUID)
+		}
+		return fmt.Errorf("get tenant: %w", err)
+	}
+
+	user, err := q.CreateUser(ctx, dbGen.CreateUserParams{
+		Email:      *email,
+		Name:       pgtype.Text{String: *name, Valid: *name != ""},
+		TenantUuid: tenant.Uuid,
+	})
+	if err != nil {
+		return fmt.Errorf("create user: %w", err)
+	}
+
+	_, err = fmt.Fprintf(w, "uuid:   %s\ntenant: %s\nemail:  %s\n", user.Uuid, tenant.Uuid, user.Email)
+	return err
+}
+
+func listUsers(ctx context.Context, w io.Writer, q *dbGen.Queries, args []string) error {
+	fs := flag.NewFlagSet("users list", flag.ContinueOnError)
+	tenantUUID := fs.String("tenant", "", "uuid of the tenant")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *tenantUUID == "" {
+		return errors.New("--tenant is required")
+	}
+
+	users, err := q.ListUsers(ctx, *tenantUUID)
+	if err != nil {
+		return fmt.Errorf("list users: %w", err)
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "UUID\tEMAIL\tNAME\tCREATED")
+	for _, user := range users {
+		name := "-"
+		if user.Name.Valid {
+			name = user.Name.String
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", user.Uuid, user.Email, name, user.CreatedAt.Time.Format(time.RFC3339))
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,149 @@
+// Package auth issues and checks the API keys of the service, and verifies
+// the JWTs of the identity provider. A key looks like
+// `dde_<prefix>_<secret>`, the prefix is stored in plain text to find the
+// key and the whole key is only stored as a SHA-256 hash.
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const keyPrefix = "dde"
+
+// Scopes granted to API keys
+const (
+	ScopeAll               = "*"
+	ScopeLedgersRead       = "ledgers:read"
+	ScopeLedgersWrite      = "ledgers:write"
+	ScopeAccountsRead      = "accounts:read"
+	ScopeAccountsWrite     = "accounts:write"
+	ScopeTransactionsRead  = "transactions:read"
+	ScopeTransactionsWrite = "transactions:write"
+	ScopeReportsRead       = "reports:read"
+	ScopeAuditRead         = "audit:read"
+	ScopeWebhooksRead      = "webhooks:read"
+	ScopeWebhooksWrite     = "webhooks:write"
+)
+
+// Scopes lists every scope that can be granted
+var Scopes = []string{
+	ScopeAll,
+	ScopeLedgersRead,
+	ScopeLedgersWrite,
+	ScopeAccountsRead,
+	ScopeAccountsWrite,
+	ScopeTransactionsRead,
+	ScopeTransactionsWrite,
+	ScopeReportsRead,
+	ScopeAuditRead,
+	ScopeWebhooksRead,
+	ScopeWebhooksWrite,
+}
+
+var ErrMalformedKey = errors.New("malformed API key")
+
+// Key is a newly generated API key, Plain is only known at creation time
+type Key struct {
+	Plain  string
+	Prefix string
+	Hash   string
+}
+
+// Principal is the authenticated caller of a request, it only has access
+// to the ledgers of its tenant. UserUUID is empty for keys that act for the
+// whole tenant rather than for a user, and KeyUUID is empty for users
+// authenticated with a JWT.
+type Principal struct {
+	KeyUUID  string
+	Name     string
+	TenantID int64
+	UserUUID string
+	Scopes   []string
+}
+
+// HasScope reports whether the principal was granted the scope
+func (p *Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope) || slices.Contains(p.Scopes, ScopeAll)
+}
+
+// LimitKey identifies the principal to the rate limits and the idempotency
+// keys, by its key or, for JWTs, by its user
+func (p *Principal) LimitKey() string {
+	if p.KeyUUID != "" {
+		return "key:" + p.KeyUUID
+	}
+	return "user:" + p.UserUUID
+}
+
+// GenerateKey returns a random API key
+func GenerateKey() (*Key, error) {
+	prefix, err := randomHex(4)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return nil, err
+	}
+
+	plain := fmt.Sprintf("%s_%s_%s", keyPrefix, prefix, secret)
+	return &Key{
+		Plain:  plain,
+		Prefix: prefix,
+		Hash:   HashKey(plain),
+	}, nil
+}
+
+// ParseKey returns the prefix of a key
+func ParseKey(plain string) (string, error) {
+	parts := strings.Split(plain, "_")
+	if len(parts) != 3 || parts[0] != keyPrefix || parts[1] == "" || parts[2] == "" {
+		return "", ErrMalformedKey
+	}
+	return parts[1], nil
+}
+
+// HashKey hashes the whole key. Keys are random so a fast hash is enough,
+// there is nothing to brute force.
+func HashKey(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyKey compares a key with a stored hash in constant time
+func VerifyKey(plain, hash string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashKey(plain)), []byte(hash)) == 1
+}
+
+// ValidScope reports whether the scope can be granted
+func ValidScope(scope string) bool {
+	return slices.Contains(Scopes, scope)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns nil for unauthenticated requests
+func PrincipalFromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("read random bytes: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,34 @@
+package auth
+
+import (
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	is := is_.New(t)
+
+	key, err := GenerateKey()
+	is.NoErr(err)
+
+	prefix, err := ParseKey(key.Plain)
+	is.NoErr(err)
+	is.Equal(prefix, key.Prefix)
+
+	is.True(VerifyKey(key.Plain, key.Hash))
+	is.True(!VerifyKey(key.Plain+"x", key.Hash))
+
+	_, err = ParseKey("Bearer nope")
+	is.Equal(err, ErrMalformedKey)
+}
+
+func TestHasScope(t *testing.T) {
+	is := is_.New(t)
+
+	p := &Principal{Scopes: []string{ScopeLedgersRead}}
+	is.True(p.HasScope(ScopeLedgersRead))
+	is.True(!p.HasScope(ScopeLedgersWrite))
+
+	admin := &Principal{Scopes: []string{ScopeAll}}
+	is.True(admin.HasScope(ScopeReportsRead))
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"log/slog"
+)
+
+// ErrInvalidCredentials is returned when the bearer token doesn't
+// authenticate anyone
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Authenticator resolves the bearer tokens of the HTTP and gRPC APIs into
+// principals
+type Authenticator struct {
+	client *db.Client
+	// verifier checks the JWTs of the identity provider, nil when only
+	// API keys are accepted
+	verifier TokenVerifier
+}
+
+func NewAuthenticator(client *db.Client, verifier TokenVerifier) *Authenticator {
+	return &Authenticator{client: client, verifier: verifier}
+}
+
+// Authenticate returns the principal of an API key or a JWT, it's
+// ErrInvalidCredentials when the token doesn't authenticate anyone
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	// API keys have a prefix, anything else is taken for a JWT
+	if _, err := ParseKey(token); err == nil || a.verifier == nil {
+		return a.keyPrincipal(ctx, token)
+	}
+	return a.tokenPrincipal(ctx, token)
+}
+
+// keyPrincipal is the principal of an API key
+func (a *Authenticator) keyPrincipal(ctx context.Context, token string) (*Principal, error) {
+	prefix, err := ParseKey(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	key, err := a.client.Queries.GetApiKeyByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: api key %q not found", ErrInvalidCredentials, prefix)
+		}
+		return nil, fmt.Errorf("get api key: %w", err)
+	}
+
+	if !VerifyKey(token, key.KeyHash) {
+		return nil, fmt.Errorf("%w: invalid api key %q", ErrInvalidCredentials, prefix)
+	}
+
+	return &Principal{
+		KeyUUID:  key.Uuid,
+		Name:     key.Name,
+		TenantID: key.TenantID,
+		UserUUID: key.UserUuid.String,
+		Scopes:   key.Scopes,
+	}, nil
+}
+
+// tokenPrincipal is the user of a JWT. Users are created the first time
+// they sign in, their roles on each ledger are granted like any other
+// user's. Tokens without scopes are granted all of them, the roles of the
+// user still apply.
+func (a *Authenticator) tokenPrincipal(ctx context.Context, token string) (*Principal, error) {
+	claims, err := a.verifier.Verify(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidToken) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+		}
+		return nil, fmt.Errorf("verify token: %w", err)
+	}
+
+	tenant, err := a.client.Queries.GetTenant(ctx, claims.TenantUUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("%w: tenant %q not found", ErrInvalidCredentials, claims.TenantUUID)
+		}
+		return nil, fmt.Errorf("get tenant: %w", err)
+	}
+
+	user, err := a.signInUser(ctx, tenant, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := claims.Scopes
+	if scopes == nil {
+		scopes = []string{ScopeAll}
+	}
+
+	return &Principal{
+		Name:     user.Email,
+		TenantID: tenant.ID,
+		UserUUID: user.Uuid,
+		Scopes:   scopes,
+	}, nil
+}
+
+// signInUser returns the user of the tenant with the issuer and subject of
+// the claims. Users signing in for the first time take over the user
+// created ahead for their email, if it isn't taken already, or a new one
+// is created. The email is never used again, it can change.
+func (a *Authenticator) signInUser(ctx context.Context, tenant *dbGen.Tenant, claims *Claims) (*dbGen.User, error) {
+	params := dbGen.GetUserBySubjectParams{TenantID: tenant.ID, Issuer: claims.Issuer, Subject: claims.Subject}
+
+	user, err := a.client.Queries.GetUserBySubject(ctx, params)
+	if err == nil {
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+
+	user, err = a.client.Queries.BindUserSubject(ctx, dbGen.BindUserSubjectParams{
+		Issuer:   claims.Issuer,
+		Subject:  claims.Subject,
+		TenantID: tenant.ID,
+		Email:    claims.Email,
+	})
+	if err == nil {
+		slog.InfoContext(ctx, "user signed in for the first time", "user_uuid", user.Uuid, "tenant_uuid", tenant.Uuid)
+		return user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("bind user: %w", err)
+	}
+
+	user, err = a.client.Queries.CreateUser(ctx, dbGen.CreateUserParams{
+		Email:      claims.Email,
+		Issuer:     pgtype.Text{String: claims.Issuer, Valid: true},
+		Subject:    pgtype.Text{String: claims.Subject, Valid: true},
+		TenantUuid: tenant.Uuid,
+	})
+	if err != nil {
+		if dbErr := db.ParseDBError(err); dbErr != nil && dbErr.Code == db.UniqueViolation {
+			// the first requests of a user can race to create it, otherwise
+			// the email is of another user
+			user, err = a.client.Queries.GetUserBySubject(ctx, params)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Errorf("%w: email %q is taken by another user", ErrInvalidCredentials, claims.Email)
+			}
+			return user, err
+		}
+		return nil, fmt.Errorf("create user: %w", err)
+	}
+
+	slog.InfoContext(ctx, "user signed in for the first time", "user_uuid", user.Uuid, "tenant_uuid", tenant.Uuid)
+	return user, nil
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// KeySet finds the public key a token was signed with by the `kid` of its
+// header, which may be empty
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// StaticKeys is a fixed set of public keys by id. A key with an empty id
+// verifies tokens of any kid, e.g., a single key read from a PEM file.
+type StaticKeys map[string]crypto.PublicKey
+
+func (k StaticKeys) Key(_ context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := k[kid]; ok {
+		return key, nil
+	}
+	if key, ok := k[""]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidToken, kid)
+}
+
+// LoadKeyFile reads the public keys of a file, either a JWKS document or
+// a PEM encoded public key or certificate
+func LoadKeyFile(path string) (StaticKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read key file: %w", err)
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return ParseJWKS(trimmed)
+	}
+
+	keys := StaticKeys{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var key crypto.PublicKey
+		switch block.Type {
+		case "PUBLIC KEY":
+			key, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			cert, err = x509.ParseCertificate(block.Bytes)
+			if err == nil {
+				key = cert.PublicKey
+			}
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", block.Type, err)
+		}
+
+		// keys can't be told apart without ids, several ones need a JWKS
+		if _, ok := keys[""]; ok {
+			return nil, errors.New("several public keys found in key file, use a JWKS file with key ids")
+		}
+		keys[""] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no public key found in key file")
+	}
+	return keys, nil
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	X string `json:"x"`
+	Y string `json:"y"`
+}
+
+// ParseJWKS reads the signature keys of a JWKS document, the keys of
+// unsupported types are skipped
+func ParseJWKS(data []byte) (StaticKeys, error) {
+	var doc struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal jwks: %w", err)
+	}
+
+	keys := StaticKeys{}
+	for _, k := range doc.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", k.Kid, err)
+		}
+		if key != nil {
+			keys[k.Kid] = key
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil, errors.New("no signature key found in jwks")
+	}
+	return keys, nil
+}
+
+// publicKey returns nil for unsupported key types
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("decode n: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("decode e: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 || exponent.Int64() < 3 {
+			return nil, errors.New("invalid exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var validate ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, validate = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, validate = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, validate = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("decode y: %w", err)
+		}
+
+		// the uncompressed point is rejected when it isn't on the curve
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid point")
+		}
+		if _, err := validate.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, fmt.Errorf("invalid point: %w", err)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("decode x: %w", err)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid key size")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+const (
+	// jwksTTL is how long the keys of a JWKS URL are used before fetching
+	// them again
+	jwksTTL = time.Hour
+	// jwksMinRefresh limits how often unknown key ids trigger a fetch, so
+	// forged tokens can't hammer the identity provider
+	jwksMinRefresh = 30 * time.Second
+)
+
+// RemoteKeys fetches the keys of a JWKS URL, they are cached and fetched
+// again when they expire or a token names a key that isn't known yet,
+// e.g., after the identity provider rotated its keys
+type RemoteKeys struct {
+	url    string
+	client *http.Client
+
+	mu          sync.Mutex
+	keys        StaticKeys
+	fetchedAt   time.Time
+	attemptedAt time.Time
+}
+
+func NewRemoteKeys(url string, client *http.Client) *RemoteKeys {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &RemoteKeys{url: url, client: client}
+}
+
+func (k *RemoteKeys) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.keys != nil && time.Since(k.fetchedAt) < jwksTTL {
+		if key, err := k.keys.Key(ctx, kid); err == nil {
+			return key, nil
+		}
+	}
+
+	if time.Since(k.attemptedAt) >= jwksMinRefresh {
+		k.attemptedAt = time.Now()
+		keys, err := k.fetch(ctx)
+		if err != nil && k.keys == nil {
+			return nil, err
+		}
+		// on errors the known keys are kept while the identity provider
+		// is down
+		if err == nil {
+			k.keys = keys
+			k.fetchedAt = time.Now()
+		}
+	}
+
+	if k.keys == nil {
+		return nil, errors.New("jwks unavailable")
+	}
+	return k.keys.Key(ctx, kid)
+}
+
+func (k *RemoteKeys) fetch(ctx context.Context) (StaticKeys, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, k.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create jwks request: %w", err)
+	}
+
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+
+	return ParseJWKS(data)
+}
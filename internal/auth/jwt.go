@@ -0,0 +1,342 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenVerifier checks the bearer tokens issued by an identity provider.
+// The JWT verifier is the one used in production, tests can provide their
+// own.
+type TokenVerifier interface {
+	Verify(ctx context.Context, token string) (*Claims, error)
+}
+
+// Claims of a verified token, mapped to the service's concepts. Issuer
+// and Subject identify the user, the email can change.
+type Claims struct {
+	Issuer     string
+	Subject    string
+	Email      string
+	TenantUUID string
+	// Scopes is nil when the token doesn't carry any
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// JWTConfig describes the tokens of the identity provider, the claim
+// names default to `tenant`, `email` and `scope`
+type JWTConfig struct {
+	// Issuer and Audience are required, tokens of other issuers or for
+	// other services are rejected
+	Issuer   string
+	Audience string
+
+	TenantClaim string
+	EmailClaim  string
+	// ScopesClaim is either a space separated string, as in OAuth, or a
+	// list of strings
+	ScopesClaim string
+
+	// Leeway allows for clock skew with the identity provider
+	Leeway time.Duration
+}
+
+// JWTVerifier verifies signed JWTs with the keys of a KeySet. Only
+// asymmetric algorithms are supported, the service never holds the
+// signing key.
+type JWTVerifier struct {
+	keys   KeySet
+	config JWTConfig
+	now    func() time.Time
+}
+
+func NewJWTVerifier(keys KeySet, config JWTConfig) (*JWTVerifier, error) {
+	if config.Issuer == "" {
+		return nil, errors.New("missing issuer")
+	}
+	if config.Audience == "" {
+		return nil, errors.New("missing audience")
+	}
+
+	if config.TenantClaim == "" {
+		config.TenantClaim = "tenant"
+	}
+	if config.EmailClaim == "" {
+		config.EmailClaim = "email"
+	}
+	if config.ScopesClaim == "" {
+		config.ScopesClaim = "scope"
+	}
+	if config.Leeway == 0 {
+		config.Leeway = time.Minute
+	}
+
+	return &JWTVerifier{
+		keys:   keys,
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid,omitempty"`
+	Typ string `json:"typ,omitempty"`
+}
+
+// Verify checks the signature and the registered claims of the token and
+// maps the rest to Claims
+func (v *JWTVerifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %v", ErrInvalidToken, err)
+	}
+
+	key, err := v.keys.Key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %v", ErrInvalidToken, err)
+	}
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %v", ErrInvalidToken, err)
+	}
+
+	return v.mapClaims(claims)
+}
+
+func (v *JWTVerifier) mapClaims(claims map[string]any) (*Claims, error) {
+	now := v.now()
+
+	exp, ok := numericDate(claims["exp"])
+	if !ok {
+		return nil, fmt.Errorf("%w: missing exp", ErrInvalidToken)
+	}
+	if now.After(exp.Add(v.config.Leeway)) {
+		return nil, fmt.Errorf("%w: expired", ErrInvalidToken)
+	}
+	if nbf, ok := numericDate(claims["nbf"]); ok && now.Add(v.config.Leeway).Before(nbf) {
+		return nil, fmt.Errorf("%w: not valid yet", ErrInvalidToken)
+	}
+
+	if claims["iss"] != v.config.Issuer {
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if !slices.Contains(stringList(claims["aud"]), v.config.Audience) {
+		return nil, fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+
+	tenant, _ := claims[v.config.TenantClaim].(string)
+	if tenant == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, v.config.TenantClaim)
+	}
+	email, _ := claims[v.config.EmailClaim].(string)
+	if email == "" {
+		return nil, fmt.Errorf("%w: missing %s claim", ErrInvalidToken, v.config.EmailClaim)
+	}
+
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return nil, fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+
+	var scopes []string
+	switch s := claims[v.config.ScopesClaim].(type) {
+	case string:
+		scopes = strings.Fields(s)
+	case []any:
+		scopes = stringList(s)
+	}
+
+	return &Claims{
+		Issuer:     v.config.Issuer,
+		Subject:    subject,
+		Email:      email,
+		TenantUUID: tenant,
+		Scopes:     scopes,
+		ExpiresAt:  exp,
+	}, nil
+}
+
+// verifySignature checks the signature of the signing input with the key,
+// the key type must match the algorithm
+func verifySignature(alg string, key crypto.PublicKey, input, signature []byte) error {
+	switch alg {
+	case "RS256", "RS384", "RS512", "PS256", "PS384", "PS512":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an RSA key", alg)
+		}
+		hash := algHash(alg)
+		digest := hashSum(hash, input)
+		if alg[0] == 'P' {
+			return rsa.VerifyPSS(pub, hash, digest, signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+		}
+		return rsa.VerifyPKCS1v15(pub, hash, digest, signature)
+	case "ES256", "ES384", "ES512":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an ECDSA key", alg)
+		}
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(signature) != 2*size {
+			return errors.New("invalid signature length")
+		}
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(pub, hashSum(algHash(alg), input), r, s) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	case "EdDSA":
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return fmt.Errorf("%s requires an Ed25519 key", alg)
+		}
+		if !ed25519.Verify(pub, input, signature) {
+			return errors.New("invalid signature")
+		}
+		return nil
+	default:
+		// covers `none` and the symmetric algorithms
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+}
+
+// SignToken signs the claims with the private key. Tokens are issued by
+// the identity provider, this is for tests and local development.
+func SignToken(key crypto.Signer, kid string, claims map[string]any) (string, error) {
+	var alg string
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		alg = "RS256"
+	case *ecdsa.PrivateKey:
+		alg = map[int]string{256: "ES256", 384: "ES384", 521: "ES512"}[k.Curve.Params().BitSize]
+	case ed25519.PrivateKey:
+		alg = "EdDSA"
+	}
+	if alg == "" {
+		return "", fmt.Errorf("unsupported key %T", key)
+	}
+
+	header, err := json.Marshal(jwtHeader{Alg: alg, Kid: kid, Typ: "JWT"})
+	if err != nil {
+		return "", fmt.Errorf("marshal header: %w", err)
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("marshal claims: %w", err)
+	}
+	input := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	var signature []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, hashSum(crypto.SHA256, []byte(input)))
+	case *ecdsa.PrivateKey:
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k, hashSum(algHash(alg), []byte(input)))
+		if err == nil {
+			size := (k.Curve.Params().BitSize + 7) / 8
+			signature = make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+		}
+	case ed25519.PrivateKey:
+		signature = ed25519.Sign(k, []byte(input))
+	}
+	if err != nil {
+		return "", fmt.Errorf("sign token: %w", err)
+	}
+
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func algHash(alg string) crypto.Hash {
+	switch alg[2:] {
+	case "384":
+		return crypto.SHA384
+	case "512":
+		return crypto.SHA512
+	default:
+		return crypto.SHA256
+	}
+}
+
+func hashSum(hash crypto.Hash, input []byte) []byte {
+	h := hash.New()
+	h.Write(input)
+	return h.Sum(nil)
+}
+
+func decodeSegment(segment string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// numericDate reads a JWT NumericDate, seconds since the epoch
+func numericDate(v any) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+// stringList reads a claim that is either a string or a list of strings
+func stringList(v any) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []any:
+		var out []string
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
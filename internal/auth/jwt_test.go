@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	is_ "github.com/matryer/is"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testClaims() map[string]any {
+	return map[string]any{
+		"sub":    "sso|123",
+		"iss":    "https://id.example.com",
+		"aud":    []string{"doubleed"},
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"tenant": "t1",
+		"email":  "staff@example.com",
+		"scope":  "ledgers:read reports:read",
+	}
+}
+
+func testJWTConfig() JWTConfig {
+	return JWTConfig{Issuer: "https://id.example.com", Audience: "doubleed"}
+}
+
+func TestJWTVerifier(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	is.NoErr(err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	is.NoErr(err)
+
+	verifier, err := NewJWTVerifier(StaticKeys{
+		"rsa": rsaKey.Public(),
+		"ec":  ecKey.Public(),
+		"ed":  edKey.Public(),
+	}, testJWTConfig())
+	is.NoErr(err)
+
+	for kid, key := range map[string]crypto.Signer{"rsa": rsaKey, "ec": ecKey, "ed": edKey} {
+		token, err := SignToken(key, kid, testClaims())
+		is.NoErr(err)
+
+		claims, err := verifier.Verify(ctx, token)
+		is.NoErr(err) // valid token
+		is.Equal(claims.TenantUUID, "t1")
+		is.Equal(claims.Email, "staff@example.com")
+		is.Equal(claims.Issuer, "https://id.example.com")
+		is.Equal(claims.Subject, "sso|123")
+		is.Equal(claims.Scopes, []string{ScopeLedgersRead, ScopeReportsRead})
+	}
+
+	invalid := func(kid string, key crypto.Signer, edit func(map[string]any)) error {
+		claims := testClaims()
+		edit(claims)
+		token, err := SignToken(key, kid, claims)
+		is.NoErr(err)
+		_, err = verifier.Verify(ctx, token)
+		return err
+	}
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() })
+	is.True(errors.Is(err, ErrInvalidToken)) // expired
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { delete(c, "exp") })
+	is.True(errors.Is(err, ErrInvalidToken)) // no expiration
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { c["iss"] = "https://evil.example.com" })
+	is.True(errors.Is(err, ErrInvalidToken)) // other issuer
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { delete(c, "iss") })
+	is.True(errors.Is(err, ErrInvalidToken)) // no issuer
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { c["aud"] = "other" })
+	is.True(errors.Is(err, ErrInvalidToken)) // other audience
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { delete(c, "aud") })
+	is.True(errors.Is(err, ErrInvalidToken)) // no audience
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { delete(c, "sub") })
+	is.True(errors.Is(err, ErrInvalidToken)) // no subject
+
+	err = invalid("rsa", rsaKey, func(c map[string]any) { delete(c, "tenant") })
+	is.True(errors.Is(err, ErrInvalidToken)) // no tenant
+
+	err = invalid("ec", rsaKey, func(map[string]any) {})
+	is.True(errors.Is(err, ErrInvalidToken)) // key of another type
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+	err = invalid("rsa", otherKey, func(map[string]any) {})
+	is.True(errors.Is(err, ErrInvalidToken)) // signed with another key
+
+	// unsigned tokens are never accepted
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none","kid":"rsa"}`))
+	payload, err := json.Marshal(testClaims())
+	is.NoErr(err)
+	_, err = verifier.Verify(ctx, header+"."+base64.RawURLEncoding.EncodeToString(payload)+".")
+	is.True(errors.Is(err, ErrInvalidToken))
+}
+
+func TestJWTVerifierScopesList(t *testing.T) {
+	is := is_.New(t)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	is.NoErr(err)
+	config := testJWTConfig()
+	config.TenantClaim = "org"
+	config.ScopesClaim = "permissions"
+	verifier, err := NewJWTVerifier(StaticKeys{"": key.Public()}, config)
+	is.NoErr(err)
+
+	claims := testClaims()
+	claims["org"] = "t2"
+	claims["permissions"] = []string{ScopeAuditRead}
+	token, err := SignToken(key, "", claims)
+	is.NoErr(err)
+
+	got, err := verifier.Verify(context.Background(), token)
+	is.NoErr(err)
+	is.Equal(got.TenantUUID, "t2")
+	is.Equal(got.Scopes, []string{ScopeAuditRead})
+}
+
+func TestLoadKeyFile(t *testing.T) {
+	is := is_.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	is.NoErr(err)
+
+	path := filepath.Join(t.TempDir(), "idp.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+	is.NoErr(err)
+
+	keys, err := LoadKeyFile(path)
+	is.NoErr(err)
+
+	token, err := SignToken(key, "any", testClaims())
+	is.NoErr(err)
+	verifier, err := NewJWTVerifier(keys, testJWTConfig())
+	is.NoErr(err)
+	_, err = verifier.Verify(context.Background(), token)
+	is.NoErr(err) // a single PEM key verifies every kid
+
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+	otherDer, err := x509.MarshalPKIXPublicKey(other.Public())
+	is.NoErr(err)
+	data := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	data = append(data, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: otherDer})...)
+	is.NoErr(os.WriteFile(path, data, 0o600))
+
+	_, err = LoadKeyFile(path)
+	is.True(err != nil) // keys without ids can't be told apart
+}
+
+func TestNewJWTVerifier(t *testing.T) {
+	is := is_.New(t)
+
+	_, err := NewJWTVerifier(StaticKeys{}, JWTConfig{Audience: "doubleed"})
+	is.True(err != nil) // missing issuer
+
+	_, err = NewJWTVerifier(StaticKeys{}, JWTConfig{Issuer: "https://id.example.com"})
+	is.True(err != nil) // missing audience
+}
+
+func TestRemoteKeys(t *testing.T) {
+	is := is_.New(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	is.NoErr(err)
+
+	fetches := 0
+	idp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": "k1",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	defer idp.Close()
+
+	verifier, err := NewJWTVerifier(NewRemoteKeys(idp.URL, idp.Client()), testJWTConfig())
+	is.NoErr(err)
+
+	token, err := SignToken(key, "k1", testClaims())
+	is.NoErr(err)
+	for range 3 {
+		_, err = verifier.Verify(context.Background(), token)
+		is.NoErr(err)
+	}
+	is.Equal(fetches, 1) // keys are cached
+
+	token, err = SignToken(key, "k2", testClaims())
+	is.NoErr(err)
+	_, err = verifier.Verify(context.Background(), token)
+	is.True(err != nil && strings.Contains(err.Error(), "unknown key")) // unknown kid
+	is.Equal(fetches, 1)                                                // refetches are rate limited
+}
@@ -0,0 +1,81 @@
+// Package authz decides what the roles granted on a ledger allow. Users
+// are granted one role per ledger, API keys without a user act for the
+// whole tenant and are only limited by their scopes.
+package authz
+
+import (
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"slices"
+)
+
+// Permission is an action on a ledger or on what's in it
+type Permission string
+
+const (
+	ReadLedger        Permission = "ledger:read"
+	WriteLedger       Permission = "ledger:write"
+	ReadAccounts      Permission = "accounts:read"
+	WriteAccounts     Permission = "accounts:write"
+	ReadTransactions  Permission = "transactions:read"
+	WriteTransactions Permission = "transactions:write"
+	ReadReports       Permission = "reports:read"
+	ReadAudit         Permission = "audit:read"
+	ManageGrants      Permission = "grants:manage"
+)
+
+// Role is granted to a user on a ledger
+type Role = dbGen.LedgerRole
+
+const (
+	RoleOwner      = dbGen.LedgerRoleOwner
+	RoleAccountant = dbGen.LedgerRoleAccountant
+	RoleViewer     = dbGen.LedgerRoleViewer
+	RoleAuditor    = dbGen.LedgerRoleAuditor
+)
+
+var rolePermissions = map[Role][]Permission{
+	RoleOwner: {
+		ReadLedger,
+		WriteLedger,
+		ReadAccounts,
+		WriteAccounts,
+		ReadTransactions,
+		WriteTransactions,
+		ReadReports,
+		ReadAudit,
+		ManageGrants,
+	},
+	// keeps the books but doesn't manage access
+	RoleAccountant: {
+		ReadLedger,
+		ReadAccounts,
+		WriteAccounts,
+		ReadTransactions,
+		WriteTransactions,
+		ReadReports,
+	},
+	RoleViewer: {
+		ReadReports,
+	},
+	// reads everything, including the audit trail, and changes nothing
+	RoleAuditor: {
+		ReadLedger,
+		ReadAccounts,
+		ReadTransactions,
+		ReadReports,
+		ReadAudit,
+	},
+}
+
+// Roles lists every role that can be granted
+var Roles = []Role{RoleOwner, RoleAccountant, RoleViewer, RoleAuditor}
+
+// Can reports whether the role allows the permission
+func Can(role Role, permission Permission) bool {
+	return slices.Contains(rolePermissions[role], permission)
+}
+
+// ValidRole reports whether the role can be granted
+func ValidRole(role string) bool {
+	return slices.Contains(Roles, Role(role))
+}
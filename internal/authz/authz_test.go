@@ -0,0 +1,23 @@
+package authz
+
+import (
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestCan(t *testing.T) {
+	is := is_.New(t)
+
+	is.True(Can(RoleOwner, ManageGrants))
+
+	is.True(Can(RoleAccountant, WriteTransactions))
+	is.True(!Can(RoleAccountant, ReadAudit))
+
+	is.True(Can(RoleViewer, ReadReports))
+	is.True(!Can(RoleViewer, ReadTransactions))
+
+	is.True(Can(RoleAuditor, ReadAudit))
+	is.True(!Can(RoleAuditor, WriteTransactions))
+
+	is.True(!Can(Role("intern"), ReadReports))
+}
@@ -0,0 +1,345 @@
+// Package config loads the configuration of the service. Values come from
+// the defaults, an optional YAML file, the environment and the command
+// line flags, each one overriding the previous ones.
+package config
+
+import (
+	"encoding"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	"github.com/j0lvera/go-double-e/internal/server"
+	"gopkg.in/yaml.v3"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// FileEnv is the environment variable of the config file, the `--config`
+// flag takes precedence
+const FileEnv = "CONFIG_FILE"
+
+// Config of the service. Every field can be set in the file with its
+// `yaml` key, in the environment with its `env` variable, and on the
+// command line with its `flag`.
+type Config struct {
+	Port     int `yaml:"port" env:"PORT" flag:"port" usage:"port of the HTTP server" validate:"min=1,max=65535"`
+	GRPCPort int `yaml:"grpc_port" env:"GRPC_PORT" flag:"grpc-port" usage:"port of the gRPC server, 0 disables it" validate:"min=0,max=65535"`
+	// MetricsPort serves /metrics apart from the API, the metrics are of
+	// every tenant so it must not be exposed to them
+	MetricsPort int    `yaml:"metrics_port" env:"METRICS_PORT" flag:"metrics-port" usage:"port of the admin server of the metrics, 0 disables it" validate:"min=0,max=65535"`
+	Debug       bool   `yaml:"debug" env:"DEBUG" flag:"debug" usage:"log debug records"`
+	LogFormat   string `yaml:"log_format" env:"LOG_FORMAT" flag:"log-format" usage:"format of the logs, text or json" validate:"oneof=text json"`
+	// MigrateOnStart applies the pending migrations before serving
+	MigrateOnStart bool `yaml:"migrate_on_start" env:"MIGRATE_ON_START" flag:"migrate-on-start" usage:"apply the pending migrations before serving"`
+
+	Database   Database   `yaml:"database"`
+	Shutdown   Shutdown   `yaml:"shutdown"`
+	Ready      Ready      `yaml:"ready"`
+	Tracing    Tracing    `yaml:"tracing"`
+	RateLimits RateLimits `yaml:"rate_limits"`
+	JWT        JWT        `yaml:"jwt"`
+	Webhooks   Webhooks   `yaml:"webhooks"`
+}
+
+// Database is the connection pool
+type Database struct {
+	URL               string        `yaml:"url" env:"DATABASE_URL" flag:"database-url" usage:"connection string of the database" validate:"required"`
+	MaxConns          int32         `yaml:"max_conns" env:"DB_MAX_CONNS" flag:"db-max-conns" usage:"maximum number of connections" validate:"min=1"`
+	MinConns          int32         `yaml:"min_conns" env:"DB_MIN_CONNS" flag:"db-min-conns" usage:"connections kept open when idle" validate:"min=0,ltefield=MaxConns"`
+	MaxConnLifetime   time.Duration `yaml:"max_conn_lifetime" env:"DB_MAX_CONN_LIFETIME" flag:"db-max-conn-lifetime" usage:"maximum lifetime of a connection" validate:"gt=0"`
+	MaxConnIdleTime   time.Duration `yaml:"max_conn_idle_time" env:"DB_MAX_CONN_IDLE_TIME" flag:"db-max-conn-idle-time" usage:"maximum idle time of a connection" validate:"gt=0"`
+	HealthCheckPeriod time.Duration `yaml:"health_check_period" env:"DB_HEALTH_CHECK_PERIOD" flag:"db-health-check-period" usage:"how often idle connections are checked" validate:"gt=0"`
+}
+
+// Shutdown of the HTTP server
+type Shutdown struct {
+	Delay   time.Duration `yaml:"delay" env:"SHUTDOWN_DELAY" flag:"shutdown-delay" usage:"time between failing the readiness probe and shutting down" validate:"min=0"`
+	Timeout time.Duration `yaml:"timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" usage:"time given to the requests in flight on shutdown" validate:"gt=0"`
+}
+
+// Ready is the readiness probe
+type Ready struct {
+	MaxReplicationLag time.Duration `yaml:"max_replication_lag" env:"READY_MAX_REPLICATION_LAG" flag:"ready-max-replication-lag" usage:"lag of the database replica at which the instance isn't ready, 0 skips the check" validate:"min=0"`
+}
+
+// Tracing of the requests and queries
+type Tracing struct {
+	Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" usage:"exporter of the spans, off, otlp or stdout" validate:"oneof=off otlp stdout"`
+}
+
+// RateLimits per API key, user or client IP, e.g., `600/m` or `off`
+type RateLimits struct {
+	Read   ratelimit.Limit `yaml:"read" env:"RATE_LIMIT_READ" flag:"rate-limit-read" usage:"limit of the read requests"`
+	Write  ratelimit.Limit `yaml:"write" env:"RATE_LIMIT_WRITE" flag:"rate-limit-write" usage:"limit of the write requests"`
+	Export ratelimit.Limit `yaml:"export" env:"RATE_LIMIT_EXPORT" flag:"rate-limit-export" usage:"limit of the exports and dumps"`
+	Auth   ratelimit.Limit `yaml:"auth" env:"RATE_LIMIT_AUTH" flag:"rate-limit-auth" usage:"limit of the failed authentications per client IP"`
+}
+
+// JWT authentication, it's enabled by either a JWKS URL or a key file.
+// The issuer and the audience are required then.
+type JWT struct {
+	JWKSURL     string `yaml:"jwks_url" env:"JWT_JWKS_URL" flag:"jwt-jwks-url" usage:"URL of the JWKS of the identity provider" validate:"omitempty,url,excluded_with=KeyFile"`
+	KeyFile     string `yaml:"key_file" env:"JWT_KEY_FILE" flag:"jwt-key-file" usage:"PEM or JWKS file of the keys of the identity provider"`
+	Issuer      string `yaml:"issuer" env:"JWT_ISSUER" flag:"jwt-issuer" usage:"expected issuer of the tokens" validate:"required_with=JWKSURL KeyFile"`
+	Audience    string `yaml:"audience" env:"JWT_AUDIENCE" flag:"jwt-audience" usage:"expected audience of the tokens" validate:"required_with=JWKSURL KeyFile"`
+	TenantClaim string `yaml:"tenant_claim" env:"JWT_TENANT_CLAIM" flag:"jwt-tenant-claim" usage:"claim of the tenant UUID"`
+	EmailClaim  string `yaml:"email_claim" env:"JWT_EMAIL_CLAIM" flag:"jwt-email-claim" usage:"claim of the email of the user"`
+	ScopesClaim string `yaml:"scopes_claim" env:"JWT_SCOPES_CLAIM" flag:"jwt-scopes-claim" usage:"claim of the scopes of the user"`
+}
+
+// Webhooks of the tenants
+type Webhooks struct {
+	// AllowPrivateAddresses lets the webhooks reach loopback and private
+	// addresses, for development only, any tenant could reach the network
+	// of the service
+	AllowPrivateAddresses bool `yaml:"allow_private_addresses" env:"WEBHOOKS_ALLOW_PRIVATE_ADDRESSES" flag:"webhooks-allow-private-addresses" usage:"let the webhooks reach private addresses, for development only"`
+}
+
+// Default is the configuration of every environment unless told otherwise
+func Default() Config {
+	limits := server.DefaultRateLimits()
+	return Config{
+		Port:        8080,
+		GRPCPort:    9090,
+		MetricsPort: 9100,
+		LogFormat:   "text",
+		Database: Database{
+			MaxConns:          25,
+			MinConns:          5,
+			MaxConnLifetime:   time.Hour,
+			MaxConnIdleTime:   30 * time.Minute,
+			HealthCheckPeriod: time.Minute,
+		},
+		Shutdown: Shutdown{
+			Delay:   5 * time.Second,
+			Timeout: 5 * time.Second,
+		},
+		Tracing: Tracing{Exporter: "off"},
+		// the defaults of auth.JWTConfig, so they are printed
+		JWT: JWT{
+			TenantClaim: "tenant",
+			EmailClaim:  "email",
+			ScopesClaim: "scope",
+		},
+		RateLimits: RateLimits{
+			Read:   limits.Read,
+			Write:  limits.Write,
+			Export: limits.Export,
+			Auth:   limits.Auth,
+		},
+	}
+}
+
+// Load reads the configuration of the command line arguments, the
+// environment and the config file, the file is given by `--config` or
+// CONFIG_FILE. The result is validated.
+func Load(name string, args []string) (*Config, error) {
+	fs, flags := newFlagSet(name)
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments %v", fs.Args())
+	}
+
+	cfg := Default()
+
+	path := os.Getenv(FileEnv)
+	if file, ok := flags["config"]; ok {
+		path = file
+	}
+	if path != "" {
+		if err := loadFile(&cfg, path); err != nil {
+			return nil, err
+		}
+	}
+
+	err := walk(&cfg, func(field reflect.StructField, v reflect.Value) error {
+		// empty variables are taken for unset ones
+		if value := os.Getenv(field.Tag.Get("env")); value != "" {
+			if err := set(v, value); err != nil {
+				return fmt.Errorf("%s: %w", field.Tag.Get("env"), err)
+			}
+		}
+		if value, ok := flags[field.Tag.Get("flag")]; ok {
+			if err := set(v, value); err != nil {
+				return fmt.Errorf("--%s: %w", field.Tag.Get("flag"), err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validator.New(validator.WithRequiredStructEnabled()).Struct(cfg); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// Print writes the configuration as YAML, with the password of the
+// database redacted, so it can be shared and loaded back as a file
+func (c *Config) Print(w io.Writer) error {
+	redacted := *c
+	redacted.Database.URL = redactURL(c.Database.URL)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(redacted); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func loadFile(cfg *Config, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	// typos would be silently ignored otherwise
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// newFlagSet declares the flags of the fields, the values set on the
+// command line are collected raw, so they are applied after the file and
+// the environment
+func newFlagSet(name string) (*flag.FlagSet, map[string]string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags := make(map[string]string)
+
+	fs.Func("config", "YAML config file, or "+FileEnv, func(value string) error {
+		flags["config"] = value
+		return nil
+	})
+
+	defaults := Default()
+	// the tags are fixed, walking the defaults can't fail
+	_ = walk(&defaults, func(field reflect.StructField, v reflect.Value) error {
+		name := field.Tag.Get("flag")
+		usage := fmt.Sprintf("%s (%s)", field.Tag.Get("usage"), field.Tag.Get("env"))
+		fs.Var(&rawFlag{name: name, flags: flags, value: format(v), isBool: v.Kind() == reflect.Bool}, name, usage)
+		return nil
+	})
+
+	return fs, flags
+}
+
+// rawFlag records the value of the flag as is
+type rawFlag struct {
+	name   string
+	flags  map[string]string
+	value  string
+	isBool bool
+}
+
+func (f *rawFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.value
+}
+
+func (f *rawFlag) Set(value string) error {
+	f.flags[f.name] = value
+	return nil
+}
+
+func (f *rawFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// walk calls fn with every field of cfg that has an env variable
+func walk(cfg *Config, fn func(field reflect.StructField, v reflect.Value) error) error {
+	var visit func(v reflect.Value) error
+	visit = func(v reflect.Value) error {
+		for i := range v.NumField() {
+			field := v.Type().Field(i)
+			value := v.Field(i)
+
+			if field.Tag.Get("env") == "" && value.Kind() == reflect.Struct {
+				if err := visit(value); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := fn(field, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(reflect.ValueOf(cfg).Elem())
+}
+
+// set parses s into the field
+func set(v reflect.Value, s string) error {
+	if v.Addr().Type().Implements(textUnmarshaler) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Interface().(type) {
+	case time.Duration:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// format is the default of the field in the usage of the flags
+func format(v reflect.Value) string {
+	if stringer, ok := v.Interface().(fmt.Stringer); ok {
+		return stringer.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+var dsnPassword = regexp.MustCompile(`password=\S+`)
+
+// redactURL hides the password of URLs and key/value connection strings
+func redactURL(s string) string {
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		return u.Redacted()
+	}
+	return dsnPassword.ReplaceAllString(s, "password=xxxxx")
+}
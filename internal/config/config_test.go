@@ -0,0 +1,79 @@
+package config
+
+import (
+	"bytes"
+	is_ "github.com/matryer/is"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	is := is_.New(t)
+
+	path := filepath.Join(t.TempDir(), "doubleed.yaml")
+	err := os.WriteFile(path, []byte(`
+port: 9000
+database:
+  url: postgres://app:secret@db:5432/ledger
+  max_conns: 50
+rate_limits:
+  export: 5/m
+`), 0o600)
+	is.NoErr(err)
+
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("PORT", "9100")
+	t.Setenv("DB_MAX_CONN_LIFETIME", "2h")
+
+	cfg, err := Load("doubleed", []string{"--config", path, "--port", "9200", "--debug"})
+	is.NoErr(err)
+
+	is.Equal(cfg.Port, 9200)                                           // flags override the environment
+	is.Equal(cfg.Database.MaxConnLifetime, 2*time.Hour)                // the environment overrides the file
+	is.Equal(cfg.Database.MaxConns, int32(50))                         // the file overrides the defaults
+	is.Equal(cfg.Database.MinConns, int32(5))                          // defaults
+	is.Equal(cfg.Database.URL, "postgres://app:secret@db:5432/ledger") // empty variables are ignored
+	is.Equal(cfg.RateLimits.Export.String(), "5/m")
+	is.True(cfg.Debug)
+
+	var out bytes.Buffer
+	is.NoErr(cfg.Print(&out))
+	is.True(!strings.Contains(out.String(), "secret")) // the password is redacted
+	is.True(strings.Contains(out.String(), "max_conn_lifetime: 2h0m0s"))
+	is.True(strings.Contains(out.String(), "export: 5/m"))
+}
+
+func TestLoadInvalid(t *testing.T) {
+	is := is_.New(t)
+
+	t.Setenv("DATABASE_URL", "postgres://localhost/ledger")
+
+	_, err := Load("doubleed", []string{"--db-min-conns", "30"})
+	is.True(err != nil) // more idle connections than the maximum
+
+	_, err = Load("doubleed", []string{"--log-format", "xml"})
+	is.True(err != nil)
+
+	_, err = Load("doubleed", []string{"--rate-limit-read", "fast"})
+	is.True(err != nil)
+
+	_, err = Load("doubleed", []string{"--jwt-key-file", "idp.pem", "--jwt-issuer", "https://id.example.com"})
+	is.True(err != nil) // JWTs of any audience
+
+	_, err = Load("doubleed", []string{"--jwt-key-file", "idp.pem", "--jwt-issuer", "https://id.example.com", "--jwt-audience", "doubleed"})
+	is.NoErr(err)
+
+	t.Setenv("DATABASE_URL", "")
+	_, err = Load("doubleed", nil)
+	is.True(err != nil) // the database is required
+
+	path := filepath.Join(t.TempDir(), "doubleed.yaml")
+	is.NoErr(os.WriteFile(path, []byte("prot: 9000\n"), 0o600))
+	t.Setenv(FileEnv, path)
+	t.Setenv("DATABASE_URL", "postgres://localhost/ledger")
+	_, err = Load("doubleed", nil)
+	is.True(err != nil) // unknown keys are typos
+}
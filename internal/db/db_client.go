@@ -1,17 +1,77 @@
 package db
 
 import (
+	"context"
+	"fmt"
 	db "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 type Client struct {
 	// Make sqlc queries public so handlers can use it directly
 	Queries *db.Queries
+
+	pool *pgxpool.Pool
+	// tx is set by NewTxClient, the transactions of the client are
+	// savepoints in it
+	tx pgx.Tx
 }
 
 func NewClient(pool *pgxpool.Pool) *Client {
 	return &Client{
 		Queries: db.New(pool),
+		pool:    pool,
+	}
+}
+
+// NewTxClient runs the queries in a transaction owned by the caller, which
+// commits or rolls it back. WithTx creates savepoints in it. The client has
+// no pool, so it can't Ping or Listen.
+func NewTxClient(tx pgx.Tx) *Client {
+	return &Client{
+		Queries: db.New(tx),
+		tx:      tx,
+	}
+}
+
+// WithTx runs fn inside a database transaction. The transaction is
+// committed when fn returns nil and rolled back otherwise.
+func (c *Client) WithTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	return c.withTx(ctx, pgx.TxOptions{}, fn)
+}
+
+// WithReadTx runs fn inside a read only transaction that sees a single
+// snapshot of the database, the writes committed while it runs are not
+// visible to it.
+func (c *Client) WithReadTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	return c.withTx(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}, fn)
+}
+
+func (c *Client) withTx(ctx context.Context, opts pgx.TxOptions, fn func(q *db.Queries) error) error {
+	tx, err := c.begin(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if err := fn(c.Queries.WithTx(tx)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// begin starts a transaction, or a savepoint when the client is bound to
+// one, the options of the outer transaction apply then.
+func (c *Client) begin(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error) {
+	if c.tx != nil {
+		return c.tx.Begin(ctx)
 	}
+	return c.pool.BeginTx(ctx, opts)
 }
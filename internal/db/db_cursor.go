@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	db "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+)
+
+// exportTransactions selects the same columns as ListTransactionsByLedger,
+// the metadata filter is optional.
+const exportTransactions = `
+  with ledger as (select ledgers.id
+                    from ledgers
+                   where ledgers.uuid = $1::text
+                     and ledgers.tenant_id = current_tenant_id())
+select t.uuid,
+       t.created_at,
+       t.updated_at,
+       t.amount,
+       t.date,
+       t.description,
+       t.metadata,
+       credit.uuid as credit_account_uuid,
+       debit.uuid  as debit_account_uuid
+  from transactions t
+       join accounts credit on credit.id = t.credit_account_id
+       join accounts debit on debit.id = t.debit_account_id
+ where t.ledger_id = (select id from ledger)
+   and ($2::jsonb is null or t.metadata @> $2::jsonb)
+ order by t.id`
+
+// TransactionStream reads every transaction of a ledger through a
+// server-side cursor of the database transaction it's bound to, see
+// StreamTransactions. It can be called once per transaction.
+type TransactionStream func(
+	ctx context.Context,
+	ledgerUUID string,
+	metadata []byte,
+	batchSize int,
+	fn func(rows []*db.ListTransactionsByLedgerRow) error,
+) error
+
+// StreamTransactions reads every transaction of a ledger through a
+// server-side cursor, fetching batchSize rows at a time so memory stays
+// constant regardless of the ledger size. fn is called once per batch and
+// the batch slice is reused between calls.
+func (c *Client) StreamTransactions(
+	ctx context.Context,
+	ledgerUUID string,
+	metadata []byte,
+	batchSize int,
+	fn func(rows []*db.ListTransactionsByLedgerRow) error,
+) error {
+	tx, err := c.begin(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	return streamTransactions(tx)(ctx, ledgerUUID, metadata, batchSize, fn)
+}
+
+// WithReadStream is WithReadTx with a TransactionStream that reads the
+// same snapshot, e.g., to dump a ledger with its accounts and transactions
+func (c *Client) WithReadStream(ctx context.Context, fn func(q *db.Queries, stream TransactionStream) error) error {
+	tx, err := c.begin(ctx, pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly})
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// nothing to commit, the transaction is read only
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	return fn(c.Queries.WithTx(tx), streamTransactions(tx))
+}
+
+func streamTransactions(tx pgx.Tx) TransactionStream {
+	return func(
+		ctx context.Context,
+		ledgerUUID string,
+		metadata []byte,
+		batchSize int,
+		fn func(rows []*db.ListTransactionsByLedgerRow) error,
+	) error {
+		_, err := tx.Exec(ctx, "declare transactions_export no scroll cursor for "+exportTransactions, ledgerUUID, metadata)
+		if err != nil {
+			return fmt.Errorf("declare cursor: %w", err)
+		}
+
+		fetch := fmt.Sprintf("fetch forward %d from transactions_export", batchSize)
+		batch := make([]*db.ListTransactionsByLedgerRow, 0, batchSize)
+		for {
+			batch = batch[:0]
+
+			rows, err := tx.Query(ctx, fetch)
+			if err != nil {
+				return fmt.Errorf("fetch from cursor: %w", err)
+			}
+
+			for rows.Next() {
+				var i db.ListTransactionsByLedgerRow
+				if err := rows.Scan(
+					&i.Uuid,
+					&i.CreatedAt,
+					&i.UpdatedAt,
+					&i.Amount,
+					&i.Date,
+					&i.Description,
+					&i.Metadata,
+					&i.CreditAccountUuid,
+					&i.DebitAccountUuid,
+				); err != nil {
+					rows.Close()
+					return fmt.Errorf("scan row: %w", err)
+				}
+				batch = append(batch, &i)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("fetch from cursor: %w", err)
+			}
+
+			if len(batch) == 0 {
+				return nil
+			}
+
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+	}
+}
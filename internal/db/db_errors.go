@@ -5,6 +5,13 @@ import (
 	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// Postgres error codes of constraint violations
+const (
+	NotNullViolation    = "23502"
+	ForeignKeyViolation = "23503"
+	UniqueViolation     = "23505"
+)
+
 // ConstraintError is the error returned when a constraint violation occurs
 type ConstraintError struct {
 	Field      string
@@ -27,3 +34,11 @@ func ParseDBError(err error) *ConstraintError {
 	}
 	return nil
 }
+
+// IsReferenceViolation reports whether a row references another that
+// doesn't exist, or isn't visible to the tenant. Queries resolve uuids to
+// null in that case, which fails the not null constraint.
+func IsReferenceViolation(err error) bool {
+	dbErr := ParseDBError(err)
+	return dbErr != nil && (dbErr.Code == NotNullViolation || dbErr.Code == ForeignKeyViolation)
+}
@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"time"
+)
+
+// Ping acquires a connection of the pool and checks it's alive
+func (c *Client) Ping(ctx context.Context) error {
+	return c.pool.Ping(ctx)
+}
+
+// currentSchemaVersion follows goose, the newest version applied that
+// wasn't rolled back afterwards
+const currentSchemaVersion = `
+select version_id
+  from goose_db_version applied
+ where is_applied
+   and not exists (select 1
+                     from goose_db_version rolled_back
+                    where rolled_back.version_id = applied.version_id
+                      and rolled_back.id > applied.id
+                      and not rolled_back.is_applied)
+ order by id desc
+ limit 1`
+
+// SchemaVersion is the goose version of the schema, zero when no migration
+// was applied
+func (c *Client) SchemaVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := c.pool.QueryRow(ctx, currentSchemaVersion).Scan(&version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, nil
+	}
+	return version, err
+}
+
+// ReplicationLag is how far behind its primary the database is, zero when
+// it isn't a replica, or when it replayed everything the primary sent
+func (c *Client) ReplicationLag(ctx context.Context) (time.Duration, error) {
+	var seconds float64
+	err := c.pool.QueryRow(ctx, `
+select case
+         when not pg_is_in_recovery() then 0
+         when pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() then 0
+         else coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)
+       end::float8`).Scan(&seconds)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+)
+
+// Listen calls fn with the payload of every notification sent to channel
+// until ctx is done or the connection fails. It holds a connection of the
+// pool for as long as it runs.
+func (c *Client) Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	conn, err := c.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "listen "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listen %s: %w", channel, err)
+	}
+	// don't hand a listening connection back to the pool
+	defer func() { _, _ = conn.Exec(context.Background(), "unlisten *") }()
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("wait for notification: %w", err)
+		}
+		fn(notification.Payload)
+	}
+}
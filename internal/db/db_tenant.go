@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"log/slog"
+	"strconv"
+)
+
+type tenantKey struct{}
+
+// WithTenant scopes the queries run with ctx to the tenant
+func WithTenant(ctx context.Context, tenantID int64) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns false when ctx isn't scoped to a tenant
+func TenantFromContext(ctx context.Context) (int64, bool) {
+	tenantID, ok := ctx.Value(tenantKey{}).(int64)
+	return tenantID, ok
+}
+
+// ScopeToTenant makes the pool set `app.tenant_id` to the tenant of the
+// context every time a connection is acquired, queries and row level
+// security policies read it through `current_tenant_id()`. Without a
+// tenant the setting is cleared, so a connection never keeps the tenant
+// of a previous request.
+func ScopeToTenant(config *pgxpool.Config) {
+	config.BeforeAcquire = func(ctx context.Context, conn *pgx.Conn) bool {
+		var tenant string
+		if tenantID, ok := TenantFromContext(ctx); ok {
+			tenant = strconv.FormatInt(tenantID, 10)
+		}
+
+		if _, err := conn.Exec(ctx, "select set_config('app.tenant_id', $1, false)", tenant); err != nil {
+			// the connection is destroyed and another one acquired
+			slog.Error("unable to set tenant", "error", err)
+			return false
+		}
+		return true
+	}
+}
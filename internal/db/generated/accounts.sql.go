@@ -7,12 +7,15 @@ package db
 
 import (
 	"context"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createAccount = `-- name: CreateAccount :one
      with ledger as (select id
                        from ledgers
-                      where uuid = $4::text)
+                      where uuid = $4::text
+                        and tenant_id = current_tenant_id())
    insert
      into accounts (name, type, metadata, ledger_id)
    values ($1, $2, $3, (select id from ledger))
@@ -30,7 +33,8 @@ type CreateAccountParams struct {
 //
 //	     with ledger as (select id
 //	                       from ledgers
-//	                      where uuid = $4::text)
+//	                      where uuid = $4::text
+//	                        and tenant_id = current_tenant_id())
 //	   insert
 //	     into accounts (name, type, metadata, ledger_id)
 //	   values ($1, $2, $3, (select id from ledger))
@@ -60,6 +64,7 @@ const getAccount = `-- name: GetAccount :one
 select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
   from accounts
  where uuid = $1
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
  limit 1
 `
 
@@ -68,6 +73,7 @@ select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
 //	select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
 //	  from accounts
 //	 where uuid = $1
+//	   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 //	 limit 1
 func (q *Queries) GetAccount(ctx context.Context, uuid string) (*Account, error) {
 	row := q.db.QueryRow(ctx, getAccount, uuid)
@@ -85,8 +91,63 @@ func (q *Queries) GetAccount(ctx context.Context, uuid string) (*Account, error)
 	return &i, err
 }
 
+const getAccountForUpdate = `-- name: GetAccountForUpdate :one
+select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+  from accounts
+ where uuid = $1
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
+ limit 1
+   for update
+`
+
+// GetAccountForUpdate
+//
+//	select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+//	  from accounts
+//	 where uuid = $1
+//	   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
+//	 limit 1
+//	   for update
+func (q *Queries) GetAccountForUpdate(ctx context.Context, uuid string) (*Account, error) {
+	row := q.db.QueryRow(ctx, getAccountForUpdate, uuid)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Type,
+		&i.Metadata,
+		&i.LedgerID,
+	)
+	return &i, err
+}
+
+const getAccountLedgerUuid = `-- name: GetAccountLedgerUuid :one
+select l.uuid as ledger_uuid
+  from accounts a
+       join ledgers l on l.id = a.ledger_id
+ where a.uuid = $1::text
+   and l.tenant_id = current_tenant_id()
+`
+
+// GetAccountLedgerUuid
+//
+//	select l.uuid as ledger_uuid
+//	  from accounts a
+//	       join ledgers l on l.id = a.ledger_id
+//	 where a.uuid = $1::text
+//	   and l.tenant_id = current_tenant_id()
+func (q *Queries) GetAccountLedgerUuid(ctx context.Context, uuid string) (string, error) {
+	row := q.db.QueryRow(ctx, getAccountLedgerUuid, uuid)
+	var ledger_uuid string
+	err := row.Scan(&ledger_uuid)
+	return ledger_uuid, err
+}
+
 const listAccounts = `-- name: ListAccounts :many
-  with ledger as (select id from ledgers where uuid = $2::text)
+  with ledger as (select id from ledgers where uuid = $2::text and tenant_id = current_tenant_id())
 select uuid, name, type, metadata
   from accounts
  where ledger_id = (select id from ledger)
@@ -107,7 +168,7 @@ type ListAccountsRow struct {
 
 // ListAccounts
 //
-//	  with ledger as (select id from ledgers where uuid = $2::text)
+//	  with ledger as (select id from ledgers where uuid = $2::text and tenant_id = current_tenant_id())
 //	select uuid, name, type, metadata
 //	  from accounts
 //	 where ledger_id = (select id from ledger)
@@ -137,12 +198,112 @@ func (q *Queries) ListAccounts(ctx context.Context, arg ListAccountsParams) ([]*
 	return items, nil
 }
 
+const listAccountsByLedger = `-- name: ListAccountsByLedger :many
+  with ledger as (select id from ledgers where uuid = $1::text and tenant_id = current_tenant_id())
+select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+  from accounts
+ where ledger_id = (select id from ledger)
+ order by id
+`
+
+// ListAccountsByLedger
+//
+//	  with ledger as (select id from ledgers where uuid = $1::text and tenant_id = current_tenant_id())
+//	select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+//	  from accounts
+//	 where ledger_id = (select id from ledger)
+//	 order by id
+func (q *Queries) ListAccountsByLedger(ctx context.Context, ledgerUuid string) ([]*Account, error) {
+	rows, err := q.db.Query(ctx, listAccountsByLedger, ledgerUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*Account
+	for rows.Next() {
+		var i Account
+		if err := rows.Scan(
+			&i.ID,
+			&i.Uuid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Type,
+			&i.Metadata,
+			&i.LedgerID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const restoreAccount = `-- name: RestoreAccount :one
+     with ledger as (select id
+                       from ledgers
+                      where uuid = $7::text
+                        and tenant_id = current_tenant_id())
+   insert
+     into accounts (uuid, created_at, updated_at, name, type, metadata, ledger_id)
+   values ($1, $2, $3, $4, $5, $6, (select id from ledger))
+returning id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+`
+
+type RestoreAccountParams struct {
+	Uuid       string             `json:"uuid"`
+	CreatedAt  pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt  pgtype.Timestamptz `json:"updatedAt"`
+	Name       string             `json:"name"`
+	Type       AccountType        `json:"type"`
+	Metadata   []byte             `json:"metadata"`
+	LedgerUuid string             `json:"ledgerUuid"`
+}
+
+// RestoreAccount
+//
+//	     with ledger as (select id
+//	                       from ledgers
+//	                      where uuid = $7::text
+//	                        and tenant_id = current_tenant_id())
+//	   insert
+//	     into accounts (uuid, created_at, updated_at, name, type, metadata, ledger_id)
+//	   values ($1, $2, $3, $4, $5, $6, (select id from ledger))
+//	returning id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+func (q *Queries) RestoreAccount(ctx context.Context, arg RestoreAccountParams) (*Account, error) {
+	row := q.db.QueryRow(ctx, restoreAccount,
+		arg.Uuid,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Type,
+		arg.Metadata,
+		arg.LedgerUuid,
+	)
+	var i Account
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Type,
+		&i.Metadata,
+		&i.LedgerID,
+	)
+	return &i, err
+}
+
 const updateAccount = `-- name: UpdateAccount :one
    update accounts
       set name     = coalesce($2, name),
           type     = coalesce($3, type),
           metadata = coalesce($4, metadata)
     where uuid = $1
+      and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 returning id, uuid, created_at, updated_at, name, type, metadata, ledger_id
 `
 
@@ -160,6 +321,7 @@ type UpdateAccountParams struct {
 //	          type     = coalesce($3, type),
 //	          metadata = coalesce($4, metadata)
 //	    where uuid = $1
+//	      and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 //	returning id, uuid, created_at, updated_at, name, type, metadata, ledger_id
 func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) (*Account, error) {
 	row := q.db.QueryRow(ctx, updateAccount,
@@ -12,9 +12,9 @@ import (
 )
 
 const createLedger = `-- name: CreateLedger :one
-   insert into ledgers (name, description, metadata)
-   values ($1, $2, $3)
-returning id, uuid, created_at, updated_at, name, description, metadata
+   insert into ledgers (name, description, metadata, tenant_id)
+   values ($1, $2, $3, current_tenant_id())
+returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 `
 
 type CreateLedgerParams struct {
@@ -25,9 +25,9 @@ type CreateLedgerParams struct {
 
 // CreateLedger
 //
-//	   insert into ledgers (name, description, metadata)
-//	   values ($1, $2, $3)
-//	returning id, uuid, created_at, updated_at, name, description, metadata
+//	   insert into ledgers (name, description, metadata, tenant_id)
+//	   values ($1, $2, $3, current_tenant_id())
+//	returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 func (q *Queries) CreateLedger(ctx context.Context, arg CreateLedgerParams) (*Ledger, error) {
 	row := q.db.QueryRow(ctx, createLedger, arg.Name, arg.Description, arg.Metadata)
 	var i Ledger
@@ -39,22 +39,43 @@ func (q *Queries) CreateLedger(ctx context.Context, arg CreateLedgerParams) (*Le
 		&i.Name,
 		&i.Description,
 		&i.Metadata,
+		&i.TenantID,
 	)
 	return &i, err
 }
 
+const deleteLedger = `-- name: DeleteLedger :exec
+delete
+  from ledgers
+ where uuid = $1::text
+   and tenant_id = current_tenant_id()
+`
+
+// DeleteLedger
+//
+//	delete
+//	  from ledgers
+//	 where uuid = $1::text
+//	   and tenant_id = current_tenant_id()
+func (q *Queries) DeleteLedger(ctx context.Context, uuid string) error {
+	_, err := q.db.Exec(ctx, deleteLedger, uuid)
+	return err
+}
+
 const getLedger = `-- name: GetLedger :one
-select id, uuid, created_at, updated_at, name, description, metadata
+select id, uuid, created_at, updated_at, name, description, metadata, tenant_id
   from ledgers
  where uuid = $1
+   and tenant_id = current_tenant_id()
  limit 1
 `
 
 // GetLedger
 //
-//	select id, uuid, created_at, updated_at, name, description, metadata
+//	select id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 //	  from ledgers
 //	 where uuid = $1
+//	   and tenant_id = current_tenant_id()
 //	 limit 1
 func (q *Queries) GetLedger(ctx context.Context, uuid string) (*Ledger, error) {
 	row := q.db.QueryRow(ctx, getLedger, uuid)
@@ -67,6 +88,40 @@ func (q *Queries) GetLedger(ctx context.Context, uuid string) (*Ledger, error) {
 		&i.Name,
 		&i.Description,
 		&i.Metadata,
+		&i.TenantID,
+	)
+	return &i, err
+}
+
+const getLedgerForUpdate = `-- name: GetLedgerForUpdate :one
+select id, uuid, created_at, updated_at, name, description, metadata, tenant_id
+  from ledgers
+ where uuid = $1
+   and tenant_id = current_tenant_id()
+ limit 1
+   for update
+`
+
+// GetLedgerForUpdate
+//
+//	select id, uuid, created_at, updated_at, name, description, metadata, tenant_id
+//	  from ledgers
+//	 where uuid = $1
+//	   and tenant_id = current_tenant_id()
+//	 limit 1
+//	   for update
+func (q *Queries) GetLedgerForUpdate(ctx context.Context, uuid string) (*Ledger, error) {
+	row := q.db.QueryRow(ctx, getLedgerForUpdate, uuid)
+	var i Ledger
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Description,
+		&i.Metadata,
+		&i.TenantID,
 	)
 	return &i, err
 }
@@ -75,8 +130,19 @@ const listLedgers = `-- name: ListLedgers :many
 select uuid, name, description, metadata
   from ledgers
  where metadata @> $1::jsonb
+   and tenant_id = current_tenant_id()
+   -- users only see the ledgers they were granted
+   and ($2::text is null or id in (select g.ledger_id
+                                                       from ledger_grants g
+                                                            join users u on u.id = g.user_id
+                                                      where u.uuid = $2::text))
 `
 
+type ListLedgersParams struct {
+	Column1  []byte      `json:"column1"`
+	UserUuid pgtype.Text `json:"userUuid"`
+}
+
 type ListLedgersRow struct {
 	Uuid        string      `json:"uuid"`
 	Name        string      `json:"name"`
@@ -89,8 +155,14 @@ type ListLedgersRow struct {
 //	select uuid, name, description, metadata
 //	  from ledgers
 //	 where metadata @> $1::jsonb
-func (q *Queries) ListLedgers(ctx context.Context, dollar_1 []byte) ([]*ListLedgersRow, error) {
-	rows, err := q.db.Query(ctx, listLedgers, dollar_1)
+//	   and tenant_id = current_tenant_id()
+//	   -- users only see the ledgers they were granted
+//	   and ($2::text is null or id in (select g.ledger_id
+//	                                                       from ledger_grants g
+//	                                                            join users u on u.id = g.user_id
+//	                                                      where u.uuid = $2::text))
+func (q *Queries) ListLedgers(ctx context.Context, arg ListLedgersParams) ([]*ListLedgersRow, error) {
+	rows, err := q.db.Query(ctx, listLedgers, arg.Column1, arg.UserUuid)
 	if err != nil {
 		return nil, err
 	}
@@ -114,13 +186,57 @@ func (q *Queries) ListLedgers(ctx context.Context, dollar_1 []byte) ([]*ListLedg
 	return items, nil
 }
 
+const restoreLedger = `-- name: RestoreLedger :one
+   insert into ledgers (uuid, created_at, updated_at, name, description, metadata, tenant_id)
+   values ($1, $2, $3, $4, $5, $6, current_tenant_id())
+returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
+`
+
+type RestoreLedgerParams struct {
+	Uuid        string             `json:"uuid"`
+	CreatedAt   pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt   pgtype.Timestamptz `json:"updatedAt"`
+	Name        string             `json:"name"`
+	Description pgtype.Text        `json:"description"`
+	Metadata    []byte             `json:"metadata"`
+}
+
+// RestoreLedger
+//
+//	   insert into ledgers (uuid, created_at, updated_at, name, description, metadata, tenant_id)
+//	   values ($1, $2, $3, $4, $5, $6, current_tenant_id())
+//	returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
+func (q *Queries) RestoreLedger(ctx context.Context, arg RestoreLedgerParams) (*Ledger, error) {
+	row := q.db.QueryRow(ctx, restoreLedger,
+		arg.Uuid,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Name,
+		arg.Description,
+		arg.Metadata,
+	)
+	var i Ledger
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Description,
+		&i.Metadata,
+		&i.TenantID,
+	)
+	return &i, err
+}
+
 const updateLedger = `-- name: UpdateLedger :one
    update ledgers
       set name        = coalesce($2, name),
           description = coalesce($3, description),
           metadata    = coalesce($4, metadata)
     where uuid = $1
-returning id, uuid, created_at, updated_at, name, description, metadata
+      and tenant_id = current_tenant_id()
+returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 `
 
 type UpdateLedgerParams struct {
@@ -137,7 +253,8 @@ type UpdateLedgerParams struct {
 //	          description = coalesce($3, description),
 //	          metadata    = coalesce($4, metadata)
 //	    where uuid = $1
-//	returning id, uuid, created_at, updated_at, name, description, metadata
+//	      and tenant_id = current_tenant_id()
+//	returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 func (q *Queries) UpdateLedger(ctx context.Context, arg UpdateLedgerParams) (*Ledger, error) {
 	row := q.db.QueryRow(ctx, updateLedger,
 		arg.Uuid,
@@ -154,6 +271,7 @@ func (q *Queries) UpdateLedger(ctx context.Context, arg UpdateLedgerParams) (*Le
 		&i.Name,
 		&i.Description,
 		&i.Metadata,
+		&i.TenantID,
 	)
 	return &i, err
 }
@@ -56,6 +56,50 @@ func (ns NullAccountType) Value() (driver.Value, error) {
 	return string(ns.AccountType), nil
 }
 
+type LedgerRole string
+
+const (
+	LedgerRoleOwner      LedgerRole = "owner"
+	LedgerRoleAccountant LedgerRole = "accountant"
+	LedgerRoleViewer     LedgerRole = "viewer"
+	LedgerRoleAuditor    LedgerRole = "auditor"
+)
+
+func (e *LedgerRole) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = LedgerRole(s)
+	case string:
+		*e = LedgerRole(s)
+	default:
+		return fmt.Errorf("unsupported scan type for LedgerRole: %T", src)
+	}
+	return nil
+}
+
+type NullLedgerRole struct {
+	LedgerRole LedgerRole `json:"ledgerRole"`
+	Valid      bool       `json:"valid"` // Valid is true if LedgerRole is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullLedgerRole) Scan(value interface{}) error {
+	if value == nil {
+		ns.LedgerRole, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.LedgerRole.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullLedgerRole) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.LedgerRole), nil
+}
+
 type TransactionStatus string
 
 const (
@@ -98,6 +142,50 @@ func (ns NullTransactionStatus) Value() (driver.Value, error) {
 	return string(ns.TransactionStatus), nil
 }
 
+type WebhookDeliveryStatus string
+
+const (
+	WebhookDeliveryStatusPending   WebhookDeliveryStatus = "pending"
+	WebhookDeliveryStatusFailed    WebhookDeliveryStatus = "failed"
+	WebhookDeliveryStatusDelivered WebhookDeliveryStatus = "delivered"
+	WebhookDeliveryStatusDead      WebhookDeliveryStatus = "dead"
+)
+
+func (e *WebhookDeliveryStatus) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case []byte:
+		*e = WebhookDeliveryStatus(s)
+	case string:
+		*e = WebhookDeliveryStatus(s)
+	default:
+		return fmt.Errorf("unsupported scan type for WebhookDeliveryStatus: %T", src)
+	}
+	return nil
+}
+
+type NullWebhookDeliveryStatus struct {
+	WebhookDeliveryStatus WebhookDeliveryStatus `json:"webhookDeliveryStatus"`
+	Valid                 bool                  `json:"valid"` // Valid is true if WebhookDeliveryStatus is not NULL
+}
+
+// Scan implements the Scanner interface.
+func (ns *NullWebhookDeliveryStatus) Scan(value interface{}) error {
+	if value == nil {
+		ns.WebhookDeliveryStatus, ns.Valid = "", false
+		return nil
+	}
+	ns.Valid = true
+	return ns.WebhookDeliveryStatus.Scan(value)
+}
+
+// Value implements the driver Valuer interface.
+func (ns NullWebhookDeliveryStatus) Value() (driver.Value, error) {
+	if !ns.Valid {
+		return nil, nil
+	}
+	return string(ns.WebhookDeliveryStatus), nil
+}
+
 type Account struct {
 	ID        int64              `json:"id"`
 	Uuid      string             `json:"uuid"`
@@ -109,6 +197,45 @@ type Account struct {
 	LedgerID  int64              `json:"ledgerId"`
 }
 
+type ApiKey struct {
+	ID        int64              `json:"id"`
+	Uuid      string             `json:"uuid"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+	Name      string             `json:"name"`
+	Prefix    string             `json:"prefix"`
+	KeyHash   string             `json:"keyHash"`
+	Scopes    []string           `json:"scopes"`
+	RevokedAt pgtype.Timestamptz `json:"revokedAt"`
+	TenantID  int64              `json:"tenantId"`
+	UserID    pgtype.Int8        `json:"userId"`
+}
+
+type AuditEvent struct {
+	ID         int64              `json:"id"`
+	Uuid       string             `json:"uuid"`
+	CreatedAt  pgtype.Timestamptz `json:"createdAt"`
+	Actor      string             `json:"actor"`
+	Action     string             `json:"action"`
+	EntityType string             `json:"entityType"`
+	EntityUuid string             `json:"entityUuid"`
+	Before     []byte             `json:"before"`
+	After      []byte             `json:"after"`
+	RequestID  pgtype.Text        `json:"requestId"`
+	TenantID   int64              `json:"tenantId"`
+}
+
+type IdempotencyKey struct {
+	ID           int64              `json:"id"`
+	CreatedAt    pgtype.Timestamptz `json:"createdAt"`
+	Key          string             `json:"key"`
+	RequestHash  string             `json:"requestHash"`
+	StatusCode   pgtype.Int4        `json:"statusCode"`
+	ResponseBody []byte             `json:"responseBody"`
+	TenantID     int64              `json:"tenantId"`
+	Principal    string             `json:"principal"`
+}
+
 type Ledger struct {
 	ID          int64              `json:"id"`
 	Uuid        string             `json:"uuid"`
@@ -117,6 +244,38 @@ type Ledger struct {
 	Name        string             `json:"name"`
 	Description pgtype.Text        `json:"description"`
 	Metadata    []byte             `json:"metadata"`
+	TenantID    int64              `json:"tenantId"`
+}
+
+type LedgerGrant struct {
+	ID        int64              `json:"id"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+	Role      LedgerRole         `json:"role"`
+	LedgerID  int64              `json:"ledgerId"`
+	UserID    int64              `json:"userId"`
+	Uuid      string             `json:"uuid"`
+}
+
+type OutboxEvent struct {
+	ID         int64              `json:"id"`
+	Uuid       string             `json:"uuid"`
+	CreatedAt  pgtype.Timestamptz `json:"createdAt"`
+	EventType  string             `json:"eventType"`
+	EntityType string             `json:"entityType"`
+	EntityUuid string             `json:"entityUuid"`
+	Payload    []byte             `json:"payload"`
+	LedgerUuid pgtype.Text        `json:"ledgerUuid"`
+	TenantID   int64              `json:"tenantId"`
+	XactID     int64              `json:"xactId"`
+}
+
+type Tenant struct {
+	ID        int64              `json:"id"`
+	Uuid      string             `json:"uuid"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+	Name      string             `json:"name"`
 }
 
 type Transaction struct {
@@ -132,3 +291,41 @@ type Transaction struct {
 	DebitAccountID  int64              `json:"debitAccountId"`
 	LedgerID        int64              `json:"ledgerId"`
 }
+
+type User struct {
+	ID        int64              `json:"id"`
+	Uuid      string             `json:"uuid"`
+	CreatedAt pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt pgtype.Timestamptz `json:"updatedAt"`
+	Email     string             `json:"email"`
+	Name      pgtype.Text        `json:"name"`
+	TenantID  int64              `json:"tenantId"`
+	Issuer    pgtype.Text        `json:"issuer"`
+	Subject   pgtype.Text        `json:"subject"`
+}
+
+type Webhook struct {
+	ID         int64              `json:"id"`
+	Uuid       string             `json:"uuid"`
+	CreatedAt  pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt  pgtype.Timestamptz `json:"updatedAt"`
+	Url        string             `json:"url"`
+	Secret     string             `json:"secret"`
+	EventTypes []string           `json:"eventTypes"`
+	TenantID   int64              `json:"tenantId"`
+}
+
+type WebhookDelivery struct {
+	ID             int64                 `json:"id"`
+	Uuid           string                `json:"uuid"`
+	CreatedAt      pgtype.Timestamptz    `json:"createdAt"`
+	UpdatedAt      pgtype.Timestamptz    `json:"updatedAt"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int32                 `json:"attempts"`
+	NextAttemptAt  pgtype.Timestamptz    `json:"nextAttemptAt"`
+	LastStatusCode pgtype.Int4           `json:"lastStatusCode"`
+	LastError      pgtype.Text           `json:"lastError"`
+	DeliveredAt    pgtype.Timestamptz    `json:"deliveredAt"`
+	WebhookID      int64                 `json:"webhookId"`
+	EventID        int64                 `json:"eventId"`
+}
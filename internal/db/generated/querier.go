@@ -9,33 +9,166 @@ import (
 )
 
 type Querier interface {
+	//BindUserSubject
+	//
+	//  update users
+	//     set issuer  = $1::text,
+	//         subject = $2::text
+	//   where tenant_id = $3::bigint
+	//     and email = $4::text
+	//     and subject is null
+	//  returning id, uuid, created_at, updated_at, email, name, tenant_id, issuer, subject
+	BindUserSubject(ctx context.Context, arg BindUserSubjectParams) (*User, error)
+	//ClaimIdempotencyKey
+	//
+	//  insert into idempotency_keys (key, principal, request_hash, tenant_id)
+	//  values ($1::text, $2::text, $3::text, current_tenant_id())
+	//      -- no row is returned while the key is taken, expired keys are claimed again
+	//      on conflict (tenant_id, principal, key) do update
+	//      set created_at    = current_timestamp,
+	//          request_hash  = excluded.request_hash,
+	//          status_code   = null,
+	//          response_body = null
+	//    where idempotency_keys.created_at < $4::timestamptz
+	//  returning id
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (int64, error)
+	//ClaimWebhookDeliveries
+	//
+	//    with due as (select id
+	//                   from webhook_deliveries
+	//                  where status in ('pending', 'failed')
+	//                    and next_attempt_at <= current_timestamp
+	//                  order by next_attempt_at
+	//                  limit $2 for update skip locked)
+	//  update webhook_deliveries d
+	//     set next_attempt_at = $1::timestamptz
+	//    from due,
+	//         webhooks w,
+	//         outbox_events e
+	//   where d.id = due.id
+	//     and w.id = d.webhook_id
+	//     and e.id = d.event_id
+	//  returning d.id,
+	//            d.attempts,
+	//            w.url,
+	//            w.secret,
+	//            e.uuid       as event_uuid,
+	//            e.event_type,
+	//            e.created_at as event_created_at,
+	//            e.payload
+	ClaimWebhookDeliveries(ctx context.Context, arg ClaimWebhookDeliveriesParams) ([]*ClaimWebhookDeliveriesRow, error)
+	//CompleteIdempotencyKey
+	//
+	//  update idempotency_keys
+	//     set status_code   = $1::int,
+	//         response_body = $2::bytea
+	//   where key = $3::text
+	//     and principal = $4::text
+	//     and tenant_id = current_tenant_id()
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	//CountLedgerOwners
+	//
+	//  select count(*)
+	//    from ledger_grants
+	//   where ledger_id = (select id from ledgers where uuid = $1::text and tenant_id = current_tenant_id())
+	//     and role = 'owner'
+	CountLedgerOwners(ctx context.Context, ledgerUuid string) (int64, error)
 	//CreateAccount
 	//
 	//       with ledger as (select id
 	//                         from ledgers
-	//                        where uuid = $4::text)
+	//                        where uuid = $4::text
+	//                          and tenant_id = current_tenant_id())
 	//     insert
 	//       into accounts (name, type, metadata, ledger_id)
 	//     values ($1, $2, $3, (select id from ledger))
 	//  returning id, uuid, created_at, updated_at, name, type, metadata, ledger_id
 	CreateAccount(ctx context.Context, arg CreateAccountParams) (*Account, error)
+	//CreateApiKey
+	//
+	//    with tenant as (select id from tenants where uuid = $5::text),
+	//         usr as (select id
+	//                   from users
+	//                  where uuid = $6::text
+	//                    and tenant_id = (select id from tenant))
+	//  insert into api_keys (name, prefix, key_hash, scopes, tenant_id, user_id)
+	//  values ($1::text,
+	//          $2::text,
+	//          $3::text,
+	//          $4::text[],
+	//          (select id from tenant),
+	//          (select id from usr))
+	//  returning id, uuid, created_at, updated_at, name, prefix, key_hash, scopes, revoked_at, tenant_id, user_id
+	CreateApiKey(ctx context.Context, arg CreateApiKeyParams) (*ApiKey, error)
+	//CreateAuditEvent
+	//
+	//  insert into audit_events (actor,
+	//                            action,
+	//                            entity_type,
+	//                            entity_uuid,
+	//                            before,
+	//                            after,
+	//                            request_id,
+	//                            tenant_id)
+	//  values ($1::text,
+	//          $2::text,
+	//          $3::text,
+	//          $4::text,
+	//          $5::jsonb,
+	//          $6::jsonb,
+	//          $7::text,
+	//          current_tenant_id())
+	//  returning id, uuid, created_at, actor, action, entity_type, entity_uuid, before, after, request_id, tenant_id
+	CreateAuditEvent(ctx context.Context, arg CreateAuditEventParams) (*AuditEvent, error)
 	//CreateLedger
 	//
-	//     insert into ledgers (name, description, metadata)
-	//     values ($1, $2, $3)
-	//  returning id, uuid, created_at, updated_at, name, description, metadata
+	//     insert into ledgers (name, description, metadata, tenant_id)
+	//     values ($1, $2, $3, current_tenant_id())
+	//  returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 	CreateLedger(ctx context.Context, arg CreateLedgerParams) (*Ledger, error)
+	//CreateOutboxEvent
+	//
+	//    with event as (
+	//        insert into outbox_events (event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id)
+	//        values ($1::text,
+	//                $2::text,
+	//                $3::text,
+	//                $4::jsonb,
+	//                $5::text,
+	//                current_tenant_id())
+	//        returning id, uuid, created_at, event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id, xact_id),
+	//         deliveries as (
+	//             insert into webhook_deliveries (webhook_id, event_id)
+	//             select w.id, event.id
+	//               from webhooks w,
+	//                    event
+	//              where w.tenant_id = event.tenant_id
+	//                and (cardinality(w.event_types) = 0
+	//                 or event.event_type = any (w.event_types)))
+	//  select id, uuid, created_at, event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id, xact_id
+	//    from event
+	CreateOutboxEvent(ctx context.Context, arg CreateOutboxEventParams) (*OutboxEvent, error)
+	//CreateTenant
+	//
+	//  insert into tenants (name)
+	//  values ($1::text)
+	//  returning id, uuid, created_at, updated_at, name
+	CreateTenant(ctx context.Context, name string) (*Tenant, error)
 	//CreateTransaction
 	//
-	//       WITH credit_account AS (SELECT id
+	//       WITH ledger_id AS (SELECT id
+	//                            FROM ledgers
+	//                           WHERE ledgers.uuid = $7::text
+	//                             AND ledgers.tenant_id = current_tenant_id()),
+	//            -- accounts of other ledgers are never linked
+	//            credit_account AS (SELECT id
 	//                                 FROM accounts
-	//                                WHERE accounts.uuid = $5::text),
+	//                                WHERE accounts.uuid = $5::text
+	//                                  AND accounts.ledger_id = (SELECT id FROM ledger_id)),
 	//            debit_account AS (SELECT id
 	//                                FROM accounts
-	//                               WHERE accounts.uuid = $6::text),
-	//            ledger_id AS (SELECT id
-	//                            FROM ledgers
-	//                           WHERE ledgers.uuid = $7::text)
+	//                               WHERE accounts.uuid = $6::text
+	//                                 AND accounts.ledger_id = (SELECT id FROM ledger_id))
 	//     INSERT
 	//       INTO transactions (amount,
 	//                          date,
@@ -53,58 +186,437 @@ type Querier interface {
 	//             (SELECT id FROM ledger_id))
 	//  RETURNING id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
 	CreateTransaction(ctx context.Context, arg CreateTransactionParams) (*Transaction, error)
+	//CreateUser
+	//
+	//    with tenant as (select id from tenants where uuid = $5::text)
+	//  insert into users (email, name, issuer, subject, tenant_id)
+	//  values ($1::text, $2::text, $3::text, $4::text, (select id from tenant))
+	//  returning id, uuid, created_at, updated_at, email, name, tenant_id, issuer, subject
+	CreateUser(ctx context.Context, arg CreateUserParams) (*User, error)
+	//CreateWebhook
+	//
+	//  insert into webhooks (url, secret, event_types, tenant_id)
+	//  values ($1::text, $2::text, $3::text[], current_tenant_id())
+	//  returning id, uuid, created_at, updated_at, url, secret, event_types, tenant_id
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (*Webhook, error)
+	//DeleteLedger
+	//
+	//  delete
+	//    from ledgers
+	//   where uuid = $1::text
+	//     and tenant_id = current_tenant_id()
+	DeleteLedger(ctx context.Context, uuid string) error
+	//DeleteLedgerGrant
+	//
+	//  delete
+	//    from ledger_grants
+	//   where ledger_id = (select id from ledgers where uuid = $1::text and tenant_id = current_tenant_id())
+	//     and user_id = (select id from users where uuid = $2::text and tenant_id = current_tenant_id())
+	DeleteLedgerGrant(ctx context.Context, arg DeleteLedgerGrantParams) (int64, error)
 	//DeleteTransaction
 	//
 	//  delete
 	//    from transactions
 	//   where uuid = $1::text
+	//     and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 	DeleteTransaction(ctx context.Context, uuid string) error
+	//DeleteWebhook
+	//
+	//  delete
+	//    from webhooks
+	//   where uuid = $1::text
+	//     and tenant_id = current_tenant_id()
+	DeleteWebhook(ctx context.Context, uuid string) (int64, error)
 	//GetAccount
 	//
 	//  select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
 	//    from accounts
 	//   where uuid = $1
+	//     and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 	//   limit 1
 	GetAccount(ctx context.Context, uuid string) (*Account, error)
+	//GetAccountBalances
+	//
+	//    with ledger as (select id from ledgers where uuid = $3::text and tenant_id = current_tenant_id())
+	//  select a.uuid,
+	//         a.name,
+	//         a.type,
+	//         coalesce(sum(t.amount) filter (where t.debit_account_id = a.id), 0)::bigint  as debit,
+	//         coalesce(sum(t.amount) filter (where t.credit_account_id = a.id), 0)::bigint as credit
+	//    from accounts a
+	//         left join transactions t
+	//                on (t.debit_account_id = a.id or t.credit_account_id = a.id)
+	//               and ($1::date is null or t.date >= $1::date)
+	//               and ($2::date is null or t.date <= $2::date)
+	//   where a.ledger_id = (select id from ledger)
+	//   group by a.id
+	//   order by a.name
+	GetAccountBalances(ctx context.Context, arg GetAccountBalancesParams) ([]*GetAccountBalancesRow, error)
+	//GetAccountForUpdate
+	//
+	//  select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+	//    from accounts
+	//   where uuid = $1
+	//     and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
+	//   limit 1
+	//     for update
+	GetAccountForUpdate(ctx context.Context, uuid string) (*Account, error)
+	//GetAccountLedgerUuid
+	//
+	//  select l.uuid as ledger_uuid
+	//    from accounts a
+	//         join ledgers l on l.id = a.ledger_id
+	//   where a.uuid = $1::text
+	//     and l.tenant_id = current_tenant_id()
+	GetAccountLedgerUuid(ctx context.Context, uuid string) (string, error)
+	//GetAccountOpeningBalance
+	//
+	//    with account as (select id
+	//                       from accounts
+	//                      where uuid = $2::text
+	//                        and ledger_id in (select id from ledgers where tenant_id = current_tenant_id()))
+	//  select (coalesce(sum(amount) filter (where debit_account_id = (select id from account)), 0) -
+	//          coalesce(sum(amount) filter (where credit_account_id = (select id from account)), 0))::bigint as balance
+	//    from transactions
+	//   where (debit_account_id = (select id from account) or credit_account_id = (select id from account))
+	//     and date < $1::date
+	GetAccountOpeningBalance(ctx context.Context, arg GetAccountOpeningBalanceParams) (int64, error)
+	//GetAccountSnapshot
+	//
+	//  select jsonb_build_object(
+	//                 'uuid', a.uuid,
+	//                 'name', a.name,
+	//                 'type', a.type,
+	//                 'metadata', a.metadata,
+	//                 'ledger_uuid', l.uuid,
+	//                 'created_at', a.created_at,
+	//                 'updated_at', a.updated_at
+	//         )::jsonb as snapshot
+	//    from accounts a
+	//         join ledgers l on l.id = a.ledger_id
+	//   where a.uuid = $1::text
+	//     and l.tenant_id = current_tenant_id()
+	GetAccountSnapshot(ctx context.Context, uuid string) ([]byte, error)
+	//GetApiKeyByPrefix
+	//
+	//  select k.uuid,
+	//         k.name,
+	//         k.key_hash,
+	//         k.scopes,
+	//         k.tenant_id,
+	//         u.uuid as user_uuid
+	//    from api_keys k
+	//         left join users u on u.id = k.user_id
+	//   where k.prefix = $1::text
+	//     and k.revoked_at is null
+	GetApiKeyByPrefix(ctx context.Context, prefix string) (*GetApiKeyByPrefixRow, error)
+	//GetIdempotencyKey
+	//
+	//  select id, created_at, key, request_hash, status_code, response_body, tenant_id, principal
+	//    from idempotency_keys
+	//   where key = $1::text
+	//     and principal = $2::text
+	//     and tenant_id = current_tenant_id()
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (*IdempotencyKey, error)
+	//GetLatestLedgerEventID
+	//
+	//  select coalesce((select id
+	//                     from outbox_events
+	//                    where ledger_uuid = $1::text
+	//                      and tenant_id = current_tenant_id()
+	//                      and xact_id < outbox_events_watermark()
+	//                    order by xact_id desc, id desc
+	//                    limit 1), 0)::bigint as id
+	GetLatestLedgerEventID(ctx context.Context, ledgerUuid string) (int64, error)
 	//GetLedger
 	//
-	//  select id, uuid, created_at, updated_at, name, description, metadata
+	//  select id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 	//    from ledgers
 	//   where uuid = $1
+	//     and tenant_id = current_tenant_id()
 	//   limit 1
 	GetLedger(ctx context.Context, uuid string) (*Ledger, error)
+	//GetLedgerForUpdate
+	//
+	//  select id, uuid, created_at, updated_at, name, description, metadata, tenant_id
+	//    from ledgers
+	//   where uuid = $1
+	//     and tenant_id = current_tenant_id()
+	//   limit 1
+	//     for update
+	GetLedgerForUpdate(ctx context.Context, uuid string) (*Ledger, error)
+	//GetLedgerGrantForUpdate
+	//
+	//  select id, created_at, updated_at, role, ledger_id, user_id, uuid
+	//    from ledger_grants
+	//   where ledger_id = (select id from ledgers where uuid = $1::text and tenant_id = current_tenant_id())
+	//     and user_id = (select id from users where uuid = $2::text and tenant_id = current_tenant_id())
+	//     for update
+	GetLedgerGrantForUpdate(ctx context.Context, arg GetLedgerGrantForUpdateParams) (*LedgerGrant, error)
+	//GetLedgerGrantSnapshot
+	//
+	//  select jsonb_build_object(
+	//                 'uuid', g.uuid,
+	//                 'role', g.role,
+	//                 'user_uuid', u.uuid,
+	//                 'ledger_uuid', l.uuid,
+	//                 'created_at', g.created_at,
+	//                 'updated_at', g.updated_at
+	//         )::jsonb as snapshot
+	//    from ledger_grants g
+	//         join ledgers l on l.id = g.ledger_id
+	//         join users u on u.id = g.user_id
+	//   where g.uuid = $1::text
+	//     and l.tenant_id = current_tenant_id()
+	GetLedgerGrantSnapshot(ctx context.Context, uuid string) ([]byte, error)
+	//GetLedgerRole
+	//
+	//  select g.role
+	//    from ledger_grants g
+	//         join ledgers l on l.id = g.ledger_id
+	//         join users u on u.id = g.user_id
+	//   where l.uuid = $1::text
+	//     and l.tenant_id = current_tenant_id()
+	//     and u.uuid = $2::text
+	GetLedgerRole(ctx context.Context, arg GetLedgerRoleParams) (LedgerRole, error)
+	//GetLedgerSnapshot
+	//
+	//  select jsonb_build_object(
+	//                 'uuid', uuid,
+	//                 'name', name,
+	//                 'description', description,
+	//                 'metadata', metadata,
+	//                 'created_at', created_at,
+	//                 'updated_at', updated_at
+	//         )::jsonb as snapshot
+	//    from ledgers
+	//   where uuid = $1::text
+	//     and tenant_id = current_tenant_id()
+	GetLedgerSnapshot(ctx context.Context, uuid string) ([]byte, error)
+	//GetTenant
+	//
+	//  select id, uuid, created_at, updated_at, name
+	//    from tenants
+	//   where uuid = $1::text
+	GetTenant(ctx context.Context, uuid string) (*Tenant, error)
 	//GetTransaction
 	//
 	//  select id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
 	//    from transactions
 	//   where uuid = $1::text
+	//     and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 	//   limit 1
 	GetTransaction(ctx context.Context, uuid string) (*Transaction, error)
+	//GetTransactionLedgerUuid
+	//
+	//  select l.uuid as ledger_uuid
+	//    from transactions t
+	//         join ledgers l on l.id = t.ledger_id
+	//   where t.uuid = $1::text
+	//     and l.tenant_id = current_tenant_id()
+	GetTransactionLedgerUuid(ctx context.Context, uuid string) (string, error)
+	//GetTransactionSnapshot
+	//
+	//  select jsonb_build_object(
+	//                 'uuid', t.uuid,
+	//                 'amount', t.amount,
+	//                 'date', t.date,
+	//                 'description', t.description,
+	//                 'metadata', t.metadata,
+	//                 'credit_account_uuid', credit.uuid,
+	//                 'debit_account_uuid', debit.uuid,
+	//                 'ledger_uuid', l.uuid,
+	//                 'created_at', t.created_at,
+	//                 'updated_at', t.updated_at
+	//         )::jsonb as snapshot
+	//    from transactions t
+	//         join accounts credit on credit.id = t.credit_account_id
+	//         join accounts debit on debit.id = t.debit_account_id
+	//         join ledgers l on l.id = t.ledger_id
+	//   where t.uuid = $1::text
+	//     and l.tenant_id = current_tenant_id()
+	GetTransactionSnapshot(ctx context.Context, uuid string) ([]byte, error)
 	//GetTransactionsCount
 	//
-	//    with ledger as (select ledgers.id from ledgers where ledgers.uuid = $2::text)
+	//    with ledger as (select ledgers.id
+	//                      from ledgers
+	//                     where ledgers.uuid = $2::text
+	//                       and ledgers.tenant_id = current_tenant_id())
 	//  select count(*)
 	//    from transactions
 	//   where ledger_id = (select id from ledger)
 	//     and metadata @> $1::jsonb
 	GetTransactionsCount(ctx context.Context, arg GetTransactionsCountParams) (int64, error)
+	//GetUser
+	//
+	//  select id, uuid, created_at, updated_at, email, name, tenant_id, issuer, subject
+	//    from users
+	//   where uuid = $1::text
+	GetUser(ctx context.Context, uuid string) (*User, error)
+	//GetUserByEmail
+	//
+	//  select id, uuid, created_at, updated_at, email, name, tenant_id, issuer, subject
+	//    from users
+	//   where tenant_id = $1::bigint
+	//     and email = $2::text
+	GetUserByEmail(ctx context.Context, arg GetUserByEmailParams) (*User, error)
+	//GetUserBySubject
+	//
+	//  select id, uuid, created_at, updated_at, email, name, tenant_id, issuer, subject
+	//    from users
+	//   where tenant_id = $1::bigint
+	//     and issuer = $2::text
+	//     and subject = $3::text
+	GetUserBySubject(ctx context.Context, arg GetUserBySubjectParams) (*User, error)
+	//HasPendingLedgerEvents
+	//
+	//  select exists (select 1
+	//                   from outbox_events
+	//                  where ledger_uuid = $1::text
+	//                    and tenant_id = current_tenant_id()
+	//                    and xact_id >= outbox_events_watermark()
+	//                    and (xact_id, id) > (coalesce((select a.xact_id
+	//                                                     from outbox_events a
+	//                                                    where a.id = $2::bigint
+	//                                                      and a.tenant_id = current_tenant_id()), 0),
+	//                                         $2::bigint))
+	HasPendingLedgerEvents(ctx context.Context, arg HasPendingLedgerEventsParams) (bool, error)
+	//ListAccountBalancesByUuids
+	//
+	//  select a.uuid,
+	//         a.name,
+	//         a.type,
+	//         coalesce(sum(t.amount) filter (where t.debit_account_id = a.id), 0)::bigint  as debit,
+	//         coalesce(sum(t.amount) filter (where t.credit_account_id = a.id), 0)::bigint as credit
+	//    from accounts a
+	//         left join transactions t on t.debit_account_id = a.id or t.credit_account_id = a.id
+	//   where a.uuid = any ($1::text[])
+	//     and a.ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
+	//   group by a.id
+	//   order by a.name
+	ListAccountBalancesByUuids(ctx context.Context, uuids []string) ([]*ListAccountBalancesByUuidsRow, error)
+	//ListAccountEntries
+	//
+	//    with account as (select id
+	//                       from accounts
+	//                      where uuid = $3::text
+	//                        and ledger_id in (select id from ledgers where tenant_id = current_tenant_id()))
+	//  select t.uuid,
+	//         t.date,
+	//         t.description,
+	//         (case when t.debit_account_id = (select id from account) then t.amount else 0 end)::bigint  as debit,
+	//         (case when t.credit_account_id = (select id from account) then t.amount else 0 end)::bigint as credit,
+	//         counterpart.uuid                                                                         as counterpart_uuid,
+	//         counterpart.name                                                                         as counterpart_name
+	//    from transactions t
+	//         join accounts counterpart
+	//              on counterpart.id = case
+	//                                      when t.debit_account_id = (select id from account) then t.credit_account_id
+	//                                      else t.debit_account_id
+	//                                  end
+	//   where (t.debit_account_id = (select id from account) or t.credit_account_id = (select id from account))
+	//     and ($1::date is null or t.date >= $1::date)
+	//     and ($2::date is null or t.date <= $2::date)
+	//   order by t.date, t.id
+	ListAccountEntries(ctx context.Context, arg ListAccountEntriesParams) ([]*ListAccountEntriesRow, error)
 	//ListAccounts
 	//
-	//    with ledger as (select id from ledgers where uuid = $2::text)
+	//    with ledger as (select id from ledgers where uuid = $2::text and tenant_id = current_tenant_id())
 	//  select uuid, name, type, metadata
 	//    from accounts
 	//   where ledger_id = (select id from ledger)
 	//     and metadata @> $1::jsonb
 	ListAccounts(ctx context.Context, arg ListAccountsParams) ([]*ListAccountsRow, error)
+	//ListAccountsByLedger
+	//
+	//    with ledger as (select id from ledgers where uuid = $1::text and tenant_id = current_tenant_id())
+	//  select id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+	//    from accounts
+	//   where ledger_id = (select id from ledger)
+	//   order by id
+	ListAccountsByLedger(ctx context.Context, ledgerUuid string) ([]*Account, error)
+	//ListApiKeys
+	//
+	//  select k.uuid,
+	//         k.created_at,
+	//         k.name,
+	//         k.prefix,
+	//         k.scopes,
+	//         k.revoked_at,
+	//         t.uuid as tenant_uuid,
+	//         u.uuid as user_uuid
+	//    from api_keys k
+	//         join tenants t on t.id = k.tenant_id
+	//         left join users u on u.id = k.user_id
+	//   order by k.id
+	ListApiKeys(ctx context.Context) ([]*ListApiKeysRow, error)
+	//ListAuditEvents
+	//
+	//  select id, uuid, created_at, actor, action, entity_type, entity_uuid, before, after, request_id, tenant_id
+	//    from audit_events
+	//   where tenant_id = current_tenant_id()
+	//     and ($1::text is null or entity_type = $1::text)
+	//     and ($2::text is null or entity_uuid = $2::text)
+	//     -- the events of a ledger and of everything in it
+	//     and ($5::text is null
+	//         or (entity_type = 'ledger' and entity_uuid = $5::text)
+	//         or after ->> 'ledger_uuid' = $5::text
+	//         or before ->> 'ledger_uuid' = $5::text)
+	//   order by id desc
+	//   limit $3 offset $4
+	ListAuditEvents(ctx context.Context, arg ListAuditEventsParams) ([]*AuditEvent, error)
+	//ListLedgerEvents
+	//
+	//  select id, uuid, created_at, event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id, xact_id
+	//    from outbox_events
+	//   where ledger_uuid = $1::text
+	//     and tenant_id = current_tenant_id()
+	//     and ($2::boolean or xact_id < outbox_events_watermark())
+	//     and (xact_id, id) > (coalesce((select a.xact_id
+	//                                      from outbox_events a
+	//                                     where a.id = $3::bigint
+	//                                       and a.tenant_id = current_tenant_id()), 0),
+	//                          $3::bigint)
+	//   order by xact_id, id
+	//   limit $4
+	ListLedgerEvents(ctx context.Context, arg ListLedgerEventsParams) ([]*OutboxEvent, error)
+	//ListLedgerGrants
+	//
+	//    with ledger as (select id from ledgers where uuid = $1::text and tenant_id = current_tenant_id())
+	//  select u.uuid as user_uuid,
+	//         u.email,
+	//         g.role,
+	//         g.created_at,
+	//         g.updated_at
+	//    from ledger_grants g
+	//         join users u on u.id = g.user_id
+	//   where g.ledger_id = (select id from ledger)
+	//   order by g.id
+	ListLedgerGrants(ctx context.Context, ledgerUuid string) ([]*ListLedgerGrantsRow, error)
 	//ListLedgers
 	//
 	//  select uuid, name, description, metadata
 	//    from ledgers
 	//   where metadata @> $1::jsonb
-	ListLedgers(ctx context.Context, dollar_1 []byte) ([]*ListLedgersRow, error)
+	//     and tenant_id = current_tenant_id()
+	//     -- users only see the ledgers they were granted
+	//     and ($2::text is null or id in (select g.ledger_id
+	//                                                         from ledger_grants g
+	//                                                              join users u on u.id = g.user_id
+	//                                                        where u.uuid = $2::text))
+	ListLedgers(ctx context.Context, arg ListLedgersParams) ([]*ListLedgersRow, error)
+	//ListTenants
+	//
+	//  select id, uuid, created_at, updated_at, name
+	//    from tenants
+	//   order by id
+	ListTenants(ctx context.Context) ([]*Tenant, error)
 	//ListTransactions
 	//
-	//    with ledger as (select ledgers.id from ledgers where ledgers.uuid = $4::text)
+	//    with ledger as (select ledgers.id
+	//                      from ledgers
+	//                     where ledgers.uuid = $4::text
+	//                       and ledgers.tenant_id = current_tenant_id())
 	//  select uuid, amount, date, description, metadata
 	//    from transactions
 	//   where ledger_id = (select id from ledger)
@@ -112,6 +624,187 @@ type Querier interface {
 	//   order by created_at desc
 	//   limit $3 offset $2
 	ListTransactions(ctx context.Context, arg ListTransactionsParams) ([]*ListTransactionsRow, error)
+	//ListTransactionsByLedger
+	//
+	//    with ledger as (select ledgers.id
+	//                      from ledgers
+	//                     where ledgers.uuid = $1::text
+	//                       and ledgers.tenant_id = current_tenant_id())
+	//  select t.uuid,
+	//         t.created_at,
+	//         t.updated_at,
+	//         t.amount,
+	//         t.date,
+	//         t.description,
+	//         t.metadata,
+	//         credit.uuid as credit_account_uuid,
+	//         debit.uuid  as debit_account_uuid
+	//    from transactions t
+	//         join accounts credit on credit.id = t.credit_account_id
+	//         join accounts debit on debit.id = t.debit_account_id
+	//   where t.ledger_id = (select id from ledger)
+	//   order by t.id
+	ListTransactionsByLedger(ctx context.Context, ledgerUuid string) ([]*ListTransactionsByLedgerRow, error)
+	//ListUsers
+	//
+	//    with tenant as (select id from tenants where uuid = $1::text)
+	//  select id, uuid, created_at, updated_at, email, name, tenant_id, issuer, subject
+	//    from users
+	//   where tenant_id = (select id from tenant)
+	//   order by id
+	ListUsers(ctx context.Context, tenantUuid string) ([]*User, error)
+	//ListWebhookDeliveries
+	//
+	//    with webhook as (select id
+	//                       from webhooks
+	//                      where uuid = $4::text
+	//                        and tenant_id = current_tenant_id())
+	//  select d.uuid,
+	//         d.created_at,
+	//         d.updated_at,
+	//         d.status,
+	//         d.attempts,
+	//         d.next_attempt_at,
+	//         d.last_status_code,
+	//         d.last_error,
+	//         d.delivered_at,
+	//         e.uuid       as event_uuid,
+	//         e.event_type
+	//    from webhook_deliveries d
+	//         join outbox_events e on e.id = d.event_id
+	//   where d.webhook_id = (select id from webhook)
+	//     and ($1::webhook_delivery_status is null or d.status = $1::webhook_delivery_status)
+	//   order by d.id desc
+	//   limit $2 offset $3
+	ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeliveriesParams) ([]*ListWebhookDeliveriesRow, error)
+	//ListWebhooks
+	//
+	//  select id, uuid, created_at, updated_at, url, secret, event_types, tenant_id
+	//    from webhooks
+	//   where tenant_id = current_tenant_id()
+	//   order by id
+	ListWebhooks(ctx context.Context) ([]*Webhook, error)
+	//LockTransactionLedgerUuid
+	//
+	//  select l.uuid as ledger_uuid
+	//    from transactions t
+	//         join ledgers l on l.id = t.ledger_id
+	//   where t.uuid = $1::text
+	//     and l.tenant_id = current_tenant_id()
+	//     for update of t
+	LockTransactionLedgerUuid(ctx context.Context, uuid string) (string, error)
+	//MarkWebhookDeliveryDelivered
+	//
+	//  update webhook_deliveries
+	//     set status           = 'delivered',
+	//         attempts         = attempts + 1,
+	//         last_status_code = $1::integer,
+	//         last_error       = null,
+	//         delivered_at     = current_timestamp
+	//   where id = $2::bigint
+	MarkWebhookDeliveryDelivered(ctx context.Context, arg MarkWebhookDeliveryDeliveredParams) error
+	//MarkWebhookDeliveryFailed
+	//
+	//  update webhook_deliveries
+	//     set status           = $1::webhook_delivery_status,
+	//         attempts         = attempts + 1,
+	//         last_status_code = $2::integer,
+	//         last_error       = $3::text,
+	//         next_attempt_at  = $4::timestamptz
+	//   where id = $5::bigint
+	MarkWebhookDeliveryFailed(ctx context.Context, arg MarkWebhookDeliveryFailedParams) error
+	//PutLedgerGrant
+	//
+	//       with ledger as (select id from ledgers where uuid = $2::text and tenant_id = current_tenant_id()),
+	//            usr as (select id from users where uuid = $3::text and tenant_id = current_tenant_id())
+	//     insert
+	//       into ledger_grants (role, ledger_id, user_id)
+	//     values ($1::ledger_role, (select id from ledger), (select id from usr))
+	//         on conflict (ledger_id, user_id) do update set role = excluded.role
+	//  returning id, created_at, updated_at, role, ledger_id, user_id, uuid
+	PutLedgerGrant(ctx context.Context, arg PutLedgerGrantParams) (*LedgerGrant, error)
+	//ReleaseIdempotencyKey
+	//
+	//  delete
+	//    from idempotency_keys
+	//   where key = $1::text
+	//     and principal = $2::text
+	//     -- the request failed, it can be retried with the same key
+	//     and status_code is null
+	//     and tenant_id = current_tenant_id()
+	ReleaseIdempotencyKey(ctx context.Context, arg ReleaseIdempotencyKeyParams) error
+	//ReplayWebhookDelivery
+	//
+	//  update webhook_deliveries
+	//     set status          = 'pending',
+	//         attempts        = 0,
+	//         next_attempt_at = current_timestamp,
+	//         last_error      = null
+	//   where uuid = $1::text
+	//     and webhook_id in (select id from webhooks where tenant_id = current_tenant_id())
+	//  returning id, uuid, created_at, updated_at, status, attempts, next_attempt_at, last_status_code, last_error, delivered_at, webhook_id, event_id
+	ReplayWebhookDelivery(ctx context.Context, uuid string) (*WebhookDelivery, error)
+	//RestoreAccount
+	//
+	//       with ledger as (select id
+	//                         from ledgers
+	//                        where uuid = $7::text
+	//                          and tenant_id = current_tenant_id())
+	//     insert
+	//       into accounts (uuid, created_at, updated_at, name, type, metadata, ledger_id)
+	//     values ($1, $2, $3, $4, $5, $6, (select id from ledger))
+	//  returning id, uuid, created_at, updated_at, name, type, metadata, ledger_id
+	RestoreAccount(ctx context.Context, arg RestoreAccountParams) (*Account, error)
+	//RestoreLedger
+	//
+	//     insert into ledgers (uuid, created_at, updated_at, name, description, metadata, tenant_id)
+	//     values ($1, $2, $3, $4, $5, $6, current_tenant_id())
+	//  returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
+	RestoreLedger(ctx context.Context, arg RestoreLedgerParams) (*Ledger, error)
+	//RestoreTransaction
+	//
+	//       with ledger as (select id
+	//                         from ledgers
+	//                        where ledgers.uuid = $10::text
+	//                          and ledgers.tenant_id = current_tenant_id()),
+	//            credit_account as (select id
+	//                                 from accounts
+	//                                where accounts.uuid = $8::text
+	//                                  and accounts.ledger_id = (select id from ledger)),
+	//            debit_account as (select id
+	//                                from accounts
+	//                               where accounts.uuid = $9::text
+	//                                 and accounts.ledger_id = (select id from ledger))
+	//     insert
+	//       into transactions (uuid,
+	//                          created_at,
+	//                          updated_at,
+	//                          amount,
+	//                          date,
+	//                          description,
+	//                          metadata,
+	//                          credit_account_id,
+	//                          debit_account_id,
+	//                          ledger_id)
+	//     values ($1::text,
+	//             $2::timestamptz,
+	//             $3::timestamptz,
+	//             $4::bigint,
+	//             $5::date,
+	//             $6::text,
+	//             $7::jsonb,
+	//             (select id from credit_account),
+	//             (select id from debit_account),
+	//             (select id from ledger))
+	//  returning id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
+	RestoreTransaction(ctx context.Context, arg RestoreTransactionParams) (*Transaction, error)
+	//RevokeApiKey
+	//
+	//  update api_keys
+	//     set revoked_at = current_timestamp
+	//   where (uuid = $1::text or prefix = $1::text)
+	//     and revoked_at is null
+	RevokeApiKey(ctx context.Context, key string) (int64, error)
 	//UpdateAccount
 	//
 	//     update accounts
@@ -119,6 +812,7 @@ type Querier interface {
 	//            type     = coalesce($3, type),
 	//            metadata = coalesce($4, metadata)
 	//      where uuid = $1
+	//        and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 	//  returning id, uuid, created_at, updated_at, name, type, metadata, ledger_id
 	UpdateAccount(ctx context.Context, arg UpdateAccountParams) (*Account, error)
 	//UpdateLedger
@@ -128,22 +822,47 @@ type Querier interface {
 	//            description = coalesce($3, description),
 	//            metadata    = coalesce($4, metadata)
 	//      where uuid = $1
-	//  returning id, uuid, created_at, updated_at, name, description, metadata
+	//        and tenant_id = current_tenant_id()
+	//  returning id, uuid, created_at, updated_at, name, description, metadata, tenant_id
 	UpdateLedger(ctx context.Context, arg UpdateLedgerParams) (*Ledger, error)
 	//UpdateTransaction
 	//
-	//       with credit_account as (select id from accounts where accounts.uuid = $6::text),
-	//            debit_account as (select id from accounts where accounts.uuid = $7::text),
-	//            ledger as (select id from ledgers where ledgers.uuid = $8::text)
+	//       with txn as (select id, ledger_id
+	//                      from transactions
+	//                     where transactions.uuid = $5
+	//                       and transactions.ledger_id in (select id from ledgers where tenant_id = current_tenant_id())),
+	//            ledger as (select id
+	//                         from ledgers
+	//                        where ledgers.uuid = $8::text
+	//                          and ledgers.tenant_id = current_tenant_id()),
+	//            -- accounts must belong to the ledger the transaction ends up in
+	//            credit_account as (select id
+	//                                 from accounts
+	//                                where accounts.uuid = $6::text
+	//                                  and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn))),
+	//            debit_account as (select id
+	//                                from accounts
+	//                               where accounts.uuid = $7::text
+	//                                 and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn)))
 	//     update transactions
 	//        set amount            = coalesce($1::bigint, amount),
 	//            date              = coalesce($2, date),
 	//            description       = coalesce($3, description),
 	//            metadata          = coalesce($4, metadata),
-	//            credit_account_id = coalesce((select id from credit_account), credit_account_id),
-	//            debit_account_id  = coalesce((select id from debit_account), debit_account_id),
-	//            ledger_id         = coalesce((select id from ledger), ledger_id)
-	//      where transactions.uuid = $5
+	//            -- an unknown uuid sets null and fails, instead of being ignored
+	//            credit_account_id = case
+	//                                    when $6::text is null then credit_account_id
+	//                                    else (select id from credit_account)
+	//                                end,
+	//            debit_account_id  = case
+	//                                    when $7::text is null then debit_account_id
+	//                                    else (select id from debit_account)
+	//                                end,
+	//            ledger_id         = case
+	//                                    when $8::text is null then ledger_id
+	//                                    else (select id from ledger)
+	//                                end
+	//      where transactions.id = (select id from txn)
 	//  returning id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
 	UpdateTransaction(ctx context.Context, arg UpdateTransactionParams) (*Transaction, error)
 }
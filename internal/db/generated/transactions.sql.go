@@ -12,15 +12,19 @@ import (
 )
 
 const createTransaction = `-- name: CreateTransaction :one
-     WITH credit_account AS (SELECT id
+     WITH ledger_id AS (SELECT id
+                          FROM ledgers
+                         WHERE ledgers.uuid = $7::text
+                           AND ledgers.tenant_id = current_tenant_id()),
+          -- accounts of other ledgers are never linked
+          credit_account AS (SELECT id
                                FROM accounts
-                              WHERE accounts.uuid = $5::text),
+                              WHERE accounts.uuid = $5::text
+                                AND accounts.ledger_id = (SELECT id FROM ledger_id)),
           debit_account AS (SELECT id
                               FROM accounts
-                             WHERE accounts.uuid = $6::text),
-          ledger_id AS (SELECT id
-                          FROM ledgers
-                         WHERE ledgers.uuid = $7::text)
+                             WHERE accounts.uuid = $6::text
+                               AND accounts.ledger_id = (SELECT id FROM ledger_id))
    INSERT
      INTO transactions (amount,
                         date,
@@ -51,15 +55,19 @@ type CreateTransactionParams struct {
 
 // CreateTransaction
 //
-//	     WITH credit_account AS (SELECT id
+//	     WITH ledger_id AS (SELECT id
+//	                          FROM ledgers
+//	                         WHERE ledgers.uuid = $7::text
+//	                           AND ledgers.tenant_id = current_tenant_id()),
+//	          -- accounts of other ledgers are never linked
+//	          credit_account AS (SELECT id
 //	                               FROM accounts
-//	                              WHERE accounts.uuid = $5::text),
+//	                              WHERE accounts.uuid = $5::text
+//	                                AND accounts.ledger_id = (SELECT id FROM ledger_id)),
 //	          debit_account AS (SELECT id
 //	                              FROM accounts
-//	                             WHERE accounts.uuid = $6::text),
-//	          ledger_id AS (SELECT id
-//	                          FROM ledgers
-//	                         WHERE ledgers.uuid = $7::text)
+//	                             WHERE accounts.uuid = $6::text
+//	                               AND accounts.ledger_id = (SELECT id FROM ledger_id))
 //	   INSERT
 //	     INTO transactions (amount,
 //	                        date,
@@ -107,6 +115,7 @@ const deleteTransaction = `-- name: DeleteTransaction :exec
 delete
   from transactions
  where uuid = $1::text
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 `
 
 // DeleteTransaction
@@ -114,6 +123,7 @@ delete
 //	delete
 //	  from transactions
 //	 where uuid = $1::text
+//	   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 func (q *Queries) DeleteTransaction(ctx context.Context, uuid string) error {
 	_, err := q.db.Exec(ctx, deleteTransaction, uuid)
 	return err
@@ -123,6 +133,7 @@ const getTransaction = `-- name: GetTransaction :one
 select id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
   from transactions
  where uuid = $1::text
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
  limit 1
 `
 
@@ -131,6 +142,7 @@ select id, uuid, created_at, updated_at, amount, date, description, metadata, cr
 //	select id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
 //	  from transactions
 //	 where uuid = $1::text
+//	   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 //	 limit 1
 func (q *Queries) GetTransaction(ctx context.Context, uuid string) (*Transaction, error) {
 	row := q.db.QueryRow(ctx, getTransaction, uuid)
@@ -151,8 +163,33 @@ func (q *Queries) GetTransaction(ctx context.Context, uuid string) (*Transaction
 	return &i, err
 }
 
+const getTransactionLedgerUuid = `-- name: GetTransactionLedgerUuid :one
+select l.uuid as ledger_uuid
+  from transactions t
+       join ledgers l on l.id = t.ledger_id
+ where t.uuid = $1::text
+   and l.tenant_id = current_tenant_id()
+`
+
+// GetTransactionLedgerUuid
+//
+//	select l.uuid as ledger_uuid
+//	  from transactions t
+//	       join ledgers l on l.id = t.ledger_id
+//	 where t.uuid = $1::text
+//	   and l.tenant_id = current_tenant_id()
+func (q *Queries) GetTransactionLedgerUuid(ctx context.Context, uuid string) (string, error) {
+	row := q.db.QueryRow(ctx, getTransactionLedgerUuid, uuid)
+	var ledger_uuid string
+	err := row.Scan(&ledger_uuid)
+	return ledger_uuid, err
+}
+
 const getTransactionsCount = `-- name: GetTransactionsCount :one
-  with ledger as (select ledgers.id from ledgers where ledgers.uuid = $2::text)
+  with ledger as (select ledgers.id
+                    from ledgers
+                   where ledgers.uuid = $2::text
+                     and ledgers.tenant_id = current_tenant_id())
 select count(*)
   from transactions
  where ledger_id = (select id from ledger)
@@ -166,7 +203,10 @@ type GetTransactionsCountParams struct {
 
 // GetTransactionsCount
 //
-//	  with ledger as (select ledgers.id from ledgers where ledgers.uuid = $2::text)
+//	  with ledger as (select ledgers.id
+//	                    from ledgers
+//	                   where ledgers.uuid = $2::text
+//	                     and ledgers.tenant_id = current_tenant_id())
 //	select count(*)
 //	  from transactions
 //	 where ledger_id = (select id from ledger)
@@ -179,7 +219,10 @@ func (q *Queries) GetTransactionsCount(ctx context.Context, arg GetTransactionsC
 }
 
 const listTransactions = `-- name: ListTransactions :many
-  with ledger as (select ledgers.id from ledgers where ledgers.uuid = $4::text)
+  with ledger as (select ledgers.id
+                    from ledgers
+                   where ledgers.uuid = $4::text
+                     and ledgers.tenant_id = current_tenant_id())
 select uuid, amount, date, description, metadata
   from transactions
  where ledger_id = (select id from ledger)
@@ -205,7 +248,10 @@ type ListTransactionsRow struct {
 
 // ListTransactions
 //
-//	  with ledger as (select ledgers.id from ledgers where ledgers.uuid = $4::text)
+//	  with ledger as (select ledgers.id
+//	                    from ledgers
+//	                   where ledgers.uuid = $4::text
+//	                     and ledgers.tenant_id = current_tenant_id())
 //	select uuid, amount, date, description, metadata
 //	  from transactions
 //	 where ledger_id = (select id from ledger)
@@ -243,19 +289,266 @@ func (q *Queries) ListTransactions(ctx context.Context, arg ListTransactionsPara
 	return items, nil
 }
 
+const listTransactionsByLedger = `-- name: ListTransactionsByLedger :many
+  with ledger as (select ledgers.id
+                    from ledgers
+                   where ledgers.uuid = $1::text
+                     and ledgers.tenant_id = current_tenant_id())
+select t.uuid,
+       t.created_at,
+       t.updated_at,
+       t.amount,
+       t.date,
+       t.description,
+       t.metadata,
+       credit.uuid as credit_account_uuid,
+       debit.uuid  as debit_account_uuid
+  from transactions t
+       join accounts credit on credit.id = t.credit_account_id
+       join accounts debit on debit.id = t.debit_account_id
+ where t.ledger_id = (select id from ledger)
+ order by t.id
+`
+
+type ListTransactionsByLedgerRow struct {
+	Uuid              string             `json:"uuid"`
+	CreatedAt         pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt         pgtype.Timestamptz `json:"updatedAt"`
+	Amount            int64              `json:"amount"`
+	Date              pgtype.Date        `json:"date"`
+	Description       pgtype.Text        `json:"description"`
+	Metadata          []byte             `json:"metadata"`
+	CreditAccountUuid string             `json:"creditAccountUuid"`
+	DebitAccountUuid  string             `json:"debitAccountUuid"`
+}
+
+// ListTransactionsByLedger
+//
+//	  with ledger as (select ledgers.id
+//	                    from ledgers
+//	                   where ledgers.uuid = $1::text
+//	                     and ledgers.tenant_id = current_tenant_id())
+//	select t.uuid,
+//	       t.created_at,
+//	       t.updated_at,
+//	       t.amount,
+//	       t.date,
+//	       t.description,
+//	       t.metadata,
+//	       credit.uuid as credit_account_uuid,
+//	       debit.uuid  as debit_account_uuid
+//	  from transactions t
+//	       join accounts credit on credit.id = t.credit_account_id
+//	       join accounts debit on debit.id = t.debit_account_id
+//	 where t.ledger_id = (select id from ledger)
+//	 order by t.id
+func (q *Queries) ListTransactionsByLedger(ctx context.Context, ledgerUuid string) ([]*ListTransactionsByLedgerRow, error) {
+	rows, err := q.db.Query(ctx, listTransactionsByLedger, ledgerUuid)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []*ListTransactionsByLedgerRow
+	for rows.Next() {
+		var i ListTransactionsByLedgerRow
+		if err := rows.Scan(
+			&i.Uuid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Amount,
+			&i.Date,
+			&i.Description,
+			&i.Metadata,
+			&i.CreditAccountUuid,
+			&i.DebitAccountUuid,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, &i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const lockTransactionLedgerUuid = `-- name: LockTransactionLedgerUuid :one
+select l.uuid as ledger_uuid
+  from transactions t
+       join ledgers l on l.id = t.ledger_id
+ where t.uuid = $1::text
+   and l.tenant_id = current_tenant_id()
+   for update of t
+`
+
+// LockTransactionLedgerUuid
+//
+//	select l.uuid as ledger_uuid
+//	  from transactions t
+//	       join ledgers l on l.id = t.ledger_id
+//	 where t.uuid = $1::text
+//	   and l.tenant_id = current_tenant_id()
+//	   for update of t
+func (q *Queries) LockTransactionLedgerUuid(ctx context.Context, uuid string) (string, error) {
+	row := q.db.QueryRow(ctx, lockTransactionLedgerUuid, uuid)
+	var ledger_uuid string
+	err := row.Scan(&ledger_uuid)
+	return ledger_uuid, err
+}
+
+const restoreTransaction = `-- name: RestoreTransaction :one
+     with ledger as (select id
+                       from ledgers
+                      where ledgers.uuid = $10::text
+                        and ledgers.tenant_id = current_tenant_id()),
+          credit_account as (select id
+                               from accounts
+                              where accounts.uuid = $8::text
+                                and accounts.ledger_id = (select id from ledger)),
+          debit_account as (select id
+                              from accounts
+                             where accounts.uuid = $9::text
+                               and accounts.ledger_id = (select id from ledger))
+   insert
+     into transactions (uuid,
+                        created_at,
+                        updated_at,
+                        amount,
+                        date,
+                        description,
+                        metadata,
+                        credit_account_id,
+                        debit_account_id,
+                        ledger_id)
+   values ($1::text,
+           $2::timestamptz,
+           $3::timestamptz,
+           $4::bigint,
+           $5::date,
+           $6::text,
+           $7::jsonb,
+           (select id from credit_account),
+           (select id from debit_account),
+           (select id from ledger))
+returning id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
+`
+
+type RestoreTransactionParams struct {
+	Uuid              string             `json:"uuid"`
+	CreatedAt         pgtype.Timestamptz `json:"createdAt"`
+	UpdatedAt         pgtype.Timestamptz `json:"updatedAt"`
+	Amount            int64              `json:"amount"`
+	Date              pgtype.Date        `json:"date"`
+	Description       pgtype.Text        `json:"description"`
+	Metadata          []byte             `json:"metadata"`
+	CreditAccountUuid string             `json:"creditAccountUuid"`
+	DebitAccountUuid  string             `json:"debitAccountUuid"`
+	LedgerUuid        string             `json:"ledgerUuid"`
+}
+
+// RestoreTransaction
+//
+//	     with ledger as (select id
+//	                       from ledgers
+//	                      where ledgers.uuid = $10::text
+//	                        and ledgers.tenant_id = current_tenant_id()),
+//	          credit_account as (select id
+//	                               from accounts
+//	                              where accounts.uuid = $8::text
+//	                                and accounts.ledger_id = (select id from ledger)),
+//	          debit_account as (select id
+//	                              from accounts
+//	                             where accounts.uuid = $9::text
+//	                               and accounts.ledger_id = (select id from ledger))
+//	   insert
+//	     into transactions (uuid,
+//	                        created_at,
+//	                        updated_at,
+//	                        amount,
+//	                        date,
+//	                        description,
+//	                        metadata,
+//	                        credit_account_id,
+//	                        debit_account_id,
+//	                        ledger_id)
+//	   values ($1::text,
+//	           $2::timestamptz,
+//	           $3::timestamptz,
+//	           $4::bigint,
+//	           $5::date,
+//	           $6::text,
+//	           $7::jsonb,
+//	           (select id from credit_account),
+//	           (select id from debit_account),
+//	           (select id from ledger))
+//	returning id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
+func (q *Queries) RestoreTransaction(ctx context.Context, arg RestoreTransactionParams) (*Transaction, error) {
+	row := q.db.QueryRow(ctx, restoreTransaction,
+		arg.Uuid,
+		arg.CreatedAt,
+		arg.UpdatedAt,
+		arg.Amount,
+		arg.Date,
+		arg.Description,
+		arg.Metadata,
+		arg.CreditAccountUuid,
+		arg.DebitAccountUuid,
+		arg.LedgerUuid,
+	)
+	var i Transaction
+	err := row.Scan(
+		&i.ID,
+		&i.Uuid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Amount,
+		&i.Date,
+		&i.Description,
+		&i.Metadata,
+		&i.CreditAccountID,
+		&i.DebitAccountID,
+		&i.LedgerID,
+	)
+	return &i, err
+}
+
 const updateTransaction = `-- name: UpdateTransaction :one
-     with credit_account as (select id from accounts where accounts.uuid = $6::text),
-          debit_account as (select id from accounts where accounts.uuid = $7::text),
-          ledger as (select id from ledgers where ledgers.uuid = $8::text)
+     with txn as (select id, ledger_id
+                    from transactions
+                   where transactions.uuid = $5
+                     and transactions.ledger_id in (select id from ledgers where tenant_id = current_tenant_id())),
+          ledger as (select id
+                       from ledgers
+                      where ledgers.uuid = $8::text
+                        and ledgers.tenant_id = current_tenant_id()),
+          -- accounts must belong to the ledger the transaction ends up in
+          credit_account as (select id
+                               from accounts
+                              where accounts.uuid = $6::text
+                                and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn))),
+          debit_account as (select id
+                              from accounts
+                             where accounts.uuid = $7::text
+                               and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn)))
    update transactions
       set amount            = coalesce($1::bigint, amount),
           date              = coalesce($2, date),
           description       = coalesce($3, description),
           metadata          = coalesce($4, metadata),
-          credit_account_id = coalesce((select id from credit_account), credit_account_id),
-          debit_account_id  = coalesce((select id from debit_account), debit_account_id),
-          ledger_id         = coalesce((select id from ledger), ledger_id)
-    where transactions.uuid = $5
+          -- an unknown uuid sets null and fails, instead of being ignored
+          credit_account_id = case
+                                  when $6::text is null then credit_account_id
+                                  else (select id from credit_account)
+                              end,
+          debit_account_id  = case
+                                  when $7::text is null then debit_account_id
+                                  else (select id from debit_account)
+                              end,
+          ledger_id         = case
+                                  when $8::text is null then ledger_id
+                                  else (select id from ledger)
+                              end
+    where transactions.id = (select id from txn)
 returning id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
 `
 
@@ -272,18 +565,42 @@ type UpdateTransactionParams struct {
 
 // UpdateTransaction
 //
-//	     with credit_account as (select id from accounts where accounts.uuid = $6::text),
-//	          debit_account as (select id from accounts where accounts.uuid = $7::text),
-//	          ledger as (select id from ledgers where ledgers.uuid = $8::text)
+//	     with txn as (select id, ledger_id
+//	                    from transactions
+//	                   where transactions.uuid = $5
+//	                     and transactions.ledger_id in (select id from ledgers where tenant_id = current_tenant_id())),
+//	          ledger as (select id
+//	                       from ledgers
+//	                      where ledgers.uuid = $8::text
+//	                        and ledgers.tenant_id = current_tenant_id()),
+//	          -- accounts must belong to the ledger the transaction ends up in
+//	          credit_account as (select id
+//	                               from accounts
+//	                              where accounts.uuid = $6::text
+//	                                and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn))),
+//	          debit_account as (select id
+//	                              from accounts
+//	                             where accounts.uuid = $7::text
+//	                               and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn)))
 //	   update transactions
 //	      set amount            = coalesce($1::bigint, amount),
 //	          date              = coalesce($2, date),
 //	          description       = coalesce($3, description),
 //	          metadata          = coalesce($4, metadata),
-//	          credit_account_id = coalesce((select id from credit_account), credit_account_id),
-//	          debit_account_id  = coalesce((select id from debit_account), debit_account_id),
-//	          ledger_id         = coalesce((select id from ledger), ledger_id)
-//	    where transactions.uuid = $5
+//	          -- an unknown uuid sets null and fails, instead of being ignored
+//	          credit_account_id = case
+//	                                  when $6::text is null then credit_account_id
+//	                                  else (select id from credit_account)
+//	                              end,
+//	          debit_account_id  = case
+//	                                  when $7::text is null then debit_account_id
+//	                                  else (select id from debit_account)
+//	                              end,
+//	          ledger_id         = case
+//	                                  when $8::text is null then ledger_id
+//	                                  else (select id from ledger)
+//	                              end
+//	    where transactions.id = (select id from txn)
 //	returning id, uuid, created_at, updated_at, amount, date, description, metadata, credit_account_id, debit_account_id, ledger_id
 func (q *Queries) UpdateTransaction(ctx context.Context, arg UpdateTransactionParams) (*Transaction, error) {
 	row := q.db.QueryRow(ctx, updateTransaction,
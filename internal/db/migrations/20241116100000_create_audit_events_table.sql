@@ -0,0 +1,45 @@
+-- +goose Up
+-- +goose StatementBegin
+create table audit_events
+(
+    id          bigint generated always as identity primary key,
+    uuid        text        not null default nanoid(10),
+
+    created_at  timestamptz not null default current_timestamp,
+
+    actor       text        not null,
+    action      text        not null,
+    entity_type text        not null,
+    entity_uuid text        not null,
+    before      jsonb,
+    after       jsonb,
+    request_id  text,
+
+    -- constraints
+    constraint audit_events_uuid_unique unique (uuid)
+);
+
+create index audit_events_entity_idx on audit_events (entity_type, entity_uuid, id);
+
+-- audit events are never changed once written
+create or replace function prevent_audit_event_change()
+    returns trigger as
+$$
+begin
+    raise exception 'audit_events is append-only';
+end;
+$$ language plpgsql;
+
+create trigger audit_event_append_only
+    before update or delete
+    on audit_events
+    for each row
+execute procedure prevent_audit_event_change();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger audit_event_append_only on audit_events;
+drop function prevent_audit_event_change();
+drop table audit_events;
+-- +goose StatementEnd
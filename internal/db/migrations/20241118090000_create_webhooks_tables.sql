@@ -0,0 +1,87 @@
+-- +goose Up
+-- +goose StatementBegin
+create type webhook_delivery_status as enum ('pending', 'failed', 'delivered', 'dead');
+
+-- events are written in the same transaction as the change they describe
+create table outbox_events
+(
+    id          bigint generated always as identity primary key,
+    uuid        text        not null default nanoid(10),
+
+    created_at  timestamptz not null default current_timestamp,
+
+    event_type  text        not null,
+    entity_type text        not null,
+    entity_uuid text        not null,
+    payload     jsonb       not null,
+
+    -- constraints
+    constraint outbox_events_uuid_unique unique (uuid)
+);
+
+create table webhooks
+(
+    id          bigint generated always as identity primary key,
+    uuid        text        not null default nanoid(10),
+
+    created_at  timestamptz not null default current_timestamp,
+    updated_at  timestamptz not null default current_timestamp,
+
+    url         text        not null,
+    secret      text        not null,
+    -- an empty list subscribes to every event type
+    event_types text[]      not null default '{}',
+
+    -- constraints
+    constraint webhooks_uuid_unique unique (uuid),
+    constraint webhooks_url_length_check check (char_length(url) < 2048)
+);
+
+create trigger webhook_updated_at
+    before update
+    on webhooks
+    for each row
+execute procedure set_updated_at();
+
+create table webhook_deliveries
+(
+    id               bigint generated always as identity primary key,
+    uuid             text                    not null default nanoid(10),
+
+    created_at       timestamptz             not null default current_timestamp,
+    updated_at       timestamptz             not null default current_timestamp,
+
+    status           webhook_delivery_status not null default 'pending',
+    attempts         integer                 not null default 0,
+    next_attempt_at  timestamptz             not null default current_timestamp,
+    last_status_code integer,
+    last_error       text,
+    delivered_at     timestamptz,
+
+    webhook_id       bigint                  not null references webhooks (id) on delete cascade,
+    event_id         bigint                  not null references outbox_events (id) on delete cascade,
+
+    -- constraints
+    constraint webhook_deliveries_uuid_unique unique (uuid),
+    constraint webhook_deliveries_event_unique unique (webhook_id, event_id)
+);
+
+create index webhook_deliveries_due_idx on webhook_deliveries (next_attempt_at)
+    where status in ('pending', 'failed');
+
+create trigger webhook_delivery_updated_at
+    before update
+    on webhook_deliveries
+    for each row
+execute procedure set_updated_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger webhook_delivery_updated_at on webhook_deliveries;
+drop table webhook_deliveries;
+drop trigger webhook_updated_at on webhooks;
+drop table webhooks;
+drop table outbox_events;
+drop type webhook_delivery_status;
+-- +goose StatementEnd
@@ -0,0 +1,35 @@
+-- +goose Up
+-- +goose StatementBegin
+alter table outbox_events
+    add column ledger_uuid text;
+
+create index outbox_events_ledger_idx on outbox_events (ledger_uuid, id);
+
+-- wakes up the event streams of the ledger, notifications are only sent
+-- once the transaction that wrote the event commits
+create or replace function notify_outbox_event()
+    returns trigger as
+$$
+begin
+    if new.ledger_uuid is not null then
+        perform pg_notify('ledger_events', new.ledger_uuid);
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger outbox_event_notify
+    after insert
+    on outbox_events
+    for each row
+execute procedure notify_outbox_event();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger outbox_event_notify on outbox_events;
+drop function notify_outbox_event();
+drop index outbox_events_ledger_idx;
+alter table outbox_events
+    drop column ledger_uuid;
+-- +goose StatementEnd
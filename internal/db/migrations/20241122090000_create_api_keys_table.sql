@@ -0,0 +1,36 @@
+-- +goose Up
+-- +goose StatementBegin
+create table api_keys
+(
+    id         bigint generated always as identity primary key,
+    uuid       text        not null default nanoid(10),
+
+    created_at timestamptz not null default current_timestamp,
+    updated_at timestamptz not null default current_timestamp,
+
+    name       text        not null,
+    -- the public part of the key used to look it up, the rest is only
+    -- stored as a hash
+    prefix     text        not null,
+    key_hash   text        not null,
+    scopes     text[]      not null default '{}',
+    revoked_at timestamptz,
+
+    -- constraints
+    constraint api_keys_uuid_unique unique (uuid),
+    constraint api_keys_prefix_unique unique (prefix),
+    constraint api_keys_name_length_check check (char_length(name) < 255)
+);
+
+create trigger api_key_updated_at
+    before update
+    on api_keys
+    for each row
+execute procedure set_updated_at();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop trigger api_key_updated_at on api_keys;
+drop table api_keys;
+-- +goose StatementEnd
@@ -0,0 +1,145 @@
+-- +goose Up
+-- +goose StatementBegin
+create table tenants
+(
+    id         bigint generated always as identity primary key,
+    uuid       text        not null default nanoid(10),
+
+    created_at timestamptz not null default current_timestamp,
+    updated_at timestamptz not null default current_timestamp,
+
+    name       text        not null,
+
+    -- constraints
+    constraint tenants_uuid_unique unique (uuid),
+    constraint tenants_name_length_check check (char_length(name) < 255)
+);
+
+create trigger tenant_updated_at
+    before update
+    on tenants
+    for each row
+execute procedure set_updated_at();
+
+-- the tenant of the current connection, set by the application for each
+-- request, null when there is none
+create or replace function current_tenant_id()
+    returns bigint as
+$$
+select nullif(current_setting('app.tenant_id', true), '')::bigint;
+$$ language sql stable;
+
+-- existing rows belong to a default tenant
+insert into tenants (name)
+select 'default'
+ where exists (select 1 from ledgers)
+    or exists (select 1 from api_keys)
+    or exists (select 1 from webhooks)
+    or exists (select 1 from outbox_events)
+    or exists (select 1 from audit_events);
+
+alter table ledgers
+    add column tenant_id bigint references tenants (id) on delete cascade;
+alter table api_keys
+    add column tenant_id bigint references tenants (id) on delete cascade;
+alter table webhooks
+    add column tenant_id bigint references tenants (id) on delete cascade;
+alter table outbox_events
+    add column tenant_id bigint references tenants (id) on delete cascade;
+alter table audit_events
+    add column tenant_id bigint references tenants (id) on delete cascade;
+
+update ledgers set tenant_id = (select min(id) from tenants);
+update api_keys set tenant_id = (select min(id) from tenants);
+update webhooks set tenant_id = (select min(id) from tenants);
+update outbox_events set tenant_id = (select min(id) from tenants);
+
+alter table audit_events disable trigger audit_event_append_only;
+update audit_events set tenant_id = (select min(id) from tenants);
+alter table audit_events enable trigger audit_event_append_only;
+
+alter table ledgers alter column tenant_id set not null;
+alter table api_keys alter column tenant_id set not null;
+alter table webhooks alter column tenant_id set not null;
+alter table outbox_events alter column tenant_id set not null;
+alter table audit_events alter column tenant_id set not null;
+
+create index ledgers_tenant_id_idx on ledgers (tenant_id);
+create index webhooks_tenant_id_idx on webhooks (tenant_id);
+create index audit_events_tenant_id_idx on audit_events (tenant_id, id);
+
+-- a transaction can only move money between accounts of its own ledger,
+-- transactions already linking accounts of another ledger must be fixed
+-- before migrating
+alter table accounts
+    add constraint accounts_id_ledger_id_unique unique (id, ledger_id);
+alter table transactions
+    add constraint transactions_credit_account_ledger_fk
+        foreign key (credit_account_id, ledger_id) references accounts (id, ledger_id) on delete cascade,
+    add constraint transactions_debit_account_ledger_fk
+        foreign key (debit_account_id, ledger_id) references accounts (id, ledger_id) on delete cascade;
+
+-- row level security is a second line of defense, the queries are already
+-- scoped to the tenant. The policies are forced so they apply to the table
+-- owner too, but superusers and BYPASSRLS roles are never subject to them:
+-- the service must connect as a regular role.
+--
+-- api keys are read before the tenant is known, and the webhook dispatcher
+-- works across tenants, so api_keys, webhooks and the outbox are only
+-- scoped by the queries.
+alter table ledgers enable row level security;
+alter table ledgers force row level security;
+create policy ledgers_tenant_isolation on ledgers
+    using (tenant_id = current_tenant_id())
+    with check (tenant_id = current_tenant_id());
+
+alter table accounts enable row level security;
+alter table accounts force row level security;
+create policy accounts_tenant_isolation on accounts
+    using (ledger_id in (select id from ledgers where tenant_id = current_tenant_id()))
+    with check (ledger_id in (select id from ledgers where tenant_id = current_tenant_id()));
+
+alter table transactions enable row level security;
+alter table transactions force row level security;
+create policy transactions_tenant_isolation on transactions
+    using (ledger_id in (select id from ledgers where tenant_id = current_tenant_id()))
+    with check (ledger_id in (select id from ledgers where tenant_id = current_tenant_id()));
+
+alter table audit_events enable row level security;
+alter table audit_events force row level security;
+create policy audit_events_tenant_isolation on audit_events
+    using (tenant_id = current_tenant_id())
+    with check (tenant_id = current_tenant_id());
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop policy audit_events_tenant_isolation on audit_events;
+alter table audit_events no force row level security;
+alter table audit_events disable row level security;
+drop policy transactions_tenant_isolation on transactions;
+alter table transactions no force row level security;
+alter table transactions disable row level security;
+drop policy accounts_tenant_isolation on accounts;
+alter table accounts no force row level security;
+alter table accounts disable row level security;
+drop policy ledgers_tenant_isolation on ledgers;
+alter table ledgers no force row level security;
+alter table ledgers disable row level security;
+
+alter table transactions
+    drop constraint transactions_debit_account_ledger_fk,
+    drop constraint transactions_credit_account_ledger_fk;
+alter table accounts
+    drop constraint accounts_id_ledger_id_unique;
+
+alter table audit_events drop column tenant_id;
+alter table outbox_events drop column tenant_id;
+alter table webhooks drop column tenant_id;
+alter table api_keys drop column tenant_id;
+alter table ledgers drop column tenant_id;
+
+drop function current_tenant_id();
+drop trigger tenant_updated_at on tenants;
+drop table tenants;
+-- +goose StatementEnd
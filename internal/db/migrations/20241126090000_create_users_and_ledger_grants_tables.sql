@@ -0,0 +1,78 @@
+-- +goose Up
+-- +goose StatementBegin
+create type ledger_role as enum ('owner', 'accountant', 'viewer', 'auditor');
+
+create table users
+(
+    id         bigint generated always as identity primary key,
+    uuid       text        not null default nanoid(10),
+
+    created_at timestamptz not null default current_timestamp,
+    updated_at timestamptz not null default current_timestamp,
+
+    email      text        not null,
+    name       text,
+
+    tenant_id  bigint      not null references tenants (id) on delete cascade,
+
+    -- constraints
+    constraint users_uuid_unique unique (uuid),
+    constraint users_tenant_id_email_unique unique (tenant_id, email),
+    constraint users_email_length_check check (char_length(email) < 255),
+    constraint users_name_length_check check (char_length(name) < 255)
+);
+
+create trigger user_updated_at
+    before update
+    on users
+    for each row
+execute procedure set_updated_at();
+
+-- keys of a user act on their behalf, keys without one have access to
+-- every ledger of the tenant
+alter table api_keys
+    add column user_id bigint references users (id) on delete cascade;
+
+create table ledger_grants
+(
+    id         bigint generated always as identity primary key,
+
+    created_at timestamptz not null default current_timestamp,
+    updated_at timestamptz not null default current_timestamp,
+
+    role       ledger_role not null,
+
+    ledger_id  bigint      not null references ledgers (id) on delete cascade,
+    user_id    bigint      not null references users (id) on delete cascade,
+
+    -- constraints
+    constraint ledger_grants_ledger_id_user_id_unique unique (ledger_id, user_id)
+);
+
+create index ledger_grants_user_id_idx on ledger_grants (user_id);
+
+create trigger ledger_grant_updated_at
+    before update
+    on ledger_grants
+    for each row
+execute procedure set_updated_at();
+
+-- users are read with the api keys, before the tenant is known, so only
+-- the grants are subject to row level security
+alter table ledger_grants enable row level security;
+alter table ledger_grants force row level security;
+create policy ledger_grants_tenant_isolation on ledger_grants
+    using (ledger_id in (select id from ledgers where tenant_id = current_tenant_id()))
+    with check (ledger_id in (select id from ledgers where tenant_id = current_tenant_id()));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop policy ledger_grants_tenant_isolation on ledger_grants;
+drop trigger ledger_grant_updated_at on ledger_grants;
+drop table ledger_grants;
+alter table api_keys drop column user_id;
+drop trigger user_updated_at on users;
+drop table users;
+drop type ledger_role;
+-- +goose StatementEnd
@@ -0,0 +1,38 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the responses of the POST requests sent with an `Idempotency-Key`
+-- header, the retries of a request get its response back instead of
+-- running it again
+create table idempotency_keys
+(
+    id            bigint generated always as identity primary key,
+
+    created_at    timestamptz not null default current_timestamp,
+
+    key           text        not null,
+    -- the method, path and body of the request, a key can't be reused for
+    -- another request
+    request_hash  text        not null,
+    -- null while the request is in progress
+    status_code   int,
+    response_body bytea,
+
+    tenant_id     bigint      not null references tenants (id) on delete cascade,
+
+    -- constraints
+    constraint idempotency_keys_tenant_id_key_unique unique (tenant_id, key),
+    constraint idempotency_keys_key_length_check check (char_length(key) < 255)
+);
+
+alter table idempotency_keys enable row level security;
+alter table idempotency_keys force row level security;
+create policy idempotency_keys_tenant_isolation on idempotency_keys
+    using (tenant_id = current_tenant_id())
+    with check (tenant_id = current_tenant_id());
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop policy idempotency_keys_tenant_isolation on idempotency_keys;
+drop table idempotency_keys;
+-- +goose StatementEnd
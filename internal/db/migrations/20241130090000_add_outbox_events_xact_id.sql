@@ -0,0 +1,26 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the transaction that wrote the event, ids are taken on insert but the
+-- transactions commit in any order, so the event streams only read the
+-- events below the oldest transaction still running, in this order
+alter table outbox_events
+    add column xact_id bigint not null default pg_current_xact_id()::text::bigint;
+
+create index outbox_events_ledger_xact_idx on outbox_events (ledger_uuid, xact_id, id);
+
+-- the oldest transaction still running, events written by older ones are
+-- all committed or rolled back
+create or replace function outbox_events_watermark()
+    returns bigint as
+$$
+select pg_snapshot_xmin(pg_current_snapshot())::text::bigint;
+$$ language sql volatile;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop function outbox_events_watermark();
+drop index outbox_events_ledger_xact_idx;
+alter table outbox_events
+    drop column xact_id;
+-- +goose StatementEnd
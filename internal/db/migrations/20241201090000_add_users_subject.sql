@@ -0,0 +1,23 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the issuer and subject of the JWTs of the user, they identify it across
+-- changes of its email. Users created ahead of their first sign-in have
+-- none until then.
+alter table users
+    add column issuer  text,
+    add column subject text,
+    add constraint users_subject_issuer_check check ((issuer is null) = (subject is null));
+
+create unique index users_tenant_id_issuer_subject_unique
+    on users (tenant_id, issuer, subject)
+    where subject is not null;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+drop index users_tenant_id_issuer_subject_unique;
+alter table users
+    drop constraint users_subject_issuer_check,
+    drop column subject,
+    drop column issuer;
+-- +goose StatementEnd
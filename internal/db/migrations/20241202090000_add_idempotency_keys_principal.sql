@@ -0,0 +1,29 @@
+-- +goose Up
+-- +goose StatementBegin
+-- the API key or user that sent the request, another principal of the
+-- tenant reusing a key runs its own request instead of getting the stored
+-- response back
+alter table idempotency_keys
+    add column principal text not null default '',
+    drop constraint idempotency_keys_tenant_id_key_unique,
+    add constraint idempotency_keys_tenant_id_principal_key_unique unique (tenant_id, principal, key);
+
+alter table idempotency_keys
+    alter column principal drop default;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- the keys are kept for a day, the ones taken by several principals go
+delete
+  from idempotency_keys k
+ using idempotency_keys other
+ where other.tenant_id = k.tenant_id
+   and other.key = k.key
+   and other.id > k.id;
+
+alter table idempotency_keys
+    drop constraint idempotency_keys_tenant_id_principal_key_unique,
+    add constraint idempotency_keys_tenant_id_key_unique unique (tenant_id, key),
+    drop column principal;
+-- +goose StatementEnd
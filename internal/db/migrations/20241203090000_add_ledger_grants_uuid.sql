@@ -0,0 +1,14 @@
+-- +goose Up
+-- +goose StatementBegin
+-- grant changes are audited and published like the other records
+alter table ledger_grants
+    add column uuid text not null default nanoid(10),
+    add constraint ledger_grants_uuid_unique unique (uuid);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+alter table ledger_grants
+    drop constraint ledger_grants_uuid_unique,
+    drop column uuid;
+-- +goose StatementEnd
@@ -0,0 +1,66 @@
+// Package migrations embeds the goose migrations of the schema, so the
+// binary migrates the database on its own and knows the version of the
+// schema it expects.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/pressly/goose/v3"
+	"github.com/pressly/goose/v3/lock"
+	"io/fs"
+	"log/slog"
+)
+
+//go:embed *.sql
+var FS embed.FS
+
+// Latest is the version of the newest migration, the schema must be at
+// this version for the queries of the build to work
+func Latest() (int64, error) {
+	names, err := fs.Glob(FS, "*.sql")
+	if err != nil {
+		return 0, err
+	}
+
+	var latest int64
+	for _, name := range names {
+		version, err := goose.NumericComponent(name)
+		if err != nil {
+			return 0, fmt.Errorf("migration %s: %w", name, err)
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+// NewProvider runs the embedded migrations on db. Every run holds a
+// Postgres advisory lock, so instances migrating on start at the same time
+// apply each migration once.
+func NewProvider(db *sql.DB) (*goose.Provider, error) {
+	locker, err := lock.NewPostgresSessionLocker()
+	if err != nil {
+		return nil, fmt.Errorf("create migrations lock: %w", err)
+	}
+
+	return goose.NewProvider(goose.DialectPostgres, db, FS, goose.WithSessionLocker(locker))
+}
+
+// Up applies the pending migrations
+func Up(ctx context.Context, db *sql.DB) error {
+	provider, err := NewProvider(db)
+	if err != nil {
+		return err
+	}
+
+	results, err := provider.Up(ctx)
+	if err != nil {
+		return fmt.Errorf("apply migrations: %w", err)
+	}
+	for _, result := range results {
+		slog.InfoContext(ctx, "migration applied", "migration", result.Source.Path, "duration", result.Duration)
+	}
+	return nil
+}
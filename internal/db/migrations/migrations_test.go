@@ -0,0 +1,22 @@
+package migrations
+
+import (
+	is_ "github.com/matryer/is"
+	"io/fs"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLatest(t *testing.T) {
+	is := is_.New(t)
+
+	names, err := fs.Glob(FS, "*.sql")
+	is.NoErr(err)
+	is.True(len(names) > 0) // migrations embedded
+
+	// the names start with their version, the last one is the newest
+	latest, err := Latest()
+	is.NoErr(err)
+	is.True(strings.HasPrefix(names[len(names)-1], strconv.FormatInt(latest, 10)+"_"))
+}
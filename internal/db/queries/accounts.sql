@@ -2,29 +2,64 @@
 select *
   from accounts
  where uuid = $1
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
  limit 1;
 
+-- name: GetAccountForUpdate :one
+select *
+  from accounts
+ where uuid = $1
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
+ limit 1
+   for update;
+
 -- name: UpdateAccount :one
    update accounts
       set name     = coalesce($2, name),
           type     = coalesce($3, type),
           metadata = coalesce($4, metadata)
     where uuid = $1
+      and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
 returning *;
 
 
 -- name: CreateAccount :one
      with ledger as (select id
                        from ledgers
-                      where uuid = sqlc.arg(ledger_uuid)::text)
+                      where uuid = sqlc.arg(ledger_uuid)::text
+                        and tenant_id = current_tenant_id())
    insert
      into accounts (name, type, metadata, ledger_id)
    values ($1, $2, $3, (select id from ledger))
 returning *;
 
 -- name: ListAccounts :many
-  with ledger as (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text)
+  with ledger as (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id())
 select uuid, name, type, metadata
   from accounts
  where ledger_id = (select id from ledger)
-   and metadata @> sqlc.arg(metadata)::jsonb;
\ No newline at end of file
+   and metadata @> sqlc.arg(metadata)::jsonb;
+
+-- name: ListAccountsByLedger :many
+  with ledger as (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id())
+select *
+  from accounts
+ where ledger_id = (select id from ledger)
+ order by id;
+
+-- name: RestoreAccount :one
+     with ledger as (select id
+                       from ledgers
+                      where uuid = sqlc.arg(ledger_uuid)::text
+                        and tenant_id = current_tenant_id())
+   insert
+     into accounts (uuid, created_at, updated_at, name, type, metadata, ledger_id)
+   values ($1, $2, $3, $4, $5, $6, (select id from ledger))
+returning *;
+
+-- name: GetAccountLedgerUuid :one
+select l.uuid as ledger_uuid
+  from accounts a
+       join ledgers l on l.id = a.ledger_id
+ where a.uuid = sqlc.arg(uuid)::text
+   and l.tenant_id = current_tenant_id();
@@ -0,0 +1,46 @@
+-- name: CreateApiKey :one
+  with tenant as (select id from tenants where uuid = sqlc.arg(tenant_uuid)::text),
+       usr as (select id
+                 from users
+                where uuid = sqlc.narg(user_uuid)::text
+                  and tenant_id = (select id from tenant))
+insert into api_keys (name, prefix, key_hash, scopes, tenant_id, user_id)
+values (sqlc.arg(name)::text,
+        sqlc.arg(prefix)::text,
+        sqlc.arg(key_hash)::text,
+        sqlc.arg(scopes)::text[],
+        (select id from tenant),
+        (select id from usr))
+returning *;
+
+-- name: GetApiKeyByPrefix :one
+select k.uuid,
+       k.name,
+       k.key_hash,
+       k.scopes,
+       k.tenant_id,
+       u.uuid as user_uuid
+  from api_keys k
+       left join users u on u.id = k.user_id
+ where k.prefix = sqlc.arg(prefix)::text
+   and k.revoked_at is null;
+
+-- name: ListApiKeys :many
+select k.uuid,
+       k.created_at,
+       k.name,
+       k.prefix,
+       k.scopes,
+       k.revoked_at,
+       t.uuid as tenant_uuid,
+       u.uuid as user_uuid
+  from api_keys k
+       join tenants t on t.id = k.tenant_id
+       left join users u on u.id = k.user_id
+ order by k.id;
+
+-- name: RevokeApiKey :execrows
+update api_keys
+   set revoked_at = current_timestamp
+ where (uuid = sqlc.arg(key)::text or prefix = sqlc.arg(key)::text)
+   and revoked_at is null;
@@ -0,0 +1,95 @@
+-- name: CreateAuditEvent :one
+insert into audit_events (actor,
+                          action,
+                          entity_type,
+                          entity_uuid,
+                          before,
+                          after,
+                          request_id,
+                          tenant_id)
+values (sqlc.arg(actor)::text,
+        sqlc.arg(action)::text,
+        sqlc.arg(entity_type)::text,
+        sqlc.arg(entity_uuid)::text,
+        sqlc.narg(before)::jsonb,
+        sqlc.narg(after)::jsonb,
+        sqlc.narg(request_id)::text,
+        current_tenant_id())
+returning *;
+
+-- name: ListAuditEvents :many
+select *
+  from audit_events
+ where tenant_id = current_tenant_id()
+   and (sqlc.narg(entity_type)::text is null or entity_type = sqlc.narg(entity_type)::text)
+   and (sqlc.narg(entity_uuid)::text is null or entity_uuid = sqlc.narg(entity_uuid)::text)
+   -- the events of a ledger and of everything in it
+   and (sqlc.narg(ledger_uuid)::text is null
+       or (entity_type = 'ledger' and entity_uuid = sqlc.narg(ledger_uuid)::text)
+       or after ->> 'ledger_uuid' = sqlc.narg(ledger_uuid)::text
+       or before ->> 'ledger_uuid' = sqlc.narg(ledger_uuid)::text)
+ order by id desc
+ limit sqlc.arg('limit') offset sqlc.arg('offset');
+
+-- name: GetLedgerSnapshot :one
+select jsonb_build_object(
+               'uuid', uuid,
+               'name', name,
+               'description', description,
+               'metadata', metadata,
+               'created_at', created_at,
+               'updated_at', updated_at
+       )::jsonb as snapshot
+  from ledgers
+ where uuid = sqlc.arg(uuid)::text
+   and tenant_id = current_tenant_id();
+
+-- name: GetAccountSnapshot :one
+select jsonb_build_object(
+               'uuid', a.uuid,
+               'name', a.name,
+               'type', a.type,
+               'metadata', a.metadata,
+               'ledger_uuid', l.uuid,
+               'created_at', a.created_at,
+               'updated_at', a.updated_at
+       )::jsonb as snapshot
+  from accounts a
+       join ledgers l on l.id = a.ledger_id
+ where a.uuid = sqlc.arg(uuid)::text
+   and l.tenant_id = current_tenant_id();
+
+-- name: GetTransactionSnapshot :one
+select jsonb_build_object(
+               'uuid', t.uuid,
+               'amount', t.amount,
+               'date', t.date,
+               'description', t.description,
+               'metadata', t.metadata,
+               'credit_account_uuid', credit.uuid,
+               'debit_account_uuid', debit.uuid,
+               'ledger_uuid', l.uuid,
+               'created_at', t.created_at,
+               'updated_at', t.updated_at
+       )::jsonb as snapshot
+  from transactions t
+       join accounts credit on credit.id = t.credit_account_id
+       join accounts debit on debit.id = t.debit_account_id
+       join ledgers l on l.id = t.ledger_id
+ where t.uuid = sqlc.arg(uuid)::text
+   and l.tenant_id = current_tenant_id();
+
+-- name: GetLedgerGrantSnapshot :one
+select jsonb_build_object(
+               'uuid', g.uuid,
+               'role', g.role,
+               'user_uuid', u.uuid,
+               'ledger_uuid', l.uuid,
+               'created_at', g.created_at,
+               'updated_at', g.updated_at
+       )::jsonb as snapshot
+  from ledger_grants g
+       join ledgers l on l.id = g.ledger_id
+       join users u on u.id = g.user_id
+ where g.uuid = sqlc.arg(uuid)::text
+   and l.tenant_id = current_tenant_id();
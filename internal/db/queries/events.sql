@@ -0,0 +1,47 @@
+-- name: ListLedgerEvents :many
+select id, uuid, created_at, event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id, xact_id
+  from outbox_events
+ where ledger_uuid = sqlc.arg(ledger_uuid)::text
+   and tenant_id = current_tenant_id()
+   and (sqlc.arg(include_pending)::boolean or xact_id < outbox_events_watermark())
+   and (xact_id, id) > (coalesce((select a.xact_id
+                                    from outbox_events a
+                                   where a.id = sqlc.arg(after_id)::bigint
+                                     and a.tenant_id = current_tenant_id()), 0),
+                        sqlc.arg(after_id)::bigint)
+ order by xact_id, id
+ limit sqlc.arg('limit');
+
+-- name: GetLatestLedgerEventID :one
+select coalesce((select id
+                   from outbox_events
+                  where ledger_uuid = sqlc.arg(ledger_uuid)::text
+                    and tenant_id = current_tenant_id()
+                    and xact_id < outbox_events_watermark()
+                  order by xact_id desc, id desc
+                  limit 1), 0)::bigint as id;
+
+-- name: HasPendingLedgerEvents :one
+select exists (select 1
+                 from outbox_events
+                where ledger_uuid = sqlc.arg(ledger_uuid)::text
+                  and tenant_id = current_tenant_id()
+                  and xact_id >= outbox_events_watermark()
+                  and (xact_id, id) > (coalesce((select a.xact_id
+                                                   from outbox_events a
+                                                  where a.id = sqlc.arg(after_id)::bigint
+                                                    and a.tenant_id = current_tenant_id()), 0),
+                                       sqlc.arg(after_id)::bigint));
+
+-- name: ListAccountBalancesByUuids :many
+select a.uuid,
+       a.name,
+       a.type,
+       coalesce(sum(t.amount) filter (where t.debit_account_id = a.id), 0)::bigint  as debit,
+       coalesce(sum(t.amount) filter (where t.credit_account_id = a.id), 0)::bigint as credit
+  from accounts a
+       left join transactions t on t.debit_account_id = a.id or t.credit_account_id = a.id
+ where a.uuid = any (sqlc.arg(uuids)::text[])
+   and a.ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
+ group by a.id
+ order by a.name;
@@ -0,0 +1,48 @@
+-- name: GetLedgerRole :one
+select g.role
+  from ledger_grants g
+       join ledgers l on l.id = g.ledger_id
+       join users u on u.id = g.user_id
+ where l.uuid = sqlc.arg(ledger_uuid)::text
+   and l.tenant_id = current_tenant_id()
+   and u.uuid = sqlc.arg(user_uuid)::text;
+
+-- name: ListLedgerGrants :many
+  with ledger as (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id())
+select u.uuid as user_uuid,
+       u.email,
+       g.role,
+       g.created_at,
+       g.updated_at
+  from ledger_grants g
+       join users u on u.id = g.user_id
+ where g.ledger_id = (select id from ledger)
+ order by g.id;
+
+-- name: PutLedgerGrant :one
+     with ledger as (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id()),
+          usr as (select id from users where uuid = sqlc.arg(user_uuid)::text and tenant_id = current_tenant_id())
+   insert
+     into ledger_grants (role, ledger_id, user_id)
+   values (sqlc.arg(role)::ledger_role, (select id from ledger), (select id from usr))
+       on conflict (ledger_id, user_id) do update set role = excluded.role
+returning *;
+
+-- name: DeleteLedgerGrant :execrows
+delete
+  from ledger_grants
+ where ledger_id = (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id())
+   and user_id = (select id from users where uuid = sqlc.arg(user_uuid)::text and tenant_id = current_tenant_id());
+
+-- name: GetLedgerGrantForUpdate :one
+select *
+  from ledger_grants
+ where ledger_id = (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id())
+   and user_id = (select id from users where uuid = sqlc.arg(user_uuid)::text and tenant_id = current_tenant_id())
+   for update;
+
+-- name: CountLedgerOwners :one
+select count(*)
+  from ledger_grants
+ where ledger_id = (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id())
+   and role = 'owner';
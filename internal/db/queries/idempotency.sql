@@ -0,0 +1,35 @@
+-- name: ClaimIdempotencyKey :one
+insert into idempotency_keys (key, principal, request_hash, tenant_id)
+values (sqlc.arg(key)::text, sqlc.arg(principal)::text, sqlc.arg(request_hash)::text, current_tenant_id())
+    -- no row is returned while the key is taken, expired keys are claimed again
+    on conflict (tenant_id, principal, key) do update
+    set created_at    = current_timestamp,
+        request_hash  = excluded.request_hash,
+        status_code   = null,
+        response_body = null
+  where idempotency_keys.created_at < sqlc.arg(expired_before)::timestamptz
+returning id;
+
+-- name: GetIdempotencyKey :one
+select *
+  from idempotency_keys
+ where key = sqlc.arg(key)::text
+   and principal = sqlc.arg(principal)::text
+   and tenant_id = current_tenant_id();
+
+-- name: CompleteIdempotencyKey :exec
+update idempotency_keys
+   set status_code   = sqlc.arg(status_code)::int,
+       response_body = sqlc.arg(response_body)::bytea
+ where key = sqlc.arg(key)::text
+   and principal = sqlc.arg(principal)::text
+   and tenant_id = current_tenant_id();
+
+-- name: ReleaseIdempotencyKey :exec
+delete
+  from idempotency_keys
+ where key = sqlc.arg(key)::text
+   and principal = sqlc.arg(principal)::text
+   -- the request failed, it can be retried with the same key
+   and status_code is null
+   and tenant_id = current_tenant_id();
@@ -2,11 +2,20 @@
 select *
   from ledgers
  where uuid = $1
+   and tenant_id = current_tenant_id()
  limit 1;
 
+-- name: GetLedgerForUpdate :one
+select *
+  from ledgers
+ where uuid = $1
+   and tenant_id = current_tenant_id()
+ limit 1
+   for update;
+
 -- name: CreateLedger :one
-   insert into ledgers (name, description, metadata)
-   values ($1, $2, $3)
+   insert into ledgers (name, description, metadata, tenant_id)
+   values ($1, $2, $3, current_tenant_id())
 returning *;
 
 
@@ -16,11 +25,29 @@ returning *;
           description = coalesce($3, description),
           metadata    = coalesce($4, metadata)
     where uuid = $1
+      and tenant_id = current_tenant_id()
 returning *;
 
 -- name: ListLedgers :many
 select uuid, name, description, metadata
   from ledgers
- where metadata @> $1::jsonb;
+ where metadata @> $1::jsonb
+   and tenant_id = current_tenant_id()
+   -- users only see the ledgers they were granted
+   and (sqlc.narg(user_uuid)::text is null or id in (select g.ledger_id
+                                                       from ledger_grants g
+                                                            join users u on u.id = g.user_id
+                                                      where u.uuid = sqlc.narg(user_uuid)::text));
 
 
+
+-- name: RestoreLedger :one
+   insert into ledgers (uuid, created_at, updated_at, name, description, metadata, tenant_id)
+   values ($1, $2, $3, $4, $5, $6, current_tenant_id())
+returning *;
+
+-- name: DeleteLedger :exec
+delete
+  from ledgers
+ where uuid = sqlc.arg(uuid)::text
+   and tenant_id = current_tenant_id();
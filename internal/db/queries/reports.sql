@@ -0,0 +1,49 @@
+-- name: GetAccountBalances :many
+  with ledger as (select id from ledgers where uuid = sqlc.arg(ledger_uuid)::text and tenant_id = current_tenant_id())
+select a.uuid,
+       a.name,
+       a.type,
+       coalesce(sum(t.amount) filter (where t.debit_account_id = a.id), 0)::bigint  as debit,
+       coalesce(sum(t.amount) filter (where t.credit_account_id = a.id), 0)::bigint as credit
+  from accounts a
+       left join transactions t
+              on (t.debit_account_id = a.id or t.credit_account_id = a.id)
+             and (sqlc.narg(from_date)::date is null or t.date >= sqlc.narg(from_date)::date)
+             and (sqlc.narg(to_date)::date is null or t.date <= sqlc.narg(to_date)::date)
+ where a.ledger_id = (select id from ledger)
+ group by a.id
+ order by a.name;
+
+-- name: ListAccountEntries :many
+  with account as (select id
+                     from accounts
+                    where uuid = sqlc.arg(account_uuid)::text
+                      and ledger_id in (select id from ledgers where tenant_id = current_tenant_id()))
+select t.uuid,
+       t.date,
+       t.description,
+       (case when t.debit_account_id = (select id from account) then t.amount else 0 end)::bigint  as debit,
+       (case when t.credit_account_id = (select id from account) then t.amount else 0 end)::bigint as credit,
+       counterpart.uuid                                                                         as counterpart_uuid,
+       counterpart.name                                                                         as counterpart_name
+  from transactions t
+       join accounts counterpart
+            on counterpart.id = case
+                                    when t.debit_account_id = (select id from account) then t.credit_account_id
+                                    else t.debit_account_id
+                                end
+ where (t.debit_account_id = (select id from account) or t.credit_account_id = (select id from account))
+   and (sqlc.narg(from_date)::date is null or t.date >= sqlc.narg(from_date)::date)
+   and (sqlc.narg(to_date)::date is null or t.date <= sqlc.narg(to_date)::date)
+ order by t.date, t.id;
+
+-- name: GetAccountOpeningBalance :one
+  with account as (select id
+                     from accounts
+                    where uuid = sqlc.arg(account_uuid)::text
+                      and ledger_id in (select id from ledgers where tenant_id = current_tenant_id()))
+select (coalesce(sum(amount) filter (where debit_account_id = (select id from account)), 0) -
+        coalesce(sum(amount) filter (where credit_account_id = (select id from account)), 0))::bigint as balance
+  from transactions
+ where (debit_account_id = (select id from account) or credit_account_id = (select id from account))
+   and date < sqlc.arg(before_date)::date;
@@ -0,0 +1,14 @@
+-- name: CreateTenant :one
+insert into tenants (name)
+values (sqlc.arg(name)::text)
+returning *;
+
+-- name: GetTenant :one
+select *
+  from tenants
+ where uuid = sqlc.arg(uuid)::text;
+
+-- name: ListTenants :many
+select *
+  from tenants
+ order by id;
@@ -2,34 +2,63 @@
 select *
   from transactions
  where uuid = sqlc.arg(uuid)::text
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id())
  limit 1;
 
 -- name: UpdateTransaction :one
-     with credit_account as (select id from accounts where accounts.uuid = sqlc.narg('credit_account_uuid')::text),
-          debit_account as (select id from accounts where accounts.uuid = sqlc.narg('debit_account_uuid')::text),
-          ledger as (select id from ledgers where ledgers.uuid = sqlc.narg('ledger_uuid')::text)
+     with txn as (select id, ledger_id
+                    from transactions
+                   where transactions.uuid = sqlc.arg('uuid')
+                     and transactions.ledger_id in (select id from ledgers where tenant_id = current_tenant_id())),
+          ledger as (select id
+                       from ledgers
+                      where ledgers.uuid = sqlc.narg('ledger_uuid')::text
+                        and ledgers.tenant_id = current_tenant_id()),
+          -- accounts must belong to the ledger the transaction ends up in
+          credit_account as (select id
+                               from accounts
+                              where accounts.uuid = sqlc.narg('credit_account_uuid')::text
+                                and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn))),
+          debit_account as (select id
+                              from accounts
+                             where accounts.uuid = sqlc.narg('debit_account_uuid')::text
+                               and accounts.ledger_id = coalesce((select id from ledger), (select ledger_id from txn)))
    update transactions
       set amount            = coalesce(sqlc.narg('amount')::bigint, amount),
           date              = coalesce(sqlc.narg('date'), date),
           description       = coalesce(sqlc.narg('description'), description),
           metadata          = coalesce(sqlc.narg('metadata'), metadata),
-          credit_account_id = coalesce((select id from credit_account), credit_account_id),
-          debit_account_id  = coalesce((select id from debit_account), debit_account_id),
-          ledger_id         = coalesce((select id from ledger), ledger_id)
-    where transactions.uuid = sqlc.arg('uuid')
+          -- an unknown uuid sets null and fails, instead of being ignored
+          credit_account_id = case
+                                  when sqlc.narg('credit_account_uuid')::text is null then credit_account_id
+                                  else (select id from credit_account)
+                              end,
+          debit_account_id  = case
+                                  when sqlc.narg('debit_account_uuid')::text is null then debit_account_id
+                                  else (select id from debit_account)
+                              end,
+          ledger_id         = case
+                                  when sqlc.narg('ledger_uuid')::text is null then ledger_id
+                                  else (select id from ledger)
+                              end
+    where transactions.id = (select id from txn)
 returning *;
 
 
 -- name: CreateTransaction :one
-     WITH credit_account AS (SELECT id
+     WITH ledger_id AS (SELECT id
+                          FROM ledgers
+                         WHERE ledgers.uuid = sqlc.arg(ledger_uuid)::text
+                           AND ledgers.tenant_id = current_tenant_id()),
+          -- accounts of other ledgers are never linked
+          credit_account AS (SELECT id
                                FROM accounts
-                              WHERE accounts.uuid = sqlc.arg(credit_account_uuid)::text),
+                              WHERE accounts.uuid = sqlc.arg(credit_account_uuid)::text
+                                AND accounts.ledger_id = (SELECT id FROM ledger_id)),
           debit_account AS (SELECT id
                               FROM accounts
-                             WHERE accounts.uuid = sqlc.arg(debit_account_uuid)::text),
-          ledger_id AS (SELECT id
-                          FROM ledgers
-                         WHERE ledgers.uuid = sqlc.arg(ledger_uuid)::text)
+                             WHERE accounts.uuid = sqlc.arg(debit_account_uuid)::text
+                               AND accounts.ledger_id = (SELECT id FROM ledger_id))
    INSERT
      INTO transactions (amount,
                         date,
@@ -48,7 +77,10 @@ returning *;
 RETURNING *;
 
 -- name: ListTransactions :many
-  with ledger as (select ledgers.id from ledgers where ledgers.uuid = sqlc.arg(ledger_uuid)::text)
+  with ledger as (select ledgers.id
+                    from ledgers
+                   where ledgers.uuid = sqlc.arg(ledger_uuid)::text
+                     and ledgers.tenant_id = current_tenant_id())
 select uuid, amount, date, description, metadata
   from transactions
  where ledger_id = (select id from ledger)
@@ -60,12 +92,88 @@ select uuid, amount, date, description, metadata
 -- name: DeleteTransaction :exec
 delete
   from transactions
- where uuid = sqlc.arg(uuid)::text;
+ where uuid = sqlc.arg(uuid)::text
+   and ledger_id in (select id from ledgers where tenant_id = current_tenant_id());
 
 
 -- name: GetTransactionsCount :one
-  with ledger as (select ledgers.id from ledgers where ledgers.uuid = sqlc.arg(ledger_uuid)::text)
+  with ledger as (select ledgers.id
+                    from ledgers
+                   where ledgers.uuid = sqlc.arg(ledger_uuid)::text
+                     and ledgers.tenant_id = current_tenant_id())
 select count(*)
   from transactions
  where ledger_id = (select id from ledger)
-   and metadata @> sqlc.arg(metadata)::jsonb;
\ No newline at end of file
+   and metadata @> sqlc.arg(metadata)::jsonb;
+
+-- name: ListTransactionsByLedger :many
+  with ledger as (select ledgers.id
+                    from ledgers
+                   where ledgers.uuid = sqlc.arg(ledger_uuid)::text
+                     and ledgers.tenant_id = current_tenant_id())
+select t.uuid,
+       t.created_at,
+       t.updated_at,
+       t.amount,
+       t.date,
+       t.description,
+       t.metadata,
+       credit.uuid as credit_account_uuid,
+       debit.uuid  as debit_account_uuid
+  from transactions t
+       join accounts credit on credit.id = t.credit_account_id
+       join accounts debit on debit.id = t.debit_account_id
+ where t.ledger_id = (select id from ledger)
+ order by t.id;
+
+
+-- name: RestoreTransaction :one
+     with ledger as (select id
+                       from ledgers
+                      where ledgers.uuid = sqlc.arg(ledger_uuid)::text
+                        and ledgers.tenant_id = current_tenant_id()),
+          credit_account as (select id
+                               from accounts
+                              where accounts.uuid = sqlc.arg(credit_account_uuid)::text
+                                and accounts.ledger_id = (select id from ledger)),
+          debit_account as (select id
+                              from accounts
+                             where accounts.uuid = sqlc.arg(debit_account_uuid)::text
+                               and accounts.ledger_id = (select id from ledger))
+   insert
+     into transactions (uuid,
+                        created_at,
+                        updated_at,
+                        amount,
+                        date,
+                        description,
+                        metadata,
+                        credit_account_id,
+                        debit_account_id,
+                        ledger_id)
+   values (sqlc.arg(uuid)::text,
+           sqlc.arg(created_at)::timestamptz,
+           sqlc.arg(updated_at)::timestamptz,
+           sqlc.arg(amount)::bigint,
+           sqlc.narg(date)::date,
+           sqlc.narg(description)::text,
+           sqlc.arg(metadata)::jsonb,
+           (select id from credit_account),
+           (select id from debit_account),
+           (select id from ledger))
+returning *;
+
+-- name: GetTransactionLedgerUuid :one
+select l.uuid as ledger_uuid
+  from transactions t
+       join ledgers l on l.id = t.ledger_id
+ where t.uuid = sqlc.arg(uuid)::text
+   and l.tenant_id = current_tenant_id();
+
+-- name: LockTransactionLedgerUuid :one
+select l.uuid as ledger_uuid
+  from transactions t
+       join ledgers l on l.id = t.ledger_id
+ where t.uuid = sqlc.arg(uuid)::text
+   and l.tenant_id = current_tenant_id()
+   for update of t;
@@ -0,0 +1,39 @@
+-- name: CreateUser :one
+  with tenant as (select id from tenants where uuid = sqlc.arg(tenant_uuid)::text)
+insert into users (email, name, issuer, subject, tenant_id)
+values (sqlc.arg(email)::text, sqlc.narg(name)::text, sqlc.narg(issuer)::text, sqlc.narg(subject)::text, (select id from tenant))
+returning *;
+
+-- name: GetUser :one
+select *
+  from users
+ where uuid = sqlc.arg(uuid)::text;
+
+-- name: ListUsers :many
+  with tenant as (select id from tenants where uuid = sqlc.arg(tenant_uuid)::text)
+select *
+  from users
+ where tenant_id = (select id from tenant)
+ order by id;
+
+-- name: GetUserByEmail :one
+select *
+  from users
+ where tenant_id = sqlc.arg(tenant_id)::bigint
+   and email = sqlc.arg(email)::text;
+
+-- name: GetUserBySubject :one
+select *
+  from users
+ where tenant_id = sqlc.arg(tenant_id)::bigint
+   and issuer = sqlc.arg(issuer)::text
+   and subject = sqlc.arg(subject)::text;
+
+-- name: BindUserSubject :one
+update users
+   set issuer  = sqlc.arg(issuer)::text,
+       subject = sqlc.arg(subject)::text
+ where tenant_id = sqlc.arg(tenant_id)::bigint
+   and email = sqlc.arg(email)::text
+   and subject is null
+returning *;
@@ -0,0 +1,112 @@
+-- name: CreateOutboxEvent :one
+  with event as (
+      insert into outbox_events (event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id)
+      values (sqlc.arg(event_type)::text,
+              sqlc.arg(entity_type)::text,
+              sqlc.arg(entity_uuid)::text,
+              sqlc.arg(payload)::jsonb,
+              sqlc.narg(ledger_uuid)::text,
+              current_tenant_id())
+      returning id, uuid, created_at, event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id, xact_id),
+       deliveries as (
+           insert into webhook_deliveries (webhook_id, event_id)
+           select w.id, event.id
+             from webhooks w,
+                  event
+            where w.tenant_id = event.tenant_id
+              and (cardinality(w.event_types) = 0
+               or event.event_type = any (w.event_types)))
+select id, uuid, created_at, event_type, entity_type, entity_uuid, payload, ledger_uuid, tenant_id, xact_id
+  from event;
+
+-- name: CreateWebhook :one
+insert into webhooks (url, secret, event_types, tenant_id)
+values (sqlc.arg(url)::text, sqlc.arg(secret)::text, sqlc.arg(event_types)::text[], current_tenant_id())
+returning *;
+
+-- name: ListWebhooks :many
+select *
+  from webhooks
+ where tenant_id = current_tenant_id()
+ order by id;
+
+-- name: DeleteWebhook :execrows
+delete
+  from webhooks
+ where uuid = sqlc.arg(uuid)::text
+   and tenant_id = current_tenant_id();
+
+-- name: ClaimWebhookDeliveries :many
+  with due as (select id
+                 from webhook_deliveries
+                where status in ('pending', 'failed')
+                  and next_attempt_at <= current_timestamp
+                order by next_attempt_at
+                limit sqlc.arg(batch_size) for update skip locked)
+update webhook_deliveries d
+   set next_attempt_at = sqlc.arg(lease_until)::timestamptz
+  from due,
+       webhooks w,
+       outbox_events e
+ where d.id = due.id
+   and w.id = d.webhook_id
+   and e.id = d.event_id
+returning d.id,
+          d.attempts,
+          w.url,
+          w.secret,
+          e.uuid       as event_uuid,
+          e.event_type,
+          e.created_at as event_created_at,
+          e.payload;
+
+-- name: MarkWebhookDeliveryDelivered :exec
+update webhook_deliveries
+   set status           = 'delivered',
+       attempts         = attempts + 1,
+       last_status_code = sqlc.arg(status_code)::integer,
+       last_error       = null,
+       delivered_at     = current_timestamp
+ where id = sqlc.arg(id)::bigint;
+
+-- name: MarkWebhookDeliveryFailed :exec
+update webhook_deliveries
+   set status           = sqlc.arg(status)::webhook_delivery_status,
+       attempts         = attempts + 1,
+       last_status_code = sqlc.narg(status_code)::integer,
+       last_error       = sqlc.arg(last_error)::text,
+       next_attempt_at  = sqlc.arg(next_attempt_at)::timestamptz
+ where id = sqlc.arg(id)::bigint;
+
+-- name: ReplayWebhookDelivery :one
+update webhook_deliveries
+   set status          = 'pending',
+       attempts        = 0,
+       next_attempt_at = current_timestamp,
+       last_error      = null
+ where uuid = sqlc.arg(uuid)::text
+   and webhook_id in (select id from webhooks where tenant_id = current_tenant_id())
+returning *;
+
+-- name: ListWebhookDeliveries :many
+  with webhook as (select id
+                     from webhooks
+                    where uuid = sqlc.arg(webhook_uuid)::text
+                      and tenant_id = current_tenant_id())
+select d.uuid,
+       d.created_at,
+       d.updated_at,
+       d.status,
+       d.attempts,
+       d.next_attempt_at,
+       d.last_status_code,
+       d.last_error,
+       d.delivered_at,
+       e.uuid       as event_uuid,
+       e.event_type
+  from webhook_deliveries d
+       join outbox_events e on e.id = d.event_id
+ where d.webhook_id = (select id from webhook)
+   and (sqlc.narg(status)::webhook_delivery_status is null or d.status = sqlc.narg(status)::webhook_delivery_status)
+ order by d.id desc
+ limit sqlc.arg('limit') offset sqlc.arg('offset');
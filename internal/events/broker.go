@@ -0,0 +1,110 @@
+// Package events fans out the Postgres notifications of ledger changes to
+// the event streams of this instance. Notifications only wake the streams
+// up, the events themselves are read from the outbox so every instance
+// sees the same events in the same order.
+package events
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/db"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Channel is the channel notified by the outbox_events trigger, the
+// payload is the uuid of the ledger
+const Channel = "ledger_events"
+
+type Broker struct {
+	client *db.Client
+
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+func NewBroker(client *db.Client) *Broker {
+	return &Broker{
+		client:      client,
+		subscribers: map[string]map[chan struct{}]struct{}{},
+	}
+}
+
+// Run listens for notifications until ctx is done, reconnecting with
+// backoff when the connection is lost
+func (b *Broker) Run(ctx context.Context) {
+	backoff := time.Second
+	for {
+		slog.Info("Listening for ledger events", "channel", Channel)
+
+		err := b.client.Listen(ctx, Channel, b.Publish)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("ledger events listener stopped", "error", err, "retry_in", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, 30*time.Second)
+
+		// events may have been missed while disconnected
+		b.publishAll()
+	}
+}
+
+// Subscribe returns a channel that receives a value when the ledger has
+// new events. Wake ups are coalesced, so subscribers must read every event
+// after the last one they've seen. cancel must be called once done.
+func (b *Broker) Subscribe(ledgerUUID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	b.mu.Lock()
+	if b.subscribers[ledgerUUID] == nil {
+		b.subscribers[ledgerUUID] = map[chan struct{}]struct{}{}
+	}
+	b.subscribers[ledgerUUID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[ledgerUUID], ch)
+		if len(b.subscribers[ledgerUUID]) == 0 {
+			delete(b.subscribers, ledgerUUID)
+		}
+	}
+
+	return ch, cancel
+}
+
+// Publish wakes up the subscribers of the ledger
+func (b *Broker) Publish(ledgerUUID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers[ledgerUUID] {
+		notify(ch)
+	}
+}
+
+func (b *Broker) publishAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, subscribers := range b.subscribers {
+		for ch := range subscribers {
+			notify(ch)
+		}
+	}
+}
+
+// notify doesn't block, a pending wake up already covers the new events
+func notify(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
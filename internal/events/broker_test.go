@@ -0,0 +1,28 @@
+package events
+
+import (
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestBroker(t *testing.T) {
+	is := is_.New(t)
+
+	b := NewBroker(nil)
+	ch, cancel := b.Subscribe("ledger1")
+	other, cancelOther := b.Subscribe("ledger2")
+	defer cancelOther()
+
+	// wake ups are coalesced
+	b.Publish("ledger1")
+	b.Publish("ledger1")
+	is.Equal(len(ch), 1)
+	is.Equal(len(other), 0)
+
+	<-ch
+	cancel()
+	b.Publish("ledger1")
+	is.Equal(len(ch), 0) // cancelled subscribers aren't notified
+	_, ok := b.subscribers["ledger1"]
+	is.True(!ok)
+}
@@ -0,0 +1,85 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"log/slog"
+)
+
+type accountService struct {
+	doubleedv1.UnimplementedAccountServiceServer
+	*Server
+}
+
+func (s *accountService) CreateAccount(ctx context.Context, req *doubleedv1.CreateAccountRequest) (*doubleedv1.Account, error) {
+	slog.DebugContext(ctx, "grpc.account.create.start")
+
+	a, err := s.ledger.CreateAccount(ctx, ledger.CreateAccountParams{
+		Name:       req.GetName(),
+		Type:       req.GetType(),
+		Metadata:   metadataMap(req.GetMetadata()),
+		LedgerUUID: req.GetLedgerUuid(),
+	})
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to create account", err)
+	}
+
+	res, err := accountMessage(a.Uuid, a.Name, a.Type, a.Metadata, req.GetLedgerUuid())
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to convert account", err)
+	}
+
+	slog.InfoContext(ctx, "account created", "uuid", a.Uuid, "name", a.Name)
+	slog.DebugContext(ctx, "grpc.account.create.complete", "account_uuid", a.Uuid)
+	return res, nil
+}
+
+func (s *accountService) ListAccounts(ctx context.Context, req *doubleedv1.ListAccountsRequest) (*doubleedv1.ListAccountsResponse, error) {
+	slog.DebugContext(ctx, "grpc.account.list.start", "ledger_uuid", req.GetLedgerUuid())
+
+	rows, err := s.ledger.ListAccounts(ctx, req.GetLedgerUuid(), metadataMap(req.GetMetadata()))
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to list accounts", err)
+	}
+
+	res := &doubleedv1.ListAccountsResponse{Accounts: make([]*doubleedv1.Account, 0, len(rows))}
+	for _, row := range rows {
+		a, err := accountMessage(row.Uuid, row.Name, row.Type, row.Metadata, req.GetLedgerUuid())
+		if err != nil {
+			return nil, ledgerError(ctx, "unable to convert account", err)
+		}
+		res.Accounts = append(res.Accounts, a)
+	}
+
+	slog.DebugContext(ctx, "grpc.account.list.complete", "count", len(res.Accounts))
+	return res, nil
+}
+
+func (s *accountService) UpdateAccount(ctx context.Context, req *doubleedv1.UpdateAccountRequest) (*doubleedv1.Account, error) {
+	slog.DebugContext(ctx, "grpc.account.update.start", "uuid", req.GetUuid())
+
+	a, err := s.ledger.UpdateAccount(ctx, req.GetUuid(), ledger.UpdateAccountParams{
+		Name:     req.GetName(),
+		Type:     req.GetType(),
+		Metadata: metadataMap(req.GetMetadata()),
+	})
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to update account", err)
+	}
+
+	// the row of the update has the id of the ledger, not its uuid
+	ledgerUUID, err := s.ledger.LedgerOfAccount(ctx, a.Uuid)
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to find the ledger of the account", err)
+	}
+
+	res, err := accountMessage(a.Uuid, a.Name, a.Type, a.Metadata, ledgerUUID)
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to convert account", err)
+	}
+
+	slog.InfoContext(ctx, "account updated", "uuid", a.Uuid)
+	slog.DebugContext(ctx, "grpc.account.update.complete", "account_uuid", a.Uuid)
+	return res, nil
+}
@@ -0,0 +1,109 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/db"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"strings"
+)
+
+// scopes is the scope each method requires, the same its HTTP route does.
+// Methods missing from it are rejected.
+var scopes = map[string]string{
+	doubleedv1.LedgerService_CreateLedger_FullMethodName: auth.ScopeLedgersWrite,
+	doubleedv1.LedgerService_ListLedgers_FullMethodName:  auth.ScopeLedgersRead,
+	doubleedv1.LedgerService_UpdateLedger_FullMethodName: auth.ScopeLedgersWrite,
+
+	doubleedv1.AccountService_CreateAccount_FullMethodName: auth.ScopeAccountsWrite,
+	doubleedv1.AccountService_ListAccounts_FullMethodName:  auth.ScopeAccountsRead,
+	doubleedv1.AccountService_UpdateAccount_FullMethodName: auth.ScopeAccountsWrite,
+
+	doubleedv1.TransactionService_PostTransaction_FullMethodName:   auth.ScopeTransactionsWrite,
+	doubleedv1.TransactionService_GetTransaction_FullMethodName:    auth.ScopeTransactionsRead,
+	doubleedv1.TransactionService_ListTransactions_FullMethodName:  auth.ScopeTransactionsRead,
+	doubleedv1.TransactionService_UpdateTransaction_FullMethodName: auth.ScopeTransactionsWrite,
+	doubleedv1.TransactionService_DeleteTransaction_FullMethodName: auth.ScopeTransactionsWrite,
+
+	doubleedv1.ReportService_GetTrialBalance_FullMethodName:     auth.ScopeReportsRead,
+	doubleedv1.ReportService_GetBalanceSheet_FullMethodName:     auth.ScopeReportsRead,
+	doubleedv1.ReportService_GetIncomeStatement_FullMethodName:  auth.ScopeReportsRead,
+	doubleedv1.ReportService_GetAccountStatement_FullMethodName: auth.ScopeReportsRead,
+}
+
+func (s *Server) unaryAuth(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamAuth(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate resolves the API key or the JWT of the `authorization`
+// metadata into the principal of the call, checks it was granted the scope
+// of the method and scopes the queries to its tenant. Unlike the HTTP API
+// every method requires credentials. Peers over the limit of failed
+// authentications of the HTTP API are rejected before their credentials
+// are checked.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	scope, ok := scopes[method]
+	if !ok {
+		return nil, status.Error(codes.Unimplemented, "unknown method")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		slog.InfoContext(ctx, "missing credentials", "method", method)
+		return nil, status.Error(codes.Unauthenticated, "missing credentials")
+	}
+
+	ip := clientIP(ctx)
+	if res := s.limiters.Peek(ratelimit.GroupAuth, ip); !res.Allowed {
+		slog.InfoContext(ctx, "failed authentication limit exceeded", "key", ip)
+		return nil, tooManyRequests(res)
+	}
+
+	token, ok := strings.CutPrefix(values[0], "Bearer ")
+	if !ok {
+		slog.InfoContext(ctx, "unsupported authorization scheme")
+		s.limiters.Allow(ratelimit.GroupAuth, ip)
+		return nil, status.Error(codes.Unauthenticated, "unsupported authorization scheme")
+	}
+	token = strings.TrimSpace(token)
+
+	principal, err := s.authenticator.Authenticate(ctx, token)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			slog.InfoContext(ctx, "unable to authenticate", "error", err)
+			s.limiters.Allow(ratelimit.GroupAuth, ip)
+			return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+		}
+
+		slog.ErrorContext(ctx, "unable to authenticate", "error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if !principal.HasScope(scope) {
+		slog.InfoContext(ctx, "principal lacks scope", "key_uuid", principal.KeyUUID, "user_uuid", principal.UserUUID, "scope", scope)
+		return nil, status.Error(codes.PermissionDenied, "forbidden")
+	}
+
+	ctx = auth.WithPrincipal(ctx, principal)
+	return db.WithTenant(ctx, principal.TenantID), nil
+}
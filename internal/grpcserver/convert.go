@@ -0,0 +1,157 @@
+package grpcserver
+
+import (
+	"encoding/json"
+	"fmt"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/j0lvera/go-double-e/internal/report"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"time"
+)
+
+// metadataMap is nil when the struct is, so optional filters and updates
+// stay unset
+func metadataMap(s *structpb.Struct) map[string]any {
+	if s == nil {
+		return nil
+	}
+	return s.AsMap()
+}
+
+// metadataStruct converts the JSON metadata of a row, an empty column is
+// an empty struct
+func metadataStruct(data []byte) (*structpb.Struct, error) {
+	m := map[string]any{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("unmarshal metadata: %w", err)
+		}
+	}
+	return structpb.NewStruct(m)
+}
+
+// parseDate expects YYYY-MM-DD, field names the request field in the error
+func parseDate(field, value string) (time.Time, error) {
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, status.Errorf(codes.InvalidArgument, "%s must be a YYYY-MM-DD date", field)
+	}
+	return t, nil
+}
+
+func textValue(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+func dateValue(d pgtype.Date) string {
+	if !d.Valid {
+		return ""
+	}
+	return d.Time.Format(time.DateOnly)
+}
+
+func ledgerMessage(uuid, name string, description pgtype.Text, metadata []byte) (*doubleedv1.Ledger, error) {
+	md, err := metadataStruct(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &doubleedv1.Ledger{
+		Uuid:        uuid,
+		Name:        name,
+		Description: textValue(description),
+		Metadata:    md,
+	}, nil
+}
+
+func accountMessage(uuid, name string, accountType dbGen.AccountType, metadata []byte, ledgerUUID string) (*doubleedv1.Account, error) {
+	md, err := metadataStruct(metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &doubleedv1.Account{
+		Uuid:       uuid,
+		Name:       name,
+		Type:       string(accountType),
+		Metadata:   md,
+		LedgerUuid: ledgerUUID,
+	}, nil
+}
+
+func transactionMessage(t *ledger.Transaction) (*doubleedv1.Transaction, error) {
+	md, err := structpb.NewStruct(t.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("convert metadata: %w", err)
+	}
+	return &doubleedv1.Transaction{
+		Uuid:              t.UUID,
+		Amount:            t.Amount,
+		Date:              t.Date,
+		Description:       t.Description,
+		Metadata:          md,
+		CreditAccountUuid: t.CreditAccountUUID,
+		DebitAccountUuid:  t.DebitAccountUUID,
+		LedgerUuid:        t.LedgerUUID,
+		CreatedAt:         timestamppb.New(t.CreatedAt),
+		UpdatedAt:         timestamppb.New(t.UpdatedAt),
+	}, nil
+}
+
+func streamedTransactionMessage(row *dbGen.ListTransactionsByLedgerRow, ledgerUUID string) (*doubleedv1.Transaction, error) {
+	md, err := metadataStruct(row.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	return &doubleedv1.Transaction{
+		Uuid:              row.Uuid,
+		Amount:            row.Amount,
+		Date:              dateValue(row.Date),
+		Description:       textValue(row.Description),
+		Metadata:          md,
+		CreditAccountUuid: row.CreditAccountUuid,
+		DebitAccountUuid:  row.DebitAccountUuid,
+		LedgerUuid:        ledgerUUID,
+		CreatedAt:         timestamppb.New(row.CreatedAt.Time),
+		UpdatedAt:         timestamppb.New(row.UpdatedAt.Time),
+	}, nil
+}
+
+func reportPeriod(p *doubleedv1.Period) report.Period {
+	return report.Period{From: p.GetFrom(), To: p.GetTo()}
+}
+
+func periodMessage(p report.Period) *doubleedv1.Period {
+	return &doubleedv1.Period{From: p.From, To: p.To}
+}
+
+func linesMessage(lines []report.Line) []*doubleedv1.ReportLine {
+	out := make([]*doubleedv1.ReportLine, 0, len(lines))
+	for _, l := range lines {
+		out = append(out, &doubleedv1.ReportLine{
+			AccountUuid: l.AccountUUID,
+			Name:        l.Name,
+			Type:        l.Type,
+			Depth:       int32(l.Depth),
+			Debit:       l.Debit,
+			Credit:      l.Credit,
+			Balance:     l.Balance,
+		})
+	}
+	return out
+}
+
+func sectionMessage(s report.Section) *doubleedv1.ReportSection {
+	return &doubleedv1.ReportSection{
+		Name:  s.Name,
+		Lines: linesMessage(s.Lines),
+		Total: s.Total,
+	}
+}
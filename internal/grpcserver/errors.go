@@ -0,0 +1,60 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"log/slog"
+)
+
+// ledgerError turns the errors of the ledger service into statuses, msg is
+// logged along with the error. Validation errors carry a BadRequest detail
+// with a violation per field.
+func ledgerError(ctx context.Context, msg string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		// e.g., a stream that failed to send
+		return err
+	}
+
+	var validationErrors ledger.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrors):
+		slog.InfoContext(ctx, msg, "error", err)
+
+		badRequest := &errdetails.BadRequest{}
+		for _, ve := range validationErrors {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       ve.Field,
+				Description: ve.Message,
+			})
+		}
+		st, detailErr := status.New(codes.InvalidArgument, "invalid request").WithDetails(badRequest)
+		if detailErr != nil {
+			return status.Error(codes.InvalidArgument, "invalid request")
+		}
+		return st.Err()
+	case errors.Is(err, ledger.ErrNotFound):
+		slog.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.NotFound, "not found")
+	case errors.Is(err, ledger.ErrForbidden):
+		slog.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.PermissionDenied, "forbidden")
+	case errors.Is(err, ledger.ErrConflict):
+		slog.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.AlreadyExists, "already exists")
+	case errors.Is(err, ledger.ErrInvalidReference),
+		errors.Is(err, ledger.ErrLedgerRequired),
+		errors.Is(err, ledger.ErrMetadataRequired),
+		errors.Is(err, ledger.ErrNoChanges):
+		slog.InfoContext(ctx, msg, "error", err)
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, "canceled")
+	default:
+		slog.ErrorContext(ctx, msg, "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
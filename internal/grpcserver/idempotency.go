@@ -0,0 +1,199 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"log/slog"
+	"time"
+)
+
+// Metadata of the idempotent calls, like the `Idempotency-Key` and
+// `Idempotent-Replayed` headers of the HTTP API
+const (
+	idempotencyKeyMetadata     = "idempotency-key"
+	idempotentReplayedMetadata = "idempotent-replayed"
+)
+
+// idempotencyTTL is how long a key is remembered, the same as the HTTP API
+const idempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength is the longest key the table accepts
+const maxIdempotencyKeyLength = 254
+
+// idempotentMethods are the methods that create something, the ones the
+// HTTP API serves with a POST
+var idempotentMethods = map[string]bool{
+	doubleedv1.LedgerService_CreateLedger_FullMethodName:         true,
+	doubleedv1.AccountService_CreateAccount_FullMethodName:       true,
+	doubleedv1.TransactionService_PostTransaction_FullMethodName: true,
+}
+
+// unaryIdempotent stores the responses of the calls with an
+// `idempotency-key`, a retry gets the stored response back. Keys are
+// scoped to the principal, so it must run after the authentication. A call
+// reusing the key of another one fails with FailedPrecondition and a retry
+// of a call still in progress with Aborted. Failed calls release the key.
+func (s *Server) unaryIdempotent(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := md.Get(idempotencyKeyMetadata)
+	if len(keys) == 0 || keys[0] == "" || !idempotentMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	key := keys[0]
+
+	slog.DebugContext(ctx, "grpc.idempotent.start", "key", key)
+
+	if len(key) > maxIdempotencyKeyLength {
+		return nil, status.Error(codes.InvalidArgument, "idempotency-key is too long")
+	}
+
+	// another principal of the tenant reusing the key runs its own call, a
+	// stored response is only sent back to its principal
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil {
+		return handler(ctx, req)
+	}
+	owner := principal.LimitKey()
+
+	hash, err := callHash(owner, info.FullMethod, req)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to hash call", "error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	_, err = s.client.Queries.ClaimIdempotencyKey(ctx, dbGen.ClaimIdempotencyKeyParams{
+		Key:           key,
+		Principal:     owner,
+		RequestHash:   hash,
+		ExpiredBefore: pgtype.Timestamptz{Time: time.Now().Add(-idempotencyTTL), Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return s.replay(ctx, key, owner, hash)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to claim idempotency key", "error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	res, err := handler(ctx, req)
+
+	// the key must not stay in progress when the client goes away
+	storeCtx := context.WithoutCancel(ctx)
+	if err != nil {
+		s.releaseKey(storeCtx, key, owner)
+		return nil, err
+	}
+
+	// a response that can't be stored can't be replayed, the retries run
+	// the call again
+	body, err := marshalResponse(res)
+	if err != nil {
+		slog.ErrorContext(storeCtx, "unable to marshal response", "error", err)
+		s.releaseKey(storeCtx, key, owner)
+		return res, nil
+	}
+	err = s.client.Queries.CompleteIdempotencyKey(storeCtx, dbGen.CompleteIdempotencyKeyParams{
+		StatusCode:   int32(codes.OK),
+		ResponseBody: body,
+		Key:          key,
+		Principal:    owner,
+	})
+	if err != nil {
+		slog.ErrorContext(storeCtx, "unable to complete idempotency key", "error", err)
+	}
+
+	slog.DebugContext(storeCtx, "grpc.idempotent.complete", "key", key)
+	return res, nil
+}
+
+// releaseKey lets the key be claimed again by a retry
+func (s *Server) releaseKey(ctx context.Context, key, owner string) {
+	err := s.client.Queries.ReleaseIdempotencyKey(ctx, dbGen.ReleaseIdempotencyKeyParams{Key: key, Principal: owner})
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to release idempotency key", "error", err)
+	}
+}
+
+// replay returns the stored response of the call with the key
+func (s *Server) replay(ctx context.Context, key, owner, hash string) (any, error) {
+	stored, err := s.client.Queries.GetIdempotencyKey(ctx, dbGen.GetIdempotencyKeyParams{Key: key, Principal: owner})
+	if err != nil {
+		// released in the meantime, the client can retry
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, status.Error(codes.Aborted, "a call with this idempotency-key failed, retry it")
+		}
+		slog.ErrorContext(ctx, "unable to get idempotency key", "error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	if stored.RequestHash != hash {
+		return nil, status.Error(codes.FailedPrecondition, "idempotency-key was used for another call")
+	}
+	if !stored.StatusCode.Valid {
+		return nil, status.Error(codes.Aborted, "a call with this idempotency-key is in progress")
+	}
+
+	res, err := unmarshalResponse(stored.ResponseBody)
+	if err != nil {
+		slog.ErrorContext(ctx, "unable to unmarshal stored response", "error", err)
+		return nil, status.Error(codes.Internal, "internal error")
+	}
+
+	slog.DebugContext(ctx, "grpc.idempotent.replay", "key", key)
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(idempotentReplayedMetadata, "true"))
+	return res, nil
+}
+
+// callHash identifies a call by its principal, method and request, the
+// HTTP requests never hash the same
+func callHash(owner, method string, req any) (string, error) {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return "", errors.New("request is not a protobuf message")
+	}
+	b, err := proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	h.Write([]byte(owner + "\ngrpc " + method + "\n"))
+	h.Write(b)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// marshalResponse keeps the type of the response along with it, so the
+// replay can unmarshal it without knowing the method
+func marshalResponse(res any) ([]byte, error) {
+	msg, ok := res.(proto.Message)
+	if !ok {
+		return nil, errors.New("response is not a protobuf message")
+	}
+	a, err := anypb.New(msg)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(a)
+}
+
+func unmarshalResponse(b []byte) (proto.Message, error) {
+	var a anypb.Any
+	if err := proto.Unmarshal(b, &a); err != nil {
+		return nil, err
+	}
+	return a.UnmarshalNew()
+}
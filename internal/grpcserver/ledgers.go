@@ -0,0 +1,78 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"log/slog"
+)
+
+type ledgerService struct {
+	doubleedv1.UnimplementedLedgerServiceServer
+	*Server
+}
+
+func (s *ledgerService) CreateLedger(ctx context.Context, req *doubleedv1.CreateLedgerRequest) (*doubleedv1.Ledger, error) {
+	slog.DebugContext(ctx, "grpc.ledger.create.start")
+
+	l, err := s.ledger.CreateLedger(ctx, ledger.CreateLedgerParams{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Metadata:    metadataMap(req.GetMetadata()),
+	})
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to create ledger", err)
+	}
+
+	res, err := ledgerMessage(l.Uuid, l.Name, l.Description, l.Metadata)
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to convert ledger", err)
+	}
+
+	slog.InfoContext(ctx, "ledger created", "uuid", l.Uuid, "name", l.Name)
+	slog.DebugContext(ctx, "grpc.ledger.create.complete", "ledger_uuid", l.Uuid)
+	return res, nil
+}
+
+func (s *ledgerService) ListLedgers(ctx context.Context, req *doubleedv1.ListLedgersRequest) (*doubleedv1.ListLedgersResponse, error) {
+	slog.DebugContext(ctx, "grpc.ledger.list.start")
+
+	rows, err := s.ledger.ListLedgers(ctx, metadataMap(req.GetMetadata()))
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to list ledgers", err)
+	}
+
+	res := &doubleedv1.ListLedgersResponse{Ledgers: make([]*doubleedv1.Ledger, 0, len(rows))}
+	for _, row := range rows {
+		l, err := ledgerMessage(row.Uuid, row.Name, row.Description, row.Metadata)
+		if err != nil {
+			return nil, ledgerError(ctx, "unable to convert ledger", err)
+		}
+		res.Ledgers = append(res.Ledgers, l)
+	}
+
+	slog.DebugContext(ctx, "grpc.ledger.list.complete", "count", len(res.Ledgers))
+	return res, nil
+}
+
+func (s *ledgerService) UpdateLedger(ctx context.Context, req *doubleedv1.UpdateLedgerRequest) (*doubleedv1.Ledger, error) {
+	slog.DebugContext(ctx, "grpc.ledger.update.start", "uuid", req.GetUuid())
+
+	l, err := s.ledger.UpdateLedger(ctx, req.GetUuid(), ledger.UpdateLedgerParams{
+		Name:        req.GetName(),
+		Description: req.GetDescription(),
+		Metadata:    metadataMap(req.GetMetadata()),
+	})
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to update ledger", err)
+	}
+
+	res, err := ledgerMessage(l.Uuid, l.Name, l.Description, l.Metadata)
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to convert ledger", err)
+	}
+
+	slog.InfoContext(ctx, "ledger updated", "uuid", l.Uuid)
+	slog.DebugContext(ctx, "grpc.ledger.update.complete", "ledger_uuid", l.Uuid)
+	return res, nil
+}
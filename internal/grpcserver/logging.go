@@ -0,0 +1,83 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"log/slog"
+	"time"
+)
+
+// requestIDMetadata carries the ID of a call, like the `X-Request-ID`
+// header of the HTTP API
+const requestIDMetadata = "x-request-id"
+
+func (s *Server) unaryLog(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx = withRequestID(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, logging.RequestIDFromContext(ctx)))
+
+	res, err := handler(ctx, req)
+
+	accessLog(ctx, info.FullMethod, err, start)
+	return res, err
+}
+
+func (s *Server) streamLog(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withRequestID(ss.Context())
+	_ = ss.SetHeader(metadata.Pairs(requestIDMetadata, logging.RequestIDFromContext(ctx)))
+
+	err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+
+	accessLog(ctx, info.FullMethod, err, start)
+	return err
+}
+
+// withRequestID assigns or propagates the ID of the call, it's logged with
+// every record of the call's context
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ids := md.Get(requestIDMetadata); len(ids) > 0 {
+		id = ids[0]
+	}
+	return logging.WithRequestID(ctx, logging.RequestID(id))
+}
+
+// accessLog logs a line per call once it's served
+func accessLog(ctx context.Context, method string, err error, start time.Time) {
+	code := status.Code(err)
+
+	var addr string
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+
+	level := slog.LevelInfo
+	if code == codes.Internal || code == codes.Unknown {
+		level = slog.LevelError
+	}
+
+	slog.Log(ctx, level, "call",
+		"method", method,
+		"code", code.String(),
+		"duration", time.Since(start),
+		"peer", addr,
+	)
+}
+
+// contextStream carries the context of the interceptors to the stream
+// handlers
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
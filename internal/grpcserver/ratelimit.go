@@ -0,0 +1,93 @@
+package grpcserver
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"log/slog"
+	"net"
+	"strings"
+)
+
+func (s *Server) unaryRateLimit(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	if err := s.rateLimit(ctx, info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (s *Server) streamRateLimit(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := s.rateLimit(ss.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
+
+// rateLimit rejects the calls of clients over the limit of the method's
+// group with ResourceExhausted, the status tells when to retry. The
+// buckets are the ones of the HTTP API.
+func (s *Server) rateLimit(ctx context.Context, method string) error {
+	group := methodGroup(method)
+	key := rateLimitKey(ctx)
+
+	res := s.limiters.Allow(group, key)
+	if res.Allowed {
+		return nil
+	}
+
+	slog.InfoContext(ctx, "rate limit exceeded", "key", key, "group", group, "method", method)
+	return tooManyRequests(res)
+}
+
+// tooManyRequests is the ResourceExhausted status of a rejected call, with
+// the delay before the client can retry
+func tooManyRequests(res ratelimit.Result) error {
+	st, err := status.New(codes.ResourceExhausted, "too many requests").WithDetails(&errdetails.RetryInfo{
+		RetryDelay: durationpb.New(res.RetryAfter),
+	})
+	if err != nil {
+		return status.Error(codes.ResourceExhausted, "too many requests")
+	}
+	return st.Err()
+}
+
+// methodGroup classifies the method by its cost like the HTTP routes are,
+// listing the transactions streams a whole ledger like an export does
+func methodGroup(method string) ratelimit.Group {
+	switch {
+	case method == doubleedv1.TransactionService_ListTransactions_FullMethodName:
+		return ratelimit.GroupExport
+	case strings.HasSuffix(scopes[method], ":read"):
+		return ratelimit.GroupRead
+	default:
+		return ratelimit.GroupWrite
+	}
+}
+
+// rateLimitKey identifies the client, by its credentials when it's
+// authenticated and by its IP otherwise
+func rateLimitKey(ctx context.Context) string {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		return principal.LimitKey()
+	}
+	return clientIP(ctx)
+}
+
+// clientIP is the rate limit key of the peer's IP
+func clientIP(ctx context.Context) string {
+	var host string
+	if p, ok := peer.FromContext(ctx); ok {
+		host = p.Addr.String()
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	return "ip:" + host
+}
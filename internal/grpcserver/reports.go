@@ -0,0 +1,102 @@
+package grpcserver
+
+import (
+	"context"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"log/slog"
+)
+
+type reportService struct {
+	doubleedv1.UnimplementedReportServiceServer
+	*Server
+}
+
+func (s *reportService) GetTrialBalance(ctx context.Context, req *doubleedv1.GetTrialBalanceRequest) (*doubleedv1.TrialBalance, error) {
+	slog.DebugContext(ctx, "grpc.report.trial_balance.start", "ledger_uuid", req.GetLedgerUuid())
+
+	tb, err := s.ledger.TrialBalance(ctx, req.GetLedgerUuid(), reportPeriod(req.GetPeriod()))
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to build trial balance", err)
+	}
+
+	slog.DebugContext(ctx, "grpc.report.trial_balance.complete", "lines", len(tb.Lines))
+	return &doubleedv1.TrialBalance{
+		Period:      periodMessage(tb.Period),
+		Lines:       linesMessage(tb.Lines),
+		TotalDebit:  tb.TotalDebit,
+		TotalCredit: tb.TotalCredit,
+	}, nil
+}
+
+func (s *reportService) GetBalanceSheet(ctx context.Context, req *doubleedv1.GetBalanceSheetRequest) (*doubleedv1.BalanceSheet, error) {
+	slog.DebugContext(ctx, "grpc.report.balance_sheet.start", "ledger_uuid", req.GetLedgerUuid())
+
+	bs, err := s.ledger.BalanceSheet(ctx, req.GetLedgerUuid(), reportPeriod(req.GetPeriod()))
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to build balance sheet", err)
+	}
+
+	slog.DebugContext(ctx, "grpc.report.balance_sheet.complete")
+	return &doubleedv1.BalanceSheet{
+		Period:                    periodMessage(bs.Period),
+		Assets:                    sectionMessage(bs.Assets),
+		Liabilities:               sectionMessage(bs.Liabilities),
+		Equity:                    sectionMessage(bs.Equity),
+		NetIncome:                 bs.NetIncome,
+		TotalLiabilitiesAndEquity: bs.TotalLiabilitiesAndEquity,
+	}, nil
+}
+
+func (s *reportService) GetIncomeStatement(ctx context.Context, req *doubleedv1.GetIncomeStatementRequest) (*doubleedv1.IncomeStatement, error) {
+	slog.DebugContext(ctx, "grpc.report.income_statement.start", "ledger_uuid", req.GetLedgerUuid())
+
+	is, err := s.ledger.IncomeStatement(ctx, req.GetLedgerUuid(), reportPeriod(req.GetPeriod()))
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to build income statement", err)
+	}
+
+	slog.DebugContext(ctx, "grpc.report.income_statement.complete")
+	return &doubleedv1.IncomeStatement{
+		Period:    periodMessage(is.Period),
+		Revenue:   sectionMessage(is.Revenue),
+		Expenses:  sectionMessage(is.Expenses),
+		NetIncome: is.NetIncome,
+	}, nil
+}
+
+func (s *reportService) GetAccountStatement(ctx context.Context, req *doubleedv1.GetAccountStatementRequest) (*doubleedv1.AccountStatement, error) {
+	slog.DebugContext(ctx, "grpc.report.account_statement.start", "account_uuid", req.GetAccountUuid())
+
+	st, err := s.ledger.AccountStatement(ctx, req.GetAccountUuid(), reportPeriod(req.GetPeriod()))
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to build account statement", err)
+	}
+
+	entries := make([]*doubleedv1.StatementEntry, 0, len(st.Entries))
+	for _, e := range st.Entries {
+		entries = append(entries, &doubleedv1.StatementEntry{
+			TransactionUuid: e.TransactionUUID,
+			Date:            e.Date,
+			Description:     e.Description,
+			CounterpartUuid: e.CounterpartUUID,
+			CounterpartName: e.CounterpartName,
+			Debit:           e.Debit,
+			Credit:          e.Credit,
+			Balance:         e.Balance,
+		})
+	}
+
+	slog.DebugContext(ctx, "grpc.report.account_statement.complete", "entries", len(entries))
+	return &doubleedv1.AccountStatement{
+		Account: &doubleedv1.Account{
+			Uuid:       st.Account.UUID,
+			Name:       st.Account.Name,
+			Type:       st.Account.Type,
+			LedgerUuid: st.Account.LedgerUUID,
+		},
+		Period:         periodMessage(st.Period),
+		OpeningBalance: st.OpeningBalance,
+		Entries:        entries,
+		ClosingBalance: st.ClosingBalance,
+	}, nil
+}
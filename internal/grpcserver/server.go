@@ -0,0 +1,78 @@
+// Package grpcserver serves the ledgers, accounts, transactions and
+// reports over gRPC. The handlers translate the protobuf messages to the
+// ledger service, the one the HTTP handlers use, so both APIs share the
+// same rules.
+package grpcserver
+
+import (
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/db"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/j0lvera/go-double-e/internal/metrics"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"net"
+)
+
+type Server struct {
+	client        *db.Client
+	ledger        *ledger.Service
+	authenticator *auth.Authenticator
+	// limiters are shared with the HTTP API, nil doesn't limit anything
+	limiters ratelimit.Limiters
+	// metrics counts the transactions, nil disables them
+	metrics *metrics.Metrics
+
+	grpc *grpc.Server
+}
+
+// Options of the server, the zero value accepts API keys only, without
+// rate limits nor metrics
+type Options struct {
+	Verifier auth.TokenVerifier
+	// RateLimiters are the ones of the HTTP server, so a client has a
+	// single budget across both APIs
+	RateLimiters ratelimit.Limiters
+	Metrics      *metrics.Metrics
+}
+
+func NewServer(client *db.Client, opts Options) *Server {
+	srv := &Server{
+		client:        client,
+		ledger:        ledger.NewService(client),
+		authenticator: auth.NewAuthenticator(client, opts.Verifier),
+		limiters:      opts.RateLimiters,
+		metrics:       opts.Metrics,
+	}
+
+	// the first interceptor runs first, the calls are traced by the stats
+	// handler around them
+	srv.grpc = grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(srv.unaryLog, srv.unaryAuth, srv.unaryRateLimit, srv.unaryIdempotent),
+		grpc.ChainStreamInterceptor(srv.streamLog, srv.streamAuth, srv.streamRateLimit),
+	)
+	doubleedv1.RegisterLedgerServiceServer(srv.grpc, &ledgerService{Server: srv})
+	doubleedv1.RegisterAccountServiceServer(srv.grpc, &accountService{Server: srv})
+	doubleedv1.RegisterTransactionServiceServer(srv.grpc, &transactionService{Server: srv})
+	doubleedv1.RegisterReportServiceServer(srv.grpc, &reportService{Server: srv})
+
+	return srv
+}
+
+// Serve accepts the connections of the listener until Stop or
+// GracefulStop is called
+func (s *Server) Serve(lis net.Listener) error {
+	return s.grpc.Serve(lis)
+}
+
+// GracefulStop waits for the calls in flight, Stop cancels them
+func (s *Server) GracefulStop() {
+	s.grpc.GracefulStop()
+}
+
+func (s *Server) Stop() {
+	s.grpc.Stop()
+}
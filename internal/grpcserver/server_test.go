@@ -0,0 +1,234 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	is_ "github.com/matryer/is"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// every method must require a scope, or the interceptors reject it
+func TestScopes(t *testing.T) {
+	is := is_.New(t)
+
+	for _, desc := range []grpc.ServiceDesc{
+		doubleedv1.LedgerService_ServiceDesc,
+		doubleedv1.AccountService_ServiceDesc,
+		doubleedv1.TransactionService_ServiceDesc,
+		doubleedv1.ReportService_ServiceDesc,
+	} {
+		for _, m := range desc.Methods {
+			_, ok := scopes["/"+desc.ServiceName+"/"+m.MethodName]
+			is.True(ok) // unary method without a scope
+		}
+		for _, st := range desc.Streams {
+			_, ok := scopes["/"+desc.ServiceName+"/"+st.StreamName]
+			is.True(ok) // stream without a scope
+		}
+	}
+}
+
+func TestLedgerError(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		err  error
+		code codes.Code
+	}{
+		{ledger.ErrNotFound, codes.NotFound},
+		{ledger.ErrForbidden, codes.PermissionDenied},
+		{fmt.Errorf("create: %w", ledger.ErrInvalidReference), codes.InvalidArgument},
+		{ledger.ErrMetadataRequired, codes.InvalidArgument},
+		{status.Error(codes.Unavailable, "gone"), codes.Unavailable},
+		{errors.New("boom"), codes.Internal},
+	}
+	for _, tt := range tests {
+		is.Equal(status.Code(ledgerError(ctx, "test", tt.err)), tt.code)
+	}
+
+	err := ledgerError(ctx, "test", ledger.ValidationErrors{{Field: "Name", Message: "This field is required"}})
+	st := status.Convert(err)
+	is.Equal(st.Code(), codes.InvalidArgument)
+	is.Equal(len(st.Details()), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	is.True(ok)
+	is.Equal(badRequest.FieldViolations[0].Field, "Name")
+}
+
+// calls are rejected before the database is used
+func TestAuthenticate(t *testing.T) {
+	is := is_.New(t)
+
+	srv := NewServer(&db.Client{}, Options{})
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	is.NoErr(err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	ctx := context.Background()
+	ledgers := doubleedv1.NewLedgerServiceClient(conn)
+
+	_, err = ledgers.ListLedgers(ctx, &doubleedv1.ListLedgersRequest{})
+	is.Equal(status.Code(err), codes.Unauthenticated) // missing credentials
+
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Basic dXNlcjpwYXNz")
+	_, err = ledgers.ListLedgers(ctx, &doubleedv1.ListLedgersRequest{})
+	is.Equal(status.Code(err), codes.Unauthenticated) // unsupported scheme
+
+	stream, err := doubleedv1.NewTransactionServiceClient(conn).ListTransactions(context.Background(), &doubleedv1.ListTransactionsRequest{})
+	is.NoErr(err)
+	_, err = stream.Recv()
+	is.Equal(status.Code(err), codes.Unauthenticated) // streams are authenticated too
+
+	var header metadata.MD
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-grpc-1")
+	_, err = ledgers.ListLedgers(ctx, &doubleedv1.ListLedgersRequest{}, grpc.Header(&header))
+	is.Equal(status.Code(err), codes.Unauthenticated)
+	is.Equal(header.Get("x-request-id"), []string{"req-grpc-1"}) // request id not propagated
+}
+
+func TestRateLimit(t *testing.T) {
+	is := is_.New(t)
+
+	limiters := ratelimit.Limiters{
+		ratelimit.GroupRead:  ratelimit.NewLimiter(ratelimit.Limit{Requests: 2, Per: time.Minute}),
+		ratelimit.GroupWrite: ratelimit.NewLimiter(ratelimit.Limit{Requests: 2, Per: time.Minute}),
+	}
+	srv := &Server{limiters: limiters}
+
+	principal := &auth.Principal{KeyUUID: "importer"}
+	ctx := auth.WithPrincipal(context.Background(), principal)
+	call := func(method string) error {
+		_, err := srv.unaryRateLimit(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(context.Context, any) (any, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	// the HTTP API takes a token of the same bucket
+	is.True(limiters.Allow(ratelimit.GroupWrite, principal.LimitKey()).Allowed)
+
+	is.NoErr(call(doubleedv1.TransactionService_PostTransaction_FullMethodName))
+	err := call(doubleedv1.TransactionService_PostTransaction_FullMethodName)
+	is.Equal(status.Code(err), codes.ResourceExhausted) // write limit exceeded
+	retryInfo, ok := status.Convert(err).Details()[0].(*errdetails.RetryInfo)
+	is.True(ok)
+	delay := retryInfo.RetryDelay.AsDuration()
+	is.True(delay > 29*time.Second && delay <= 30*time.Second) // two writes a minute
+
+	is.NoErr(call(doubleedv1.LedgerService_ListLedgers_FullMethodName)) // reads have their own limit
+	is.Equal(methodGroup(doubleedv1.TransactionService_ListTransactions_FullMethodName), ratelimit.GroupExport)
+}
+
+func TestFailedAuthenticationLimit(t *testing.T) {
+	is := is_.New(t)
+
+	srv := &Server{limiters: ratelimit.Limiters{
+		ratelimit.GroupAuth: ratelimit.NewLimiter(ratelimit.Limit{Requests: 1, Per: time.Minute}),
+	}}
+	authenticate := func(authorization string) error {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", authorization))
+		_, err := srv.authenticate(ctx, doubleedv1.LedgerService_ListLedgers_FullMethodName)
+		return err
+	}
+
+	err := authenticate("Basic dXNlcjpwYXNz")
+	is.Equal(status.Code(err), codes.Unauthenticated)
+
+	// the authenticator is never reached, the server has none
+	err = authenticate("Bearer guess")
+	is.Equal(status.Code(err), codes.ResourceExhausted) // failed authentication limit exceeded
+}
+
+func TestCallHash(t *testing.T) {
+	is := is_.New(t)
+
+	method := doubleedv1.LedgerService_CreateLedger_FullMethodName
+	hash := func(owner string, req any) string {
+		h, err := callHash(owner, method, req)
+		is.NoErr(err)
+		return h
+	}
+
+	books := &doubleedv1.CreateLedgerRequest{Name: "Books"}
+	is.Equal(hash("key:a", books), hash("key:a", &doubleedv1.CreateLedgerRequest{Name: "Books"}))
+	is.True(hash("key:a", books) != hash("key:a", &doubleedv1.CreateLedgerRequest{Name: "Other"})) // another request
+	is.True(hash("key:a", books) != hash("user:a", books))                                         // another principal
+
+	_, err := callHash("key:a", method, "not a message")
+	is.True(err != nil)
+}
+
+// fakeDB claims every idempotency key and records the statements run
+type fakeDB struct {
+	execs []string
+}
+
+func (f *fakeDB) Exec(_ context.Context, sql string, _ ...any) (pgconn.CommandTag, error) {
+	f.execs = append(f.execs, sql)
+	return pgconn.CommandTag{}, nil
+}
+
+func (f *fakeDB) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeDB) QueryRow(context.Context, string, ...any) pgx.Row {
+	return fakeRow{}
+}
+
+type fakeRow struct{}
+
+func (fakeRow) Scan(dest ...any) error {
+	*dest[0].(*int64) = 1
+	return nil
+}
+
+func TestIdempotentUnstoredResponse(t *testing.T) {
+	is := is_.New(t)
+
+	fake := &fakeDB{}
+	srv := &Server{client: &db.Client{Queries: dbGen.New(fake)}}
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{KeyUUID: "key", TenantID: 1})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(idempotencyKeyMetadata, "retry-me"))
+	info := &grpc.UnaryServerInfo{FullMethod: doubleedv1.LedgerService_CreateLedger_FullMethodName}
+
+	// a response that isn't a protobuf message can't be stored
+	res, err := srv.unaryIdempotent(ctx, &doubleedv1.CreateLedgerRequest{Name: "Books"}, info, func(ctx context.Context, req any) (any, error) {
+		return "not a message", nil
+	})
+	is.NoErr(err)
+	is.Equal(res, "not a message")
+
+	is.Equal(len(fake.execs), 1)
+	is.True(strings.Contains(fake.execs[0], "name: ReleaseIdempotencyKey")) // key released for the retries
+}
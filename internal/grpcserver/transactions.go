@@ -0,0 +1,151 @@
+package grpcserver
+
+import (
+	"context"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	doubleedv1 "github.com/j0lvera/go-double-e/pkg/proto/doubleed/v1"
+	"log/slog"
+	"time"
+)
+
+type transactionService struct {
+	doubleedv1.UnimplementedTransactionServiceServer
+	*Server
+}
+
+func (s *transactionService) PostTransaction(ctx context.Context, req *doubleedv1.PostTransactionRequest) (*doubleedv1.Transaction, error) {
+	slog.DebugContext(ctx, "grpc.transaction.create.start")
+
+	// an empty date is left to the validation of the service
+	var date time.Time
+	if req.GetDate() != "" {
+		var err error
+		if date, err = parseDate("date", req.GetDate()); err != nil {
+			return nil, err
+		}
+	}
+
+	transaction, err := s.ledger.PostTransaction(ctx, ledger.PostTransactionParams{
+		Amount:            req.GetAmount(),
+		Date:              date,
+		Description:       req.GetDescription(),
+		Metadata:          metadataMap(req.GetMetadata()),
+		CreditAccountUUID: req.GetCreditAccountUuid(),
+		DebitAccountUUID:  req.GetDebitAccountUuid(),
+		LedgerUUID:        req.GetLedgerUuid(),
+	})
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to create transaction", err)
+	}
+
+	s.metrics.TransactionCreated(req.GetLedgerUuid(), transaction.Amount)
+
+	res, err := s.transaction(ctx, transaction.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "transaction created", "uuid", transaction.Uuid, "description", transaction.Description)
+	slog.DebugContext(ctx, "grpc.transaction.create.complete", "transaction_uuid", transaction.Uuid)
+	return res, nil
+}
+
+func (s *transactionService) GetTransaction(ctx context.Context, req *doubleedv1.GetTransactionRequest) (*doubleedv1.Transaction, error) {
+	slog.DebugContext(ctx, "grpc.transaction.get.start", "uuid", req.GetUuid())
+
+	res, err := s.transaction(ctx, req.GetUuid())
+	if err != nil {
+		return nil, err
+	}
+
+	slog.DebugContext(ctx, "grpc.transaction.get.complete", "transaction_uuid", req.GetUuid())
+	return res, nil
+}
+
+func (s *transactionService) ListTransactions(req *doubleedv1.ListTransactionsRequest, stream doubleedv1.TransactionService_ListTransactionsServer) error {
+	ctx := stream.Context()
+	slog.DebugContext(ctx, "grpc.transaction.list.start", "ledger_uuid", req.GetLedgerUuid())
+
+	var count int
+	err := s.ledger.StreamTransactions(ctx, req.GetLedgerUuid(), metadataMap(req.GetMetadata()), func(rows []*dbGen.ListTransactionsByLedgerRow) error {
+		for _, row := range rows {
+			transaction, err := streamedTransactionMessage(row, req.GetLedgerUuid())
+			if err != nil {
+				return err
+			}
+			if err := stream.Send(transaction); err != nil {
+				return err
+			}
+		}
+		count += len(rows)
+		return nil
+	})
+	if err != nil {
+		return ledgerError(ctx, "unable to stream transactions", err)
+	}
+
+	slog.DebugContext(ctx, "grpc.transaction.list.complete", "count", count)
+	return nil
+}
+
+func (s *transactionService) UpdateTransaction(ctx context.Context, req *doubleedv1.UpdateTransactionRequest) (*doubleedv1.Transaction, error) {
+	slog.DebugContext(ctx, "grpc.transaction.update.start", "uuid", req.GetUuid())
+
+	params := ledger.UpdateTransactionParams{
+		Amount:            req.Amount,
+		Description:       req.Description,
+		Metadata:          metadataMap(req.GetMetadata()),
+		CreditAccountUUID: req.CreditAccountUuid,
+		DebitAccountUUID:  req.DebitAccountUuid,
+		LedgerUUID:        req.LedgerUuid,
+	}
+	if req.Date != nil {
+		date, err := parseDate("date", req.GetDate())
+		if err != nil {
+			return nil, err
+		}
+		params.Date = &date
+	}
+
+	transaction, err := s.ledger.UpdateTransaction(ctx, req.GetUuid(), params)
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to update transaction", err)
+	}
+
+	res, err := s.transaction(ctx, transaction.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.InfoContext(ctx, "transaction updated", "uuid", transaction.Uuid)
+	slog.DebugContext(ctx, "grpc.transaction.update.complete", "transaction_uuid", transaction.Uuid)
+	return res, nil
+}
+
+func (s *transactionService) DeleteTransaction(ctx context.Context, req *doubleedv1.DeleteTransactionRequest) (*doubleedv1.DeleteTransactionResponse, error) {
+	slog.DebugContext(ctx, "grpc.transaction.delete.start", "uuid", req.GetUuid())
+
+	if err := s.ledger.DeleteTransaction(ctx, req.GetUuid()); err != nil {
+		return nil, ledgerError(ctx, "unable to delete transaction", err)
+	}
+
+	slog.InfoContext(ctx, "transaction deleted", "uuid", req.GetUuid())
+	slog.DebugContext(ctx, "grpc.transaction.delete.complete", "transaction_uuid", req.GetUuid())
+	return &doubleedv1.DeleteTransactionResponse{}, nil
+}
+
+// transaction reads a transaction with the uuids of its accounts and
+// ledger, which the rows of the writes don't have
+func (s *transactionService) transaction(ctx context.Context, uuid string) (*doubleedv1.Transaction, error) {
+	transaction, err := s.ledger.GetTransaction(ctx, uuid)
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to get transaction", err)
+	}
+
+	res, err := transactionMessage(transaction)
+	if err != nil {
+		return nil, ledgerError(ctx, "unable to convert transaction", err)
+	}
+	return res, nil
+}
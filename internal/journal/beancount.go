@@ -0,0 +1,363 @@
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	metadataLineRe = regexp.MustCompile(`^([a-z][a-zA-Z0-9_-]*):(\s+.*)?$`)
+
+	beancountRootOptions = map[string]string{
+		"name_assets":      "assets",
+		"name_liabilities": "liabilities",
+		"name_equity":      "equity",
+		"name_income":      "income",
+		"name_expenses":    "expenses",
+	}
+)
+
+// token is a whitespace separated field of a Beancount line
+type token struct {
+	text   string
+	quoted bool
+}
+
+// parseBeancount parses the subset of the Beancount syntax that maps onto
+// the service: options, open, close, commodity, balance and transactions.
+func (p *parser) parseBeancount(content string) {
+	var (
+		txn     *Transaction
+		account *Account
+	)
+
+	flush := func() {
+		if txn != nil {
+			p.addTransaction(txn)
+		}
+		txn, account = nil, nil
+	}
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := i + 1
+		raw = strings.TrimRight(raw, "\r")
+
+		trimmed := strings.TrimSpace(raw)
+		if trimmed == "" || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		if !indented {
+			flush()
+
+			// org-mode headings are allowed to structure the file
+			if strings.HasPrefix(raw, "*") {
+				continue
+			}
+
+			tokens, err := tokenize(raw)
+			if err != nil {
+				p.errorf(line, "%s", err)
+				continue
+			}
+
+			txn, account = p.beancountDirective(tokens, line)
+			continue
+		}
+
+		tokens, err := tokenize(trimmed)
+		if err != nil {
+			p.errorf(line, "%s", err)
+			continue
+		}
+
+		if m := metadataLineRe.FindStringSubmatch(trimmed); m != nil {
+			value := metadataValue(tokens[1:])
+			switch {
+			case txn != nil && len(txn.Postings) > 0:
+				p.errorf(line, "posting metadata is not supported")
+			case txn != nil:
+				txn.Metadata[m[1]] = value
+			case account != nil:
+				account.Metadata[m[1]] = value
+			}
+			continue
+		}
+
+		if txn == nil {
+			p.errorf(line, "unexpected indented line outside of a transaction")
+			continue
+		}
+
+		if posting, ok := p.beancountPosting(tokens, line); ok {
+			txn.Postings = append(txn.Postings, posting)
+		}
+	}
+
+	flush()
+}
+
+// beancountDirective handles an unindented line. It returns the
+// transaction or account that the following indented lines belong to.
+func (p *parser) beancountDirective(tokens []token, line int) (*Transaction, *Account) {
+	keyword := tokens[0].text
+	switch keyword {
+	case "option":
+		p.beancountOption(tokens, line)
+		return nil, nil
+	case "include", "plugin", "pushtag", "poptag":
+		p.errorf(line, "unsupported directive %q", keyword)
+		return nil, nil
+	}
+
+	date, err := parseDate(keyword)
+	if err != nil || len(tokens) < 2 {
+		p.errorf(line, "unable to parse directive %q", keyword)
+		return nil, nil
+	}
+
+	directive := tokens[1].text
+	args := tokens[2:]
+	switch directive {
+	case "*", "!", "txn":
+		return p.beancountTransaction(date, directive, args, line), nil
+	case "open":
+		return nil, p.beancountOpen(date, args, line)
+	case "close":
+		if len(args) != 1 {
+			p.errorf(line, "close expects an account")
+			return nil, nil
+		}
+		if acc, ok := p.account(args[0].text, line); ok {
+			acc.Closed = date
+		}
+	case "commodity":
+		// commodities carry no information the service can store
+	case "balance":
+		p.beancountBalance(date, args, line)
+	default:
+		p.errorf(line, "unsupported directive %q", directive)
+	}
+
+	return nil, nil
+}
+
+func (p *parser) beancountOption(tokens []token, line int) {
+	if len(tokens) != 3 || !tokens[1].quoted || !tokens[2].quoted {
+		p.errorf(line, "option expects a quoted name and value")
+		return
+	}
+
+	name, value := tokens[1].text, tokens[2].text
+	switch {
+	case name == "title":
+		if len(value) > maxTextLength {
+			p.errorf(line, "title is longer than %d characters", maxTextLength)
+			return
+		}
+		p.journal.Title = value
+	case name == "operating_currency":
+		// amounts are imported in their own commodity
+	case beancountRootOptions[name] != "":
+		p.roots[strings.ToLower(value)] = p.roots[beancountRootOptions[name]]
+	default:
+		p.errorf(line, "unsupported option %q", name)
+	}
+}
+
+func (p *parser) beancountOpen(date time.Time, args []token, line int) *Account {
+	if len(args) == 0 {
+		p.errorf(line, "open expects an account")
+		return nil
+	}
+
+	acc, ok := p.account(args[0].text, line)
+	if !ok {
+		return nil
+	}
+	acc.Opened = date
+	acc.Line = line
+
+	for _, arg := range args[1:] {
+		if arg.quoted {
+			acc.Metadata["booking"] = arg.text
+			continue
+		}
+		for _, currency := range strings.Split(arg.text, ",") {
+			if currency != "" {
+				acc.Currencies = append(acc.Currencies, currency)
+			}
+		}
+	}
+
+	return acc
+}
+
+func (p *parser) beancountBalance(date time.Time, args []token, line int) {
+	if len(args) != 3 {
+		p.errorf(line, "balance expects an account, an amount and a commodity")
+		return
+	}
+
+	amount, err := p.parseAmount(args[1].text)
+	if err != nil {
+		p.errorf(line, "%s", err)
+		return
+	}
+
+	p.balances = append(p.balances, balanceAssertion{
+		date:      date,
+		account:   args[0].text,
+		amount:    amount,
+		commodity: args[2].text,
+		line:      line,
+	})
+}
+
+func (p *parser) beancountTransaction(date time.Time, flag string, args []token, line int) *Transaction {
+	txn := &Transaction{
+		Date:     date,
+		Flag:     flag,
+		Metadata: map[string]interface{}{},
+		Line:     line,
+	}
+	if flag == "txn" {
+		txn.Flag = "*"
+	}
+
+	var strs []string
+	for _, arg := range args {
+		switch {
+		case arg.quoted:
+			strs = append(strs, arg.text)
+		case strings.HasPrefix(arg.text, "#"):
+			txn.Tags = append(txn.Tags, arg.text[1:])
+		case strings.HasPrefix(arg.text, "^"):
+			txn.Links = append(txn.Links, arg.text[1:])
+		default:
+			p.errorf(line, "unexpected token %q in transaction header", arg.text)
+		}
+	}
+
+	switch len(strs) {
+	case 0:
+	case 1:
+		txn.Narration = strs[0]
+	case 2:
+		txn.Payee, txn.Narration = strs[0], strs[1]
+	default:
+		p.errorf(line, "transaction header has too many strings")
+	}
+
+	return txn
+}
+
+func (p *parser) beancountPosting(tokens []token, line int) (*Posting, bool) {
+	if tokens[0].text == "*" || tokens[0].text == "!" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		p.errorf(line, "posting expects an account")
+		return nil, false
+	}
+
+	if _, ok := p.account(tokens[0].text, line); !ok {
+		return nil, false
+	}
+
+	posting := &Posting{
+		Account: tokens[0].text,
+		Line:    line,
+	}
+
+	rest := tokens[1:]
+	if len(rest) == 0 {
+		posting.elided = true
+		return posting, true
+	}
+
+	for _, tok := range rest {
+		if strings.HasPrefix(tok.text, "{") || tok.text == "@" || tok.text == "@@" {
+			p.errorf(line, "costs and prices are not supported")
+			return nil, false
+		}
+	}
+
+	if len(rest) != 2 {
+		p.errorf(line, "posting expects an amount followed by a commodity")
+		return nil, false
+	}
+
+	amount, err := p.parseAmount(rest[0].text)
+	if err != nil {
+		p.errorf(line, "%s", err)
+		return nil, false
+	}
+	posting.Amount = amount
+	posting.Commodity = rest[1].text
+
+	return posting, true
+}
+
+// tokenize splits a line into whitespace separated tokens, keeping quoted
+// strings together and dropping trailing comments.
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	for {
+		s = strings.TrimLeft(s, " \t")
+		if s == "" || s[0] == ';' {
+			return tokens, nil
+		}
+
+		if s[0] == '"' {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return nil, errors.New("unterminated string")
+			}
+			tokens = append(tokens, token{text: s[1 : end+1], quoted: true})
+			s = s[end+2:]
+			continue
+		}
+
+		end := strings.IndexAny(s, " \t")
+		if end < 0 {
+			end = len(s)
+		}
+		tokens = append(tokens, token{text: s[:end]})
+		s = s[end:]
+	}
+}
+
+// metadataValue converts a Beancount metadata value to its JSON form
+func metadataValue(tokens []token) interface{} {
+	if len(tokens) == 0 {
+		return nil
+	}
+
+	tok := tokens[0]
+	if tok.quoted {
+		return tok.text
+	}
+
+	switch tok.text {
+	case "TRUE":
+		return true
+	case "FALSE":
+		return false
+	}
+
+	if _, err := strconv.ParseFloat(tok.text, 64); err == nil && len(tokens) == 1 {
+		return json.Number(tok.text)
+	}
+
+	texts := make([]string, len(tokens))
+	for i, t := range tokens {
+		texts[i] = t.text
+	}
+	return strings.Join(texts, " ")
+}
@@ -0,0 +1,359 @@
+package journal
+
+import (
+	"errors"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"regexp"
+	"strings"
+)
+
+var (
+	hledgerTagRe         = regexp.MustCompile(`([^\s,:]+):([^,]*)`)
+	hledgerAmountSplitRe = regexp.MustCompile(`\s{2,}|\t`)
+
+	hledgerAccountTypes = map[string]dbGen.AccountType{
+		"a":          dbGen.AccountTypeAsset,
+		"asset":      dbGen.AccountTypeAsset,
+		"c":          dbGen.AccountTypeAsset,
+		"cash":       dbGen.AccountTypeAsset,
+		"l":          dbGen.AccountTypeLiability,
+		"liability":  dbGen.AccountTypeLiability,
+		"e":          dbGen.AccountTypeEquity,
+		"equity":     dbGen.AccountTypeEquity,
+		"v":          dbGen.AccountTypeEquity,
+		"conversion": dbGen.AccountTypeEquity,
+		"r":          dbGen.AccountTypeRevenue,
+		"revenue":    dbGen.AccountTypeRevenue,
+		"x":          dbGen.AccountTypeExpense,
+		"expense":    dbGen.AccountTypeExpense,
+	}
+)
+
+// parseHledger parses the subset of the hledger journal syntax that maps
+// onto the service: account and commodity declarations and transactions.
+func (p *parser) parseHledger(content string) {
+	var (
+		txn      *Transaction
+		account  *Account
+		comments []string
+		inBlock  bool
+	)
+
+	flush := func() {
+		if txn != nil {
+			if len(comments) > 0 {
+				txn.Metadata["comment"] = strings.Join(comments, "\n")
+			}
+			p.addTransaction(txn)
+		}
+		txn, account, comments = nil, nil, nil
+	}
+
+	for i, raw := range strings.Split(content, "\n") {
+		line := i + 1
+		raw = strings.TrimRight(raw, "\r")
+		trimmed := strings.TrimSpace(raw)
+
+		if inBlock {
+			inBlock = trimmed != "end comment"
+			continue
+		}
+
+		if trimmed == "" {
+			flush()
+			continue
+		}
+
+		indented := raw[0] == ' ' || raw[0] == '\t'
+		if !indented {
+			flush()
+
+			switch raw[0] {
+			case ';', '#', '*', '%', '|':
+				continue
+			case '~', '=':
+				p.errorf(line, "periodic and auto transactions are not supported")
+				continue
+			}
+
+			if raw == "comment" {
+				inBlock = true
+				continue
+			}
+
+			if raw[0] >= '0' && raw[0] <= '9' {
+				txn = p.hledgerTransaction(raw, line)
+				if txn != nil {
+					if comment, ok := hledgerComment(raw); ok {
+						comments = append(comments, comment)
+						hledgerTags(comment, txn.Metadata)
+					}
+				}
+				continue
+			}
+
+			account = p.hledgerDirective(raw, line)
+			continue
+		}
+
+		if strings.HasPrefix(trimmed, ";") {
+			comment := strings.TrimSpace(trimmed[1:])
+			switch {
+			case txn != nil:
+				comments = append(comments, comment)
+				hledgerTags(comment, txn.Metadata)
+			case account != nil:
+				p.hledgerAccountTags(account, comment, line)
+			}
+			continue
+		}
+
+		if txn == nil {
+			// sub-directives, e.g. the format of a commodity, are ignored
+			continue
+		}
+
+		if posting, ok := p.hledgerPosting(trimmed, line); ok {
+			txn.Postings = append(txn.Postings, posting)
+		}
+		if comment, ok := hledgerComment(trimmed); ok {
+			comments = append(comments, comment)
+			hledgerTags(comment, txn.Metadata)
+		}
+	}
+
+	flush()
+}
+
+// hledgerDirective handles an unindented line that is not a transaction.
+// It returns the declared account so that following comment lines can
+// attach tags to it.
+func (p *parser) hledgerDirective(raw string, line int) *Account {
+	body, comment, _ := strings.Cut(raw, ";")
+	fields := strings.Fields(body)
+	keyword := fields[0]
+
+	switch keyword {
+	case "account":
+		name := strings.TrimSpace(strings.TrimPrefix(body, "account"))
+		if parts := hledgerAmountSplitRe.Split(name, 2); len(parts) > 1 {
+			name = parts[0]
+		}
+		if name == "" {
+			p.errorf(line, "account expects a name")
+			return nil
+		}
+
+		return p.declareHledgerAccount(name, comment, line)
+	case "commodity", "payee", "tag":
+		// declarations that carry no information the service can store
+	case "decimal-mark":
+		if len(fields) != 2 || fields[1] != "." {
+			p.errorf(line, "only \".\" is supported as decimal mark")
+		}
+	default:
+		p.errorf(line, "unsupported directive %q", keyword)
+	}
+
+	return nil
+}
+
+// declareHledgerAccount declares an account, honouring an explicit
+// `type:` tag for accounts whose root does not reveal the type.
+func (p *parser) declareHledgerAccount(name, comment string, line int) *Account {
+	tags := map[string]interface{}{}
+	hledgerTags(comment, tags)
+
+	if value, ok := tags["type"].(string); ok {
+		accountType, ok := hledgerAccountTypes[strings.ToLower(value)]
+		if !ok {
+			p.errorf(line, "unknown account type %q", value)
+			return nil
+		}
+
+		if _, exists := p.accounts[name]; !exists {
+			acc := &Account{
+				Name:     name,
+				Type:     accountType,
+				Metadata: map[string]interface{}{},
+				Line:     line,
+			}
+			p.accounts[name] = acc
+			p.journal.Accounts = append(p.journal.Accounts, acc)
+		}
+	}
+
+	acc, ok := p.account(name, line)
+	if !ok {
+		return nil
+	}
+
+	for key, value := range tags {
+		if key == "type" {
+			continue
+		}
+		acc.Metadata[key] = value
+	}
+
+	return acc
+}
+
+func (p *parser) hledgerAccountTags(acc *Account, comment string, line int) {
+	tags := map[string]interface{}{}
+	hledgerTags(comment, tags)
+
+	for key, value := range tags {
+		if key == "type" {
+			p.errorf(line, "the account type must be declared on the account line")
+			continue
+		}
+		acc.Metadata[key] = value
+	}
+}
+
+// hledgerTransaction parses a transaction header of the form
+// DATE[=DATE2] [STATUS] [(CODE)] DESCRIPTION [; COMMENT]
+func (p *parser) hledgerTransaction(raw string, line int) *Transaction {
+	header, _, _ := strings.Cut(raw, ";")
+	header = strings.TrimSpace(header)
+
+	dateField, rest, _ := strings.Cut(header, " ")
+	dateField, _, _ = strings.Cut(dateField, "=")
+	date, err := parseDate(dateField)
+	if err != nil {
+		p.errorf(line, "unable to parse date %q", dateField)
+		return nil
+	}
+
+	txn := &Transaction{
+		Date:     date,
+		Metadata: map[string]interface{}{},
+		Line:     line,
+	}
+
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "*") || strings.HasPrefix(rest, "!") {
+		txn.Flag = rest[:1]
+		rest = strings.TrimSpace(rest[1:])
+	}
+
+	if strings.HasPrefix(rest, "(") {
+		if end := strings.IndexByte(rest, ')'); end > 0 {
+			txn.Metadata["code"] = rest[1:end]
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+	}
+
+	if payee, note, ok := strings.Cut(rest, "|"); ok {
+		txn.Payee = strings.TrimSpace(payee)
+		txn.Narration = strings.TrimSpace(note)
+	} else {
+		txn.Narration = rest
+	}
+
+	return txn
+}
+
+func (p *parser) hledgerPosting(raw string, line int) (*Posting, bool) {
+	body, _, _ := strings.Cut(raw, ";")
+	body = strings.TrimSpace(body)
+	if body == "" {
+		return nil, false
+	}
+
+	if body[0] == '*' || body[0] == '!' {
+		body = strings.TrimSpace(body[1:])
+	}
+
+	if body[0] == '(' || body[0] == '[' {
+		p.errorf(line, "virtual postings are not supported")
+		return nil, false
+	}
+
+	parts := hledgerAmountSplitRe.Split(body, 2)
+	name := parts[0]
+
+	if _, ok := p.account(name, line); !ok {
+		return nil, false
+	}
+
+	posting := &Posting{
+		Account: name,
+		Line:    line,
+	}
+
+	if len(parts) == 1 {
+		posting.elided = true
+		return posting, true
+	}
+
+	amount := strings.TrimSpace(parts[1])
+	switch {
+	case strings.ContainsAny(amount, "@"):
+		p.errorf(line, "costs and prices are not supported")
+		return nil, false
+	case strings.ContainsAny(amount, "="):
+		p.errorf(line, "balance assertions are not supported")
+		return nil, false
+	}
+
+	number, commodity, err := splitHledgerAmount(amount)
+	if err != nil {
+		p.errorf(line, "%s", err)
+		return nil, false
+	}
+
+	posting.Amount, err = p.parseAmount(number)
+	if err != nil {
+		p.errorf(line, "%s", err)
+		return nil, false
+	}
+	posting.Commodity = commodity
+
+	return posting, true
+}
+
+// splitHledgerAmount separates the number from the commodity symbol, which
+// can be written on either side, e.g., "$-10.00", "-$10", "10 USD".
+func splitHledgerAmount(s string) (number, commodity string, err error) {
+	start := strings.IndexAny(s, "0123456789")
+	if start < 0 {
+		return "", "", errors.New("invalid amount " + s)
+	}
+	if start > 0 && (s[start-1] == '-' || s[start-1] == '+') {
+		start--
+	}
+
+	end := start + 1
+	for end < len(s) && strings.IndexByte("0123456789.,", s[end]) >= 0 {
+		end++
+	}
+
+	number = s[start:end]
+	left := strings.TrimSpace(s[:start])
+	right := strings.TrimSpace(s[end:])
+
+	if strings.HasPrefix(left, "-") {
+		number = "-" + number
+		left = strings.TrimSpace(left[1:])
+	}
+
+	if left != "" && right != "" {
+		return "", "", errors.New("invalid amount " + s)
+	}
+
+	return number, strings.Trim(left+right, `"`), nil
+}
+
+// hledgerComment returns the comment that follows a `;` on the line
+func hledgerComment(raw string) (string, bool) {
+	_, comment, ok := strings.Cut(raw, ";")
+	return strings.TrimSpace(comment), ok
+}
+
+// hledgerTags collects `name:value` tags found in a comment
+func hledgerTags(comment string, into map[string]interface{}) {
+	for _, m := range hledgerTagRe.FindAllStringSubmatch(comment, -1) {
+		into[m[1]] = strings.TrimSpace(m[2])
+	}
+}
@@ -0,0 +1,454 @@
+// Package journal parses plain-text accounting journals (Beancount and
+// hledger) into ledgers, accounts and transactions that can be imported
+// into the service.
+package journal
+
+import (
+	"fmt"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type Format string
+
+const (
+	FormatBeancount Format = "beancount"
+	FormatHledger   Format = "hledger"
+)
+
+// maxTextLength mirrors the `char_length(...) < 255` checks on the tables
+const maxTextLength = 254
+
+// Journal is the result of parsing a journal file, Scale is the one of the
+// Options it was parsed with
+type Journal struct {
+	Title        string
+	Scale        int
+	Accounts     []*Account
+	Transactions []*Transaction
+}
+
+// Commodities lists the commodities of the postings, sorted
+func (j *Journal) Commodities() []string {
+	seen := map[string]bool{}
+	for _, txn := range j.Transactions {
+		for _, commodity := range txn.commodities() {
+			seen[commodity] = true
+		}
+	}
+
+	commodities := make([]string, 0, len(seen))
+	for commodity := range seen {
+		commodities = append(commodities, commodity)
+	}
+	sort.Strings(commodities)
+	return commodities
+}
+
+// Account is an account declared or used in the journal
+type Account struct {
+	Name       string
+	Type       dbGen.AccountType
+	Opened     time.Time
+	Closed     time.Time
+	Currencies []string
+	Metadata   map[string]interface{}
+	Line       int
+}
+
+// Posting is a single leg of a journal transaction. Amount is expressed
+// in minor units, positive amounts are debits and negative amounts credits.
+type Posting struct {
+	Account   string
+	Amount    int64
+	Commodity string
+	Line      int
+
+	elided bool
+}
+
+// Transaction is a journal transaction with all its postings
+type Transaction struct {
+	Date      time.Time
+	Flag      string
+	Payee     string
+	Narration string
+	Tags      []string
+	Links     []string
+	Metadata  map[string]interface{}
+	Postings  []*Posting
+	Line      int
+}
+
+// Transfer is a debit/credit pair derived from a journal transaction, it
+// maps one to one to a transaction in the service.
+type Transfer struct {
+	DebitAccount  string
+	CreditAccount string
+	Amount        int64
+	Commodity     string
+}
+
+// Description returns the text stored in the transaction description
+func (t *Transaction) Description() string {
+	if t.Payee != "" && t.Narration != "" {
+		return t.Payee + " | " + t.Narration
+	}
+	return t.Payee + t.Narration
+}
+
+// Transfers splits the postings of the transaction into debit/credit
+// pairs. Postings are matched in the order they appear in the journal so
+// that a transaction with a single credit and N debits yields N transfers.
+func (t *Transaction) Transfers() []Transfer {
+	type leg struct {
+		account string
+		amount  int64
+	}
+
+	var transfers []Transfer
+	for _, commodity := range t.commodities() {
+		var debits, credits []*leg
+		for _, p := range t.Postings {
+			if p.Commodity != commodity || p.Amount == 0 {
+				continue
+			}
+			if p.Amount > 0 {
+				debits = append(debits, &leg{p.Account, p.Amount})
+			} else {
+				credits = append(credits, &leg{p.Account, -p.Amount})
+			}
+		}
+
+		for len(debits) > 0 && len(credits) > 0 {
+			debit, credit := debits[0], credits[0]
+			amount := min(debit.amount, credit.amount)
+
+			transfers = append(transfers, Transfer{
+				DebitAccount:  debit.account,
+				CreditAccount: credit.account,
+				Amount:        amount,
+				Commodity:     commodity,
+			})
+
+			debit.amount -= amount
+			credit.amount -= amount
+			if debit.amount == 0 {
+				debits = debits[1:]
+			}
+			if credit.amount == 0 {
+				credits = credits[1:]
+			}
+		}
+	}
+
+	return transfers
+}
+
+// commodities returns the commodities used in the transaction in order of
+// first appearance.
+func (t *Transaction) commodities() []string {
+	var commodities []string
+	seen := map[string]bool{}
+	for _, p := range t.Postings {
+		if !seen[p.Commodity] {
+			seen[p.Commodity] = true
+			commodities = append(commodities, p.Commodity)
+		}
+	}
+	return commodities
+}
+
+// LineError is an error tied to a line of the journal file
+type LineError struct {
+	Line    int
+	Message string
+}
+
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Errors is the list of errors found while parsing a journal
+type Errors []*LineError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Options configures how a journal is parsed
+type Options struct {
+	// Scale is the number of decimal places kept when converting amounts
+	// to minor units. Amounts with more decimals are rejected instead of
+	// being rounded.
+	Scale int
+}
+
+// Parse reads a journal in the given format. Every problem found is
+// reported as a LineError and returned together as Errors.
+func Parse(r io.Reader, format Format, opts Options) (*Journal, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("read journal: %w", err)
+	}
+
+	p := newParser(opts)
+	switch format {
+	case FormatBeancount:
+		p.parseBeancount(string(content))
+	case FormatHledger:
+		p.parseHledger(string(content))
+	default:
+		return nil, fmt.Errorf("unsupported journal format %q", format)
+	}
+
+	p.finish()
+	if len(p.errs) > 0 {
+		return nil, p.errs
+	}
+
+	return p.journal, nil
+}
+
+// parser holds the state shared by both journal syntaxes
+type parser struct {
+	opts     Options
+	journal  *Journal
+	accounts map[string]*Account
+	roots    map[string]dbGen.AccountType
+	balances []balanceAssertion
+	errs     Errors
+}
+
+// balanceAssertion checks the balance of an account, including its
+// sub-accounts, at the beginning of the given date.
+type balanceAssertion struct {
+	date      time.Time
+	account   string
+	amount    int64
+	commodity string
+	line      int
+}
+
+func newParser(opts Options) *parser {
+	return &parser{
+		opts:     opts,
+		journal:  &Journal{Scale: opts.Scale},
+		accounts: map[string]*Account{},
+		roots: map[string]dbGen.AccountType{
+			"assets":      dbGen.AccountTypeAsset,
+			"asset":       dbGen.AccountTypeAsset,
+			"liabilities": dbGen.AccountTypeLiability,
+			"liability":   dbGen.AccountTypeLiability,
+			"equity":      dbGen.AccountTypeEquity,
+			"income":      dbGen.AccountTypeRevenue,
+			"revenue":     dbGen.AccountTypeRevenue,
+			"revenues":    dbGen.AccountTypeRevenue,
+			"expenses":    dbGen.AccountTypeExpense,
+			"expense":     dbGen.AccountTypeExpense,
+		},
+	}
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) {
+	p.errs = append(p.errs, &LineError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// account returns the account with the given name, declaring it on first
+// use. The account type is derived from the root of the account name.
+func (p *parser) account(name string, line int) (*Account, bool) {
+	if acc, ok := p.accounts[name]; ok {
+		return acc, true
+	}
+
+	if len(name) > maxTextLength {
+		p.errorf(line, "account name %q is longer than %d characters", name, maxTextLength)
+		return nil, false
+	}
+
+	root, _, _ := strings.Cut(name, ":")
+	accountType, ok := p.roots[strings.ToLower(root)]
+	if !ok {
+		p.errorf(line, "unable to infer the account type of %q, the root account must be one of Assets, Liabilities, Equity, Income or Expenses", name)
+		return nil, false
+	}
+
+	acc := &Account{
+		Name:     name,
+		Type:     accountType,
+		Metadata: map[string]interface{}{},
+		Line:     line,
+	}
+	p.accounts[name] = acc
+	p.journal.Accounts = append(p.journal.Accounts, acc)
+
+	return acc, true
+}
+
+// addTransaction balances the transaction, filling in an elided amount,
+// and appends it to the journal.
+func (p *parser) addTransaction(txn *Transaction) {
+	if len(txn.Description()) > maxTextLength {
+		p.errorf(txn.Line, "description is longer than %d characters", maxTextLength)
+		return
+	}
+
+	if len(txn.Postings) < 2 {
+		p.errorf(txn.Line, "transaction must have at least two postings")
+		return
+	}
+
+	var elided *Posting
+	sums := map[string]int64{}
+	for _, posting := range txn.Postings {
+		if posting.elided {
+			if elided != nil {
+				p.errorf(posting.Line, "only one posting per transaction may omit its amount")
+				return
+			}
+			elided = posting
+			continue
+		}
+		sums[posting.Commodity] += posting.Amount
+	}
+
+	if elided != nil {
+		if len(sums) != 1 {
+			p.errorf(elided.Line, "unable to infer the amount of a posting in a multi-commodity transaction")
+			return
+		}
+		for commodity, sum := range sums {
+			elided.Amount = -sum
+			elided.Commodity = commodity
+			sums[commodity] = 0
+		}
+	}
+
+	for commodity, sum := range sums {
+		if sum != 0 {
+			p.errorf(txn.Line, "transaction does not balance, %s is off by %s", displayCommodity(commodity), formatAmount(sum, p.opts.Scale))
+			return
+		}
+	}
+
+	p.journal.Transactions = append(p.journal.Transactions, txn)
+}
+
+// finish verifies the balance assertions once every transaction is known
+func (p *parser) finish() {
+	if len(p.errs) > 0 {
+		return
+	}
+
+	// journals are not required to be sorted, the service orders by date
+	sort.SliceStable(p.journal.Transactions, func(i, j int) bool {
+		return p.journal.Transactions[i].Date.Before(p.journal.Transactions[j].Date)
+	})
+
+	for _, assertion := range p.balances {
+		var balance int64
+		for _, txn := range p.journal.Transactions {
+			if !txn.Date.Before(assertion.date) {
+				break
+			}
+			for _, posting := range txn.Postings {
+				if posting.Commodity == assertion.commodity && isSubAccount(posting.Account, assertion.account) {
+					balance += posting.Amount
+				}
+			}
+		}
+
+		if balance != assertion.amount {
+			p.errorf(
+				assertion.line,
+				"balance assertion failed for %s, expected %s %s but got %s",
+				assertion.account,
+				formatAmount(assertion.amount, p.opts.Scale),
+				displayCommodity(assertion.commodity),
+				formatAmount(balance, p.opts.Scale),
+			)
+		}
+	}
+}
+
+// parseAmount converts a decimal number, e.g., "-1,234.50", to minor units
+func (p *parser) parseAmount(s string) (int64, error) {
+	raw := s
+	s = strings.ReplaceAll(s, ",", "")
+
+	negative := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		negative = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" {
+		return 0, fmt.Errorf("invalid amount %q", raw)
+	}
+	if len(frac) > p.opts.Scale {
+		return 0, fmt.Errorf("amount %q has more than %d decimal places", raw, p.opts.Scale)
+	}
+	frac += strings.Repeat("0", p.opts.Scale-len(frac))
+
+	var amount int64
+	for _, r := range whole + frac {
+		if r < '0' || r > '9' {
+			return 0, fmt.Errorf("invalid amount %q", raw)
+		}
+		d := int64(r - '0')
+		if amount > (math.MaxInt64-d)/10 {
+			return 0, fmt.Errorf("amount %q is too large", raw)
+		}
+		amount = amount*10 + d
+	}
+
+	if negative {
+		amount = -amount
+	}
+
+	return amount, nil
+}
+
+// parseDate accepts the date separators used by both journal formats
+func parseDate(s string) (time.Time, error) {
+	s = strings.NewReplacer("/", "-", ".", "-").Replace(s)
+	return time.Parse(time.DateOnly, s)
+}
+
+// isSubAccount reports whether name is account or one of its descendants
+func isSubAccount(name, account string) bool {
+	return name == account || strings.HasPrefix(name, account+":")
+}
+
+func formatAmount(amount int64, scale int) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+
+	s := fmt.Sprintf("%0*d", scale+1, amount)
+	if scale == 0 {
+		return sign + s
+	}
+	return sign + s[:len(s)-scale] + "." + s[len(s)-scale:]
+}
+
+func displayCommodity(commodity string) string {
+	if commodity == "" {
+		return "the amount"
+	}
+	return commodity
+}
@@ -0,0 +1,185 @@
+package journal
+
+import (
+	"errors"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	is_ "github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func TestParseBeancount(t *testing.T) {
+	is := is_.New(t)
+
+	input := `option "title" "Personal books"
+
+2024-01-01 open Assets:Bank:Checking USD
+  institution: "ACME Bank"
+2024-01-01 open Expenses:Food
+2024-01-01 open Equity:Opening-Balances
+
+2024-01-01 * "Opening balance"
+  Assets:Bank:Checking   1,000.00 USD
+  Equity:Opening-Balances
+
+2024-01-05 * "Grocer" "Weekly groceries" #food ^receipt-12
+  receipt: 12
+  Expenses:Food            42.50 USD
+  Assets:Bank:Checking    -42.50 USD
+
+2024-01-06 balance Assets:Bank 957.50 USD
+`
+
+	j, err := Parse(strings.NewReader(input), FormatBeancount, Options{Scale: 2})
+	is.NoErr(err)
+
+	is.Equal(j.Title, "Personal books")
+	is.Equal(j.Scale, 2)
+	is.Equal(j.Commodities(), []string{"USD"})
+	is.Equal(len(j.Accounts), 3)
+	is.Equal(j.Accounts[0].Type, dbGen.AccountTypeAsset)
+	is.Equal(j.Accounts[0].Metadata["institution"], "ACME Bank")
+	is.Equal(j.Accounts[1].Type, dbGen.AccountTypeExpense)
+	is.Equal(j.Accounts[2].Type, dbGen.AccountTypeEquity)
+
+	is.Equal(len(j.Transactions), 2)
+
+	opening := j.Transactions[0].Transfers()
+	is.Equal(len(opening), 1)
+	is.Equal(opening[0], Transfer{
+		DebitAccount:  "Assets:Bank:Checking",
+		CreditAccount: "Equity:Opening-Balances",
+		Amount:        100000,
+		Commodity:     "USD",
+	})
+
+	groceries := j.Transactions[1]
+	is.Equal(groceries.Description(), "Grocer | Weekly groceries")
+	is.Equal(groceries.Tags, []string{"food"})
+	is.Equal(groceries.Links, []string{"receipt-12"})
+	is.Equal(groceries.Transfers()[0].Amount, int64(4250))
+}
+
+func TestParseHledger(t *testing.T) {
+	is := is_.New(t)
+
+	input := `account assets:checking
+account savings    ; type: A
+account expenses:food
+
+2024/01/05 * (42) Grocer | Weekly groceries  ; project:home
+    expenses:food          $30.00
+    expenses:food:snacks   $12.50  ; impulse:yes
+    assets:checking
+
+2024-01-06 Move to savings
+    savings        10 USD
+    assets:checking  -10 USD
+`
+
+	j, err := Parse(strings.NewReader(input), FormatHledger, Options{Scale: 2})
+	is.NoErr(err)
+
+	is.Equal(len(j.Accounts), 4)
+	is.Equal(j.Accounts[1].Name, "savings")
+	is.Equal(j.Accounts[1].Type, dbGen.AccountTypeAsset)
+
+	groceries := j.Transactions[0]
+	is.Equal(groceries.Flag, "*")
+	is.Equal(groceries.Payee, "Grocer")
+	is.Equal(groceries.Narration, "Weekly groceries")
+	is.Equal(groceries.Metadata["code"], "42")
+	is.Equal(groceries.Metadata["project"], "home")
+	is.Equal(groceries.Metadata["impulse"], "yes")
+
+	// a single credit covering two debits is split in two transfers
+	transfers := groceries.Transfers()
+	is.Equal(len(transfers), 2)
+	is.Equal(transfers[0].CreditAccount, "assets:checking")
+	is.Equal(transfers[0].Amount, int64(3000))
+	is.Equal(transfers[1].DebitAccount, "expenses:food:snacks")
+	is.Equal(transfers[1].Amount, int64(1250))
+
+	is.Equal(j.Transactions[1].Transfers()[0].Commodity, "USD")
+	is.Equal(j.Commodities(), []string{"$", "USD"})
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		input   string
+		line    int
+		message string
+	}{
+		{
+			name:    "unsupported beancount directive",
+			format:  FormatBeancount,
+			input:   "2024-01-01 open Assets:Bank\n2024-01-02 pad Assets:Bank Equity:Opening\n",
+			line:    2,
+			message: `unsupported directive "pad"`,
+		},
+		{
+			name:    "unbalanced transaction",
+			format:  FormatBeancount,
+			input:   "2024-01-01 * \"Lunch\"\n  Expenses:Food  10.00 USD\n  Assets:Cash  -9.00 USD\n",
+			line:    1,
+			message: "transaction does not balance, USD is off by 1.00",
+		},
+		{
+			name:    "too many decimals",
+			format:  FormatHledger,
+			input:   "2024-01-01 Fuel\n    expenses:car  $10.123\n    assets:cash\n",
+			line:    2,
+			message: `amount "10.123" has more than 2 decimal places`,
+		},
+		{
+			name:    "amount overflowing int64",
+			format:  FormatBeancount,
+			input:   "2024-01-01 * \"Lunch\"\n  Expenses:Food  20000000000000000000 USD\n  Assets:Cash\n",
+			line:    2,
+			message: `amount "20000000000000000000" is too large`,
+		},
+		{
+			name:    "amount overflowing int64 in minor units",
+			format:  FormatHledger,
+			input:   "2024-01-01 Fuel\n    expenses:car  $92233720368547758.08\n    assets:cash\n",
+			line:    2,
+			message: `amount "92233720368547758.08" is too large`,
+		},
+		{
+			name:    "unknown account root",
+			format:  FormatHledger,
+			input:   "2024-01-01 Fuel\n    car  $10\n    assets:cash\n",
+			line:    2,
+			message: `unable to infer the account type of "car", the root account must be one of Assets, Liabilities, Equity, Income or Expenses`,
+		},
+		{
+			name:    "price directive",
+			format:  FormatHledger,
+			input:   "P 2024-01-01 EUR $1.10\n",
+			line:    1,
+			message: `unsupported directive "P"`,
+		},
+		{
+			name:    "failed balance assertion",
+			format:  FormatBeancount,
+			input:   "2024-01-01 * \"Lunch\"\n  Expenses:Food  10.00 USD\n  Assets:Cash\n2024-01-02 balance Assets:Cash -5.00 USD\n",
+			line:    4,
+			message: "balance assertion failed for Assets:Cash, expected -5.00 USD but got -10.00",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is_.New(t)
+
+			_, err := Parse(strings.NewReader(tt.input), tt.format, Options{Scale: 2})
+
+			var errs Errors
+			is.True(errors.As(err, &errs)) // expected line errors
+			is.Equal(errs[0].Line, tt.line)
+			is.Equal(errs[0].Message, tt.message)
+		})
+	}
+}
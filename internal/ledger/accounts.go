@@ -0,0 +1,157 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+)
+
+type CreateAccountParams struct {
+	Name       string `validate:"required,max=255"`
+	Type       string `validate:"required"`
+	Metadata   map[string]any
+	LedgerUUID string `validate:"required"`
+}
+
+// CreateAccount creates an account, it's ErrInvalidReference when the
+// ledger doesn't exist
+func (s *Service) CreateAccount(ctx context.Context, params CreateAccountParams) (*dbGen.Account, error) {
+	if err := s.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	if err := s.Authorize(ctx, authz.WriteAccounts, params.LedgerUUID); err != nil {
+		return nil, err
+	}
+
+	metadata, err := json.Marshal(params.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	var account *dbGen.Account
+	err = s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		var txErr error
+		account, txErr = q.CreateAccount(ctx, dbGen.CreateAccountParams{
+			Name:       params.Name,
+			Type:       dbGen.AccountType(params.Type),
+			Metadata:   metadata,
+			LedgerUuid: params.LedgerUUID,
+		})
+		if txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionCreate,
+			EntityType: AuditEntityAccount,
+			EntityUUID: account.Uuid,
+		})
+	})
+	if err != nil {
+		if db.IsReferenceViolation(err) {
+			return nil, ErrInvalidReference
+		}
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// ListAccounts lists the accounts of a ledger whose metadata contains the
+// filter
+func (s *Service) ListAccounts(ctx context.Context, ledgerUUID string, metadata map[string]any) ([]*dbGen.ListAccountsRow, error) {
+	if ledgerUUID == "" {
+		return nil, ErrLedgerRequired
+	}
+
+	filter, err := metadataFilter(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Authorize(ctx, authz.ReadAccounts, ledgerUUID); err != nil {
+		return nil, err
+	}
+
+	return s.client.Queries.ListAccounts(ctx, dbGen.ListAccountsParams{
+		LedgerUuid: ledgerUUID,
+		Metadata:   filter,
+	})
+}
+
+// UpdateAccountParams changes the fields that aren't empty
+type UpdateAccountParams struct {
+	Name     string `validate:"max=255"`
+	Type     string
+	Metadata map[string]any
+}
+
+func (s *Service) UpdateAccount(ctx context.Context, uuid string, params UpdateAccountParams) (*dbGen.Account, error) {
+	if err := s.authorizeAccount(ctx, nil, authz.WriteAccounts, uuid); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	var metadata []byte
+	if params.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(params.Metadata); err != nil {
+			return nil, fmt.Errorf("marshal metadata: %w", err)
+		}
+	}
+
+	var account *dbGen.Account
+	err := s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		// the fields left out keep their value, the row is locked so a
+		// concurrent update isn't overwritten with the values read here
+		current, txErr := notFound(q.GetAccountForUpdate(ctx, uuid))
+		if txErr != nil {
+			return txErr
+		}
+
+		accountParams := dbGen.UpdateAccountParams{
+			Uuid:     uuid,
+			Name:     current.Name,
+			Type:     current.Type,
+			Metadata: current.Metadata,
+		}
+		if params.Name != "" {
+			accountParams.Name = params.Name
+		}
+		if params.Type != "" {
+			accountParams.Type = dbGen.AccountType(params.Type)
+		}
+		if metadata != nil {
+			accountParams.Metadata = metadata
+		}
+
+		before, txErr := Snapshot(ctx, q, AuditEntityAccount, uuid)
+		if txErr != nil {
+			return txErr
+		}
+
+		account, txErr = q.UpdateAccount(ctx, accountParams)
+		if txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionUpdate,
+			EntityType: AuditEntityAccount,
+			EntityUUID: account.Uuid,
+			Before:     before,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
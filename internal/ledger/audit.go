@@ -0,0 +1,42 @@
+package ledger
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// ListAuditEventsParams filters the audit events, the empty fields match
+// every event
+type ListAuditEventsParams struct {
+	Entity     string `validate:"omitempty,oneof=ledger account transaction"`
+	UUID       string
+	LedgerUUID string
+	// Limit is the size of the page, DefaultPageSize when zero
+	Limit  int32 `validate:"min=0,max=1000"`
+	Offset int32 `validate:"min=0"`
+}
+
+// ListAuditEvents returns the audit events, newest first
+func (s *Service) ListAuditEvents(ctx context.Context, params ListAuditEventsParams) ([]*dbGen.AuditEvent, error) {
+	if err := s.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	if err := s.Authorize(ctx, authz.ReadAudit, params.LedgerUUID); err != nil {
+		return nil, err
+	}
+
+	if params.Limit == 0 {
+		params.Limit = DefaultPageSize
+	}
+
+	return s.client.Queries.ListAuditEvents(ctx, dbGen.ListAuditEventsParams{
+		EntityType: pgtype.Text{String: params.Entity, Valid: params.Entity != ""},
+		EntityUuid: pgtype.Text{String: params.UUID, Valid: params.UUID != ""},
+		LedgerUuid: pgtype.Text{String: params.LedgerUUID, Valid: params.LedgerUUID != ""},
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+	})
+}
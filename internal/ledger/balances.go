@@ -0,0 +1,62 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/report"
+)
+
+// Balance returns the current debit, credit and balance of an account,
+// the balance uses the normal sign of its type
+func (s *Service) Balance(ctx context.Context, accountUUID string) (*report.Line, error) {
+	lines, err := s.Balances(ctx, accountUUID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, ErrNotFound
+	}
+	return &lines[0], nil
+}
+
+// Balances returns the current balances of the accounts ordered by name,
+// unknown accounts are left out
+func (s *Service) Balances(ctx context.Context, accountUUIDs ...string) ([]report.Line, error) {
+	// the accounts of ledgers the user has no role on are unknown to it
+	readable := make([]string, 0, len(accountUUIDs))
+	for _, accountUUID := range accountUUIDs {
+		err := s.authorizeAccount(ctx, nil, authz.ReadAccounts, accountUUID)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		readable = append(readable, accountUUID)
+	}
+
+	rows, err := s.client.Queries.ListAccountBalancesByUuids(ctx, readable)
+	if err != nil {
+		return nil, fmt.Errorf("list account balances: %w", err)
+	}
+
+	return balanceLines(rows), nil
+}
+
+// balanceLines turns the balances of the accounts into report lines
+func balanceLines(rows []*dbGen.ListAccountBalancesByUuidsRow) []report.Line {
+	lines := make([]report.Line, 0, len(rows))
+	for _, row := range rows {
+		lines = append(lines, report.NewLine(report.Balance{
+			AccountUUID: row.Uuid,
+			Name:        row.Name,
+			Type:        row.Type,
+			Debit:       row.Debit,
+			Credit:      row.Credit,
+		}))
+	}
+	return lines
+}
@@ -0,0 +1,266 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/logging"
+	"github.com/j0lvera/go-double-e/internal/report"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Audit actions
+const (
+	AuditActionCreate  = "create"
+	AuditActionUpdate  = "update"
+	AuditActionDelete  = "delete"
+	AuditActionImport  = "import"
+	AuditActionRestore = "restore"
+)
+
+// Audited entity types
+const (
+	AuditEntityLedger      = "ledger"
+	AuditEntityAccount     = "account"
+	AuditEntityTransaction = "transaction"
+	AuditEntityGrant       = "grant"
+)
+
+// anonymousActor is recorded when the context doesn't identify its actor
+const anonymousActor = "anonymous"
+
+// eventActions names the outbox event of each audit action, e.g., a
+// created transaction publishes `transaction.created`
+var eventActions = map[string]string{
+	AuditActionCreate:  "created",
+	AuditActionUpdate:  "updated",
+	AuditActionDelete:  "deleted",
+	AuditActionImport:  "imported",
+	AuditActionRestore: "restored",
+}
+
+// Change describes a change to record, Before is the snapshot taken
+// before the change, nil for creations.
+type Change struct {
+	Action     string
+	EntityType string
+	EntityUUID string
+	Before     []byte
+}
+
+// EventData is the data of an outbox event. Balances are the ones of the
+// accounts of a transaction right after the change.
+type EventData struct {
+	EntityType string          `json:"entity_type"`
+	EntityUUID string          `json:"entity_uuid"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	Balances   []report.Line   `json:"balances,omitempty"`
+}
+
+// Snapshot returns the current state of an entity as JSON, it returns
+// pgx.ErrNoRows when the entity doesn't exist.
+func Snapshot(ctx context.Context, q *dbGen.Queries, entityType, uuid string) ([]byte, error) {
+	switch entityType {
+	case AuditEntityLedger:
+		return q.GetLedgerSnapshot(ctx, uuid)
+	case AuditEntityAccount:
+		return q.GetAccountSnapshot(ctx, uuid)
+	case AuditEntityTransaction:
+		return q.GetTransactionSnapshot(ctx, uuid)
+	case AuditEntityGrant:
+		return q.GetLedgerGrantSnapshot(ctx, uuid)
+	default:
+		return nil, fmt.Errorf("unknown entity type %q", entityType)
+	}
+}
+
+// SnapshotIfExists is Snapshot for entities that may not exist, e.g., a
+// ledger that is restored for the first time
+func SnapshotIfExists(ctx context.Context, q *dbGen.Queries, entityType, uuid string) ([]byte, error) {
+	data, err := Snapshot(ctx, q, entityType, uuid)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return data, err
+}
+
+// RecordChange writes an audit event and an outbox event with the state
+// of the entity after the change. It must use the queries of the
+// transaction that made the change so everything is committed or rolled
+// back together.
+func RecordChange(ctx context.Context, q *dbGen.Queries, change Change) error {
+	var after []byte
+	if change.Action != AuditActionDelete {
+		var err error
+		after, err = Snapshot(ctx, q, change.EntityType, change.EntityUUID)
+		if err != nil {
+			return fmt.Errorf("snapshot %s %s: %w", change.EntityType, change.EntityUUID, err)
+		}
+	}
+
+	requestID := logging.RequestIDFromContext(ctx)
+
+	_, err := q.CreateAuditEvent(ctx, dbGen.CreateAuditEventParams{
+		Actor:      actor(ctx),
+		Action:     change.Action,
+		EntityType: change.EntityType,
+		EntityUuid: change.EntityUUID,
+		Before:     change.Before,
+		After:      after,
+		RequestID:  pgtype.Text{String: requestID, Valid: requestID != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("create audit event: %w", err)
+	}
+
+	return publishEvent(ctx, q, change, after)
+}
+
+// actor identifies who made the change by the API key the caller was
+// authenticated with, or by its user for JWTs
+func actor(ctx context.Context) string {
+	if principal := auth.PrincipalFromContext(ctx); principal != nil {
+		if principal.KeyUUID == "" {
+			return "user:" + principal.UserUUID
+		}
+		return "api_key:" + principal.KeyUUID
+	}
+	return anonymousActor
+}
+
+// User is the user the caller acts for, invalid for keys without a user
+func User(ctx context.Context) pgtype.Text {
+	principal := auth.PrincipalFromContext(ctx)
+	if principal == nil || principal.UserUUID == "" {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: principal.UserUUID, Valid: true}
+}
+
+// GrantOwner makes the user who created a ledger its owner
+func GrantOwner(ctx context.Context, q *dbGen.Queries, ledgerUUID string) error {
+	user := User(ctx)
+	if !user.Valid {
+		return nil
+	}
+
+	_, err := q.PutLedgerGrant(ctx, dbGen.PutLedgerGrantParams{
+		Role:       authz.RoleOwner,
+		LedgerUuid: ledgerUUID,
+		UserUuid:   user.String,
+	})
+	if err != nil {
+		return fmt.Errorf("grant owner: %w", err)
+	}
+	return nil
+}
+
+// publishEvent writes the change to the outbox, the deliveries to the
+// subscribed webhooks are created by the same statement
+func publishEvent(ctx context.Context, q *dbGen.Queries, change Change, after []byte) error {
+	data := EventData{
+		EntityType: change.EntityType,
+		EntityUUID: change.EntityUUID,
+		Before:     rawJSONOrNull(change.Before),
+		After:      rawJSONOrNull(after),
+	}
+
+	if change.EntityType == AuditEntityTransaction {
+		accountUUIDs, err := transactionAccountUUIDs(change.Before, after)
+		if err != nil {
+			return err
+		}
+		rows, err := q.ListAccountBalancesByUuids(ctx, accountUUIDs)
+		if err != nil {
+			return fmt.Errorf("list account balances: %w", err)
+		}
+		data.Balances = balanceLines(rows)
+	}
+
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal event data: %w", err)
+	}
+
+	ledgerUUID, err := eventLedgerUUID(change, after)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.CreateOutboxEvent(ctx, dbGen.CreateOutboxEventParams{
+		EventType:  change.EntityType + "." + eventActions[change.Action],
+		EntityType: change.EntityType,
+		EntityUuid: change.EntityUUID,
+		Payload:    payload,
+		LedgerUuid: pgtype.Text{String: ledgerUUID, Valid: ledgerUUID != ""},
+	})
+	if err != nil {
+		return fmt.Errorf("create outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// eventLedgerUUID returns the ledger the entity belongs to, the snapshots
+// of the other entities carry it as `ledger_uuid`
+func eventLedgerUUID(change Change, after []byte) (string, error) {
+	if change.EntityType == AuditEntityLedger {
+		return change.EntityUUID, nil
+	}
+
+	data := after
+	if data == nil {
+		data = change.Before
+	}
+
+	var snap struct {
+		LedgerUUID string `json:"ledger_uuid"`
+	}
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return "", fmt.Errorf("unmarshal snapshot: %w", err)
+	}
+
+	return snap.LedgerUUID, nil
+}
+
+// transactionAccountUUIDs returns the accounts of the transaction snapshots,
+// before and after the change
+func transactionAccountUUIDs(before, after []byte) ([]string, error) {
+	var uuids []string
+	seen := map[string]bool{}
+	for _, snapshot := range [][]byte{before, after} {
+		if len(snapshot) == 0 {
+			continue
+		}
+
+		var accounts struct {
+			CreditAccountUUID string `json:"credit_account_uuid"`
+			DebitAccountUUID  string `json:"debit_account_uuid"`
+		}
+		if err := json.Unmarshal(snapshot, &accounts); err != nil {
+			return nil, fmt.Errorf("unmarshal snapshot: %w", err)
+		}
+
+		for _, uuid := range []string{accounts.CreditAccountUUID, accounts.DebitAccountUUID} {
+			if uuid != "" && !seen[uuid] {
+				seen[uuid] = true
+				uuids = append(uuids, uuid)
+			}
+		}
+	}
+
+	return uuids, nil
+}
+
+func rawJSONOrNull(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return json.RawMessage("null")
+	}
+	return b
+}
@@ -0,0 +1,35 @@
+package ledger
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestActor(t *testing.T) {
+	is := is_.New(t)
+
+	ctx := context.Background()
+	is.Equal(actor(ctx), anonymousActor)
+
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{KeyUUID: "k3y"})
+	is.Equal(actor(ctx), "api_key:k3y")
+
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{UserUUID: "us3r"})
+	is.Equal(actor(ctx), "user:us3r")
+}
+
+func TestTransactionAccountUUIDs(t *testing.T) {
+	is := is_.New(t)
+
+	before := []byte(`{"credit_account_uuid": "cash", "debit_account_uuid": "food"}`)
+	after := []byte(`{"credit_account_uuid": "card", "debit_account_uuid": "food"}`)
+	uuids, err := transactionAccountUUIDs(before, after)
+	is.NoErr(err)
+	is.Equal(uuids, []string{"cash", "food", "card"})
+
+	uuids, err = transactionAccountUUIDs(nil, []byte(`{"credit_account_uuid": "cash"}`))
+	is.NoErr(err)
+	is.Equal(uuids, []string{"cash"})
+}
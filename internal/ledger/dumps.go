@@ -0,0 +1,382 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"log/slog"
+	"time"
+)
+
+// LedgerDumpVersion is the version of the dump format written by Dump.
+// Restores of other versions are rejected.
+const LedgerDumpVersion = 1
+
+// LedgerDump is a portable archive of a ledger. Records reference each
+// other through their uuids, internal ids are never exported because
+// they differ per database.
+type LedgerDump struct {
+	Version      int               `json:"version" validate:"required"`
+	CreatedAt    time.Time         `json:"created_at"`
+	Ledger       DumpLedger        `json:"ledger" validate:"required"`
+	Accounts     []DumpAccount     `json:"accounts" validate:"dive"`
+	Transactions []DumpTransaction `json:"transactions" validate:"dive"`
+}
+
+type DumpLedger struct {
+	UUID        string          `json:"uuid" validate:"required"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+	Name        string          `json:"name" validate:"required,max=254"`
+	Description *string         `json:"description"`
+	Metadata    json.RawMessage `json:"metadata"`
+}
+
+type DumpAccount struct {
+	UUID      string          `json:"uuid" validate:"required"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+	Name      string          `json:"name" validate:"required,max=254"`
+	Type      string          `json:"type" validate:"required,oneof=asset liability equity revenue expense"`
+	Metadata  json.RawMessage `json:"metadata"`
+}
+
+type DumpTransaction struct {
+	UUID              string          `json:"uuid" validate:"required"`
+	CreatedAt         time.Time       `json:"created_at"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	Amount            int64           `json:"amount" validate:"min=0"`
+	Date              *string         `json:"date" validate:"omitempty,datetime=2006-01-02"`
+	Description       *string         `json:"description"`
+	Metadata          json.RawMessage `json:"metadata"`
+	CreditAccountUUID string          `json:"credit_account_uuid" validate:"required"`
+	DebitAccountUUID  string          `json:"debit_account_uuid" validate:"required"`
+}
+
+// Dump returns the ledger, its accounts and its transactions as a single
+// versioned document that Restore accepts
+func (s *Service) Dump(ctx context.Context, ledgerUUID string) (*LedgerDump, error) {
+	var dump *LedgerDump
+	err := s.StreamDump(
+		ctx,
+		ledgerUUID,
+		func(head *LedgerDump) error {
+			dump = head
+			return nil
+		},
+		func(transactions []DumpTransaction) error {
+			dump.Transactions = append(dump.Transactions, transactions...)
+			return nil
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return dump, nil
+}
+
+// StreamDump passes the dump of the ledger and its accounts to head, then
+// calls fn with its transactions in batches read through a cursor. The
+// batch slice is reused between calls. Everything is read from a single
+// snapshot so the dump is consistent.
+func (s *Service) StreamDump(
+	ctx context.Context,
+	ledgerUUID string,
+	head func(dump *LedgerDump) error,
+	fn func(transactions []DumpTransaction) error,
+) error {
+	if err := s.Authorize(ctx, authz.ReadTransactions, ledgerUUID); err != nil {
+		return err
+	}
+
+	err := s.client.WithReadStream(ctx, func(q *dbGen.Queries, stream db.TransactionStream) error {
+		dump, err := dumpLedger(ctx, q, ledgerUUID)
+		if err != nil {
+			return err
+		}
+		if err := head(dump); err != nil {
+			return err
+		}
+
+		batch := make([]DumpTransaction, 0, streamBatchSize)
+		return stream(ctx, ledgerUUID, nil, streamBatchSize, func(rows []*dbGen.ListTransactionsByLedgerRow) error {
+			batch = batch[:0]
+			for _, row := range rows {
+				batch = append(batch, NewDumpTransaction(row))
+			}
+			return fn(batch)
+		})
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	return err
+}
+
+// dumpLedger dumps the ledger and its accounts, the transactions are
+// streamed
+func dumpLedger(ctx context.Context, q *dbGen.Queries, ledgerUUID string) (*LedgerDump, error) {
+	ledger, err := q.GetLedger(ctx, ledgerUUID)
+	if err != nil {
+		return nil, fmt.Errorf("get ledger: %w", err)
+	}
+
+	accounts, err := q.ListAccountsByLedger(ctx, ledgerUUID)
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+
+	dump := &LedgerDump{
+		Version:   LedgerDumpVersion,
+		CreatedAt: time.Now().UTC(),
+		Ledger: DumpLedger{
+			UUID:        ledger.Uuid,
+			CreatedAt:   ledger.CreatedAt.Time,
+			UpdatedAt:   ledger.UpdatedAt.Time,
+			Name:        ledger.Name,
+			Description: textPtr(ledger.Description),
+			Metadata:    ledger.Metadata,
+		},
+		Accounts:     make([]DumpAccount, len(accounts)),
+		Transactions: []DumpTransaction{},
+	}
+
+	for i, account := range accounts {
+		dump.Accounts[i] = DumpAccount{
+			UUID:      account.Uuid,
+			CreatedAt: account.CreatedAt.Time,
+			UpdatedAt: account.UpdatedAt.Time,
+			Name:      account.Name,
+			Type:      string(account.Type),
+			Metadata:  account.Metadata,
+		}
+	}
+
+	return dump, nil
+}
+
+// NewDumpTransaction is the dumped shape of a streamed transaction, the
+// exports use it too
+func NewDumpTransaction(txn *dbGen.ListTransactionsByLedgerRow) DumpTransaction {
+	var date *string
+	if txn.Date.Valid {
+		d := txn.Date.Time.Format(time.DateOnly)
+		date = &d
+	}
+
+	return DumpTransaction{
+		UUID:              txn.Uuid,
+		CreatedAt:         txn.CreatedAt.Time,
+		UpdatedAt:         txn.UpdatedAt.Time,
+		Amount:            txn.Amount,
+		Date:              date,
+		Description:       textPtr(txn.Description),
+		Metadata:          txn.Metadata,
+		CreditAccountUUID: txn.CreditAccountUuid,
+		DebitAccountUUID:  txn.DebitAccountUuid,
+	}
+}
+
+// Restore recreates a ledger from a dump, keeping its uuids, metadata and
+// timestamps. The foreign keys are remapped through the uuids. If the
+// ledger already exists it's ErrConflict unless replace is true, in which
+// case the existing ledger is deleted first and its grants given again.
+// Everything happens in one database transaction. Users can't restore, a
+// dump may replace any ledger of the tenant.
+func (s *Service) Restore(ctx context.Context, dump LedgerDump, replace bool) error {
+	if user := User(ctx); user.Valid {
+		slog.InfoContext(ctx, "restore denied to user", "user_uuid", user.String)
+		return ErrForbidden
+	}
+
+	if err := s.validateParams(dump); err != nil {
+		return err
+	}
+	if validationErrors := validateDump(dump); len(validationErrors) > 0 {
+		return validationErrors
+	}
+
+	err := s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		// the ledger being replaced, if any
+		before, txErr := SnapshotIfExists(ctx, q, AuditEntityLedger, dump.Ledger.UUID)
+		if txErr != nil {
+			return txErr
+		}
+
+		if txErr = restoreLedger(ctx, q, dump, replace); txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionRestore,
+			EntityType: AuditEntityLedger,
+			EntityUUID: dump.Ledger.UUID,
+			Before:     before,
+		})
+	})
+	if err != nil {
+		// the uuids are unique across tenants, which record took one isn't
+		// told to the caller
+		if dbErr := db.ParseDBError(err); dbErr != nil && dbErr.Code == db.UniqueViolation {
+			slog.InfoContext(ctx, "restore conflict", "constraint", dbErr.Constraint)
+			return ErrConflict
+		}
+		return err
+	}
+
+	return nil
+}
+
+// validateDump checks what the struct tags can't: the dump version and
+// that every transaction references accounts included in the dump.
+func validateDump(dump LedgerDump) ValidationErrors {
+	var validationErrors ValidationErrors
+
+	if dump.Version != LedgerDumpVersion {
+		validationErrors = append(validationErrors, ValidationError{
+			Field:   "version",
+			Message: fmt.Sprintf("Unsupported dump version, expected %d", LedgerDumpVersion),
+		})
+	}
+
+	accounts := make(map[string]bool, len(dump.Accounts))
+	for _, account := range dump.Accounts {
+		accounts[account.UUID] = true
+	}
+
+	for i, txn := range dump.Transactions {
+		for _, accountUUID := range []string{txn.CreditAccountUUID, txn.DebitAccountUUID} {
+			if !accounts[accountUUID] {
+				validationErrors = append(validationErrors, ValidationError{
+					Field:   fmt.Sprintf("transactions[%d]", i),
+					Message: fmt.Sprintf("Unknown account %s", accountUUID),
+				})
+			}
+		}
+	}
+
+	return validationErrors
+}
+
+func restoreLedger(ctx context.Context, q *dbGen.Queries, dump LedgerDump, replace bool) error {
+	// the grants are on users of the tenant, they aren't part of the dump
+	var grants []*dbGen.ListLedgerGrantsRow
+
+	_, err := q.GetLedger(ctx, dump.Ledger.UUID)
+	switch {
+	case err == nil && !replace:
+		return ErrConflict
+	case err == nil:
+		if grants, err = q.ListLedgerGrants(ctx, dump.Ledger.UUID); err != nil {
+			return fmt.Errorf("list grants of existing ledger: %w", err)
+		}
+		// the grants and the records of the ledger are deleted with it
+		if err := q.DeleteLedger(ctx, dump.Ledger.UUID); err != nil {
+			return fmt.Errorf("delete existing ledger: %w", err)
+		}
+	case !errors.Is(err, pgx.ErrNoRows):
+		return fmt.Errorf("get ledger: %w", err)
+	}
+
+	_, err = q.RestoreLedger(ctx, dbGen.RestoreLedgerParams{
+		Uuid:        dump.Ledger.UUID,
+		CreatedAt:   timestamptz(dump.Ledger.CreatedAt),
+		UpdatedAt:   timestamptz(dump.Ledger.UpdatedAt),
+		Name:        dump.Ledger.Name,
+		Description: textParam(dump.Ledger.Description),
+		Metadata:    rawJSON(dump.Ledger.Metadata),
+	})
+	if err != nil {
+		return fmt.Errorf("restore ledger: %w", err)
+	}
+
+	for _, grant := range grants {
+		_, err := q.PutLedgerGrant(ctx, dbGen.PutLedgerGrantParams{
+			Role:       grant.Role,
+			LedgerUuid: dump.Ledger.UUID,
+			UserUuid:   grant.UserUuid,
+		})
+		if err != nil {
+			return fmt.Errorf("restore grant of user %s: %w", grant.UserUuid, err)
+		}
+	}
+
+	for _, account := range dump.Accounts {
+		_, err := q.RestoreAccount(ctx, dbGen.RestoreAccountParams{
+			Uuid:       account.UUID,
+			CreatedAt:  timestamptz(account.CreatedAt),
+			UpdatedAt:  timestamptz(account.UpdatedAt),
+			Name:       account.Name,
+			Type:       dbGen.AccountType(account.Type),
+			Metadata:   rawJSON(account.Metadata),
+			LedgerUuid: dump.Ledger.UUID,
+		})
+		if err != nil {
+			return fmt.Errorf("restore account %s: %w", account.UUID, err)
+		}
+	}
+
+	for _, txn := range dump.Transactions {
+		var date pgtype.Date
+		if txn.Date != nil {
+			d, err := time.Parse(time.DateOnly, *txn.Date)
+			if err != nil {
+				return fmt.Errorf("parse date of transaction %s: %w", txn.UUID, err)
+			}
+			date = pgtype.Date{Time: d, Valid: true}
+		}
+
+		_, err := q.RestoreTransaction(ctx, dbGen.RestoreTransactionParams{
+			Uuid:              txn.UUID,
+			CreatedAt:         timestamptz(txn.CreatedAt),
+			UpdatedAt:         timestamptz(txn.UpdatedAt),
+			Amount:            txn.Amount,
+			Date:              date,
+			Description:       textParam(txn.Description),
+			Metadata:          rawJSON(txn.Metadata),
+			CreditAccountUuid: txn.CreditAccountUUID,
+			DebitAccountUuid:  txn.DebitAccountUUID,
+			LedgerUuid:        dump.Ledger.UUID,
+		})
+		if err != nil {
+			return fmt.Errorf("restore transaction %s: %w", txn.UUID, err)
+		}
+	}
+
+	return nil
+}
+
+// textPtr converts a nullable text column to a pointer, nil meaning NULL
+func textPtr(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
+
+// timestamptz keeps the dumped timestamp, falling back to the current
+// time when the dump doesn't have one.
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return pgtype.Timestamptz{
+		Time:  t,
+		Valid: true,
+	}
+}
+
+// rawJSON turns a JSON `null` or missing value into a NULL column
+func rawJSON(m json.RawMessage) []byte {
+	if len(m) == 0 || string(m) == "null" {
+		return nil
+	}
+	return m
+}
@@ -0,0 +1,40 @@
+package ledger
+
+import (
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestValidateDump(t *testing.T) {
+	is := is_.New(t)
+
+	dump := LedgerDump{
+		Version: LedgerDumpVersion,
+		Ledger:  DumpLedger{UUID: "ledger1", Name: "Ledger"},
+		Accounts: []DumpAccount{
+			{UUID: "cash", Name: "Cash", Type: "asset"},
+			{UUID: "food", Name: "Food", Type: "expense"},
+		},
+		Transactions: []DumpTransaction{
+			{UUID: "txn1", Amount: 100, CreditAccountUUID: "cash", DebitAccountUUID: "food"},
+		},
+	}
+
+	t.Run("valid dump", func(t *testing.T) {
+		is.Equal(len(validateDump(dump)), 0)
+	})
+
+	t.Run("unknown version and account", func(t *testing.T) {
+		invalid := dump
+		invalid.Version = 2
+		invalid.Transactions = []DumpTransaction{
+			{UUID: "txn1", Amount: 100, CreditAccountUUID: "cash", DebitAccountUUID: "missing"},
+		}
+
+		errs := validateDump(invalid)
+		is.Equal(len(errs), 2)
+		is.Equal(errs[0].Field, "version")
+		is.Equal(errs[1].Field, "transactions[0]")
+		is.Equal(errs[1].Message, "Unknown account missing")
+	})
+}
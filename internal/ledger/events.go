@@ -0,0 +1,51 @@
+package ledger
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+)
+
+// LatestEventID is the id of the last outbox event of the ledger a stream
+// can send, zero when it has none
+func (s *Service) LatestEventID(ctx context.Context, ledgerUUID string) (int64, error) {
+	if err := s.Authorize(ctx, authz.ReadTransactions, ledgerUUID); err != nil {
+		return 0, err
+	}
+
+	return s.client.Queries.GetLatestLedgerEventID(ctx, ledgerUUID)
+}
+
+// ListEvents returns up to limit outbox events of the ledger recorded
+// after the event afterID, in the order their transactions committed. An
+// event is held back while a transaction older than it is still running,
+// that one may still write events that go before it. The watermark is the
+// oldest transaction of the whole cluster, includePending sends the held
+// events anyway, the ones a still running transaction writes later on go
+// before them and are skipped by the streams already past them.
+func (s *Service) ListEvents(ctx context.Context, ledgerUUID string, afterID int64, limit int32, includePending bool) ([]*dbGen.OutboxEvent, error) {
+	if err := s.Authorize(ctx, authz.ReadTransactions, ledgerUUID); err != nil {
+		return nil, err
+	}
+
+	return s.client.Queries.ListLedgerEvents(ctx, dbGen.ListLedgerEventsParams{
+		LedgerUuid:     ledgerUUID,
+		IncludePending: includePending,
+		AfterID:        afterID,
+		Limit:          limit,
+	})
+}
+
+// PendingEvents reports whether the ledger has events after afterID that
+// ListEvents holds back, they are sent once the transactions before them
+// finish
+func (s *Service) PendingEvents(ctx context.Context, ledgerUUID string, afterID int64) (bool, error) {
+	if err := s.Authorize(ctx, authz.ReadTransactions, ledgerUUID); err != nil {
+		return false, err
+	}
+
+	return s.client.Queries.HasPendingLedgerEvents(ctx, dbGen.HasPendingLedgerEventsParams{
+		LedgerUuid: ledgerUUID,
+		AfterID:    afterID,
+	})
+}
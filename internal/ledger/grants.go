@@ -0,0 +1,156 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+)
+
+// ListGrants returns the users with a role on the ledger
+func (s *Service) ListGrants(ctx context.Context, ledgerUUID string) ([]*dbGen.ListLedgerGrantsRow, error) {
+	if err := s.Authorize(ctx, authz.ManageGrants, ledgerUUID); err != nil {
+		return nil, err
+	}
+
+	return s.client.Queries.ListLedgerGrants(ctx, ledgerUUID)
+}
+
+type PutGrantParams struct {
+	Role string `validate:"required,oneof=owner accountant viewer auditor"`
+}
+
+// PutGrant gives a user a role on the ledger, replacing the one it had.
+// It's ErrNotFound when the ledger or the user isn't one of the tenant, and
+// ErrConflict when it demotes the last owner.
+func (s *Service) PutGrant(ctx context.Context, ledgerUUID, userUUID string, params PutGrantParams) (*dbGen.LedgerGrant, error) {
+	if err := s.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	if err := s.Authorize(ctx, authz.ManageGrants, ledgerUUID); err != nil {
+		return nil, err
+	}
+
+	role := authz.Role(params.Role)
+
+	var grant *dbGen.LedgerGrant
+	err := s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		current, txErr := lockGrant(ctx, q, ledgerUUID, userUUID)
+		if txErr != nil {
+			return txErr
+		}
+
+		change := Change{Action: AuditActionCreate, EntityType: AuditEntityGrant}
+		if current != nil {
+			if txErr = keepOwner(ctx, q, ledgerUUID, current, role); txErr != nil {
+				return txErr
+			}
+			change.Action = AuditActionUpdate
+			if change.Before, txErr = Snapshot(ctx, q, AuditEntityGrant, current.Uuid); txErr != nil {
+				return fmt.Errorf("snapshot grant: %w", txErr)
+			}
+		}
+
+		grant, txErr = q.PutLedgerGrant(ctx, dbGen.PutLedgerGrantParams{
+			Role:       role,
+			LedgerUuid: ledgerUUID,
+			UserUuid:   userUUID,
+		})
+		if txErr != nil {
+			if db.IsReferenceViolation(txErr) {
+				return ErrNotFound
+			}
+			return txErr
+		}
+
+		change.EntityUUID = grant.Uuid
+		return RecordChange(ctx, q, change)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return grant, nil
+}
+
+// DeleteGrant removes the role of a user on the ledger, it's ErrNotFound
+// when the user had none and ErrConflict for the last owner
+func (s *Service) DeleteGrant(ctx context.Context, ledgerUUID, userUUID string) error {
+	if err := s.Authorize(ctx, authz.ManageGrants, ledgerUUID); err != nil {
+		return err
+	}
+
+	return s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		current, err := lockGrant(ctx, q, ledgerUUID, userUUID)
+		if err != nil {
+			return err
+		}
+		if current == nil {
+			return ErrNotFound
+		}
+		if err := keepOwner(ctx, q, ledgerUUID, current, ""); err != nil {
+			return err
+		}
+
+		before, err := Snapshot(ctx, q, AuditEntityGrant, current.Uuid)
+		if err != nil {
+			return fmt.Errorf("snapshot grant: %w", err)
+		}
+
+		if _, err := q.DeleteLedgerGrant(ctx, dbGen.DeleteLedgerGrantParams{
+			LedgerUuid: ledgerUUID,
+			UserUuid:   userUUID,
+		}); err != nil {
+			return err
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionDelete,
+			EntityType: AuditEntityGrant,
+			EntityUUID: current.Uuid,
+			Before:     before,
+		})
+	})
+}
+
+// lockGrant locks the ledger, so its grants change one at a time, and
+// returns the grant of the user, nil when it has none. It's ErrNotFound
+// when the ledger doesn't exist.
+func lockGrant(ctx context.Context, q *dbGen.Queries, ledgerUUID, userUUID string) (*dbGen.LedgerGrant, error) {
+	if _, err := notFound(q.GetLedgerForUpdate(ctx, ledgerUUID)); err != nil {
+		return nil, err
+	}
+
+	grant, err := q.GetLedgerGrantForUpdate(ctx, dbGen.GetLedgerGrantForUpdateParams{
+		LedgerUuid: ledgerUUID,
+		UserUuid:   userUUID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return grant, nil
+}
+
+// keepOwner is ErrConflict when changing the grant to role, empty for a
+// removal, leaves the ledger without owner
+func keepOwner(ctx context.Context, q *dbGen.Queries, ledgerUUID string, current *dbGen.LedgerGrant, role authz.Role) error {
+	if current.Role != authz.RoleOwner || role == authz.RoleOwner {
+		return nil
+	}
+
+	owners, err := q.CountLedgerOwners(ctx, ledgerUUID)
+	if err != nil {
+		return fmt.Errorf("count ledger owners: %w", err)
+	}
+	if owners <= 1 {
+		return fmt.Errorf("%w: last owner of ledger %s", ErrConflict, ledgerUUID)
+	}
+	return nil
+}
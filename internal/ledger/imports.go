@@ -0,0 +1,178 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/journal"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+// Import is the ledger created by Import with the number of accounts and
+// transactions it got
+type Import struct {
+	Ledger       *dbGen.Ledger
+	Accounts     int
+	Transactions int
+}
+
+// Import creates a ledger, its accounts and its transactions from a parsed
+// journal, format is the one it was parsed from. Everything is created in
+// a single database transaction, so a journal is either imported
+// completely or not at all.
+func (s *Service) Import(ctx context.Context, j *journal.Journal, name string, format journal.Format) (*Import, error) {
+	var imported *Import
+	err := s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		var txErr error
+		imported, txErr = importJournal(ctx, q, j, name, string(format))
+		if txErr != nil {
+			return txErr
+		}
+
+		if txErr = GrantOwner(ctx, q, imported.Ledger.Uuid); txErr != nil {
+			return txErr
+		}
+
+		// a single event for the whole ledger, the journal is the detail
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionImport,
+			EntityType: AuditEntityLedger,
+			EntityUUID: imported.Ledger.Uuid,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return imported, nil
+}
+
+// JournalScale is the scale an imported ledger was parsed with, amounts
+// of other ledgers are in cents
+func JournalScale(ledger *dbGen.Ledger) int {
+	var metadata struct {
+		Journal struct {
+			Scale *int `json:"scale"`
+		} `json:"journal"`
+	}
+	if err := json.Unmarshal(ledger.Metadata, &metadata); err != nil || metadata.Journal.Scale == nil {
+		return 2
+	}
+	return *metadata.Journal.Scale
+}
+
+// importJournal writes the parsed journal using the given queries. Each
+// journal transaction becomes one service transaction per debit/credit
+// pair, the original line number is kept in the metadata to group them.
+func importJournal(ctx context.Context, q *dbGen.Queries, j *journal.Journal, name, format string) (*Import, error) {
+	ledgerMetadata, err := json.Marshal(map[string]interface{}{
+		"journal": map[string]interface{}{
+			"format":      format,
+			"title":       j.Title,
+			"scale":       j.Scale,
+			"commodities": j.Commodities(),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshal ledger metadata: %w", err)
+	}
+
+	ledger, err := q.CreateLedger(ctx, dbGen.CreateLedgerParams{
+		Name:        name,
+		Description: pgtype.Text{String: j.Title, Valid: j.Title != ""},
+		Metadata:    ledgerMetadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create ledger: %w", err)
+	}
+
+	accountUUIDs := make(map[string]string, len(j.Accounts))
+	for _, acc := range j.Accounts {
+		info := map[string]interface{}{
+			"line": acc.Line,
+		}
+		if !acc.Opened.IsZero() {
+			info["opened"] = acc.Opened.Format(time.DateOnly)
+		}
+		if !acc.Closed.IsZero() {
+			info["closed"] = acc.Closed.Format(time.DateOnly)
+		}
+		if len(acc.Currencies) > 0 {
+			info["currencies"] = acc.Currencies
+		}
+
+		metadata := make(map[string]interface{}, len(acc.Metadata)+1)
+		for key, value := range acc.Metadata {
+			metadata[key] = value
+		}
+		metadata["journal"] = info
+
+		metadataBytes, err := json.Marshal(metadata)
+		if err != nil {
+			return nil, fmt.Errorf("marshal metadata of account %s: %w", acc.Name, err)
+		}
+
+		account, err := q.CreateAccount(ctx, dbGen.CreateAccountParams{
+			Name:       acc.Name,
+			Type:       acc.Type,
+			Metadata:   metadataBytes,
+			LedgerUuid: ledger.Uuid,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("create account %s: %w", acc.Name, err)
+		}
+		accountUUIDs[acc.Name] = account.Uuid
+	}
+
+	count := 0
+	for _, txn := range j.Transactions {
+		for _, transfer := range txn.Transfers() {
+			info := map[string]interface{}{
+				"line":      txn.Line,
+				"flag":      txn.Flag,
+				"payee":     txn.Payee,
+				"narration": txn.Narration,
+				"commodity": transfer.Commodity,
+			}
+			if len(txn.Tags) > 0 {
+				info["tags"] = txn.Tags
+			}
+			if len(txn.Links) > 0 {
+				info["links"] = txn.Links
+			}
+
+			metadata := make(map[string]interface{}, len(txn.Metadata)+1)
+			for key, value := range txn.Metadata {
+				metadata[key] = value
+			}
+			metadata["journal"] = info
+
+			metadataBytes, err := json.Marshal(metadata)
+			if err != nil {
+				return nil, fmt.Errorf("marshal metadata of transaction at line %d: %w", txn.Line, err)
+			}
+
+			_, err = q.CreateTransaction(ctx, dbGen.CreateTransactionParams{
+				Amount:            transfer.Amount,
+				Date:              pgtype.Date{Time: txn.Date, Valid: true},
+				Description:       txn.Description(),
+				Metadata:          metadataBytes,
+				CreditAccountUuid: accountUUIDs[transfer.CreditAccount],
+				DebitAccountUuid:  accountUUIDs[transfer.DebitAccount],
+				LedgerUuid:        ledger.Uuid,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("create transaction at line %d: %w", txn.Line, err)
+			}
+			count++
+		}
+	}
+
+	return &Import{
+		Ledger:       ledger,
+		Accounts:     len(j.Accounts),
+		Transactions: count,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package ledger
+
+import (
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestJournalScale(t *testing.T) {
+	is := is_.New(t)
+
+	tests := []struct {
+		name     string
+		metadata string
+		want     int
+	}{
+		{"imported", `{"journal": {"format": "beancount", "scale": 4}}`, 4},
+		{"imported without decimals", `{"journal": {"scale": 0}}`, 0},
+		{"not imported", `{"source": "api"}`, 2},
+		{"no metadata", ``, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			is := is.New(t)
+			is.Equal(JournalScale(&dbGen.Ledger{Metadata: []byte(tt.metadata)}), tt.want)
+		})
+	}
+}
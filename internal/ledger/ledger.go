@@ -0,0 +1,168 @@
+// Package ledger is the service layer of the ledgers, accounts,
+// transactions and reports. It owns the validation, the database
+// transactions and the audit trail of every change, so the HTTP and gRPC
+// APIs, the CLI and the jobs only translate their requests and errors.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"github.com/go-playground/validator/v10"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"slices"
+)
+
+var (
+	// ErrNotFound is returned when the entity doesn't exist, or the user
+	// has no role on its ledger
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden is returned when the role of the user on the ledger
+	// lacks the permission
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidReference is returned when the ledger or the accounts a
+	// change refers to don't exist
+	ErrInvalidReference = errors.New("ledger or accounts not found")
+	// ErrLedgerRequired is returned when the operation must name a ledger
+	ErrLedgerRequired = errors.New("a ledger is required")
+	// ErrMetadataRequired is returned when a listing has no metadata filter
+	ErrMetadataRequired = errors.New("a metadata filter is required")
+	// ErrNoChanges is returned when an update doesn't set any field
+	ErrNoChanges = errors.New("nothing to update")
+	// ErrConflict is returned when a restore would overwrite a ledger, or
+	// reuse the uuid of another one's records, and when a change would
+	// leave a ledger without owner
+	ErrConflict = errors.New("conflict")
+)
+
+type Service struct {
+	client   *db.Client
+	validate *validator.Validate
+}
+
+func NewService(client *db.Client) *Service {
+	return &Service{
+		client:   client,
+		validate: validator.New(validator.WithRequiredStructEnabled()),
+	}
+}
+
+// WithClient returns a copy of the service that uses client, e.g., one
+// bound to a transaction of the caller
+func (s *Service) WithClient(client *db.Client) *Service {
+	return &Service{
+		client:   client,
+		validate: s.validate,
+	}
+}
+
+// Authorize checks the role of the user of ctx on every ledger, a ledger
+// the user has no role on is ErrNotFound so its existence doesn't leak.
+// Keys without a user aren't subject to roles. The operations of the
+// service authorize themselves, it's exported for the routes that aren't
+// part of it.
+func (s *Service) Authorize(ctx context.Context, permission authz.Permission, ledgerUUIDs ...string) error {
+	return s.authorize(ctx, nil, permission, ledgerUUIDs...)
+}
+
+// authorize is Authorize reading the roles with q, e.g., the queries of
+// the transaction of the change, nil uses the client.
+func (s *Service) authorize(ctx context.Context, q *dbGen.Queries, permission authz.Permission, ledgerUUIDs ...string) error {
+	user := User(ctx)
+	if !user.Valid {
+		return nil
+	}
+
+	// users can only act on ledgers, the operation must name one
+	ledgerUUIDs = slices.DeleteFunc(slices.Clone(ledgerUUIDs), func(uuid string) bool { return uuid == "" })
+	if len(ledgerUUIDs) == 0 {
+		return ErrLedgerRequired
+	}
+
+	if q == nil {
+		q = s.client.Queries
+	}
+	for _, ledgerUUID := range ledgerUUIDs {
+		role, err := q.GetLedgerRole(ctx, dbGen.GetLedgerRoleParams{
+			LedgerUuid: ledgerUUID,
+			UserUuid:   user.String,
+		})
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				slog.InfoContext(ctx, "no role on ledger", "user_uuid", user.String, "ledger_uuid", ledgerUUID)
+				return ErrNotFound
+			}
+			return err
+		}
+
+		if !authz.Can(role, permission) {
+			slog.InfoContext(ctx, "role lacks permission",
+				"user_uuid", user.String,
+				"ledger_uuid", ledgerUUID,
+				"role", role,
+				"permission", permission,
+			)
+			return ErrForbidden
+		}
+	}
+
+	return nil
+}
+
+// LedgerOfAccount returns the uuid of the ledger of an account whose
+// ledger the user can read the accounts of
+func (s *Service) LedgerOfAccount(ctx context.Context, accountUUID string) (string, error) {
+	ledgerUUID, err := notFound(s.client.Queries.GetAccountLedgerUuid(ctx, accountUUID))
+	if err != nil {
+		return "", err
+	}
+	if err := s.Authorize(ctx, authz.ReadAccounts, ledgerUUID); err != nil {
+		return "", err
+	}
+	return ledgerUUID, nil
+}
+
+// authorizeAccount authorizes the permission on the ledger of an account,
+// an unknown account is ErrNotFound
+func (s *Service) authorizeAccount(ctx context.Context, q *dbGen.Queries, permission authz.Permission, accountUUID string) error {
+	if !User(ctx).Valid {
+		return nil
+	}
+	if q == nil {
+		q = s.client.Queries
+	}
+
+	ledgerUUID, err := notFound(q.GetAccountLedgerUuid(ctx, accountUUID))
+	if err != nil {
+		return err
+	}
+	return s.authorize(ctx, q, permission, ledgerUUID)
+}
+
+// authorizeTransaction authorizes the permission on the ledger of a
+// transaction, an unknown transaction is ErrNotFound
+func (s *Service) authorizeTransaction(ctx context.Context, q *dbGen.Queries, permission authz.Permission, transactionUUID string) error {
+	if !User(ctx).Valid {
+		return nil
+	}
+	if q == nil {
+		q = s.client.Queries
+	}
+
+	ledgerUUID, err := notFound(q.GetTransactionLedgerUuid(ctx, transactionUUID))
+	if err != nil {
+		return err
+	}
+	return s.authorize(ctx, q, permission, ledgerUUID)
+}
+
+// notFound turns pgx.ErrNoRows into ErrNotFound
+func notFound[T any](v T, err error) (T, error) {
+	if errors.Is(err, pgx.ErrNoRows) {
+		return v, ErrNotFound
+	}
+	return v, err
+}
@@ -0,0 +1,69 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	"github.com/j0lvera/go-double-e/internal/report"
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+// the params are validated before the database is used
+func TestValidation(t *testing.T) {
+	is := is_.New(t)
+
+	svc := NewService(nil)
+	ctx := context.Background()
+
+	_, err := svc.CreateLedger(ctx, CreateLedgerParams{Description: "Books"})
+	var validationErrors ValidationErrors
+	is.True(errors.As(err, &validationErrors))
+	is.Equal(validationErrors, ValidationErrors{{Field: "Name", Message: "This field is required"}})
+
+	_, err = svc.PostTransaction(ctx, PostTransactionParams{Amount: 100})
+	is.True(errors.As(err, &validationErrors))
+	is.Equal(len(validationErrors), 4) // date, accounts and ledger
+
+	_, err = svc.TrialBalance(ctx, "l3dger", report.Period{From: "24/11/2024"})
+	is.True(errors.As(err, &validationErrors))
+	is.Equal(validationErrors[0].Field, "From")
+
+	_, err = svc.ListLedgers(ctx, nil)
+	is.True(errors.Is(err, ErrMetadataRequired))
+
+	_, err = svc.UpdateTransaction(ctx, "tr4nsaction", UpdateTransactionParams{})
+	is.True(errors.Is(err, ErrNoChanges))
+
+	_, err = svc.PutGrant(ctx, "l3dger", "us3r", PutGrantParams{Role: "admin"})
+	is.True(errors.As(err, &validationErrors))
+	is.Equal(validationErrors[0].Field, "Role")
+
+	_, err = svc.ListAuditEvents(ctx, ListAuditEventsParams{Entity: "grant"})
+	is.True(errors.As(err, &validationErrors))
+	is.Equal(validationErrors[0].Field, "Entity")
+
+	err = svc.Restore(ctx, LedgerDump{Version: 2, Ledger: DumpLedger{UUID: "l3dger", Name: "Books"}}, false)
+	is.True(errors.As(err, &validationErrors))
+	is.Equal(validationErrors[0].Field, "version")
+}
+
+func TestAuthorize(t *testing.T) {
+	is := is_.New(t)
+
+	svc := NewService(nil)
+
+	// keys without a user aren't subject to roles
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{KeyUUID: "k3y"})
+	is.NoErr(svc.Authorize(ctx, authz.WriteLedger))
+
+	ctx = auth.WithPrincipal(context.Background(), &auth.Principal{UserUUID: "us3r"})
+	is.True(errors.Is(svc.Authorize(ctx, authz.ReadLedger), ErrLedgerRequired))
+	is.True(errors.Is(svc.Authorize(ctx, authz.ReadLedger, ""), ErrLedgerRequired)) // an empty uuid names nothing
+
+	// the operations check the roles themselves
+	_, err := svc.ListAuditEvents(ctx, ListAuditEventsParams{})
+	is.True(errors.Is(err, ErrLedgerRequired))
+	is.True(errors.Is(svc.Restore(ctx, LedgerDump{}, false), ErrForbidden))
+}
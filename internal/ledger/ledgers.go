@@ -0,0 +1,165 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type CreateLedgerParams struct {
+	Name        string `validate:"required,max=255"`
+	Description string `validate:"max=255"`
+	Metadata    map[string]any
+}
+
+// CreateLedger creates a ledger, the user who creates it is its owner
+func (s *Service) CreateLedger(ctx context.Context, params CreateLedgerParams) (*dbGen.Ledger, error) {
+	if err := s.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	metadata, err := json.Marshal(params.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	var ledger *dbGen.Ledger
+	err = s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		var txErr error
+		ledger, txErr = q.CreateLedger(ctx, dbGen.CreateLedgerParams{
+			Name:        params.Name,
+			Description: pgtype.Text{String: params.Description, Valid: true},
+			Metadata:    metadata,
+		})
+		if txErr != nil {
+			return txErr
+		}
+
+		if txErr = GrantOwner(ctx, q, ledger.Uuid); txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionCreate,
+			EntityType: AuditEntityLedger,
+			EntityUUID: ledger.Uuid,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+// GetLedger returns a ledger, it's ErrNotFound when it doesn't exist
+func (s *Service) GetLedger(ctx context.Context, uuid string) (*dbGen.Ledger, error) {
+	if err := s.Authorize(ctx, authz.ReadLedger, uuid); err != nil {
+		return nil, err
+	}
+
+	return notFound(s.client.Queries.GetLedger(ctx, uuid))
+}
+
+// ListLedgers lists the ledgers whose metadata contains the filter, users
+// only see the ledgers they have a role on
+func (s *Service) ListLedgers(ctx context.Context, metadata map[string]any) ([]*dbGen.ListLedgersRow, error) {
+	filter, err := metadataFilter(metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.Queries.ListLedgers(ctx, dbGen.ListLedgersParams{
+		Column1:  filter,
+		UserUuid: User(ctx),
+	})
+}
+
+// UpdateLedgerParams changes the fields that aren't empty
+type UpdateLedgerParams struct {
+	Name        string `validate:"max=255"`
+	Description string `validate:"max=255"`
+	Metadata    map[string]any
+}
+
+func (s *Service) UpdateLedger(ctx context.Context, uuid string, params UpdateLedgerParams) (*dbGen.Ledger, error) {
+	if err := s.Authorize(ctx, authz.WriteLedger, uuid); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	var metadata []byte
+	if params.Metadata != nil {
+		var err error
+		if metadata, err = json.Marshal(params.Metadata); err != nil {
+			return nil, fmt.Errorf("marshal metadata: %w", err)
+		}
+	}
+
+	var ledger *dbGen.Ledger
+	err := s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		// the fields left out keep their value, the row is locked so a
+		// concurrent update isn't overwritten with the values read here
+		current, txErr := notFound(q.GetLedgerForUpdate(ctx, uuid))
+		if txErr != nil {
+			return txErr
+		}
+
+		ledgerParams := dbGen.UpdateLedgerParams{
+			Uuid:        uuid,
+			Name:        current.Name,
+			Description: current.Description,
+			Metadata:    current.Metadata,
+		}
+		if params.Name != "" {
+			ledgerParams.Name = params.Name
+		}
+		if params.Description != "" {
+			ledgerParams.Description = pgtype.Text{String: params.Description, Valid: true}
+		}
+		if metadata != nil {
+			ledgerParams.Metadata = metadata
+		}
+
+		before, txErr := Snapshot(ctx, q, AuditEntityLedger, uuid)
+		if txErr != nil {
+			return txErr
+		}
+
+		ledger, txErr = q.UpdateLedger(ctx, ledgerParams)
+		if txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionUpdate,
+			EntityType: AuditEntityLedger,
+			EntityUUID: ledger.Uuid,
+			Before:     before,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ledger, nil
+}
+
+// metadataFilter marshals the filter of a listing, it must have a key
+func metadataFilter(metadata map[string]any) ([]byte, error) {
+	if len(metadata) == 0 {
+		return nil, ErrMetadataRequired
+	}
+
+	filter, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshal metadata filter: %w", err)
+	}
+	return filter, nil
+}
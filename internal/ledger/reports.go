@@ -0,0 +1,193 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/report"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+// TrialBalance lists the debit and credit totals of every account of the
+// ledger in the period
+func (s *Service) TrialBalance(ctx context.Context, ledgerUUID string, period report.Period) (*report.TrialBalance, error) {
+	balances, err := s.accountBalances(ctx, ledgerUUID, period)
+	if err != nil {
+		return nil, err
+	}
+	return report.NewTrialBalance(period, balances), nil
+}
+
+// BalanceSheet reports the assets, liabilities and equity of the ledger as
+// of the end of the period, its start is ignored since balances are
+// cumulative
+func (s *Service) BalanceSheet(ctx context.Context, ledgerUUID string, period report.Period) (*report.BalanceSheet, error) {
+	period.From = ""
+
+	balances, err := s.accountBalances(ctx, ledgerUUID, period)
+	if err != nil {
+		return nil, err
+	}
+	return report.NewBalanceSheet(period, balances), nil
+}
+
+// IncomeStatement reports the revenue and expenses of the ledger in the
+// period
+func (s *Service) IncomeStatement(ctx context.Context, ledgerUUID string, period report.Period) (*report.IncomeStatement, error) {
+	balances, err := s.accountBalances(ctx, ledgerUUID, period)
+	if err != nil {
+		return nil, err
+	}
+	return report.NewIncomeStatement(period, balances), nil
+}
+
+// AccountStatement lists the transactions of an account in the period
+// with their running balance
+func (s *Service) AccountStatement(ctx context.Context, accountUUID string, period report.Period) (*report.AccountStatement, error) {
+	if err := s.validatePeriod(period); err != nil {
+		return nil, err
+	}
+
+	if err := s.authorizeAccount(ctx, nil, authz.ReadReports, accountUUID); err != nil {
+		return nil, err
+	}
+
+	var (
+		account    *dbGen.Account
+		ledgerUUID string
+		opening    int64
+		rows       []*dbGen.ListAccountEntriesRow
+	)
+	// read everything in a single snapshot so the balances add up
+	err := s.client.WithReadTx(ctx, func(q *dbGen.Queries) error {
+		var txErr error
+		account, txErr = q.GetAccount(ctx, accountUUID)
+		if txErr != nil {
+			return txErr
+		}
+		if ledgerUUID, txErr = q.GetAccountLedgerUuid(ctx, accountUUID); txErr != nil {
+			return txErr
+		}
+
+		if period.From != "" {
+			opening, txErr = q.GetAccountOpeningBalance(ctx, dbGen.GetAccountOpeningBalanceParams{
+				BeforeDate:  dateParam(period.From),
+				AccountUuid: accountUUID,
+			})
+			if txErr != nil {
+				return fmt.Errorf("get opening balance: %w", txErr)
+			}
+		}
+
+		rows, txErr = q.ListAccountEntries(ctx, dbGen.ListAccountEntriesParams{
+			FromDate:    dateParam(period.From),
+			ToDate:      dateParam(period.To),
+			AccountUuid: accountUUID,
+		})
+		return txErr
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	entries := make([]report.Entry, 0, len(rows))
+	for _, row := range rows {
+		var date string
+		if row.Date.Valid {
+			date = row.Date.Time.Format(time.DateOnly)
+		}
+
+		entries = append(entries, report.Entry{
+			TransactionUUID: row.Uuid,
+			Date:            date,
+			Description:     row.Description.String,
+			CounterpartUUID: row.CounterpartUuid,
+			CounterpartName: row.CounterpartName,
+			Debit:           row.Debit,
+			Credit:          row.Credit,
+		})
+	}
+
+	return report.NewAccountStatement(
+		report.StatementAccount{
+			UUID:       account.Uuid,
+			Name:       account.Name,
+			Type:       string(account.Type),
+			LedgerUUID: ledgerUUID,
+		},
+		period,
+		opening,
+		entries,
+	), nil
+}
+
+// accountBalances is ErrNotFound when the ledger doesn't exist
+func (s *Service) accountBalances(ctx context.Context, ledgerUUID string, period report.Period) ([]report.Balance, error) {
+	if err := s.validatePeriod(period); err != nil {
+		return nil, err
+	}
+
+	if err := s.Authorize(ctx, authz.ReadReports, ledgerUUID); err != nil {
+		return nil, err
+	}
+
+	var rows []*dbGen.GetAccountBalancesRow
+	err := s.client.WithReadTx(ctx, func(q *dbGen.Queries) error {
+		if _, txErr := q.GetLedger(ctx, ledgerUUID); txErr != nil {
+			return txErr
+		}
+
+		var txErr error
+		rows, txErr = q.GetAccountBalances(ctx, dbGen.GetAccountBalancesParams{
+			FromDate:   dateParam(period.From),
+			ToDate:     dateParam(period.To),
+			LedgerUuid: ledgerUUID,
+		})
+		return txErr
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	balances := make([]report.Balance, 0, len(rows))
+	for _, row := range rows {
+		balances = append(balances, report.Balance{
+			AccountUUID: row.Uuid,
+			Name:        row.Name,
+			Type:        row.Type,
+			Debit:       row.Debit,
+			Credit:      row.Credit,
+		})
+	}
+
+	return balances, nil
+}
+
+// reportPeriod validates the dates of a report.Period
+type reportPeriod struct {
+	From string `validate:"omitempty,datetime=2006-01-02"`
+	To   string `validate:"omitempty,datetime=2006-01-02"`
+}
+
+func (s *Service) validatePeriod(period report.Period) error {
+	return s.validateParams(reportPeriod(period))
+}
+
+// dateParam expects a date validated as YYYY-MM-DD, empty means no date
+func dateParam(s string) pgtype.Date {
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return pgtype.Date{}
+	}
+	return pgtype.Date{Time: t, Valid: true}
+}
@@ -0,0 +1,340 @@
+package ledger
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/authz"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+// DefaultPageSize is the size of a page of transactions unless the params
+// say otherwise
+const DefaultPageSize = 30
+
+// Transaction is the snapshot of a transaction, the same the audit log
+// keeps
+type Transaction struct {
+	UUID              string                 `json:"uuid"`
+	Amount            int64                  `json:"amount"`
+	Date              string                 `json:"date"`
+	Description       *string                `json:"description"`
+	Metadata          map[string]interface{} `json:"metadata"`
+	CreditAccountUUID string                 `json:"credit_account_uuid"`
+	DebitAccountUUID  string                 `json:"debit_account_uuid"`
+	LedgerUUID        string                 `json:"ledger_uuid"`
+	CreatedAt         time.Time              `json:"created_at"`
+	UpdatedAt         time.Time              `json:"updated_at"`
+}
+
+// PostTransactionParams debits the amount, in minor units, to one account
+// and credits it to the other
+type PostTransactionParams struct {
+	Amount            int64     `validate:"required"`
+	Date              time.Time `validate:"required"`
+	Description       string    `validate:"max=255"`
+	Metadata          map[string]any
+	CreditAccountUUID string `validate:"required"`
+	DebitAccountUUID  string `validate:"required"`
+	LedgerUUID        string `validate:"required"`
+}
+
+// PostTransaction records a transaction, it's ErrInvalidReference when the
+// ledger or the accounts don't exist
+func (s *Service) PostTransaction(ctx context.Context, params PostTransactionParams) (*dbGen.Transaction, error) {
+	if err := s.validateParams(params); err != nil {
+		return nil, err
+	}
+
+	if err := s.Authorize(ctx, authz.WriteTransactions, params.LedgerUUID); err != nil {
+		return nil, err
+	}
+
+	metadata, err := json.Marshal(params.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("marshal metadata: %w", err)
+	}
+
+	var transaction *dbGen.Transaction
+	err = s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		var txErr error
+		transaction, txErr = q.CreateTransaction(ctx, dbGen.CreateTransactionParams{
+			Amount:            params.Amount,
+			Date:              pgtype.Date{Time: params.Date, Valid: true},
+			Description:       params.Description,
+			Metadata:          metadata,
+			CreditAccountUuid: params.CreditAccountUUID,
+			DebitAccountUuid:  params.DebitAccountUUID,
+			LedgerUuid:        params.LedgerUUID,
+		})
+		if txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionCreate,
+			EntityType: AuditEntityTransaction,
+			EntityUUID: transaction.Uuid,
+		})
+	})
+	if err != nil {
+		if db.IsReferenceViolation(err) {
+			return nil, ErrInvalidReference
+		}
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// GetTransaction returns a transaction with the uuids of its accounts and
+// ledger
+func (s *Service) GetTransaction(ctx context.Context, uuid string) (*Transaction, error) {
+	if err := s.authorizeTransaction(ctx, nil, authz.ReadTransactions, uuid); err != nil {
+		return nil, err
+	}
+
+	data, err := notFound(s.client.Queries.GetTransactionSnapshot(ctx, uuid))
+	if err != nil {
+		return nil, err
+	}
+
+	var transaction Transaction
+	if err := json.Unmarshal(data, &transaction); err != nil {
+		return nil, fmt.Errorf("unmarshal transaction snapshot: %w", err)
+	}
+	return &transaction, nil
+}
+
+type ListTransactionsParams struct {
+	LedgerUUID string
+	Metadata   map[string]any
+	// Limit is the size of the page, DefaultPageSize when zero
+	Limit  int32
+	Offset int32
+}
+
+// TransactionPage is a page of transactions, Total counts the
+// transactions of every page
+type TransactionPage struct {
+	Transactions []*dbGen.ListTransactionsRow
+	Total        int64
+	Limit        int32
+	Offset       int32
+}
+
+// ListTransactions returns a page of the transactions of a ledger whose
+// metadata contains the filter, newest first
+func (s *Service) ListTransactions(ctx context.Context, params ListTransactionsParams) (*TransactionPage, error) {
+	if params.LedgerUUID == "" {
+		return nil, ErrLedgerRequired
+	}
+
+	filter, err := metadataFilter(params.Metadata)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Authorize(ctx, authz.ReadTransactions, params.LedgerUUID); err != nil {
+		return nil, err
+	}
+
+	if params.Limit == 0 {
+		params.Limit = DefaultPageSize
+	}
+
+	transactions, err := s.client.Queries.ListTransactions(ctx, dbGen.ListTransactionsParams{
+		LedgerUuid: params.LedgerUUID,
+		Metadata:   filter,
+		Limit:      params.Limit,
+		Offset:     params.Offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list transactions: %w", err)
+	}
+
+	total, err := s.client.Queries.GetTransactionsCount(ctx, dbGen.GetTransactionsCountParams{
+		LedgerUuid: params.LedgerUUID,
+		Metadata:   filter,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("count transactions: %w", err)
+	}
+
+	return &TransactionPage{
+		Transactions: transactions,
+		Total:        total,
+		Limit:        params.Limit,
+		Offset:       params.Offset,
+	}, nil
+}
+
+// StreamTransactions calls fn with the transactions of a ledger in
+// batches, in the order they were recorded, the metadata filter is
+// optional. It reads them through a cursor so memory stays constant
+// regardless of the ledger size.
+func (s *Service) StreamTransactions(
+	ctx context.Context,
+	ledgerUUID string,
+	metadata map[string]any,
+	fn func(transactions []*dbGen.ListTransactionsByLedgerRow) error,
+) error {
+	if ledgerUUID == "" {
+		return ErrLedgerRequired
+	}
+
+	var filter []byte
+	if len(metadata) > 0 {
+		var err error
+		if filter, err = metadataFilter(metadata); err != nil {
+			return err
+		}
+	}
+
+	if err := s.Authorize(ctx, authz.ReadTransactions, ledgerUUID); err != nil {
+		return err
+	}
+
+	// an empty stream can't tell an unknown ledger from an empty one
+	if _, err := notFound(s.client.Queries.GetLedger(ctx, ledgerUUID)); err != nil {
+		return err
+	}
+
+	return s.client.StreamTransactions(ctx, ledgerUUID, filter, streamBatchSize, fn)
+}
+
+// streamBatchSize is the number of rows StreamTransactions fetches at a
+// time
+const streamBatchSize = 500
+
+// UpdateTransactionParams changes the fields that are set
+type UpdateTransactionParams struct {
+	Amount            *int64
+	Date              *time.Time
+	Description       *string
+	Metadata          map[string]any
+	CreditAccountUUID *string
+	DebitAccountUUID  *string
+	LedgerUUID        *string
+}
+
+// UpdateTransaction is ErrNoChanges when no field is set, and
+// ErrInvalidReference when the ledger or the accounts don't exist
+func (s *Service) UpdateTransaction(ctx context.Context, uuid string, params UpdateTransactionParams) (*dbGen.Transaction, error) {
+	if params.Amount == nil && params.Date == nil && params.Description == nil && params.Metadata == nil &&
+		params.CreditAccountUUID == nil && params.DebitAccountUUID == nil && params.LedgerUUID == nil {
+		return nil, ErrNoChanges
+	}
+
+	txnParams := dbGen.UpdateTransactionParams{
+		Uuid:              uuid,
+		CreditAccountUuid: textParam(params.CreditAccountUUID),
+		DebitAccountUuid:  textParam(params.DebitAccountUUID),
+		LedgerUuid:        textParam(params.LedgerUUID),
+		Description:       textParam(params.Description),
+	}
+	if params.Amount != nil {
+		txnParams.Amount = pgtype.Int8{Int64: *params.Amount, Valid: true}
+	}
+	if params.Date != nil {
+		txnParams.Date = pgtype.Date{Time: *params.Date, Valid: true}
+	}
+	if params.Metadata != nil {
+		var err error
+		if txnParams.Metadata, err = json.Marshal(params.Metadata); err != nil {
+			return nil, fmt.Errorf("marshal metadata: %w", err)
+		}
+	}
+
+	var transaction *dbGen.Transaction
+	err := s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		// locked so it can't move to another ledger once authorized
+		ledgerUUID, txErr := notFound(q.LockTransactionLedgerUuid(ctx, uuid))
+		if txErr != nil {
+			return txErr
+		}
+		// the ledger it's moved from and the one it's moved to
+		ledgerUUIDs := []string{ledgerUUID}
+		if params.LedgerUUID != nil {
+			ledgerUUIDs = append(ledgerUUIDs, *params.LedgerUUID)
+		}
+		if txErr = s.authorize(ctx, q, authz.WriteTransactions, ledgerUUIDs...); txErr != nil {
+			return txErr
+		}
+
+		before, txErr := Snapshot(ctx, q, AuditEntityTransaction, uuid)
+		if txErr != nil {
+			return txErr
+		}
+
+		transaction, txErr = q.UpdateTransaction(ctx, txnParams)
+		if txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionUpdate,
+			EntityType: AuditEntityTransaction,
+			EntityUUID: transaction.Uuid,
+			Before:     before,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		if db.IsReferenceViolation(err) {
+			return nil, ErrInvalidReference
+		}
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// DeleteTransaction deletes a transaction, deleting one that doesn't
+// exist isn't an error. For users it's ErrNotFound, the same as a
+// transaction of a ledger they have no role on.
+func (s *Service) DeleteTransaction(ctx context.Context, uuid string) error {
+	return s.client.WithTx(ctx, func(q *dbGen.Queries) error {
+		ledgerUUID, txErr := notFound(q.LockTransactionLedgerUuid(ctx, uuid))
+		if errors.Is(txErr, ErrNotFound) && !User(ctx).Valid {
+			// nothing to delete, so there is nothing to audit either
+			return nil
+		}
+		if txErr != nil {
+			return txErr
+		}
+		if txErr = s.authorize(ctx, q, authz.WriteTransactions, ledgerUUID); txErr != nil {
+			return txErr
+		}
+
+		before, txErr := Snapshot(ctx, q, AuditEntityTransaction, uuid)
+		if txErr != nil {
+			return txErr
+		}
+
+		if txErr = q.DeleteTransaction(ctx, uuid); txErr != nil {
+			return txErr
+		}
+
+		return RecordChange(ctx, q, Change{
+			Action:     AuditActionDelete,
+			EntityType: AuditEntityTransaction,
+			EntityUUID: uuid,
+			Before:     before,
+		})
+	})
+}
+
+func textParam(s *string) pgtype.Text {
+	if s == nil {
+		return pgtype.Text{}
+	}
+	return pgtype.Text{String: *s, Valid: true}
+}
@@ -0,0 +1,71 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"strings"
+)
+
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+// ValidationErrors is returned when the params of an operation are
+// invalid, one error per field
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	fields := make([]string, 0, len(e))
+	for _, err := range e {
+		fields = append(fields, err.Field+": "+err.Message)
+	}
+	return "invalid params: " + strings.Join(fields, ", ")
+}
+
+func ParseValidationErrors(err error) []ValidationError {
+	var validationErrors []ValidationError
+
+	var valErrs validator.ValidationErrors
+	if errors.As(err, &valErrs) {
+		for _, valErr := range valErrs {
+			validationErrors = append(validationErrors, ValidationError{
+				Field:   valErr.Field(),
+				Message: getErrorMessage(valErr),
+			})
+		}
+	}
+
+	return validationErrors
+}
+
+// validateParams returns the ValidationErrors of the params, if any
+func (s *Service) validateParams(params any) error {
+	if err := s.validate.Struct(params); err != nil {
+		if validationErrors := ParseValidationErrors(err); len(validationErrors) > 0 {
+			return ValidationErrors(validationErrors)
+		}
+		return err
+	}
+	return nil
+}
+
+func getErrorMessage(err validator.FieldError) string {
+	switch err.Tag() {
+	case "required":
+		return "This field is required"
+	case "email":
+		return "Invalid email address"
+	case "min":
+		return fmt.Sprintf("This field must be at least %s characters long", err.Param())
+	case "max":
+		return fmt.Sprintf("This field must be at most %s characters long", err.Param())
+	case "oneof":
+		return fmt.Sprintf("This field must be one of: %s", err.Param())
+	case "datetime":
+		return fmt.Sprintf("This field must be a date in the format %s", err.Param())
+	default:
+		return "Invalid value"
+	}
+}
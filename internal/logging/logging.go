@@ -0,0 +1,102 @@
+// Package logging sets up the structured logs of the service. Records
+// logged with a request's context carry its request ID and trace, so every
+// line of a request can be found from the access log, or an audit event.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/charmbracelet/log"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"log/slog"
+	"regexp"
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// NewHandler returns the handler of the format, text is meant for
+// terminals and JSON for log pipelines
+func NewHandler(w io.Writer, format string, level slog.Level) (slog.Handler, error) {
+	var handler slog.Handler
+	switch format {
+	case FormatText, "":
+		logger := log.NewWithOptions(w, log.Options{
+			ReportCaller:    true,
+			ReportTimestamp: true,
+		})
+		// charmbracelet levels have the same values as slog ones
+		logger.SetLevel(log.Level(level))
+		handler = logger
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{
+			AddSource: true,
+			Level:     level,
+		})
+	default:
+		return nil, fmt.Errorf("unknown log format %q, use %q or %q", format, FormatText, FormatJSON)
+	}
+
+	return &contextHandler{Handler: handler}, nil
+}
+
+type requestIDKey struct{}
+
+// validRequestID keeps the IDs of clients from injecting anything into
+// the logs
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID returns the ID a client or a proxy sent when it's valid, and a
+// new one otherwise
+func RequestID(id string) string {
+	if validRequestID.MatchString(id) {
+		return id
+	}
+
+	b := make([]byte, 16)
+	// crypto/rand never fails on supported platforms
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// WithRequestID returns a copy of ctx carrying the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID of ctx, empty when there is
+// none
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// contextHandler adds the request ID and the trace of the context to the
+// records
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestIDFromContext(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	if span := trace.SpanContextFromContext(ctx); span.IsValid() {
+		r.AddAttrs(slog.String("trace_id", span.TraceID().String()), slog.String("span_id", span.SpanID().String()))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
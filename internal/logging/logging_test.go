@@ -0,0 +1,37 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	is_ "github.com/matryer/is"
+	"log/slog"
+	"testing"
+)
+
+func TestRequestIDAttr(t *testing.T) {
+	is := is_.New(t)
+
+	var buf bytes.Buffer
+	handler, err := NewHandler(&buf, FormatJSON, slog.LevelInfo)
+	is.NoErr(err)
+	logger := slog.New(handler).With("component", "test")
+
+	ctx := WithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "hello")
+
+	var record map[string]any
+	is.NoErr(json.Unmarshal(buf.Bytes(), &record))
+	is.Equal(record["request_id"], "req-1") // request id of the context
+	is.Equal(record["component"], "test")   // attrs of the logger are kept
+
+	buf.Reset()
+	logger.Info("no context")
+	record = map[string]any{}
+	is.NoErr(json.Unmarshal(buf.Bytes(), &record))
+	_, ok := record["request_id"]
+	is.True(!ok)
+
+	_, err = NewHandler(&buf, "xml", slog.LevelInfo)
+	is.True(err != nil) // unknown format
+}
@@ -0,0 +1,147 @@
+// Package metrics exposes the Prometheus metrics of the service: requests
+// per route and status, the connections of the database pool, and the
+// transactions posted to each ledger.
+package metrics
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "doubleed"
+
+// Metrics of an instance of the service. The methods of a nil *Metrics do
+// nothing, so they are optional wherever they are recorded.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	transactions    *prometheus.CounterVec
+	amounts         *prometheus.CounterVec
+}
+
+// New registers the metrics of the service, along with the Go runtime and
+// process ones, and the stats of the pool when there is one
+func New(pool *pgxpool.Pool) *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by route and status.",
+		}, []string{"route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Latency of the HTTP requests, by route and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "status"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_created_total",
+			Help:      "Transactions created, by ledger.",
+		}, []string{"ledger"}),
+		amounts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transactions_amount_total",
+			Help:      "Sum of the amounts of the transactions created, in minor units, by ledger.",
+		}, []string{"ledger"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requests,
+		m.requestDuration,
+		m.transactions,
+		m.amounts,
+	)
+	if pool != nil {
+		m.registry.MustRegister(newPoolCollector(pool))
+	}
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a request served by the route, the pattern of
+// the mux, e.g., `GET /ledgers/{id}`, so the cardinality stays bounded
+func (m *Metrics) ObserveRequest(route string, status int, duration time.Duration) {
+	if m == nil {
+		return
+	}
+	labels := prometheus.Labels{"route": route, "status": strconv.Itoa(status)}
+	m.requests.With(labels).Inc()
+	m.requestDuration.With(labels).Observe(duration.Seconds())
+}
+
+// TransactionCreated records a transaction committed to the ledger
+func (m *Metrics) TransactionCreated(ledgerUUID string, amount int64) {
+	if m == nil {
+		return
+	}
+	m.transactions.WithLabelValues(ledgerUUID).Inc()
+	m.amounts.WithLabelValues(ledgerUUID).Add(float64(amount))
+}
+
+// poolCollector reads the stats of the pool on every scrape
+type poolCollector struct {
+	pool *pgxpool.Pool
+
+	acquired        *prometheus.Desc
+	idle            *prometheus.Desc
+	total           *prometheus.Desc
+	max             *prometheus.Desc
+	acquires        *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	acquireDuration *prometheus.Desc
+}
+
+func newPoolCollector(pool *pgxpool.Pool) *poolCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "db_pool", name), help, nil, nil)
+	}
+
+	return &poolCollector{
+		pool:            pool,
+		acquired:        desc("acquired_connections", "Connections in use."),
+		idle:            desc("idle_connections", "Connections ready to be acquired."),
+		total:           desc("total_connections", "Connections open, including the ones being established."),
+		max:             desc("max_connections", "Maximum size of the pool."),
+		acquires:        desc("acquires_total", "Connections acquired from the pool."),
+		emptyAcquires:   desc("empty_acquires_total", "Acquires that waited for a connection because none was idle."),
+		acquireDuration: desc("acquire_wait_seconds_total", "Time spent waiting for a connection, a growing rate means the pool is saturated."),
+	}
+}
+
+func (c *poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquired
+	ch <- c.idle
+	ch <- c.total
+	ch <- c.max
+	ch <- c.acquires
+	ch <- c.emptyAcquires
+	ch <- c.acquireDuration
+}
+
+func (c *poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := c.pool.Stat()
+
+	ch <- prometheus.MustNewConstMetric(c.acquired, prometheus.GaugeValue, float64(stat.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(stat.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(stat.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.max, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+}
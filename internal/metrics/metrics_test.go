@@ -0,0 +1,35 @@
+package metrics
+
+import (
+	is_ "github.com/matryer/is"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	is := is_.New(t)
+
+	m := New(nil)
+	m.ObserveRequest("GET /ledgers", 200, 30*time.Millisecond)
+	m.TransactionCreated("l1", 1500)
+	m.TransactionCreated("l1", 500)
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(w.Body)
+	is.NoErr(err)
+
+	out := string(body)
+	is.True(strings.Contains(out, `doubleed_http_requests_total{route="GET /ledgers",status="200"} 1`))
+	is.True(strings.Contains(out, `doubleed_http_request_duration_seconds_count{route="GET /ledgers",status="200"} 1`))
+	is.True(strings.Contains(out, `doubleed_transactions_created_total{ledger="l1"} 2`))
+	is.True(strings.Contains(out, `doubleed_transactions_amount_total{ledger="l1"} 2000`))
+
+	// nil metrics are disabled
+	var disabled *Metrics
+	disabled.ObserveRequest("GET /ledgers", 200, time.Second)
+	disabled.TransactionCreated("l1", 1)
+}
@@ -0,0 +1,280 @@
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/jackc/pgx/v5/pgtype"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// knownTypes marshal themselves, their schema can't be read from their
+// fields
+var knownTypes = map[reflect.Type]Schema{
+	reflect.TypeOf(time.Time{}):          {Type: "string", Format: "date-time"},
+	reflect.TypeOf(json.RawMessage{}):    {},
+	reflect.TypeOf(pgtype.Text{}):        {Type: "string", Nullable: true},
+	reflect.TypeOf(pgtype.Date{}):        {Type: "string", Format: "date", Nullable: true},
+	reflect.TypeOf(pgtype.Timestamptz{}): {Type: "string", Format: "date-time", Nullable: true},
+	reflect.TypeOf(pgtype.Int4{}):        {Type: "integer", Format: "int32", Nullable: true},
+	reflect.TypeOf(pgtype.Int8{}):        {Type: "integer", Format: "int64", Nullable: true},
+}
+
+var (
+	jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshaler = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Generator turns Go types into schemas. Named structs are added once to
+// the components and referenced everywhere else.
+type Generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func NewGenerator() *Generator {
+	return &Generator{
+		schemas: map[string]*Schema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+// Schemas are the components of the named structs generated so far
+func (g *Generator) Schemas() map[string]*Schema {
+	return g.schemas
+}
+
+// Request is the schema of a request body, only the fields validated as
+// `required` are required
+func (g *Generator) Request(v any) *Schema {
+	return g.schema(reflect.TypeOf(v), false)
+}
+
+// Response is the schema of a response body, the fields are always sent
+// unless they are `omitempty`
+func (g *Generator) Response(v any) *Schema {
+	return g.schema(reflect.TypeOf(v), true)
+}
+
+// Query lists the query params of the fields of the struct with a `form`
+// tag, the tag the handlers decode the query with
+func (g *Generator) Query(v any) []Parameter {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("form"), ",")
+		if !field.IsExported() || name == "" || name == "-" {
+			continue
+		}
+
+		schema := g.schema(field.Type, false)
+		required := applyValidation(schema, field.Type, field.Tag.Get("validate"))
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "query",
+			Required: required,
+			Schema:   schema,
+		})
+	}
+	return params
+}
+
+func (g *Generator) schema(t reflect.Type, response bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	if known, ok := knownTypes[t]; ok {
+		return &known
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := g.schema(t.Elem(), response)
+		// siblings of a $ref are ignored
+		if schema.Ref == "" {
+			schema.Nullable = true
+		}
+		return schema
+	case reflect.Interface:
+		return &Schema{}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		// encoding/json sends []byte as base64
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem(), response)}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem(), response)}
+	case reflect.Struct:
+		if t.Implements(jsonMarshaler) || t.Implements(textMarshaler) {
+			// no way to tell what they marshal to
+			return &Schema{}
+		}
+		if t.Name() == "" {
+			return g.object(t, response)
+		}
+		return g.named(t, response)
+	default:
+		panic(fmt.Sprintf("openapi: unsupported type %s", t))
+	}
+}
+
+// named adds the struct to the components the first time it's seen
+func (g *Generator) named(t reflect.Type, response bool) *Schema {
+	if name, ok := g.names[t]; ok {
+		return Ref(name)
+	}
+
+	name := schemaName(t)
+	if _, taken := g.schemas[name]; taken {
+		// the same name in another package
+		pkg := t.PkgPath()
+		name = pkg[strings.LastIndex(pkg, "/")+1:] + "." + name
+	}
+
+	// reserve the name first, the struct may reference itself
+	g.names[t] = name
+	g.schemas[name] = &Schema{}
+	*g.schemas[name] = *g.object(t, response)
+
+	return Ref(name)
+}
+
+func (g *Generator) object(t reflect.Type, response bool) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	g.fields(schema, t, response)
+	return schema
+}
+
+func (g *Generator) fields(schema *Schema, t reflect.Type, response bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		// the fields of embedded structs are promoted
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			g.fields(schema, field.Type, response)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		property := g.schema(field.Type, response)
+		validated := applyValidation(property, field.Type, field.Tag.Get("validate"))
+
+		omitempty := strings.Contains(options, "omitempty")
+		if validated || (response && !omitempty) {
+			schema.Required = append(schema.Required, name)
+		}
+		schema.Properties[name] = property
+	}
+}
+
+// applyValidation narrows the schema with the rules of the validate tag
+// and reports whether the value is required. Rules after `dive` apply to
+// the elements and are left out.
+func applyValidation(schema *Schema, t reflect.Type, tag string) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var required bool
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				if n, err := strconv.Atoi(value); err == nil && schema.Type == "integer" {
+					schema.Enum = append(schema.Enum, n)
+				} else {
+					schema.Enum = append(schema.Enum, value)
+				}
+			}
+		case "min", "max":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			switch t.Kind() {
+			case reflect.String:
+				if name == "min" {
+					schema.MinLength = &n
+				} else {
+					schema.MaxLength = &n
+				}
+			case reflect.Slice, reflect.Array, reflect.Map:
+				if name == "min" {
+					schema.MinItems = &n
+				} else {
+					schema.MaxItems = &n
+				}
+			default:
+				f := float64(n)
+				if name == "min" {
+					schema.Minimum = &f
+				} else {
+					schema.Maximum = &f
+				}
+			}
+		case "datetime":
+			if param == time.DateOnly {
+				schema.Format = "date"
+			}
+		case "email":
+			schema.Format = "email"
+		case "url", "http_url":
+			schema.Format = "uri"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		}
+	}
+	return required
+}
+
+// schemaName is the name of the type without its package, the type
+// arguments of generic types are appended to it, e.g.,
+// `PaginatedResponse[*generated.ListTransactionsRow]` is
+// `PaginatedResponseListTransactionsRow`
+func schemaName(t reflect.Type) string {
+	name, args, generic := strings.Cut(t.Name(), "[")
+	if !generic {
+		return name
+	}
+
+	for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+		arg = strings.TrimLeft(arg, "*[]")
+		name += arg[strings.LastIndex(arg, ".")+1:]
+	}
+	return name
+}
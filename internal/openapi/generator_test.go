@@ -0,0 +1,71 @@
+package openapi
+
+import (
+	"github.com/jackc/pgx/v5/pgtype"
+	is_ "github.com/matryer/is"
+	"testing"
+	"time"
+)
+
+type page[T any] struct {
+	Data  []T `json:"data"`
+	Total int `json:"total"`
+}
+
+type item struct {
+	UUID        string      `json:"uuid" validate:"required"`
+	Kind        string      `json:"kind" validate:"required,oneof=asset liability"`
+	Name        string      `json:"name,omitempty" validate:"max=255"`
+	Description pgtype.Text `json:"description"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Parent      *item       `json:"parent,omitempty"`
+	Tags        []string    `json:"tags" validate:"dive,required"`
+	internal    string
+}
+
+type query struct {
+	From  string `form:"from" validate:"omitempty,datetime=2006-01-02"`
+	Limit int32  `form:"limit" validate:"min=1,max=1000"`
+	Other string
+}
+
+func TestRequest(t *testing.T) {
+	is := is_.New(t)
+
+	g := NewGenerator()
+	is.Equal(g.Request(item{}), Ref("item"))
+
+	schema := g.Schemas()["item"]
+	is.Equal(schema.Required, []string{"uuid", "kind"}) // validated as required
+	is.Equal(schema.Properties["kind"].Enum, []any{"asset", "liability"})
+	is.Equal(*schema.Properties["name"].MaxLength, 255)
+	is.Equal(schema.Properties["description"], &Schema{Type: "string", Nullable: true})
+	is.Equal(schema.Properties["created_at"], &Schema{Type: "string", Format: "date-time"})
+	is.Equal(schema.Properties["parent"], Ref("item")) // references itself
+	is.Equal(schema.Properties["tags"].Items, &Schema{Type: "string"})
+	is.Equal(len(schema.Properties), 7) // unexported fields are left out
+}
+
+func TestResponse(t *testing.T) {
+	is := is_.New(t)
+
+	g := NewGenerator()
+	is.Equal(g.Response(page[*item]{}), Ref("pageitem"))
+
+	schema := g.Schemas()["pageitem"]
+	is.Equal(schema.Required, []string{"data", "total"}) // always sent
+	is.Equal(schema.Properties["data"].Items, Ref("item"))
+	is.Equal(g.Schemas()["item"].Required, []string{"uuid", "kind", "description", "created_at", "tags"})
+}
+
+func TestQuery(t *testing.T) {
+	is := is_.New(t)
+
+	params := NewGenerator().Query(query{})
+	is.Equal(len(params), 2) // fields without a form tag are left out
+	is.Equal(params[0].Name, "from")
+	is.Equal(params[0].Schema.Format, "date")
+	is.True(!params[0].Required)
+	is.Equal(*params[1].Schema.Minimum, 1.0)
+	is.Equal(*params[1].Schema.Maximum, 1000.0)
+}
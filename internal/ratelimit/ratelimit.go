@@ -0,0 +1,241 @@
+// Package ratelimit limits the requests of each client with token buckets.
+// The buckets live in memory, each instance of the service limits the
+// requests it serves.
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit allows Requests every Per, all of them at once at most. The zero
+// Limit doesn't limit anything.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Unlimited reports whether the limit lets every request through
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Per <= 0
+}
+
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "off"
+	}
+	for unit, d := range units {
+		if l.Per == d {
+			return fmt.Sprintf("%d/%s", l.Requests, unit)
+		}
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Per)
+}
+
+// MarshalText writes the limit in the format of ParseLimit
+func (l Limit) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText reads the limit with ParseLimit
+func (l *Limit) UnmarshalText(text []byte) error {
+	parsed, err := ParseLimit(string(text))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
+
+var units = map[string]time.Duration{
+	"s": time.Second,
+	"m": time.Minute,
+	"h": time.Hour,
+}
+
+// ParseLimit reads a limit like `600/m`, the period is `s`, `m`, `h` or
+// any duration, e.g., `50/10s`. `off` disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "off" {
+		return Limit{}, nil
+	}
+
+	requests, per, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("invalid limit %q, e.g., 600/m", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("invalid limit %q, requests must be a positive number", s)
+	}
+
+	d, ok := units[per]
+	if !ok {
+		d, err = time.ParseDuration(per)
+		if err != nil || d <= 0 {
+			return Limit{}, fmt.Errorf("invalid limit %q, unknown period %q", s, per)
+		}
+	}
+
+	return Limit{Requests: n, Per: d}, nil
+}
+
+// Result of a request against a limit
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// RetryAfter is how long until the next request is allowed, zero when
+	// this one was
+	RetryAfter time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// sweepEvery is how often the buckets that filled up again are dropped,
+// they are the same as new ones
+const sweepEvery = time.Minute
+
+// Limiter keeps a token bucket per key, e.g., per API key or client IP
+type Limiter struct {
+	limit Limit
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func NewLimiter(limit Limit) *Limiter {
+	return &Limiter{
+		limit:   limit,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Limit is the limit of every key
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Allow takes a token of the key's bucket if there is one. Results of
+// unlimited limiters have a zero Limit.
+func (l *Limiter) Allow(key string) Result {
+	return l.take(key, true)
+}
+
+// Peek tells whether Allow would allow the key, without taking a token
+func (l *Limiter) Peek(key string) Result {
+	return l.take(key, false)
+}
+
+func (l *Limiter) take(key string, take bool) Result {
+	if l.limit.Unlimited() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	capacity := float64(l.limit.Requests)
+	// tokens per second
+	rate := capacity / l.limit.Per.Seconds()
+
+	if now.Sub(l.lastSweep) >= sweepEvery {
+		l.sweep(now, capacity, rate)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: capacity, last: now}
+		l.buckets[key] = b
+	}
+
+	// refill since the last request
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	res := Result{Limit: l.limit.Requests}
+	if b.tokens >= 1 {
+		if take {
+			b.tokens--
+		}
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - b.tokens) / rate)
+	}
+	res.Remaining = int(b.tokens)
+	res.Reset = seconds((capacity - b.tokens) / rate)
+
+	return res
+}
+
+// sweep drops the buckets that are full by now
+func (l *Limiter) sweep(now time.Time, capacity, rate float64) {
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= capacity {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+// Group is a group of requests limited together, by their cost
+type Group string
+
+const (
+	GroupRead   Group = "read"
+	GroupWrite  Group = "write"
+	GroupExport Group = "export"
+	// GroupAuth limits the failed authentications of a client IP, so
+	// credentials can't be guessed
+	GroupAuth Group = "auth"
+)
+
+// Limiters has a limiter per group. The HTTP and gRPC APIs share them, so
+// a client has a single budget across both.
+type Limiters map[Group]*Limiter
+
+// Allow takes a token of the key's bucket in the limiter of the group,
+// groups without a limiter are unlimited
+func (l Limiters) Allow(group Group, key string) Result {
+	limiter, ok := l[group]
+	if !ok {
+		return Result{Allowed: true}
+	}
+	return limiter.Allow(key)
+}
+
+// Peek tells whether Allow would allow the key, without taking a token
+func (l Limiters) Peek(group Group, key string) Result {
+	limiter, ok := l[group]
+	if !ok {
+		return Result{Allowed: true}
+	}
+	return limiter.Peek(key)
+}
+
+// Limit is the limit of the group
+func (l Limiters) Limit(group Group) Limit {
+	limiter, ok := l[group]
+	if !ok {
+		return Limit{}
+	}
+	return limiter.Limit()
+}
@@ -0,0 +1,79 @@
+package ratelimit
+
+import (
+	is_ "github.com/matryer/is"
+	"testing"
+	"time"
+)
+
+func TestParseLimit(t *testing.T) {
+	is := is_.New(t)
+
+	limit, err := ParseLimit("600/m")
+	is.NoErr(err)
+	is.Equal(limit, Limit{Requests: 600, Per: time.Minute})
+	is.Equal(limit.String(), "600/m")
+
+	limit, err = ParseLimit("50/10s")
+	is.NoErr(err)
+	is.Equal(limit, Limit{Requests: 50, Per: 10 * time.Second})
+
+	limit, err = ParseLimit("off")
+	is.NoErr(err)
+	is.True(limit.Unlimited())
+
+	for _, invalid := range []string{"600", "0/m", "x/m", "10/fortnight"} {
+		_, err = ParseLimit(invalid)
+		is.True(err != nil) // invalid limit accepted
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	is := is_.New(t)
+
+	now := time.Now()
+	limiter := NewLimiter(Limit{Requests: 2, Per: time.Second})
+	limiter.now = func() time.Time { return now }
+
+	res := limiter.Allow("a")
+	is.True(res.Allowed)
+	is.Equal(res.Remaining, 1)
+
+	res = limiter.Allow("a")
+	is.True(res.Allowed)
+	is.Equal(res.Remaining, 0)
+	is.Equal(res.Reset, time.Second) // empty bucket
+
+	res = limiter.Allow("a")
+	is.True(!res.Allowed)                          // burst exhausted
+	is.Equal(res.RetryAfter, 500*time.Millisecond) // one token every 500ms
+
+	res = limiter.Allow("b")
+	is.True(res.Allowed) // keys have their own bucket
+
+	now = now.Add(500 * time.Millisecond)
+	res = limiter.Allow("a")
+	is.True(res.Allowed) // refilled
+
+	now = now.Add(time.Hour)
+	limiter.Allow("c")
+	is.Equal(len(limiter.buckets), 1) // full buckets are dropped
+
+	res = NewLimiter(Limit{}).Allow("a")
+	is.True(res.Allowed)
+	is.Equal(res.Limit, 0)
+}
+
+func TestLimiterPeek(t *testing.T) {
+	is := is_.New(t)
+
+	limiter := NewLimiter(Limit{Requests: 1, Per: time.Minute})
+
+	is.True(limiter.Peek("a").Allowed)
+	is.True(limiter.Peek("a").Allowed) // peeking doesn't take a token
+	is.True(limiter.Allow("a").Allowed)
+
+	res := limiter.Peek("a")
+	is.True(!res.Allowed) // bucket empty
+	is.True(res.RetryAfter > 0)
+}
@@ -0,0 +1,215 @@
+// Package report builds the financial reports of a ledger from account
+// balances: trial balance, balance sheet, income statement and account
+// statement. Reports are plain structs that serialize to JSON and can be
+// rendered as Excel workbooks.
+package report
+
+import (
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"strings"
+)
+
+// Balance is the total debited and credited to an account in a period
+type Balance struct {
+	AccountUUID string
+	Name        string
+	Type        dbGen.AccountType
+	Debit       int64
+	Credit      int64
+}
+
+// Period is the inclusive date range a report covers, empty means open
+type Period struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// Line is an account in a report. Balance uses the normal sign of the
+// account type, e.g., a positive liability balance is a credit balance.
+type Line struct {
+	AccountUUID string `json:"account_uuid"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Depth       int    `json:"depth"`
+	Debit       int64  `json:"debit"`
+	Credit      int64  `json:"credit"`
+	Balance     int64  `json:"balance"`
+}
+
+// Section is a group of accounts of the same type with its total
+type Section struct {
+	Name  string `json:"name"`
+	Lines []Line `json:"lines"`
+	Total int64  `json:"total"`
+}
+
+type TrialBalance struct {
+	Period      Period `json:"period"`
+	Lines       []Line `json:"lines"`
+	TotalDebit  int64  `json:"total_debit"`
+	TotalCredit int64  `json:"total_credit"`
+}
+
+type BalanceSheet struct {
+	Period      Period  `json:"period"`
+	Assets      Section `json:"assets"`
+	Liabilities Section `json:"liabilities"`
+	Equity      Section `json:"equity"`
+	// NetIncome is the revenue minus the expenses not yet closed to equity
+	NetIncome                 int64 `json:"net_income"`
+	TotalLiabilitiesAndEquity int64 `json:"total_liabilities_and_equity"`
+}
+
+type IncomeStatement struct {
+	Period    Period  `json:"period"`
+	Revenue   Section `json:"revenue"`
+	Expenses  Section `json:"expenses"`
+	NetIncome int64   `json:"net_income"`
+}
+
+type StatementAccount struct {
+	UUID       string `json:"uuid"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	LedgerUUID string `json:"ledger_uuid"`
+}
+
+// Entry is a transaction in an account statement, Balance is the running
+// balance after the entry.
+type Entry struct {
+	TransactionUUID string `json:"transaction_uuid"`
+	Date            string `json:"date"`
+	Description     string `json:"description"`
+	CounterpartUUID string `json:"counterpart_uuid"`
+	CounterpartName string `json:"counterpart_name"`
+	Debit           int64  `json:"debit"`
+	Credit          int64  `json:"credit"`
+	Balance         int64  `json:"balance"`
+}
+
+type AccountStatement struct {
+	Account        StatementAccount `json:"account"`
+	Period         Period           `json:"period"`
+	OpeningBalance int64            `json:"opening_balance"`
+	Entries        []Entry          `json:"entries"`
+	ClosingBalance int64            `json:"closing_balance"`
+}
+
+// NewTrialBalance lists every account with its debit and credit totals
+func NewTrialBalance(period Period, balances []Balance) *TrialBalance {
+	tb := &TrialBalance{
+		Period: period,
+		Lines:  make([]Line, 0, len(balances)),
+	}
+
+	for _, b := range balances {
+		tb.Lines = append(tb.Lines, NewLine(b))
+		tb.TotalDebit += b.Debit
+		tb.TotalCredit += b.Credit
+	}
+
+	return tb
+}
+
+// NewBalanceSheet groups the asset, liability and equity accounts. The
+// balances must be cumulative up to the end of the period.
+func NewBalanceSheet(period Period, balances []Balance) *BalanceSheet {
+	bs := &BalanceSheet{
+		Period:      period,
+		Assets:      newSection("Assets", balances, dbGen.AccountTypeAsset),
+		Liabilities: newSection("Liabilities", balances, dbGen.AccountTypeLiability),
+		Equity:      newSection("Equity", balances, dbGen.AccountTypeEquity),
+	}
+
+	bs.NetIncome = netIncome(balances)
+	bs.TotalLiabilitiesAndEquity = bs.Liabilities.Total + bs.Equity.Total + bs.NetIncome
+
+	return bs
+}
+
+// NewIncomeStatement groups the revenue and expense accounts
+func NewIncomeStatement(period Period, balances []Balance) *IncomeStatement {
+	return &IncomeStatement{
+		Period:    period,
+		Revenue:   newSection("Revenue", balances, dbGen.AccountTypeRevenue),
+		Expenses:  newSection("Expenses", balances, dbGen.AccountTypeExpense),
+		NetIncome: netIncome(balances),
+	}
+}
+
+// NewAccountStatement computes the running balance of the entries. The
+// opening balance is expressed as debits minus credits.
+func NewAccountStatement(account StatementAccount, period Period, opening int64, entries []Entry) *AccountStatement {
+	accountType := dbGen.AccountType(account.Type)
+
+	st := &AccountStatement{
+		Account:        account,
+		Period:         period,
+		OpeningBalance: normalBalance(accountType, opening, 0),
+		Entries:        entries,
+	}
+
+	balance := st.OpeningBalance
+	for i := range st.Entries {
+		balance += normalBalance(accountType, st.Entries[i].Debit, st.Entries[i].Credit)
+		st.Entries[i].Balance = balance
+	}
+	st.ClosingBalance = balance
+
+	return st
+}
+
+func newSection(name string, balances []Balance, accountType dbGen.AccountType) Section {
+	section := Section{
+		Name:  name,
+		Lines: []Line{},
+	}
+
+	for _, b := range balances {
+		if b.Type != accountType {
+			continue
+		}
+		line := NewLine(b)
+		section.Lines = append(section.Lines, line)
+		section.Total += line.Balance
+	}
+
+	return section
+}
+
+// NewLine is the line of an account with its balance in the normal sign
+func NewLine(b Balance) Line {
+	return Line{
+		AccountUUID: b.AccountUUID,
+		Name:        b.Name,
+		Type:        string(b.Type),
+		Depth:       strings.Count(b.Name, ":"),
+		Debit:       b.Debit,
+		Credit:      b.Credit,
+		Balance:     normalBalance(b.Type, b.Debit, b.Credit),
+	}
+}
+
+func netIncome(balances []Balance) int64 {
+	var income int64
+	for _, b := range balances {
+		switch b.Type {
+		case dbGen.AccountTypeRevenue:
+			income += b.Credit - b.Debit
+		case dbGen.AccountTypeExpense:
+			income -= b.Debit - b.Credit
+		}
+	}
+	return income
+}
+
+// normalBalance signs the balance so that it is positive when the account
+// has its usual balance: debit for assets and expenses, credit otherwise.
+func normalBalance(accountType dbGen.AccountType, debit, credit int64) int64 {
+	switch accountType {
+	case dbGen.AccountTypeAsset, dbGen.AccountTypeExpense:
+		return debit - credit
+	default:
+		return credit - debit
+	}
+}
@@ -0,0 +1,76 @@
+package report
+
+import (
+	"bytes"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	is_ "github.com/matryer/is"
+	"github.com/xuri/excelize/v2"
+	"testing"
+)
+
+var balances = []Balance{
+	{AccountUUID: "a1", Name: "Assets:Bank", Type: dbGen.AccountTypeAsset, Debit: 150000, Credit: 20000},
+	{AccountUUID: "a2", Name: "Equity:Opening", Type: dbGen.AccountTypeEquity, Credit: 100000},
+	{AccountUUID: "a3", Name: "Expenses:Food", Type: dbGen.AccountTypeExpense, Debit: 20000},
+	{AccountUUID: "a4", Name: "Income:Salary", Type: dbGen.AccountTypeRevenue, Credit: 50000},
+}
+
+func TestReports(t *testing.T) {
+	is := is_.New(t)
+
+	tb := NewTrialBalance(Period{}, balances)
+	is.Equal(tb.TotalDebit, tb.TotalCredit)
+	is.Equal(tb.Lines[0].Balance, int64(130000))
+	is.Equal(tb.Lines[0].Depth, 1)
+
+	bs := NewBalanceSheet(Period{To: "2024-12-31"}, balances)
+	is.Equal(bs.Assets.Total, int64(130000))
+	is.Equal(bs.Equity.Total, int64(100000))
+	is.Equal(bs.NetIncome, int64(30000))
+	is.Equal(bs.Assets.Total, bs.TotalLiabilitiesAndEquity)
+	is.Equal(len(bs.Liabilities.Lines), 0)
+
+	inc := NewIncomeStatement(Period{}, balances)
+	is.Equal(inc.Revenue.Total, int64(50000))
+	is.Equal(inc.Expenses.Total, int64(20000))
+	is.Equal(inc.NetIncome, int64(30000))
+}
+
+func TestAccountStatement(t *testing.T) {
+	is := is_.New(t)
+
+	account := StatementAccount{UUID: "l1", Name: "Liabilities:Card", Type: string(dbGen.AccountTypeLiability)}
+	entries := []Entry{
+		{TransactionUUID: "t1", Credit: 5000},
+		{TransactionUUID: "t2", Debit: 2000},
+	}
+
+	// opening balance of a liability is a credit balance
+	st := NewAccountStatement(account, Period{}, -1000, entries)
+	is.Equal(st.OpeningBalance, int64(1000))
+	is.Equal(st.Entries[0].Balance, int64(6000))
+	is.Equal(st.Entries[1].Balance, int64(4000))
+	is.Equal(st.ClosingBalance, int64(4000))
+}
+
+func TestWriteXLSX(t *testing.T) {
+	is := is_.New(t)
+
+	var buf bytes.Buffer
+	err := WriteXLSX(&buf, NewBalanceSheet(Period{}, balances), 2)
+	is.NoErr(err)
+
+	f, err := excelize.OpenReader(&buf)
+	is.NoErr(err)
+	defer f.Close()
+
+	is.Equal(f.GetSheetList(), []string{"Balance Sheet", "Assets", "Liabilities", "Equity"})
+
+	value, err := f.GetCellValue("Assets", "B2")
+	is.NoErr(err)
+	is.Equal(value, "1,300.00")
+
+	value, err = f.GetCellValue("Assets", "A3")
+	is.NoErr(err)
+	is.Equal(value, "Total assets")
+}
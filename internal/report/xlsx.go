@@ -0,0 +1,277 @@
+package report
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"io"
+	"math"
+	"strings"
+)
+
+// XLSXContentType is the media type of the workbooks written by WriteXLSX
+const XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Report is implemented by every report that can be rendered as a workbook
+type Report interface {
+	Sheets() []Sheet
+}
+
+// Sheet is a table rendered as a worksheet of the workbook
+type Sheet struct {
+	Name   string
+	Header []string
+	Rows   []Row
+}
+
+// Row is a row of a sheet. Cells of type Amount are converted to numbers
+// in major units, Indent is applied to the first cell.
+type Row struct {
+	Cells  []any
+	Indent int
+	Bold   bool
+}
+
+// Amount is an amount in minor units
+type Amount int64
+
+// WriteXLSX renders the sheets of the report as a workbook. Amounts are
+// divided by 10^scale, e.g., with scale 2, 1050 becomes 10.50.
+func WriteXLSX(w io.Writer, r Report, scale int) error {
+	f := excelize.NewFile()
+	defer func() { _ = f.Close() }()
+
+	styles := newStyleCache(f, scale)
+
+	for i, sheet := range r.Sheets() {
+		if i == 0 {
+			if err := f.SetSheetName(f.GetSheetName(0), sheet.Name); err != nil {
+				return fmt.Errorf("rename sheet: %w", err)
+			}
+		} else if _, err := f.NewSheet(sheet.Name); err != nil {
+			return fmt.Errorf("add sheet %q: %w", sheet.Name, err)
+		}
+
+		if err := writeSheet(f, styles, sheet, scale); err != nil {
+			return fmt.Errorf("write sheet %q: %w", sheet.Name, err)
+		}
+	}
+
+	if err := f.Write(w); err != nil {
+		return fmt.Errorf("write workbook: %w", err)
+	}
+
+	return nil
+}
+
+func writeSheet(f *excelize.File, styles *styleCache, sheet Sheet, scale int) error {
+	header := make([]any, len(sheet.Header))
+	for i, h := range sheet.Header {
+		header[i] = h
+	}
+	rows := append([]Row{{Cells: header, Bold: true}}, sheet.Rows...)
+
+	for i, row := range rows {
+		for j, value := range row.Cells {
+			cell, err := excelize.CoordinatesToCellName(j+1, i+1)
+			if err != nil {
+				return err
+			}
+
+			indent := 0
+			if j == 0 {
+				indent = row.Indent
+			}
+
+			_, isAmount := value.(Amount)
+			style, err := styles.get(indent, row.Bold, isAmount)
+			if err != nil {
+				return err
+			}
+
+			if amount, ok := value.(Amount); ok {
+				value = float64(amount) / math.Pow10(scale)
+			}
+
+			if err := f.SetCellValue(sheet.Name, cell, value); err != nil {
+				return err
+			}
+			if err := f.SetCellStyle(sheet.Name, cell, cell, style); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(sheet.Header) > 0 {
+		if err := f.SetColWidth(sheet.Name, "A", "A", 40); err != nil {
+			return err
+		}
+	}
+	if len(sheet.Header) > 1 {
+		last, err := excelize.ColumnNumberToName(len(sheet.Header))
+		if err != nil {
+			return err
+		}
+		if err := f.SetColWidth(sheet.Name, "B", last, 18); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type styleKey struct {
+	indent int
+	bold   bool
+	amount bool
+}
+
+// styleCache creates each combination of cell style once per workbook
+type styleCache struct {
+	f      *excelize.File
+	numFmt string
+	styles map[styleKey]int
+}
+
+func newStyleCache(f *excelize.File, scale int) *styleCache {
+	numFmt := "#,##0"
+	if scale > 0 {
+		numFmt += "." + strings.Repeat("0", scale)
+	}
+
+	return &styleCache{
+		f:      f,
+		numFmt: numFmt,
+		styles: map[styleKey]int{},
+	}
+}
+
+func (c *styleCache) get(indent int, bold, amount bool) (int, error) {
+	key := styleKey{indent: indent, bold: bold, amount: amount}
+	if id, ok := c.styles[key]; ok {
+		return id, nil
+	}
+
+	style := &excelize.Style{
+		Font: &excelize.Font{Bold: bold},
+	}
+	if indent > 0 {
+		style.Alignment = &excelize.Alignment{Indent: indent}
+	}
+	if amount {
+		style.CustomNumFmt = &c.numFmt
+	}
+
+	id, err := c.f.NewStyle(style)
+	if err != nil {
+		return 0, fmt.Errorf("create style: %w", err)
+	}
+	c.styles[key] = id
+
+	return id, nil
+}
+
+// Sheets renders the trial balance as a single sheet
+func (tb *TrialBalance) Sheets() []Sheet {
+	sheet := Sheet{
+		Name:   "Trial Balance",
+		Header: []string{"Account", "Type", "Debit", "Credit", "Balance"},
+	}
+
+	for _, l := range tb.Lines {
+		sheet.Rows = append(sheet.Rows, Row{
+			Cells:  []any{l.Name, l.Type, Amount(l.Debit), Amount(l.Credit), Amount(l.Balance)},
+			Indent: l.Depth,
+		})
+	}
+	sheet.Rows = append(sheet.Rows, Row{
+		Cells: []any{"Total", "", Amount(tb.TotalDebit), Amount(tb.TotalCredit)},
+		Bold:  true,
+	})
+
+	return []Sheet{sheet}
+}
+
+// Sheets renders a summary sheet followed by a sheet per section
+func (bs *BalanceSheet) Sheets() []Sheet {
+	summary := Sheet{
+		Name:   "Balance Sheet",
+		Header: []string{"", "Amount"},
+		Rows: []Row{
+			{Cells: []any{"Total assets", Amount(bs.Assets.Total)}},
+			{Cells: []any{"Total liabilities", Amount(bs.Liabilities.Total)}},
+			{Cells: []any{"Total equity", Amount(bs.Equity.Total)}},
+			{Cells: []any{"Net income", Amount(bs.NetIncome)}},
+			{Cells: []any{"Total liabilities and equity", Amount(bs.TotalLiabilitiesAndEquity)}, Bold: true},
+		},
+	}
+
+	return []Sheet{
+		summary,
+		sectionSheet(bs.Assets),
+		sectionSheet(bs.Liabilities),
+		sectionSheet(bs.Equity),
+	}
+}
+
+// Sheets renders a summary sheet followed by a sheet per section
+func (is *IncomeStatement) Sheets() []Sheet {
+	summary := Sheet{
+		Name:   "Income Statement",
+		Header: []string{"", "Amount"},
+		Rows: []Row{
+			{Cells: []any{"Total revenue", Amount(is.Revenue.Total)}},
+			{Cells: []any{"Total expenses", Amount(is.Expenses.Total)}},
+			{Cells: []any{"Net income", Amount(is.NetIncome)}, Bold: true},
+		},
+	}
+
+	return []Sheet{
+		summary,
+		sectionSheet(is.Revenue),
+		sectionSheet(is.Expenses),
+	}
+}
+
+// Sheets renders the entries between the opening and closing balances
+func (st *AccountStatement) Sheets() []Sheet {
+	sheet := Sheet{
+		Name:   "Statement",
+		Header: []string{"Date", "Description", "Counterpart", "Debit", "Credit", "Balance"},
+	}
+
+	sheet.Rows = append(sheet.Rows, Row{
+		Cells: []any{st.Period.From, "Opening balance", "", "", "", Amount(st.OpeningBalance)},
+		Bold:  true,
+	})
+	for _, e := range st.Entries {
+		sheet.Rows = append(sheet.Rows, Row{
+			Cells: []any{e.Date, e.Description, e.CounterpartName, Amount(e.Debit), Amount(e.Credit), Amount(e.Balance)},
+		})
+	}
+	sheet.Rows = append(sheet.Rows, Row{
+		Cells: []any{st.Period.To, "Closing balance", "", "", "", Amount(st.ClosingBalance)},
+		Bold:  true,
+	})
+
+	return []Sheet{sheet}
+}
+
+func sectionSheet(section Section) Sheet {
+	sheet := Sheet{
+		Name:   section.Name,
+		Header: []string{"Account", "Balance"},
+	}
+
+	for _, l := range section.Lines {
+		sheet.Rows = append(sheet.Rows, Row{
+			Cells:  []any{l.Name, Amount(l.Balance)},
+			Indent: l.Depth,
+		})
+	}
+	sheet.Rows = append(sheet.Rows, Row{
+		Cells: []any{"Total " + strings.ToLower(section.Name), Amount(section.Total)},
+		Bold:  true,
+	})
+
+	return sheet
+}
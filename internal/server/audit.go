@@ -0,0 +1,109 @@
+package server
+
+import (
+	"encoding/json"
+	"github.com/go-playground/form/v4"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type ListAuditEventsQuery struct {
+	Entity     string `form:"entity" json:"entity" validate:"omitempty,oneof=ledger account transaction"`
+	UUID       string `form:"uuid" json:"uuid"`
+	LedgerUUID string `form:"ledger_uuid" json:"ledger_uuid"`
+	Limit      int32  `form:"limit" json:"limit" validate:"min=1,max=1000"`
+	Offset     int32  `form:"offset" json:"offset" validate:"min=0"`
+}
+
+type AuditEventResponse struct {
+	UUID       string          `json:"uuid"`
+	CreatedAt  time.Time       `json:"created_at"`
+	Actor      string          `json:"actor"`
+	Action     string          `json:"action"`
+	EntityType string          `json:"entity_type"`
+	EntityUUID string          `json:"entity_uuid"`
+	Before     json.RawMessage `json:"before"`
+	After      json.RawMessage `json:"after"`
+	RequestID  *string         `json:"request_id"`
+}
+
+// HandleListAuditEvents returns the audit events, newest first, optionally
+// filtered by entity type and uuid, e.g., `?entity=transaction&uuid=abc`.
+func (s *Server) HandleListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	decoder := form.NewDecoder()
+
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "audit.list.start")
+
+	// default values
+	query := ListAuditEventsQuery{
+		Limit:  30,
+		Offset: 0,
+	}
+
+	// decode the query params
+	if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+		slog.InfoContext(r.Context(), "unable to decode query params", "error", err)
+		slog.DebugContext(r.Context(), "query params decoding", "query_params", r.URL.Query())
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	startQueryTime := time.Now()
+
+	events, err := s.ledger.ListAuditEvents(r.Context(), ledger.ListAuditEventsParams{
+		Entity:     query.Entity,
+		UUID:       query.UUID,
+		LedgerUUID: query.LedgerUUID,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+	})
+	if err != nil {
+		slog.DebugContext(r.Context(), "audit events listing", "query", query)
+		writeLedgerError(w, r, "unable to list audit events", err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "audit events listing",
+		"events_count", len(events),
+		"query_time", time.Since(startQueryTime),
+	)
+
+	detail := make([]AuditEventResponse, 0, len(events))
+	for _, event := range events {
+		detail = append(detail, AuditEventResponse{
+			UUID:       event.Uuid,
+			CreatedAt:  event.CreatedAt.Time,
+			Actor:      event.Actor,
+			Action:     event.Action,
+			EntityType: event.EntityType,
+			EntityUUID: event.EntityUuid,
+			Before:     rawJSONOrNull(event.Before),
+			After:      rawJSONOrNull(event.After),
+			RequestID:  textPtr(event.RequestID),
+		})
+	}
+
+	res := NewResponse("OK", len(detail), "LIST", detail)
+	err = WriteResponse(w, http.StatusOK, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "audit events listed", "count", len(detail))
+	slog.DebugContext(r.Context(), "audit.list.complete",
+		"events_count", len(detail),
+		"duration", time.Since(startReqTime),
+	)
+}
+
+func rawJSONOrNull(b []byte) json.RawMessage {
+	if len(b) == 0 {
+		return json.RawMessage("null")
+	}
+	return b
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"errors"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/db"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// authenticate resolves the API key or the JWT of the
+// `Authorization: Bearer` header into the principal of the request, and
+// scopes its queries to the tenant of the principal. Requests without the
+// header go through unauthenticated, it's up to requireScope to reject
+// them. Client IPs over the limit of failed authentications are rejected
+// before their credentials are checked.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ip := clientIP(r)
+		if res := s.limiters.Peek(ratelimit.GroupAuth, ip); !res.Allowed {
+			slog.InfoContext(r.Context(), "failed authentication limit exceeded", "key", ip)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			WriteError(w, ErrTooManyRequests, http.StatusTooManyRequests)
+			return
+		}
+
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			slog.InfoContext(r.Context(), "unsupported authorization scheme")
+			s.limiters.Allow(ratelimit.GroupAuth, ip)
+			writeUnauthorized(w)
+			return
+		}
+		token = strings.TrimSpace(token)
+
+		principal, err := s.authenticator.Authenticate(r.Context(), token)
+		if err != nil {
+			if errors.Is(err, auth.ErrInvalidCredentials) {
+				slog.InfoContext(r.Context(), "unable to authenticate", "error", err)
+				s.limiters.Allow(ratelimit.GroupAuth, ip)
+				writeUnauthorized(w)
+				return
+			}
+
+			slog.ErrorContext(r.Context(), "unable to authenticate", "error", err)
+			WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+			return
+		}
+
+		ctx := auth.WithPrincipal(r.Context(), principal)
+		ctx = db.WithTenant(ctx, principal.TenantID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireScope rejects unauthenticated requests and the ones whose key
+// wasn't granted the scope
+func requireScope(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal := auth.PrincipalFromContext(r.Context())
+		if principal == nil {
+			slog.InfoContext(r.Context(), "missing credentials", "path", r.URL.Path)
+			writeUnauthorized(w)
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			slog.InfoContext(r.Context(), "principal lacks scope", "key_uuid", principal.KeyUUID, "user_uuid", principal.UserUUID, "scope", scope)
+			WriteError(w, ErrForbidden, http.StatusForbidden)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+func writeUnauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", "Bearer")
+	WriteError(w, ErrUnauthorized, http.StatusUnauthorized)
+}
@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/auth"
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireScope(t *testing.T) {
+	is := is_.New(t)
+
+	handler := requireScope(auth.ScopeLedgersWrite, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serve := func(principal *auth.Principal) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/ledgers", nil)
+		if principal != nil {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		}
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	w := serve(nil)
+	is.Equal(w.Code, http.StatusUnauthorized)              // missing key
+	is.Equal(w.Header().Get("WWW-Authenticate"), "Bearer") // invalid challenge
+
+	w = serve(&auth.Principal{Scopes: []string{auth.ScopeLedgersRead}})
+	is.Equal(w.Code, http.StatusForbidden) // missing scope
+
+	w = serve(&auth.Principal{Scopes: []string{auth.ScopeLedgersWrite}})
+	is.Equal(w.Code, http.StatusNoContent) // granted scope
+}
@@ -0,0 +1,19 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"log/slog"
+	"net/http"
+)
+
+// tenantOnly rejects users, the route manages the whole tenant
+func tenantOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if principal := auth.PrincipalFromContext(r.Context()); principal != nil && principal.UserUUID != "" {
+			slog.InfoContext(r.Context(), "tenant route denied to user", "user_uuid", principal.UserUUID, "path", r.URL.Path)
+			WriteError(w, ErrForbidden, http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
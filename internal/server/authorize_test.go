@@ -0,0 +1,44 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/auth"
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// the handlers leave the roles to the ledger service
+func TestAuthorize(t *testing.T) {
+	is := is_.New(t)
+
+	s := NewServer(nil, nil, Options{})
+
+	r := httptest.NewRequest("GET", "/audit", nil)
+	r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{KeyUUID: "key", UserUUID: "user"}))
+	w := httptest.NewRecorder()
+	s.HandleListAuditEvents(w, r)
+	is.Equal(w.Code, http.StatusBadRequest) // users must name the ledger
+}
+
+func TestTenantOnly(t *testing.T) {
+	is := is_.New(t)
+
+	handler := tenantOnly(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	serve := func(principal *auth.Principal) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/webhooks", nil)
+		r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w
+	}
+
+	w := serve(&auth.Principal{KeyUUID: "key"})
+	is.Equal(w.Code, http.StatusNoContent) // tenant key
+
+	w = serve(&auth.Principal{KeyUUID: "key", UserUUID: "user"})
+	is.Equal(w.Code, http.StatusForbidden) // user key
+}
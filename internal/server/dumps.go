@@ -0,0 +1,207 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/jackc/pgx/v5/pgtype"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// the dump format is the one of the ledger service
+type (
+	LedgerDump      = ledger.LedgerDump
+	DumpLedger      = ledger.DumpLedger
+	DumpAccount     = ledger.DumpAccount
+	DumpTransaction = ledger.DumpTransaction
+)
+
+// HandleDumpLedger returns the ledger, its accounts and its transactions
+// as a single versioned JSON document that HandleRestoreLedger accepts.
+// The transactions are read through a cursor and written as they come, so
+// the memory used doesn't depend on the size of the ledger.
+func (s *Server) HandleDumpLedger(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "ledger.dump.start")
+
+	ledgerUUID := r.PathValue("id")
+
+	startQueryTime := time.Now()
+
+	rc := http.NewResponseController(w)
+	dw := &dumpWriter{w: w}
+	sent := false
+	accountsCount := 0
+
+	err := s.ledger.StreamDump(
+		r.Context(),
+		ledgerUUID,
+		func(dump *LedgerDump) error {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="ledger-%s.json"`, ledgerUUID))
+			w.WriteHeader(http.StatusOK)
+			sent = true
+			accountsCount = len(dump.Accounts)
+
+			return dw.head(dump)
+		},
+		func(transactions []DumpTransaction) error {
+			if err := dw.write(transactions); err != nil {
+				return err
+			}
+			return rc.Flush()
+		},
+	)
+	if err == nil {
+		err = dw.end()
+	}
+	if err != nil {
+		if !sent {
+			writeLedgerError(w, r, "unable to dump ledger", err)
+			return
+		}
+		// the status code is already sent, the client sees a truncated body
+		slog.ErrorContext(r.Context(), "unable to dump ledger", "error", err)
+		slog.DebugContext(r.Context(), "ledger dump",
+			"uuid", ledgerUUID,
+			"transactions_count", dw.count,
+		)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "ledger dump",
+		"uuid", ledgerUUID,
+		"accounts_count", accountsCount,
+		"transactions_count", dw.count,
+		"query_time", time.Since(startQueryTime),
+	)
+
+	slog.InfoContext(r.Context(), "ledger dumped", "ledger_uuid", ledgerUUID)
+	slog.DebugContext(r.Context(), "ledger.dump.complete",
+		"ledger_uuid", ledgerUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// dumpWriter writes a dump as its transactions are read, they're the last
+// field of the document
+type dumpWriter struct {
+	w     io.Writer
+	count int
+}
+
+// head writes the dump up to its transactions, leaving their array open
+func (d *dumpWriter) head(dump *LedgerDump) error {
+	head := *dump
+	head.Transactions = []DumpTransaction{}
+	b, err := json.Marshal(head)
+	if err != nil {
+		return fmt.Errorf("marshal dump: %w", err)
+	}
+
+	_, err = d.w.Write(bytes.TrimSuffix(b, []byte("]}")))
+	return err
+}
+
+func (d *dumpWriter) write(transactions []DumpTransaction) error {
+	for _, txn := range transactions {
+		b, err := json.Marshal(txn)
+		if err != nil {
+			return fmt.Errorf("marshal transaction %s: %w", txn.UUID, err)
+		}
+		if d.count > 0 {
+			b = append([]byte(","), b...)
+		}
+		if _, err := d.w.Write(b); err != nil {
+			return err
+		}
+		d.count++
+	}
+	return nil
+}
+
+// end closes the transactions and the document
+func (d *dumpWriter) end() error {
+	_, err := io.WriteString(d.w, "]}\n")
+	return err
+}
+
+// HandleRestoreLedger recreates a ledger from a dump, keeping its uuids,
+// metadata and timestamps. The foreign keys are remapped through the uuids.
+// If the ledger already exists the request fails with 409 unless
+// `?replace=true` is given, in which case the existing ledger is deleted
+// first. Everything happens in one database transaction. Dumps over
+// maxUploadSize are rejected with 413.
+func (s *Server) HandleRestoreLedger(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "ledger.restore.start")
+
+	replace, err := strconv.ParseBool(r.URL.Query().Get("replace"))
+	if err != nil && r.URL.Query().Has("replace") {
+		slog.InfoContext(r.Context(), "invalid replace query param", "error", err)
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// decode the request body
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	dump, err := Decode[LedgerDump](r)
+	if err != nil {
+		if isTooLarge(err) {
+			slog.InfoContext(r.Context(), "dump too large", "error", err)
+			WriteError(w, ErrRequestTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+		slog.InfoContext(r.Context(), "unable to decode request body", "error", err)
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	startQueryTime := time.Now()
+
+	if err = s.ledger.Restore(r.Context(), dump, replace); err != nil {
+		writeLedgerError(w, r, "unable to restore ledger", err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "ledger restore",
+		"uuid", dump.Ledger.UUID,
+		"accounts_count", len(dump.Accounts),
+		"transactions_count", len(dump.Transactions),
+		"query_time", time.Since(startQueryTime),
+	)
+
+	detail := ImportLedgerResponse{
+		UUID:         dump.Ledger.UUID,
+		Name:         dump.Ledger.Name,
+		Accounts:     len(dump.Accounts),
+		Transactions: len(dump.Transactions),
+	}
+
+	res := NewResponse("OK", 1, "OBJ", detail)
+	err = WriteResponse(w, http.StatusCreated, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "ledger restored", "ledger_uuid", dump.Ledger.UUID, "replace", replace)
+	slog.DebugContext(r.Context(), "ledger.restore.complete",
+		"ledger_uuid", dump.Ledger.UUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// textPtr converts a nullable text column to a pointer, nil meaning NULL
+func textPtr(t pgtype.Text) *string {
+	if !t.Valid {
+		return nil
+	}
+	return &t.String
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	is_ "github.com/matryer/is"
+	"testing"
+)
+
+func TestDumpWriter(t *testing.T) {
+	dump := &LedgerDump{
+		Version:  1,
+		Ledger:   DumpLedger{UUID: "ledger1", Name: "Ledger"},
+		Accounts: []DumpAccount{{UUID: "cash", Name: "Cash", Type: "asset"}},
+	}
+
+	t.Run("should write the transactions of every batch", func(t *testing.T) {
+		is := is_.New(t)
+
+		var buf bytes.Buffer
+		dw := &dumpWriter{w: &buf}
+		is.NoErr(dw.head(dump))
+		is.NoErr(dw.write([]DumpTransaction{{UUID: "txn1"}, {UUID: "txn2"}}))
+		is.NoErr(dw.write([]DumpTransaction{{UUID: "txn3"}}))
+		is.NoErr(dw.end())
+
+		var written LedgerDump
+		is.NoErr(json.Unmarshal(buf.Bytes(), &written))
+		is.Equal(written.Ledger.UUID, "ledger1")
+		is.Equal(len(written.Accounts), 1)
+		is.Equal(len(written.Transactions), 3)
+		is.Equal(written.Transactions[2].UUID, "txn3")
+		is.Equal(dw.count, 3)
+	})
+
+	t.Run("should write a ledger without transactions", func(t *testing.T) {
+		is := is_.New(t)
+
+		var buf bytes.Buffer
+		dw := &dumpWriter{w: &buf}
+		is.NoErr(dw.head(dump))
+		is.NoErr(dw.end())
+
+		var written map[string]json.RawMessage
+		is.NoErr(json.Unmarshal(buf.Bytes(), &written))
+		is.Equal(string(written["transactions"]), "[]")
+	})
+}
@@ -1,5 +1,12 @@
 package server
 
+import (
+	"errors"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"log/slog"
+	"net/http"
+)
+
 // ErrorResponse is the response sent back to the client when an error occurs
 type ErrorResponse struct {
 	Status  int         `json:"status"`
@@ -11,10 +18,41 @@ const (
 	ErrInternalServerError = "Internal Server Error"
 	ErrInvalidRequest      = "Invalid request"
 	ErrNotFound            = "Not Found"
-
-	//ErrUnauthorized        = "Unauthorized"
-	//ErrForbidden           = "Forbidden"
+	ErrConflict            = "Conflict"
+	ErrUnauthorized        = "Unauthorized"
+	ErrForbidden           = "Forbidden"
+	ErrTooManyRequests     = "Too Many Requests"
+	ErrRequestTooLarge     = "Request Entity Too Large"
 
 	//ErrUserAlreadyExists  = "Email already registered"
 	//ErrInvalidCredentials = "Invalid credentials"
 )
+
+// writeLedgerError answers the errors of the ledger service with their
+// status, msg is logged along with the error
+func writeLedgerError(w http.ResponseWriter, r *http.Request, msg string, err error) {
+	var validationErrors ledger.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrors):
+		slog.InfoContext(r.Context(), msg, "error", err)
+		WriteError(w, map[string][]ValidationError{"errors": validationErrors}, http.StatusBadRequest)
+	case errors.Is(err, ledger.ErrNotFound):
+		slog.InfoContext(r.Context(), msg, "error", err)
+		WriteError(w, ErrNotFound, http.StatusNotFound)
+	case errors.Is(err, ledger.ErrForbidden):
+		slog.InfoContext(r.Context(), msg, "error", err)
+		WriteError(w, ErrForbidden, http.StatusForbidden)
+	case errors.Is(err, ledger.ErrConflict):
+		slog.InfoContext(r.Context(), msg, "error", err)
+		WriteError(w, ErrConflict, http.StatusConflict)
+	case errors.Is(err, ledger.ErrInvalidReference),
+		errors.Is(err, ledger.ErrLedgerRequired),
+		errors.Is(err, ledger.ErrMetadataRequired),
+		errors.Is(err, ledger.ErrNoChanges):
+		slog.InfoContext(r.Context(), msg, "error", err)
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+	default:
+		slog.ErrorContext(r.Context(), msg, "error", err)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,227 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/j0lvera/go-double-e/internal/webhook"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// balanceChangedEvent follows every transaction event with the balance of
+// each account involved right after the change
+const balanceChangedEvent = "balance.changed"
+
+const (
+	// eventsBatchSize is the number of events read from the outbox at a time
+	eventsBatchSize = 500
+	// eventsKeepAlive is how often a comment is sent on idle streams so
+	// proxies don't close them
+	eventsKeepAlive = 15 * time.Second
+	// eventsPendingPoll is how often the outbox is read again while events
+	// are held back, the transaction they wait for may not notify the ledger.
+	// The delay doubles up to eventsMaxPendingPoll while they stay held.
+	eventsPendingPoll    = 250 * time.Millisecond
+	eventsMaxPendingPoll = 5 * time.Second
+	// eventsMaxLag is how long events are held back before they're sent
+	// anyway, any long transaction of the database holds them
+	eventsMaxLag = 30 * time.Second
+)
+
+// HandleLedgerEvents streams the changes of a ledger as Server-Sent Events,
+// in the order they were committed. The id of each event is its id in the
+// outbox, clients resume with the `Last-Event-ID` header, or
+// `?last_event_id=` on the first request. Without either the stream starts
+// with the next change.
+//
+// Events wait for the transactions that started before theirs, of any
+// ledger or tenant, and are sent after eventsMaxLag when one of them runs
+// longer. The events that transaction writes afterwards are then missed by
+// the streams already past them.
+func (s *Server) HandleLedgerEvents(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "ledger.events.start")
+
+	ledgerUUID := r.PathValue("id")
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	var lastID int64
+	if lastEventID != "" {
+		id, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || id < 0 {
+			slog.InfoContext(r.Context(), "invalid last event id", "last_event_id", lastEventID)
+			WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+		lastID = id
+	}
+
+	if _, err := s.ledger.GetLedger(r.Context(), ledgerUUID); err != nil {
+		writeLedgerError(w, r, "unable to get ledger", err)
+		return
+	}
+
+	// subscribe before reading the outbox so no change is missed in between
+	wakeUp, cancel := s.broker.Subscribe(ledgerUUID)
+	defer cancel()
+
+	if lastEventID == "" {
+		latestID, err := s.ledger.LatestEventID(r.Context(), ledgerUUID)
+		if err != nil {
+			writeLedgerError(w, r, "unable to get latest ledger event", err)
+			return
+		}
+		lastID = latestID
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	// disable buffering in nginx
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		slog.ErrorContext(r.Context(), "unable to flush event stream", "error", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	eventsCount := 0
+	pendingPoll := eventsPendingPoll
+	var heldSince time.Time
+	for {
+		release := !heldSince.IsZero() && time.Since(heldSince) >= eventsMaxLag
+		if release {
+			slog.InfoContext(r.Context(), "ledger events held back too long", "ledger_uuid", ledgerUUID, "held_since", heldSince)
+		}
+
+		// send everything after the last event, a wake up may cover several
+		for {
+			events, err := s.ledger.ListEvents(r.Context(), ledgerUUID, lastID, eventsBatchSize, release)
+			if err != nil {
+				if r.Context().Err() == nil {
+					slog.ErrorContext(r.Context(), "unable to list ledger events", "error", err)
+				}
+				return
+			}
+
+			for _, event := range events {
+				if err := writeLedgerEvent(w, event); err != nil {
+					slog.InfoContext(r.Context(), "unable to write ledger event", "error", err)
+					return
+				}
+				lastID = event.ID
+			}
+			eventsCount += len(events)
+
+			if err := rc.Flush(); err != nil {
+				slog.InfoContext(r.Context(), "unable to flush event stream", "error", err)
+				return
+			}
+
+			if len(events) < eventsBatchSize {
+				break
+			}
+		}
+
+		pending, err := s.ledger.PendingEvents(r.Context(), ledgerUUID, lastID)
+		if err != nil {
+			if r.Context().Err() == nil {
+				slog.ErrorContext(r.Context(), "unable to check pending ledger events", "error", err)
+			}
+			return
+		}
+		var poll <-chan time.Time
+		if pending {
+			if heldSince.IsZero() {
+				heldSince = time.Now()
+			}
+			poll = time.After(pendingPoll)
+			pendingPoll = min(2*pendingPoll, eventsMaxPendingPoll)
+		} else {
+			heldSince = time.Time{}
+			pendingPoll = eventsPendingPoll
+		}
+
+		select {
+		case <-r.Context().Done():
+			slog.InfoContext(r.Context(), "ledger event stream closed", "ledger_uuid", ledgerUUID, "count", eventsCount)
+			slog.DebugContext(r.Context(), "ledger.events.complete",
+				"ledger_uuid", ledgerUUID,
+				"last_event_id", lastID,
+				"duration", time.Since(startReqTime),
+			)
+			return
+		case <-wakeUp:
+		case <-poll:
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeLedgerEvent writes the outbox event, followed by the balances of the
+// accounts of transaction events right after the change
+func writeLedgerEvent(w io.Writer, event *dbGen.OutboxEvent) error {
+	err := writeSSE(w, strconv.FormatInt(event.ID, 10), event.EventType, webhook.Event{
+		ID:        event.Uuid,
+		Type:      event.EventType,
+		CreatedAt: event.CreatedAt.Time,
+		Data:      event.Payload,
+	})
+	if err != nil {
+		return err
+	}
+
+	if event.EntityType != ledger.AuditEntityTransaction {
+		return nil
+	}
+
+	var data ledger.EventData
+	if err := json.Unmarshal(event.Payload, &data); err != nil {
+		return fmt.Errorf("unmarshal event data: %w", err)
+	}
+
+	for _, line := range data.Balances {
+		if err := writeSSE(w, "", balanceChangedEvent, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeSSE writes an event in the text/event-stream format, the data is
+// JSON so it fits in a single data line
+func writeSSE(w io.Writer, id, event string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	return err
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"bytes"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	is_ "github.com/matryer/is"
+	"strings"
+	"testing"
+)
+
+func TestWriteSSE(t *testing.T) {
+	is := is_.New(t)
+
+	var buf bytes.Buffer
+	is.NoErr(writeSSE(&buf, "42", "transaction.created", map[string]string{"id": "evt1"}))
+	is.NoErr(writeSSE(&buf, "", balanceChangedEvent, map[string]int{"balance": 10}))
+
+	expected := "id: 42\nevent: transaction.created\ndata: {\"id\":\"evt1\"}\n\n" +
+		"event: balance.changed\ndata: {\"balance\":10}\n\n"
+	is.Equal(buf.String(), expected)
+}
+
+func TestWriteLedgerEvent(t *testing.T) {
+	is := is_.New(t)
+
+	var buf bytes.Buffer
+	err := writeLedgerEvent(&buf, &dbGen.OutboxEvent{
+		ID:         7,
+		Uuid:       "evt7",
+		EventType:  "transaction.created",
+		EntityType: ledger.AuditEntityTransaction,
+		Payload:    []byte(`{"balances": [{"account_uuid": "cash", "balance": 10}]}`),
+	})
+	is.NoErr(err)
+
+	events := strings.Split(strings.TrimSuffix(buf.String(), "\n\n"), "\n\n")
+	is.Equal(len(events), 2) // the event and the balance of its account
+	is.True(strings.HasPrefix(events[0], "id: 7\nevent: transaction.created\n"))
+	is.True(strings.HasPrefix(events[1], "event: balance.changed\n"))
+	is.True(strings.Contains(events[1], `"account_uuid":"cash"`))
+	is.True(strings.Contains(events[1], `"balance":10`))
+}
@@ -0,0 +1,208 @@
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var exportCSVHeader = []string{
+	"uuid",
+	"date",
+	"amount",
+	"description",
+	"credit_account_uuid",
+	"debit_account_uuid",
+	"metadata",
+	"created_at",
+	"updated_at",
+}
+
+type ExportTransactionsQuery struct {
+	LedgerUUID string `form:"ledger_uuid" json:"ledger_uuid" validate:"required"`
+	Format     string `form:"format" json:"format" validate:"required,oneof=ndjson csv"`
+}
+
+// HandleExportTransactions streams every transaction of a ledger as NDJSON
+// or CSV. Rows are read from a server-side cursor and flushed in batches,
+// so the memory used doesn't depend on the size of the ledger. The
+// metadata filter is optional, e.g., `metadata.user_id=24`.
+func (s *Server) HandleExportTransactions(w http.ResponseWriter, r *http.Request) {
+	decoder := form.NewDecoder()
+
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "transaction.export.start")
+
+	var query ExportTransactionsQuery
+
+	// decode the query params
+	if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+		slog.InfoContext(r.Context(), "unable to decode query params", "error", err)
+		slog.DebugContext(r.Context(), "query params decoding", "query_params", r.URL.Query())
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// validate the query params
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(query); err != nil {
+		validationErrors := ParseValidationErrors(err)
+		res := map[string][]ValidationError{
+			"errors": validationErrors,
+		}
+		slog.InfoContext(r.Context(), "unable to validate query params", "error", err)
+		slog.DebugContext(r.Context(), "query params validation", "query_params", r.URL.Query(), "validation_errors", res)
+		WriteError(w, res, http.StatusBadRequest)
+		return
+	}
+
+	// the metadata filter is optional for exports
+	metadata := parseMetadataFilter(r)
+
+	// check the ledger before the status code is sent
+	if _, err := s.ledger.GetLedger(r.Context(), query.LedgerUUID); err != nil {
+		writeLedgerError(w, r, "unable to get ledger", err)
+		return
+	}
+
+	var exporter transactionExporter
+	switch query.Format {
+	case "csv":
+		exporter = newCSVExporter(w)
+	default:
+		exporter = newNDJSONExporter(w)
+	}
+
+	w.Header().Set("Content-Type", exporter.contentType())
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="transactions-%s.%s"`, query.LedgerUUID, query.Format),
+	)
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	rowsCount := 0
+
+	err := exporter.begin()
+	if err == nil {
+		err = s.ledger.StreamTransactions(
+			r.Context(),
+			query.LedgerUUID,
+			metadata,
+			func(rows []*dbGen.ListTransactionsByLedgerRow) error {
+				for _, row := range rows {
+					if err := exporter.write(row); err != nil {
+						return fmt.Errorf("write row: %w", err)
+					}
+				}
+				rowsCount += len(rows)
+
+				if err := exporter.flush(); err != nil {
+					return fmt.Errorf("flush rows: %w", err)
+				}
+				return rc.Flush()
+			},
+		)
+	}
+	if err == nil {
+		// flush what's left, e.g., the CSV header of an empty export
+		err = exporter.flush()
+	}
+	if err != nil {
+		// the status code is already sent, the client sees a truncated body
+		slog.ErrorContext(r.Context(), "unable to export transactions", "error", err)
+		slog.DebugContext(r.Context(), "transaction export",
+			"ledger_uuid", query.LedgerUUID,
+			"rows_count", rowsCount,
+		)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "transactions exported", "ledger_uuid", query.LedgerUUID, "count", rowsCount)
+	slog.DebugContext(r.Context(), "transaction.export.complete",
+		"rows_count", rowsCount,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// transactionExporter encodes transactions in one of the export formats
+type transactionExporter interface {
+	contentType() string
+	begin() error
+	write(row *dbGen.ListTransactionsByLedgerRow) error
+	flush() error
+}
+
+type ndjsonExporter struct {
+	enc *json.Encoder
+}
+
+func newNDJSONExporter(w io.Writer) *ndjsonExporter {
+	return &ndjsonExporter{enc: json.NewEncoder(w)}
+}
+
+func (e *ndjsonExporter) contentType() string {
+	return "application/x-ndjson"
+}
+
+func (e *ndjsonExporter) begin() error {
+	return nil
+}
+
+// write uses the same shape as the transactions of a ledger dump
+func (e *ndjsonExporter) write(row *dbGen.ListTransactionsByLedgerRow) error {
+	return e.enc.Encode(ledger.NewDumpTransaction(row))
+}
+
+func (e *ndjsonExporter) flush() error {
+	return nil
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func newCSVExporter(w io.Writer) *csvExporter {
+	return &csvExporter{w: csv.NewWriter(w)}
+}
+
+func (e *csvExporter) contentType() string {
+	return "text/csv; charset=utf-8"
+}
+
+func (e *csvExporter) begin() error {
+	return e.w.Write(exportCSVHeader)
+}
+
+func (e *csvExporter) write(row *dbGen.ListTransactionsByLedgerRow) error {
+	var date string
+	if row.Date.Valid {
+		date = row.Date.Time.Format(time.DateOnly)
+	}
+
+	return e.w.Write([]string{
+		row.Uuid,
+		date,
+		strconv.FormatInt(row.Amount, 10),
+		row.Description.String,
+		row.CreditAccountUuid,
+		row.DebitAccountUuid,
+		string(row.Metadata),
+		row.CreatedAt.Time.Format(time.RFC3339Nano),
+		row.UpdatedAt.Time.Format(time.RFC3339Nano),
+	})
+}
+
+func (e *csvExporter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"bytes"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5/pgtype"
+	is_ "github.com/matryer/is"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransactionExporters(t *testing.T) {
+	created := time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC)
+	row := &dbGen.ListTransactionsByLedgerRow{
+		Uuid:              "txn1",
+		CreatedAt:         pgtype.Timestamptz{Time: created, Valid: true},
+		UpdatedAt:         pgtype.Timestamptz{Time: created, Valid: true},
+		Amount:            4250,
+		Date:              pgtype.Date{Time: created, Valid: true},
+		Description:       pgtype.Text{String: "Groceries, weekly", Valid: true},
+		Metadata:          []byte(`{"user_id":24}`),
+		CreditAccountUuid: "cash",
+		DebitAccountUuid:  "food",
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		is := is_.New(t)
+
+		var buf bytes.Buffer
+		exporter := newCSVExporter(&buf)
+		is.NoErr(exporter.begin())
+		is.NoErr(exporter.write(row))
+		is.NoErr(exporter.flush())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		is.Equal(len(lines), 2)
+		is.Equal(lines[0], strings.Join(exportCSVHeader, ","))
+		is.Equal(lines[1], `txn1,2024-01-05,4250,"Groceries, weekly",cash,food,"{""user_id"":24}",2024-01-05T10:00:00Z,2024-01-05T10:00:00Z`)
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		is := is_.New(t)
+
+		var buf bytes.Buffer
+		exporter := newNDJSONExporter(&buf)
+		is.NoErr(exporter.begin())
+		is.NoErr(exporter.write(row))
+		is.NoErr(exporter.write(row))
+		is.NoErr(exporter.flush())
+
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		is.Equal(len(lines), 2)
+		is.True(strings.HasPrefix(lines[0], `{"uuid":"txn1","created_at":"2024-01-05T10:00:00Z"`))
+		is.True(strings.Contains(lines[0], `"date":"2024-01-05","description":"Groceries, weekly","metadata":{"user_id":24}`))
+	})
+}
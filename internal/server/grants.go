@@ -0,0 +1,145 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type PutGrantRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner accountant viewer auditor"`
+}
+
+type GrantResponse struct {
+	UserUUID  string    `json:"user_uuid"`
+	Email     string    `json:"email,omitempty"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// HandleListGrants returns the users with a role on the ledger
+func (s *Server) HandleListGrants(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "grant.list.start")
+
+	ledgerUUID := r.PathValue("id")
+
+	startQueryTime := time.Now()
+
+	grants, err := s.ledger.ListGrants(r.Context(), ledgerUUID)
+	if err != nil {
+		slog.DebugContext(r.Context(), "grants listing", "ledger_uuid", ledgerUUID)
+		writeLedgerError(w, r, "unable to list grants", err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "grants listing",
+		"grants_count", len(grants),
+		"query_time", time.Since(startQueryTime),
+	)
+
+	detail := make([]GrantResponse, 0, len(grants))
+	for _, grant := range grants {
+		detail = append(detail, GrantResponse{
+			UserUUID:  grant.UserUuid,
+			Email:     grant.Email,
+			Role:      string(grant.Role),
+			CreatedAt: grant.CreatedAt.Time,
+			UpdatedAt: grant.UpdatedAt.Time,
+		})
+	}
+
+	res := NewResponse("OK", len(detail), "LIST", detail)
+	err = WriteResponse(w, http.StatusOK, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "grants listed", "ledger_uuid", ledgerUUID, "count", len(detail))
+	slog.DebugContext(r.Context(), "grant.list.complete",
+		"ledger_uuid", ledgerUUID,
+		"grants_count", len(detail),
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// HandlePutGrant gives a user a role on the ledger, replacing the one it
+// had
+func (s *Server) HandlePutGrant(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "grant.put.start")
+
+	// decode the request body
+	req, err := Decode[PutGrantRequest](r)
+	if err != nil {
+		slog.InfoContext(r.Context(), "unable to decode request body", "error", err)
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	ledgerUUID := r.PathValue("id")
+	userUUID := r.PathValue("user")
+
+	startQueryTime := time.Now()
+
+	grant, err := s.ledger.PutGrant(r.Context(), ledgerUUID, userUUID, ledger.PutGrantParams{
+		Role: req.Role,
+	})
+	if err != nil {
+		slog.DebugContext(r.Context(), "grant put", "ledger_uuid", ledgerUUID, "user_uuid", userUUID, "role", req.Role)
+		writeLedgerError(w, r, "unable to put grant", err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "grant put",
+		"ledger_uuid", ledgerUUID,
+		"user_uuid", userUUID,
+		"query_time", time.Since(startQueryTime),
+	)
+
+	res := NewResponse("OK", 1, "OBJ", GrantResponse{
+		UserUUID:  userUUID,
+		Role:      string(grant.Role),
+		CreatedAt: grant.CreatedAt.Time,
+		UpdatedAt: grant.UpdatedAt.Time,
+	})
+	err = WriteResponse(w, http.StatusOK, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "grant put", "ledger_uuid", ledgerUUID, "user_uuid", userUUID, "role", grant.Role)
+	slog.DebugContext(r.Context(), "grant.put.complete",
+		"ledger_uuid", ledgerUUID,
+		"user_uuid", userUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// HandleDeleteGrant removes the role of a user on the ledger
+func (s *Server) HandleDeleteGrant(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "grant.delete.start")
+
+	ledgerUUID := r.PathValue("id")
+	userUUID := r.PathValue("user")
+
+	if err := s.ledger.DeleteGrant(r.Context(), ledgerUUID, userUUID); err != nil {
+		slog.DebugContext(r.Context(), "grant deletion", "ledger_uuid", ledgerUUID, "user_uuid", userUUID)
+		writeLedgerError(w, r, "unable to delete grant", err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	slog.InfoContext(r.Context(), "grant deleted", "ledger_uuid", ledgerUUID, "user_uuid", userUUID)
+	slog.DebugContext(r.Context(), "grant.delete.complete",
+		"ledger_uuid", ledgerUUID,
+		"user_uuid", userUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
@@ -1,28 +1,136 @@
 package server
 
 import (
-	_ "github.com/j0lvera/go-double-e/internal/db"
+	"context"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/db/migrations"
 	"log/slog"
 	"net/http"
+	"time"
 )
 
-// HandleHealthCheck is a simple health check handler
+// readyTimeout bounds the checks of a readiness probe, a probe that hangs
+// is as bad as a failed one
+const readyTimeout = time.Second
+
+const (
+	healthOK       = "ok"
+	healthError    = "error"
+	healthDraining = "draining"
+)
+
+type healthCheck struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type healthResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]healthCheck `json:"checks,omitempty"`
+}
+
+// HandleHealthCheck is kept for the clients of the original probe, it's
+// the same as /livez
 func (s *Server) HandleHealthCheck(w http.ResponseWriter, r *http.Request) {
-	res := struct {
-		Status string `json:"status"`
-	}{
-		Status: "ok",
+	s.HandleLivez(w, r)
+}
+
+// HandleLivez reports the process is up and serving requests, it doesn't
+// depend on the database so an outage doesn't get every instance restarted
+func (s *Server) HandleLivez(w http.ResponseWriter, r *http.Request) {
+	err := WriteResponse(w, http.StatusOK, healthResponse{Status: healthOK})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
 	}
+}
+
+// HandleReadyz reports whether the instance can serve requests: the
+// database answers, its schema is at the version of the build, and the
+// replica isn't lagging behind when a maximum lag is set. It's not ready
+// as soon as the server starts draining, so load balancers stop routing
+// requests to it before it shuts down.
+func (s *Server) HandleReadyz(w http.ResponseWriter, r *http.Request) {
+	if s.draining.Load() {
+		err := WriteResponse(w, http.StatusServiceUnavailable, healthResponse{Status: healthDraining})
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), readyTimeout)
+	defer cancel()
 
-	// TODO: implement .Ping() on the Client to check the connection
-	//if err := s.client.DB().Ping(r.Context()); err != nil {
-	//	health.Status = "error"
-	//	w.WriteHeader(http.StatusServiceUnavailable)
-	//}
+	res := healthResponse{
+		Status: healthOK,
+		Checks: map[string]healthCheck{
+			"database":   s.checkDatabase(ctx),
+			"migrations": s.checkMigrations(ctx),
+		},
+	}
+	if s.maxReplicationLag > 0 {
+		res.Checks["replication_lag"] = s.checkReplicationLag(ctx)
+	}
 
-	err := WriteResponse(w, http.StatusOK, res)
+	status := http.StatusOK
+	for name, check := range res.Checks {
+		if check.Status != healthOK {
+			slog.WarnContext(r.Context(), "not ready", "check", name, "detail", check.Detail)
+			res.Status = healthError
+			status = http.StatusServiceUnavailable
+		}
+	}
+
+	err := WriteResponse(w, status, res)
 	if err != nil {
-		return
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+	}
+}
+
+// Drain marks the server as not ready, requests are still served until
+// the HTTP server is shut down
+func (s *Server) Drain() {
+	s.draining.Store(true)
+}
+
+func (s *Server) checkDatabase(ctx context.Context) healthCheck {
+	if err := s.client.Ping(ctx); err != nil {
+		return healthCheck{Status: healthError, Detail: err.Error()}
+	}
+	return healthCheck{Status: healthOK}
+}
+
+// checkMigrations accepts newer schemas, the migrations are backward
+// compatible so the previous build keeps serving during a rollout
+func (s *Server) checkMigrations(ctx context.Context) healthCheck {
+	expected, err := migrations.Latest()
+	if err != nil {
+		return healthCheck{Status: healthError, Detail: err.Error()}
+	}
+
+	version, err := s.client.SchemaVersion(ctx)
+	if err != nil {
+		return healthCheck{Status: healthError, Detail: err.Error()}
+	}
+
+	if version < expected {
+		return healthCheck{
+			Status: healthError,
+			Detail: fmt.Sprintf("schema at version %d, expected %d", version, expected),
+		}
+	}
+	return healthCheck{Status: healthOK, Detail: fmt.Sprintf("version %d", version)}
+}
+
+func (s *Server) checkReplicationLag(ctx context.Context) healthCheck {
+	lag, err := s.client.ReplicationLag(ctx)
+	if err != nil {
+		return healthCheck{Status: healthError, Detail: err.Error()}
+	}
+
+	detail := fmt.Sprintf("%s behind, at most %s", lag.Round(time.Millisecond), s.maxReplicationLag)
+	if lag > s.maxReplicationLag {
+		return healthCheck{Status: healthError, Detail: detail}
 	}
-	slog.Info("Health check")
+	return healthCheck{Status: healthOK, Detail: detail}
 }
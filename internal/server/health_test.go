@@ -0,0 +1,30 @@
+package server
+
+import (
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadyzDraining(t *testing.T) {
+	is := is_.New(t)
+
+	s := NewServer(nil, nil, Options{})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	is.Equal(w.Code, http.StatusOK)
+
+	s.Drain()
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/readyz", nil))
+	is.Equal(w.Code, http.StatusServiceUnavailable) // not ready once draining
+	is.Equal(strings.TrimSpace(w.Body.String()), `{"status":"draining"}`)
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/livez", nil))
+	is.Equal(w.Code, http.StatusOK) // still alive
+}
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader makes a POST safe to retry, the response of the
+// first request with a key is stored and the retries get it back without
+// running the request again
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on the responses sent back to retries
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// idempotencyTTL is how long a key is remembered, a request with an
+// expired key runs again
+const idempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength is the longest key the table accepts
+const maxIdempotencyKeyLength = 254
+
+// idempotent stores the responses of the POST requests with an
+// `Idempotency-Key`. Keys are scoped to the principal, so it must run after
+// authenticate. A retry gets the stored response back, a request reusing
+// the key of another one is rejected with a 422 and a retry of a request
+// still in progress with a 409. Server errors release the key so the
+// request can be retried.
+func (s *Server) idempotent(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" || r.Method != http.MethodPost {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// requireScope rejects the requests without a principal
+		principal := auth.PrincipalFromContext(r.Context())
+		if principal == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		// another principal of the tenant reusing the key runs its own
+		// request, a stored response is only sent back to its principal
+		owner := principal.LimitKey()
+
+		slog.DebugContext(r.Context(), "server.idempotent.start", "key", key)
+
+		if len(key) > maxIdempotencyKeyLength {
+			WriteError(w, "Idempotency-Key is too long", http.StatusBadRequest)
+			return
+		}
+
+		// the body is kept in memory, no larger than the handlers accept
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadSize))
+		if err != nil {
+			if isTooLarge(err) {
+				WriteError(w, ErrRequestTooLarge, http.StatusRequestEntityTooLarge)
+				return
+			}
+			WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		hash := requestHash(r, owner, body)
+
+		_, err = s.client.Queries.ClaimIdempotencyKey(r.Context(), dbGen.ClaimIdempotencyKeyParams{
+			Key:           key,
+			Principal:     owner,
+			RequestHash:   hash,
+			ExpiredBefore: pgtype.Timestamptz{Time: time.Now().Add(-idempotencyTTL), Valid: true},
+		})
+		if errors.Is(err, pgx.ErrNoRows) {
+			s.replay(w, r, key, owner, hash)
+			return
+		}
+		if err != nil {
+			slog.ErrorContext(r.Context(), "unable to claim idempotency key", "error", err)
+			WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+			return
+		}
+
+		rec := &responseRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+		next.ServeHTTP(rec, r)
+
+		// the key must not stay in progress when the client goes away
+		ctx := context.WithoutCancel(r.Context())
+		if rec.status == 0 || rec.status >= http.StatusInternalServerError {
+			err := s.client.Queries.ReleaseIdempotencyKey(ctx, dbGen.ReleaseIdempotencyKeyParams{Key: key, Principal: owner})
+			if err != nil {
+				slog.ErrorContext(ctx, "unable to release idempotency key", "error", err)
+			}
+			return
+		}
+
+		err = s.client.Queries.CompleteIdempotencyKey(ctx, dbGen.CompleteIdempotencyKeyParams{
+			StatusCode:   int32(rec.status),
+			ResponseBody: rec.body.Bytes(),
+			Key:          key,
+			Principal:    owner,
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "unable to complete idempotency key", "error", err)
+		}
+
+		slog.DebugContext(ctx, "server.idempotent.complete", "key", key, "status", rec.status)
+	})
+}
+
+// replay sends back the stored response of the request with the key
+func (s *Server) replay(w http.ResponseWriter, r *http.Request, key, owner, hash string) {
+	stored, err := s.client.Queries.GetIdempotencyKey(r.Context(), dbGen.GetIdempotencyKeyParams{Key: key, Principal: owner})
+	if err != nil {
+		// released in the meantime, the client can retry
+		if errors.Is(err, pgx.ErrNoRows) {
+			WriteError(w, "a request with this Idempotency-Key failed, retry it", http.StatusConflict)
+			return
+		}
+		slog.ErrorContext(r.Context(), "unable to get idempotency key", "error", err)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	if stored.RequestHash != hash {
+		WriteError(w, "Idempotency-Key was used for another request", http.StatusUnprocessableEntity)
+		return
+	}
+	if !stored.StatusCode.Valid {
+		WriteError(w, "a request with this Idempotency-Key is in progress", http.StatusConflict)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "server.idempotent.replay", "key", key, "status", stored.StatusCode.Int32)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set(IdempotentReplayedHeader, "true")
+	w.WriteHeader(int(stored.StatusCode.Int32))
+	_, _ = w.Write(stored.ResponseBody)
+}
+
+// requestHash identifies a request by its principal, method, path and body
+func requestHash(r *http.Request, owner string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(owner + "\n" + r.Method + " " + r.URL.RequestURI() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder keeps a copy of the response body
+type responseRecorder struct {
+	statusRecorder
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.statusRecorder.Write(b)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestIdempotentPassThrough(t *testing.T) {
+	is := is_.New(t)
+
+	// without a database, only the requests that skip it can be served
+	s := &Server{}
+	handler := s.idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	for _, r := range []*http.Request{
+		httptest.NewRequest("POST", "/ledgers", strings.NewReader(`{}`)),
+		// unauthenticated, rejected by requireScope
+		func() *http.Request {
+			r := httptest.NewRequest("POST", "/ledgers", strings.NewReader(`{}`))
+			r.Header.Set(IdempotencyKeyHeader, "key")
+			return r
+		}(),
+		func() *http.Request {
+			r := httptest.NewRequest("GET", "/ledgers", nil)
+			r.Header.Set(IdempotencyKeyHeader, "key")
+			return r
+		}(),
+	} {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		is.Equal(w.Code, http.StatusCreated)
+	}
+}
+
+func TestRequestHash(t *testing.T) {
+	is := is_.New(t)
+
+	hash := func(method, target, body string) string {
+		return requestHash(httptest.NewRequest(method, target, nil), "key:a", []byte(body))
+	}
+
+	is.Equal(hash("POST", "/ledgers", `{"name":"a"}`), hash("POST", "/ledgers", `{"name":"a"}`))
+	is.True(hash("POST", "/ledgers", `{"name":"a"}`) != hash("POST", "/ledgers", `{"name":"b"}`))  // another body
+	is.True(hash("POST", "/ledgers", `{"name":"a"}`) != hash("POST", "/accounts", `{"name":"a"}`)) // another path
+	is.True(hash("POST", "/ledgers?a=1", `{}`) != hash("POST", "/ledgers?a=2", `{}`))              // another query
+
+	other := requestHash(httptest.NewRequest("POST", "/ledgers", nil), "key:b", []byte(`{"name":"a"}`))
+	is.True(hash("POST", "/ledgers", `{"name":"a"}`) != other) // another principal
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"errors"
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/j0lvera/go-double-e/internal/journal"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type ImportLedgerQuery struct {
+	Format string `form:"format" json:"format" validate:"required,oneof=beancount hledger"`
+	Name   string `form:"name" json:"name" validate:"max=254"`
+	Scale  int    `form:"scale" json:"scale" validate:"min=0,max=9"`
+}
+
+// ImportLedgerResponse is the ledger created by an import or a restore
+// with the number of accounts and transactions it got
+type ImportLedgerResponse struct {
+	UUID         string `json:"uuid"`
+	Name         string `json:"name"`
+	Accounts     int    `json:"accounts"`
+	Transactions int    `json:"transactions"`
+}
+
+// HandleImportLedger creates a ledger, its accounts and its transactions
+// from a Beancount or hledger journal sent as the request body. Everything
+// is created in a single database transaction, so a journal is either
+// imported completely or not at all. Journals over maxUploadSize are
+// rejected with 413.
+func (s *Server) HandleImportLedger(w http.ResponseWriter, r *http.Request) {
+	decoder := form.NewDecoder()
+
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "ledger.import.start")
+
+	// default values
+	query := ImportLedgerQuery{
+		Scale: 2,
+	}
+
+	// decode the query params
+	if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+		slog.InfoContext(r.Context(), "unable to decode query params", "error", err)
+		slog.DebugContext(r.Context(), "query params decoding", "query_params", r.URL.Query())
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// validate the query params
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(query); err != nil {
+		validationErrors := ParseValidationErrors(err)
+		res := map[string][]ValidationError{
+			"errors": validationErrors,
+		}
+		slog.InfoContext(r.Context(), "unable to validate query params", "error", err)
+		slog.DebugContext(r.Context(), "query params validation", "query_params", r.URL.Query(), "validation_errors", res)
+		WriteError(w, res, http.StatusBadRequest)
+		return
+	}
+
+	// parse the journal, it's read whole
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadSize)
+	j, err := journal.Parse(r.Body, journal.Format(query.Format), journal.Options{Scale: query.Scale})
+	if err != nil {
+		if isTooLarge(err) {
+			slog.InfoContext(r.Context(), "journal too large", "error", err)
+			WriteError(w, ErrRequestTooLarge, http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		var journalErrors journal.Errors
+		if errors.As(err, &journalErrors) {
+			res := map[string]journal.Errors{
+				"errors": journalErrors,
+			}
+			slog.InfoContext(r.Context(), "unable to parse journal", "errors_count", len(journalErrors))
+			slog.DebugContext(r.Context(), "journal parsing", "errors", journalErrors)
+			WriteError(w, res, http.StatusBadRequest)
+			return
+		}
+
+		slog.InfoContext(r.Context(), "unable to read journal", "error", err)
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "journal parsing",
+		"accounts_count", len(j.Accounts),
+		"transactions_count", len(j.Transactions),
+	)
+
+	name := query.Name
+	if name == "" {
+		name = j.Title
+	}
+	if name == "" {
+		name = "Imported ledger"
+	}
+
+	startQueryTime := time.Now()
+
+	imported, err := s.ledger.Import(r.Context(), j, name, journal.Format(query.Format))
+	if err != nil {
+		slog.DebugContext(r.Context(), "journal import", "name", name, "format", query.Format)
+		writeLedgerError(w, r, "unable to import journal", err)
+		return
+	}
+
+	for _, txn := range j.Transactions {
+		for _, transfer := range txn.Transfers() {
+			s.metrics.TransactionCreated(imported.Ledger.Uuid, transfer.Amount)
+		}
+	}
+
+	slog.DebugContext(r.Context(), "journal import",
+		"uuid", imported.Ledger.Uuid,
+		"transactions_count", imported.Transactions,
+		"query_time", time.Since(startQueryTime),
+	)
+
+	detail := ImportLedgerResponse{
+		UUID:         imported.Ledger.Uuid,
+		Name:         imported.Ledger.Name,
+		Accounts:     imported.Accounts,
+		Transactions: imported.Transactions,
+	}
+
+	res := NewResponse("OK", 1, "OBJ", detail)
+	err = WriteResponse(w, http.StatusCreated, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "ledger imported", "ledger_uuid", imported.Ledger.Uuid, "name", imported.Ledger.Name)
+	slog.DebugContext(r.Context(), "ledger.import.complete",
+		"ledger_uuid", imported.Ledger.Uuid,
+		"duration", time.Since(startReqTime),
+	)
+}
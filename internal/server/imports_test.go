@@ -0,0 +1,37 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/auth"
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUploadTooLarge(t *testing.T) {
+	is := is_.New(t)
+
+	// bodies over the limit are rejected before the ledger is touched
+	s := &Server{}
+	body := strings.Repeat(" ", maxUploadSize+1)
+
+	w := httptest.NewRecorder()
+	s.HandleImportLedger(w, httptest.NewRequest("POST", "/ledgers/import?format=beancount&name=Books", strings.NewReader(body)))
+	is.Equal(w.Code, http.StatusRequestEntityTooLarge) // journal over the limit
+
+	w = httptest.NewRecorder()
+	s.HandleRestoreLedger(w, httptest.NewRequest("POST", "/ledgers/restore", strings.NewReader(body)))
+	is.Equal(w.Code, http.StatusRequestEntityTooLarge) // dump over the limit
+
+	// idempotent POSTs are read before the handlers, with the same limit
+	handler := s.idempotent(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("request over the limit served")
+	}))
+	r := httptest.NewRequest("POST", "/ledgers/import?format=beancount&name=Books", strings.NewReader(body))
+	r.Header.Set(IdempotencyKeyHeader, "key")
+	r = r.WithContext(auth.WithPrincipal(r.Context(), &auth.Principal{KeyUUID: "key", TenantID: 1}))
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	is.Equal(w.Code, http.StatusRequestEntityTooLarge) // idempotent request over the limit
+}
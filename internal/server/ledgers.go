@@ -1,11 +1,8 @@
 package server
 
 import (
-	"encoding/json"
-	"github.com/go-playground/validator/v10"
 	_ "github.com/j0lvera/go-double-e/internal/db"
-	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
-	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/j0lvera/go-double-e/internal/ledger"
 	"log/slog"
 	"net/http"
 	"time"
@@ -17,77 +14,40 @@ type CreateLedgerRequest struct {
 	Metadata    map[string]interface{} `json:"metadata"`
 }
 
+type CreateLedgerResponse struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
 // HandleCreateLedger is the handler for creating a new ledger
 func (s *Server) HandleCreateLedger(w http.ResponseWriter, r *http.Request) {
 	startReqTime := time.Now()
-	slog.Debug("ledger.create.start",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"body", r.Body,
-		"remote_add", r.RemoteAddr,
-	)
+	slog.DebugContext(r.Context(), "ledger.create.start")
 
 	// Decode the request body
 	req, err := Decode[CreateLedgerRequest](r)
 	if err != nil {
-		slog.Info("unable to Decode request body", "error", err)
-		slog.Debug("body decoding", "body", r.Body, "error", err)
+		slog.InfoContext(r.Context(), "unable to Decode request body", "error", err)
+		slog.DebugContext(r.Context(), "body decoding", "body", r.Body, "error", err)
 		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	slog.Debug("body decoding", "request", req)
-
-	// validate the request
-	validate := validator.New(validator.WithRequiredStructEnabled())
-	if err = validate.Struct(req); err != nil {
-		validationErrors := ParseValidationErrors(err)
-
-		res := map[string][]ValidationError{
-			"errors": validationErrors,
-		}
-
-		slog.Info("unable to validate request", "error", err)
-		slog.Debug("request validation", "body", r.Body, "validation_errors", res)
-
-		WriteError(w, res, http.StatusBadRequest)
-		return
-	}
-
-	// cast the description field to a pgtype.Text
-	description := pgtype.Text{
-		String: req.Description,
-		Valid:  true,
-	}
-
-	// marshal the metadata field
-	metadataBytes, err := json.Marshal(req.Metadata)
-	if err != nil {
-		slog.Info("unable to marshal metadata", "error", err)
-		slog.Debug("metadata marshalling", "metadata", req.Metadata)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
-		return
-	}
+	slog.DebugContext(r.Context(), "body decoding", "request", req)
 
 	startQueryTime := time.Now()
 
-	// add the ledger to the database
-	ledgerParams := dbGen.CreateLedgerParams{
+	ledger, err := s.ledger.CreateLedger(r.Context(), ledger.CreateLedgerParams{
 		Name:        req.Name,
-		Description: description,
-		Metadata:    metadataBytes,
-	}
-	ledger, err := s.client.Queries.CreateLedger(r.Context(), ledgerParams)
+		Description: req.Description,
+		Metadata:    req.Metadata,
+	})
 	if err != nil {
-		slog.Error("unable to create ledger", "error", err)
-		slog.Debug("ledger creation", "params", ledgerParams, "error", err)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		writeLedgerError(w, r, "unable to create ledger", err)
 		return
 	}
 
-	slog.Debug(
+	slog.DebugContext(r.Context(),
 		"ledger creation",
 		"uuid", ledger.Uuid,
 		"name", ledger.Name,
@@ -95,10 +55,7 @@ func (s *Server) HandleCreateLedger(w http.ResponseWriter, r *http.Request) {
 	)
 
 	// format the response
-	detail := struct {
-		UUID string `json:"uuid"`
-		Name string `json:"name"`
-	}{
+	detail := CreateLedgerResponse{
 		UUID: ledger.Uuid,
 		Name: ledger.Name,
 	}
@@ -106,12 +63,12 @@ func (s *Server) HandleCreateLedger(w http.ResponseWriter, r *http.Request) {
 	res := NewResponse("OK", 1, "OBJ", detail)
 	err = WriteResponse(w, http.StatusCreated, res)
 	if err != nil {
-		slog.Error("unable to write response", "error", err)
-		slog.Debug("response writing", "response", res, "error", err)
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res, "error", err)
 		return
 	}
-	slog.Info("ledger created", "ledger_uuid", ledger.Uuid, "name", ledger.Name)
-	slog.Debug(
+	slog.InfoContext(r.Context(), "ledger created", "ledger_uuid", ledger.Uuid, "name", ledger.Name)
+	slog.DebugContext(r.Context(),
 		"ledger.create.complete",
 		"ledger_uuid", ledger.Uuid,
 		"duration", time.Since(startReqTime),
@@ -124,112 +81,51 @@ type UpdateLedgerRequest struct {
 	Metadata    map[string]interface{} `json:"metadata,omitempty"`
 }
 
+type UpdateLedgerResponse struct {
+	UUID        string                 `json:"uuid"`
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Metadata    map[string]interface{} `json:"metadata"`
+}
+
 func (s *Server) HandleUpdateLedger(w http.ResponseWriter, r *http.Request) {
 	startReqTime := time.Now()
 
-	slog.Debug("ledger.update.start",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"body", r.Body,
-		"remote_add", r.RemoteAddr,
-	)
+	slog.DebugContext(r.Context(), "ledger.update.start")
 
 	ledgerUUID := r.PathValue("id")
-	currentLedger, err := s.client.Queries.GetLedger(r.Context(), ledgerUUID)
-	if err != nil {
-		slog.Error("unable to get ledger", "error", err)
-		slog.Debug("ledger retrieval", "uuid", ledgerUUID)
-		WriteError(w, ErrNotFound, http.StatusNotFound)
-		return
-	}
 
 	// Decode the request body
 	req, err := Decode[UpdateLedgerRequest](r)
 	if err != nil {
-		slog.Info("unable to Decode request body", "error", err)
-		slog.Debug("body decoding", "body", r.Body)
+		slog.InfoContext(r.Context(), "unable to Decode request body", "error", err)
+		slog.DebugContext(r.Context(), "body decoding", "body", r.Body)
 		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	slog.Debug("body decoding", "request", req)
-
-	// validate the request
-	validate := validator.New(validator.WithRequiredStructEnabled())
-	if err = validate.Struct(req); err != nil {
-		validationErrors := ParseValidationErrors(err)
-
-		res := map[string][]ValidationError{
-			"errors": validationErrors,
-		}
-
-		slog.Info("unable to validate request", "error", err)
-		slog.Debug("request validation", "body", r.Body)
-
-		WriteError(w, res, http.StatusBadRequest)
-		return
-	}
-
-	ledgerParams := dbGen.UpdateLedgerParams{
-		Uuid: ledgerUUID,
-	}
-
-	if req.Name != "" {
-		ledgerParams.Name = req.Name
-	} else {
-		ledgerParams.Name = currentLedger.Name
-	}
-
-	if req.Description != "" {
-		// cast the description field to a pgtype.Text
-		description := pgtype.Text{
-			String: req.Description,
-			Valid:  true,
-		}
-		ledgerParams.Description = description
-	} else {
-		ledgerParams.Description = currentLedger.Description
-	}
-
-	if req.Metadata != nil {
-		// marshal the metadata field
-		metadataBytes, err := json.Marshal(req.Metadata)
-		if err != nil {
-			slog.Info("unable to marshal metadata", "error", err)
-			slog.Debug("metadata marshalling", "metadata", req.Metadata)
-
-			WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
-			return
-		}
-		ledgerParams.Metadata = metadataBytes
-	} else {
-		ledgerParams.Metadata = currentLedger.Metadata
-	}
+	slog.DebugContext(r.Context(), "body decoding", "request", req)
 
 	startQueryTime := time.Now()
 
-	ledger, err := s.client.Queries.UpdateLedger(r.Context(), ledgerParams)
+	ledger, err := s.ledger.UpdateLedger(r.Context(), ledgerUUID, ledger.UpdateLedgerParams{
+		Name:        req.Name,
+		Description: req.Description,
+		Metadata:    req.Metadata,
+	})
 	if err != nil {
-		slog.Error("unable to update ledger", "error", err)
-		slog.Debug("ledger update", "params", ledgerParams, "error", err)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		writeLedgerError(w, r, "unable to update ledger", err)
 		return
 	}
 
-	slog.Debug("ledger update",
+	slog.DebugContext(r.Context(), "ledger update",
 		"uuid", ledger.Uuid,
 		"name", ledger.Name,
 		"query_time", time.Since(startQueryTime),
 	)
 
 	// format the response
-	detail := struct {
-		UUID        string                 `json:"uuid"`
-		Name        string                 `json:"name"`
-		Description string                 `json:"description"`
-		Metadata    map[string]interface{} `json:"metadata"`
-	}{
+	detail := UpdateLedgerResponse{
 		UUID:        ledger.Uuid,
 		Name:        ledger.Name,
 		Description: ledger.Description.String,
@@ -239,12 +135,12 @@ func (s *Server) HandleUpdateLedger(w http.ResponseWriter, r *http.Request) {
 	res := NewResponse("OK", 1, "OBJ", detail)
 	err = WriteResponse(w, http.StatusOK, res)
 	if err != nil {
-		slog.Error("unable to write response", "error", err)
-		slog.Debug("response writing", "response", res, "error", err)
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res, "error", err)
 		return
 	}
-	slog.Info("ledger updated", "ledger_uuid", ledger.Uuid, "name", ledger.Name)
-	slog.Debug(
+	slog.InfoContext(r.Context(), "ledger updated", "ledger_uuid", ledger.Uuid, "name", ledger.Name)
+	slog.DebugContext(r.Context(),
 		"ledger.update.complete",
 		"ledger_uuid", ledger.Uuid,
 		"duration", time.Since(startReqTime),
@@ -265,55 +161,33 @@ type MetadataQuery struct {
 // if no query string parameter is present, it will return bad request.
 func (s *Server) HandleListLedgers(w http.ResponseWriter, r *http.Request) {
 	startReqTime := time.Now()
-	slog.Debug("ledger.list.start",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"remote_add", r.RemoteAddr,
-	)
+	slog.DebugContext(r.Context(), "ledger.list.start")
 
-	// parse and marshal the metadata field
-	metadataBytes, err := parseMetadataParam(r)
-	if err != nil {
-		slog.Info("unable to parse metadata query param", "error", err)
-		slog.Debug("metadata parsing", "raw_query", r.URL.RawQuery)
+	metadata := parseMetadataFilter(r)
 
-		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
-		return
-	}
-
-	slog.Debug("metadata parsing", "raw_query", r.URL.RawQuery, "metadata", string(metadataBytes))
+	slog.DebugContext(r.Context(), "metadata parsing", "raw_query", r.URL.RawQuery, "metadata", metadata)
 
 	startQueryTime := time.Now()
 
-	// get the ledgers from the database
-	ledgers, err := s.client.Queries.ListLedgers(r.Context(), metadataBytes)
+	ledgers, err := s.ledger.ListLedgers(r.Context(), metadata)
 	if err != nil {
-		slog.Error("unable to list ledgers", "error", err)
-
-		deadline, _ := r.Context().Deadline()
-		slog.Debug(
-			"database querying",
-			"query_timeout", deadline,
-			"metadata_filter", string(metadataBytes),
-		)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		writeLedgerError(w, r, "unable to list ledgers", err)
 		return
 	}
 
 	ledgersCount := len(ledgers)
 
-	slog.Debug(
+	slog.DebugContext(r.Context(),
 		"database querying",
 		"ledgers_count", ledgersCount,
-		"metadata_filter", string(metadataBytes),
+		"metadata_filter", metadata,
 		"query_time", time.Since(startQueryTime),
 	)
 
 	// no ledgers found, return 404
 	if ledgersCount == 0 {
-		slog.Info("unable to find queries", "metadata_filter", string(metadataBytes))
-		slog.Debug(
+		slog.InfoContext(r.Context(), "unable to find queries", "metadata_filter", metadata)
+		slog.DebugContext(r.Context(),
 			"ledger.list.complete",
 			"ledgers_count", ledgersCount,
 			"duration", time.Since(startReqTime),
@@ -326,7 +200,7 @@ func (s *Server) HandleListLedgers(w http.ResponseWriter, r *http.Request) {
 	// format the response
 	detail := ledgers
 
-	slog.Debug(
+	slog.DebugContext(r.Context(),
 		"response preparation",
 		"ledgers_count", ledgersCount,
 		"first_ledger_uuid", ledgers[0].Uuid,
@@ -335,12 +209,12 @@ func (s *Server) HandleListLedgers(w http.ResponseWriter, r *http.Request) {
 	res := NewResponse("OK", ledgersCount, "LIST", detail)
 	err = WriteResponse(w, http.StatusOK, res)
 	if err != nil {
-		slog.Error("unable to write response", "error", err)
-		slog.Debug("response writing", "response", res)
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
 		return
 	}
-	slog.Info("ledgers listed", "count", ledgersCount)
-	slog.Debug(
+	slog.InfoContext(r.Context(), "ledgers listed", "count", ledgersCount)
+	slog.DebugContext(r.Context(),
 		"ledger.list.complete",
 		"ledgers_count", ledgersCount,
 		"duration", time.Since(startReqTime),
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// RequestIDHeader carries the ID of a request, it's propagated from the
+// client or a proxy when there is one, and generated otherwise
+const RequestIDHeader = "X-Request-ID"
+
+// requestID assigns or propagates the ID of the request, it's returned in
+// the response and logged with every record of the request's context
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := logging.RequestID(r.Header.Get(RequestIDHeader))
+
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// statusRecorder remembers the status and the size of a response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	n, err := w.ResponseWriter.Write(b)
+	w.bytes += n
+	return n, err
+}
+
+// Unwrap lets http.ResponseController flush the event streams
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// traceRequests starts a server span per request, named after the route
+// of the mux, e.g., `GET /ledgers/{id}`, so requests of the same route are
+// grouped together. Probes aren't traced.
+func traceRequests(mux *http.ServeMux, next http.Handler) http.Handler {
+	return otelhttp.NewHandler(next, "http.server",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !isProbe(r)
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			if _, pattern := mux.Handler(r); pattern != "" {
+				return pattern
+			}
+			return r.Method
+		}),
+	)
+}
+
+// accessLog logs a line per request once it's served
+func accessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		// probes would drown the other requests
+		level := slog.LevelInfo
+		if isProbe(r) {
+			level = slog.LevelDebug
+		}
+
+		slog.Log(r.Context(), level, "request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", status,
+			"bytes", rec.bytes,
+			"duration", time.Since(start),
+			"remote_addr", r.RemoteAddr,
+			"user_agent", r.UserAgent(),
+		)
+	})
+}
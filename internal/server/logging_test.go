@@ -0,0 +1,109 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/logging"
+	"github.com/j0lvera/go-double-e/internal/metrics"
+	is_ "github.com/matryer/is"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	is := is_.New(t)
+
+	var seen string
+	handler := requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestIDFromContext(r.Context())
+	}))
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/ledgers", nil)
+		if id != "" {
+			r.Header.Set(RequestIDHeader, id)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := serve("req-1")
+	is.Equal(seen, "req-1") // propagated from the client
+	is.Equal(w.Header().Get(RequestIDHeader), "req-1")
+
+	w = serve("")
+	is.Equal(len(seen), 32) // generated
+	is.Equal(w.Header().Get(RequestIDHeader), seen)
+
+	serve("bad\nid")
+	is.True(seen != "bad\nid") // replaced, it could forge log lines
+}
+
+func TestStatusRecorder(t *testing.T) {
+	is := is_.New(t)
+
+	rec := &statusRecorder{ResponseWriter: httptest.NewRecorder()}
+	rec.WriteHeader(http.StatusCreated)
+	_, err := rec.Write([]byte("hello"))
+	is.NoErr(err)
+
+	is.Equal(rec.status, http.StatusCreated)
+	is.Equal(rec.bytes, 5)
+	is.NoErr(http.NewResponseController(rec).Flush()) // event streams can flush
+}
+
+func TestTraceRequests(t *testing.T) {
+	is := is_.New(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ledgers/{id}", func(http.ResponseWriter, *http.Request) {})
+	mux.HandleFunc("GET /health", func(http.ResponseWriter, *http.Request) {})
+	handler := traceRequests(mux, mux)
+
+	for _, path := range []string{"/ledgers/1", "/ledgers/2", "/health"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	spans := recorder.Ended()
+	is.Equal(len(spans), 2)                        // probes aren't traced
+	is.Equal(spans[0].Name(), "GET /ledgers/{id}") // named after the route
+}
+
+func TestObserveRequests(t *testing.T) {
+	is := is_.New(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ledgers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	s := &Server{metrics: metrics.New(nil)}
+	handler := s.observeRequests(mux, mux)
+
+	for _, path := range []string{"/ledgers/1", "/ledgers/2", "/wp-admin"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	w := httptest.NewRecorder()
+	s.metrics.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	out := w.Body.String()
+	is.True(strings.Contains(out, `doubleed_http_requests_total{route="GET /ledgers/{id}",status="404"} 2`)) // by route
+	is.True(strings.Contains(out, `doubleed_http_requests_total{route="unmatched",status="404"} 1`))         // unknown paths grouped
+}
+
+func TestMetricsNotServed(t *testing.T) {
+	is := is_.New(t)
+
+	s := NewServer(nil, nil, Options{Metrics: metrics.New(nil)})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	is.True(w.Code != http.StatusOK)                         // the metrics of every tenant are public
+	is.True(!strings.Contains(w.Body.String(), "doubleed_")) // the metrics of every tenant are public
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"net/http"
+	"time"
+)
+
+// observeRequests records the route, status and latency of every request,
+// unknown paths are grouped under a single route so scanners can't blow up
+// the cardinality of the metrics
+func (s *Server) observeRequests(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isProbe(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r)
+
+		route := "unmatched"
+		if _, pattern := mux.Handler(r); pattern != "" {
+			route = pattern
+		}
+		status := rec.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		s.metrics.ObserveRequest(route, status, time.Since(start))
+	})
+}
+
+// isProbe reports whether the request comes from a health check, they
+// aren't limited, traced nor measured
+func isProbe(r *http.Request) bool {
+	switch r.URL.Path {
+	case "/health", "/livez", "/readyz":
+		return true
+	}
+	return false
+}
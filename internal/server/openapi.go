@@ -0,0 +1,249 @@
+package server
+
+import (
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/openapi"
+	"github.com/j0lvera/go-double-e/internal/report"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// operation documents a route of addRoutes, TestOpenAPI fails when a route
+// is missing
+type operation struct {
+	pattern string
+	id      string
+	tag     string
+	summary string
+	// scope the credentials need, empty for the public routes
+	scope  string
+	public bool
+
+	query  any
+	params []openapi.Parameter
+	// metadata filters by `metadata.KEY=VALUE` params, OpenAPI can't
+	// describe params with a prefix so they are described in the text
+	metadata bool
+	// body is decoded from JSON unless bodyType is set
+	body     any
+	bodyType string
+
+	status int
+	// detail is sent in a StandardResponse, raw as is
+	detail any
+	raw    any
+	// content are the other media types of the response, e.g., CSV
+	content []string
+}
+
+// operations of the API, in the order of addRoutes
+var operations = []operation{
+	// public
+	{pattern: "GET /health", id: "health", tag: "health", summary: "Alias of /livez", public: true, raw: healthResponse{}},
+	{pattern: "GET /livez", id: "livez", tag: "health", summary: "Liveness probe", public: true, raw: healthResponse{}},
+	{pattern: "GET /readyz", id: "readyz", tag: "health", summary: "Readiness probe, 503 while a check fails or the server drains", public: true, raw: healthResponse{}},
+	{pattern: "GET /openapi.json", id: "openapi", tag: "health", summary: "This document", public: true, raw: map[string]any{}},
+	{pattern: "GET /docs", id: "docs", tag: "health", summary: "Swagger UI of this document", public: true, content: []string{"text/html"}},
+
+	// ledgers
+	{pattern: "GET /ledgers", id: "listLedgers", tag: "ledgers", summary: "List the ledgers matching the metadata, 404 when none does", scope: auth.ScopeLedgersRead, metadata: true, detail: []*dbGen.ListLedgersRow{}},
+	{pattern: "POST /ledgers", id: "createLedger", tag: "ledgers", summary: "Create a ledger", scope: auth.ScopeLedgersWrite, body: CreateLedgerRequest{}, status: http.StatusCreated, detail: CreateLedgerResponse{}},
+	{pattern: "POST /ledgers/import", id: "importLedger", tag: "ledgers", summary: "Create a ledger from a Beancount or hledger journal", scope: auth.ScopeLedgersWrite, query: ImportLedgerQuery{}, bodyType: "text/plain", status: http.StatusCreated, detail: ImportLedgerResponse{}},
+	{pattern: "POST /ledgers/restore", id: "restoreLedger", tag: "ledgers", summary: "Recreate a ledger from a dump, tenant credentials only", scope: auth.ScopeLedgersWrite, body: LedgerDump{}, status: http.StatusCreated, detail: ImportLedgerResponse{}},
+	{pattern: "PATCH /ledgers/{id}", id: "updateLedger", tag: "ledgers", summary: "Update a ledger", scope: auth.ScopeLedgersWrite, body: UpdateLedgerRequest{}, detail: UpdateLedgerResponse{}},
+	{pattern: "GET /ledgers/{id}/grants", id: "listGrants", tag: "ledgers", summary: "List the users with a role on the ledger", scope: auth.ScopeLedgersRead, detail: []GrantResponse{}},
+	{pattern: "PUT /ledgers/{id}/grants/{user}", id: "putGrant", tag: "ledgers", summary: "Give a user a role on the ledger", scope: auth.ScopeLedgersWrite, body: PutGrantRequest{}, detail: GrantResponse{}},
+	{pattern: "DELETE /ledgers/{id}/grants/{user}", id: "deleteGrant", tag: "ledgers", summary: "Remove the role of a user on the ledger", scope: auth.ScopeLedgersWrite, status: http.StatusNoContent},
+	{pattern: "GET /ledgers/{id}/dump", id: "dumpLedger", tag: "ledgers", summary: "Dump the ledger, its accounts and its transactions", scope: auth.ScopeLedgersRead, raw: LedgerDump{}},
+	{pattern: "GET /ledgers/{id}/events", id: "ledgerEvents", tag: "ledgers", summary: "Stream the changes of the ledger as Server-Sent Events, resume with the Last-Event-ID header", scope: auth.ScopeLedgersRead, params: []openapi.Parameter{{Name: "last_event_id", In: "query", Schema: &openapi.Schema{Type: "string"}}}, content: []string{"text/event-stream"}},
+
+	// reports
+	{pattern: "GET /ledgers/{id}/reports/trial-balance", id: "trialBalance", tag: "reports", summary: "Debit and credit totals of every account", scope: auth.ScopeReportsRead, query: ReportQuery{}, detail: report.TrialBalance{}, content: []string{report.XLSXContentType}},
+	{pattern: "GET /ledgers/{id}/reports/balance-sheet", id: "balanceSheet", tag: "reports", summary: "Assets, liabilities and equity as of `to`", scope: auth.ScopeReportsRead, query: ReportQuery{}, detail: report.BalanceSheet{}, content: []string{report.XLSXContentType}},
+	{pattern: "GET /ledgers/{id}/reports/income-statement", id: "incomeStatement", tag: "reports", summary: "Revenue and expenses of the period", scope: auth.ScopeReportsRead, query: ReportQuery{}, detail: report.IncomeStatement{}, content: []string{report.XLSXContentType}},
+	{pattern: "GET /accounts/{id}/statement", id: "accountStatement", tag: "reports", summary: "Entries of the account with their running balance", scope: auth.ScopeReportsRead, query: ReportQuery{}, detail: report.AccountStatement{}, content: []string{report.XLSXContentType}},
+
+	// accounts
+	{pattern: "GET /accounts", id: "listAccounts", tag: "accounts", summary: "List the accounts of a ledger matching the metadata, 404 when none does", scope: auth.ScopeAccountsRead, params: []openapi.Parameter{{Name: "ledger_uuid", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}}}, metadata: true, detail: []*dbGen.ListAccountsRow{}},
+	{pattern: "POST /accounts", id: "createAccount", tag: "accounts", summary: "Create an account", scope: auth.ScopeAccountsWrite, body: CreateAccountRequest{}, status: http.StatusCreated, detail: CreateAccountResponse{}},
+	{pattern: "PATCH /accounts/{id}", id: "updateAccount", tag: "accounts", summary: "Update an account", scope: auth.ScopeAccountsWrite, body: UpdateAccountRequest{}, detail: UpdateAccountResponse{}},
+
+	// transactions
+	{pattern: "GET /transactions", id: "listTransactions", tag: "transactions", summary: "List the transactions of a ledger matching the metadata, 404 when none does", scope: auth.ScopeTransactionsRead, query: ListTransactionsQuery{}, metadata: true, detail: PaginatedResponse[*dbGen.ListTransactionsRow]{}},
+	{pattern: "GET /transactions/export", id: "exportTransactions", tag: "transactions", summary: "Stream every transaction of a ledger", scope: auth.ScopeTransactionsRead, query: ExportTransactionsQuery{}, metadata: true, content: []string{"application/x-ndjson", "text/csv"}},
+	{pattern: "GET /transactions/{uuid}", id: "getTransaction", tag: "transactions", summary: "Get a transaction", scope: auth.ScopeTransactionsRead, detail: TransactionResponse{}},
+	{pattern: "POST /transactions", id: "createTransaction", tag: "transactions", summary: "Create a transaction", scope: auth.ScopeTransactionsWrite, body: CreateTransactionRequest{}, status: http.StatusCreated, detail: CreateTransactionResponse{}},
+	{pattern: "PATCH /transactions/{uuid}", id: "updateTransaction", tag: "transactions", summary: "Update a transaction", scope: auth.ScopeTransactionsWrite, body: UpdateTransactionRequest{}, detail: UpdateTransactionResponse{}},
+	{pattern: "DELETE /transactions/{uuid}", id: "deleteTransaction", tag: "transactions", summary: "Delete a transaction", scope: auth.ScopeTransactionsWrite, status: http.StatusNoContent},
+
+	// audit
+	{pattern: "GET /audit", id: "listAuditEvents", tag: "audit", summary: "List the audit events, newest first", scope: auth.ScopeAuditRead, query: ListAuditEventsQuery{}, detail: []AuditEventResponse{}},
+
+	// webhooks
+	{pattern: "GET /webhooks", id: "listWebhooks", tag: "webhooks", summary: "List the webhooks, tenant credentials only", scope: auth.ScopeWebhooksRead, detail: []WebhookResponse{}},
+	{pattern: "POST /webhooks", id: "createWebhook", tag: "webhooks", summary: "Register a webhook, the secret is only returned here", scope: auth.ScopeWebhooksWrite, body: CreateWebhookRequest{}, status: http.StatusCreated, detail: WebhookResponse{}},
+	{pattern: "DELETE /webhooks/{id}", id: "deleteWebhook", tag: "webhooks", summary: "Remove a webhook and its pending deliveries", scope: auth.ScopeWebhooksWrite, status: http.StatusNoContent},
+	{pattern: "GET /webhooks/{id}/deliveries", id: "listWebhookDeliveries", tag: "webhooks", summary: "List the deliveries of a webhook, newest first", scope: auth.ScopeWebhooksRead, query: ListWebhookDeliveriesQuery{}, detail: []WebhookDeliveryResponse{}},
+	{pattern: "POST /webhooks/deliveries/{id}/replay", id: "replayWebhookDelivery", tag: "webhooks", summary: "Schedule a delivery again", scope: auth.ScopeWebhooksWrite, status: http.StatusAccepted, detail: ReplayWebhookDeliveryResponse{}},
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// OpenAPI is the OpenAPI document of the routes, generated from the types
+// the handlers decode and encode
+var OpenAPI = sync.OnceValue(func() *openapi.Document {
+	gen := openapi.NewGenerator()
+
+	doc := &openapi.Document{
+		OpenAPI: openapi.Version,
+		Info: openapi.Info{
+			Title:       "doubleed",
+			Description: "Double-entry accounting ledgers. Responses are wrapped in a StandardResponse, errors are ErrorResponses.",
+			Version:     "1",
+		},
+		Paths: map[string]openapi.PathItem{},
+		Components: openapi.Components{
+			SecuritySchemes: map[string]*openapi.SecurityScheme{
+				"bearer": {
+					Type:        "http",
+					Scheme:      "bearer",
+					Description: "An API key, `dde_<prefix>_<secret>`, or a JWT of the identity provider.",
+				},
+			},
+		},
+		Security: []openapi.SecurityRequirement{{"bearer": {}}},
+	}
+
+	envelope := gen.Response(StandardResponse{})
+	errorResponse := &openapi.Response{
+		Description: "Error",
+		Content:     openapi.JSON(gen.Response(ErrorResponse{})),
+	}
+
+	for _, op := range operations {
+		method, path, _ := strings.Cut(op.pattern, " ")
+
+		o := &openapi.Operation{
+			OperationID: op.id,
+			Summary:     op.summary,
+			Tags:        []string{op.tag},
+			Responses:   map[string]*openapi.Response{},
+		}
+		if op.public {
+			o.Security = []openapi.SecurityRequirement{}
+		} else {
+			o.Description = fmt.Sprintf("Requires the `%s` scope.", op.scope)
+			o.Responses["default"] = errorResponse
+		}
+		if op.metadata {
+			o.Description += " Filter by metadata with `metadata.KEY=VALUE` query params, e.g., `metadata.user_id=24`, numbers are compared as numbers."
+		}
+
+		for _, match := range pathParam.FindAllStringSubmatch(path, -1) {
+			o.Parameters = append(o.Parameters, openapi.Parameter{
+				Name:     match[1],
+				In:       "path",
+				Required: true,
+				Schema:   &openapi.Schema{Type: "string"},
+			})
+		}
+		if op.query != nil {
+			o.Parameters = append(o.Parameters, gen.Query(op.query)...)
+		}
+		o.Parameters = append(o.Parameters, op.params...)
+		if method == http.MethodPost && !op.public {
+			maxLength := maxIdempotencyKeyLength
+			o.Parameters = append(o.Parameters, openapi.Parameter{
+				Name:        IdempotencyKeyHeader,
+				In:          "header",
+				Description: "Retries with the same key get the response of the first request back, for 24 hours.",
+				Schema:      &openapi.Schema{Type: "string", MaxLength: &maxLength},
+			})
+		}
+
+		switch {
+		case op.bodyType != "":
+			o.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  map[string]openapi.MediaType{op.bodyType: {Schema: &openapi.Schema{Type: "string"}}},
+			}
+		case op.body != nil:
+			o.RequestBody = &openapi.RequestBody{
+				Required: true,
+				Content:  openapi.JSON(gen.Request(op.body)),
+			}
+		}
+
+		status := op.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		res := &openapi.Response{Description: http.StatusText(status), Content: map[string]openapi.MediaType{}}
+		switch {
+		case op.detail != nil:
+			res.Content = openapi.JSON(&openapi.Schema{AllOf: []*openapi.Schema{
+				envelope,
+				{
+					Type:       "object",
+					Properties: map[string]*openapi.Schema{"detail": gen.Response(op.detail)},
+					Required:   []string{"detail"},
+				},
+			}})
+		case op.raw != nil:
+			res.Content = openapi.JSON(gen.Response(op.raw))
+		}
+		for _, contentType := range op.content {
+			res.Content[contentType] = openapi.MediaType{}
+		}
+		o.Responses[strconv.Itoa(status)] = res
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = openapi.PathItem{}
+		}
+		doc.Paths[path][strings.ToLower(method)] = o
+	}
+
+	doc.Components.Schemas = gen.Schemas()
+	return doc
+})
+
+// HandleOpenAPI serves the OpenAPI document
+func (s *Server) HandleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if err := WriteResponse(w, http.StatusOK, OpenAPI()); err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+	}
+}
+
+// docsPage loads Swagger UI from a CDN, the server doesn't bundle it
+const docsPage = `<!doctype html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>doubleed API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// HandleDocs serves the Swagger UI of the OpenAPI document
+func (s *Server) HandleDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(docsPage)); err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// routeRecorder records the patterns of addRoutes
+type routeRecorder []string
+
+func (r *routeRecorder) Handle(pattern string, _ http.Handler) {
+	*r = append(*r, pattern)
+}
+
+func (r *routeRecorder) HandleFunc(pattern string, _ func(http.ResponseWriter, *http.Request)) {
+	*r = append(*r, pattern)
+}
+
+func TestOpenAPI(t *testing.T) {
+	is := is_.New(t)
+
+	var routes routeRecorder
+	(&Server{}).addRoutes(&routes)
+
+	doc := OpenAPI()
+	for _, pattern := range routes {
+		method, path, _ := strings.Cut(pattern, " ")
+		if doc.Paths[path][strings.ToLower(method)] == nil {
+			t.Errorf("%s is registered in addRoutes but missing from the OpenAPI document", pattern)
+		}
+	}
+	is.Equal(len(operations), len(routes)) // every operation is a route
+
+	ids := map[string]bool{}
+	for _, op := range operations {
+		is.True(!ids[op.id]) // operation ids are unique
+		ids[op.id] = true
+	}
+
+	create := doc.Components.Schemas["CreateTransactionRequest"]
+	is.Equal(create.Required, []string{"amount", "date", "credit_account_uuid", "debit_account_uuid", "ledger_uuid"})
+	is.Equal(*create.Properties["description"].MaxLength, 255)
+}
+
+func TestHandleOpenAPI(t *testing.T) {
+	is := is_.New(t)
+
+	s := NewServer(nil, nil, Options{})
+
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	is.Equal(w.Code, http.StatusOK)
+
+	var doc map[string]any
+	is.NoErr(json.NewDecoder(w.Body).Decode(&doc))
+	is.Equal(doc["openapi"], "3.0.3")
+
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+	is.Equal(w.Code, http.StatusOK)
+	is.True(strings.Contains(w.Body.String(), `url: "/openapi.json"`))
+}
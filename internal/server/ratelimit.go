@@ -0,0 +1,116 @@
+package server
+
+import (
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimits are the limits of each group of routes, per API key, user or
+// client IP. Exports read whole ledgers so they are the most expensive.
+// Auth limits the failed authentications per client IP, once it's reached
+// the IP can't even try until the bucket refills.
+type RateLimits struct {
+	Read   ratelimit.Limit
+	Write  ratelimit.Limit
+	Export ratelimit.Limit
+	Auth   ratelimit.Limit
+}
+
+// DefaultRateLimits are generous enough for interactive use and
+// integrations, and low enough that a single client can't take the
+// instance down
+func DefaultRateLimits() RateLimits {
+	return RateLimits{
+		Read:   ratelimit.Limit{Requests: 1200, Per: time.Minute},
+		Write:  ratelimit.Limit{Requests: 300, Per: time.Minute},
+		Export: ratelimit.Limit{Requests: 10, Per: time.Minute},
+		Auth:   ratelimit.Limit{Requests: 20, Per: time.Minute},
+	}
+}
+
+// Limiters returns a limiter per route group, pass them to the HTTP and
+// gRPC servers so they share the buckets
+func (l RateLimits) Limiters() ratelimit.Limiters {
+	return ratelimit.Limiters{
+		ratelimit.GroupRead:   ratelimit.NewLimiter(l.Read),
+		ratelimit.GroupWrite:  ratelimit.NewLimiter(l.Write),
+		ratelimit.GroupExport: ratelimit.NewLimiter(l.Export),
+		ratelimit.GroupAuth:   ratelimit.NewLimiter(l.Auth),
+	}
+}
+
+// requestRouteGroup classifies the request by its cost
+func requestRouteGroup(r *http.Request) ratelimit.Group {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/export"), strings.HasSuffix(r.URL.Path, "/dump"):
+		return ratelimit.GroupExport
+	case r.Method == http.MethodGet, r.Method == http.MethodHead:
+		return ratelimit.GroupRead
+	default:
+		return ratelimit.GroupWrite
+	}
+}
+
+// rateLimitKey identifies the client, by its credentials when it's
+// authenticated and by its IP otherwise
+func rateLimitKey(r *http.Request) string {
+	if principal := auth.PrincipalFromContext(r.Context()); principal != nil {
+		return principal.LimitKey()
+	}
+	return clientIP(r)
+}
+
+// clientIP is the rate limit key of the client's IP
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// rateLimit rejects the requests of clients over the limit of the route
+// group with a 429. Every limited response has the `RateLimit-*` headers,
+// so clients can slow down before they are rejected.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// probes must never be limited
+		if isProbe(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		group := requestRouteGroup(r)
+		key := rateLimitKey(r)
+		res := s.limiters.Allow(group, key)
+
+		if res.Limit > 0 {
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", res.Limit, ceilSeconds(s.limiters.Limit(group).Per)))
+		}
+
+		if !res.Allowed {
+			slog.InfoContext(r.Context(), "rate limit exceeded", "key", key, "group", group, "path", r.URL.Path)
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			WriteError(w, ErrTooManyRequests, http.StatusTooManyRequests)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds rounds up so clients never retry too early
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
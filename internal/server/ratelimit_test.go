@@ -0,0 +1,101 @@
+package server
+
+import (
+	"github.com/j0lvera/go-double-e/internal/auth"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimit(t *testing.T) {
+	is := is_.New(t)
+
+	s := &Server{limiters: RateLimits{
+		Read:   ratelimit.Limit{Requests: 2, Per: time.Minute},
+		Write:  ratelimit.Limit{Requests: 1, Per: time.Minute},
+		Export: ratelimit.Limit{Requests: 1, Per: time.Hour},
+	}.Limiters()}
+	handler := s.rateLimit(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(method, path string, principal *auth.Principal) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		if principal != nil {
+			r = r.WithContext(auth.WithPrincipal(r.Context(), principal))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	importer := &auth.Principal{KeyUUID: "importer"}
+
+	w := serve("POST", "/transactions", importer)
+	is.Equal(w.Code, http.StatusNoContent)
+	is.Equal(w.Header().Get("RateLimit-Limit"), "1")
+	is.Equal(w.Header().Get("RateLimit-Remaining"), "0")
+	is.Equal(w.Header().Get("RateLimit-Policy"), "1;w=60")
+
+	w = serve("POST", "/transactions", importer)
+	is.Equal(w.Code, http.StatusTooManyRequests)  // write limit exceeded
+	is.Equal(w.Header().Get("Retry-After"), "60") // one write a minute
+
+	w = serve("GET", "/transactions", importer)
+	is.Equal(w.Code, http.StatusNoContent) // reads have their own limit
+
+	w = serve("GET", "/transactions/export", importer)
+	is.Equal(w.Code, http.StatusNoContent)
+	w = serve("GET", "/transactions/export", importer)
+	is.Equal(w.Code, http.StatusTooManyRequests) // exports are stricter
+
+	w = serve("POST", "/transactions", &auth.Principal{KeyUUID: "other"})
+	is.Equal(w.Code, http.StatusNoContent) // keys have their own buckets
+
+	w = serve("POST", "/transactions", nil)
+	is.Equal(w.Code, http.StatusNoContent) // anonymous clients by IP
+	w = serve("POST", "/transactions", nil)
+	is.Equal(w.Code, http.StatusTooManyRequests)
+
+	for range 3 {
+		w = serve("GET", "/health", nil)
+		is.Equal(w.Code, http.StatusNoContent) // probes aren't limited
+	}
+}
+
+func TestFailedAuthenticationLimit(t *testing.T) {
+	is := is_.New(t)
+
+	s := &Server{limiters: RateLimits{
+		Auth: ratelimit.Limit{Requests: 2, Per: time.Minute},
+	}.Limiters()}
+	handler := s.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	serve := func(authorization string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/ledgers", nil)
+		if authorization != "" {
+			r.Header.Set("Authorization", authorization)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	for range 2 {
+		w := serve("Basic dXNlcjpwYXNz")
+		is.Equal(w.Code, http.StatusUnauthorized)
+	}
+
+	// the authenticator is never reached, the server has none
+	w := serve("Bearer guess")
+	is.Equal(w.Code, http.StatusTooManyRequests)  // failed authentication limit exceeded
+	is.Equal(w.Header().Get("Retry-After"), "30") // two failures a minute
+
+	w = serve("")
+	is.Equal(w.Code, http.StatusNoContent) // anonymous requests aren't authenticated
+}
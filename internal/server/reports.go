@@ -0,0 +1,205 @@
+package server
+
+import (
+	"fmt"
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/j0lvera/go-double-e/internal/report"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ReportQuery is shared by every report. Amounts are integers in minor
+// units, Scale is only used to turn them into major units in workbooks.
+// Without it workbooks use the scale of the ledger.
+type ReportQuery struct {
+	From   string `form:"from" json:"from" validate:"omitempty,datetime=2006-01-02"`
+	To     string `form:"to" json:"to" validate:"omitempty,datetime=2006-01-02"`
+	Format string `form:"format" json:"format" validate:"omitempty,oneof=json xlsx"`
+	Scale  *int   `form:"scale" json:"scale" validate:"omitempty,min=0,max=9"`
+}
+
+// HandleTrialBalance lists the debit and credit totals of every account of
+// the ledger between `from` and `to`.
+func (s *Server) HandleTrialBalance(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "report.trial_balance.start")
+
+	query, ok := decodeReportQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ledgerUUID := r.PathValue("id")
+	period := report.Period{From: query.From, To: query.To}
+
+	rep, err := s.ledger.TrialBalance(r.Context(), ledgerUUID, period)
+	if err != nil {
+		writeLedgerError(w, r, "unable to get trial balance", err)
+		return
+	}
+
+	s.writeReport(w, r, query, ledgerUUID, "trial-balance", rep)
+
+	slog.DebugContext(r.Context(), "report.trial_balance.complete",
+		"ledger_uuid", ledgerUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// HandleBalanceSheet reports the assets, liabilities and equity of the
+// ledger as of `to`, `from` is ignored since balances are cumulative.
+func (s *Server) HandleBalanceSheet(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "report.balance_sheet.start")
+
+	query, ok := decodeReportQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ledgerUUID := r.PathValue("id")
+	period := report.Period{From: query.From, To: query.To}
+
+	rep, err := s.ledger.BalanceSheet(r.Context(), ledgerUUID, period)
+	if err != nil {
+		writeLedgerError(w, r, "unable to get balance sheet", err)
+		return
+	}
+
+	s.writeReport(w, r, query, ledgerUUID, "balance-sheet", rep)
+
+	slog.DebugContext(r.Context(), "report.balance_sheet.complete",
+		"ledger_uuid", ledgerUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// HandleIncomeStatement reports the revenue and expenses of the ledger
+// between `from` and `to`.
+func (s *Server) HandleIncomeStatement(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "report.income_statement.start")
+
+	query, ok := decodeReportQuery(w, r)
+	if !ok {
+		return
+	}
+
+	ledgerUUID := r.PathValue("id")
+	period := report.Period{From: query.From, To: query.To}
+
+	rep, err := s.ledger.IncomeStatement(r.Context(), ledgerUUID, period)
+	if err != nil {
+		writeLedgerError(w, r, "unable to get income statement", err)
+		return
+	}
+
+	s.writeReport(w, r, query, ledgerUUID, "income-statement", rep)
+
+	slog.DebugContext(r.Context(), "report.income_statement.complete",
+		"ledger_uuid", ledgerUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// HandleAccountStatement lists the transactions of an account between
+// `from` and `to` with their running balance.
+func (s *Server) HandleAccountStatement(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "report.account_statement.start")
+
+	query, ok := decodeReportQuery(w, r)
+	if !ok {
+		return
+	}
+
+	accountUUID := r.PathValue("id")
+	period := report.Period{From: query.From, To: query.To}
+
+	statement, err := s.ledger.AccountStatement(r.Context(), accountUUID, period)
+	if err != nil {
+		writeLedgerError(w, r, "unable to get account statement", err)
+		return
+	}
+
+	s.writeReport(w, r, query, statement.Account.LedgerUUID, "account-statement", statement)
+
+	slog.DebugContext(r.Context(), "report.account_statement.complete",
+		"account_uuid", accountUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// decodeReportQuery writes the error response and returns false when the
+// query params are invalid
+func decodeReportQuery(w http.ResponseWriter, r *http.Request) (ReportQuery, bool) {
+	decoder := form.NewDecoder()
+
+	var query ReportQuery
+
+	// decode the query params
+	if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+		slog.InfoContext(r.Context(), "unable to decode query params", "error", err)
+		slog.DebugContext(r.Context(), "query params decoding", "query_params", r.URL.Query())
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return query, false
+	}
+
+	// validate the query params
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(query); err != nil {
+		validationErrors := ParseValidationErrors(err)
+		res := map[string][]ValidationError{
+			"errors": validationErrors,
+		}
+		slog.InfoContext(r.Context(), "unable to validate query params", "error", err)
+		slog.DebugContext(r.Context(), "query params validation", "query_params", r.URL.Query(), "validation_errors", res)
+		WriteError(w, res, http.StatusBadRequest)
+		return query, false
+	}
+
+	return query, true
+}
+
+// writeReport writes the report as JSON, or as an Excel workbook when it's
+// requested with `?format=xlsx` or the Accept header. Workbooks default to
+// the scale the ledger was imported with.
+func (s *Server) writeReport(w http.ResponseWriter, r *http.Request, query ReportQuery, ledgerUUID, name string, rep report.Report) {
+	if !wantsXLSX(r, query) {
+		res := NewResponse("OK", 1, "OBJ", rep)
+		if err := WriteResponse(w, http.StatusOK, res); err != nil {
+			slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		}
+		return
+	}
+
+	scale := 2
+	if query.Scale != nil {
+		scale = *query.Scale
+	} else if l, err := s.ledger.GetLedger(r.Context(), ledgerUUID); err == nil {
+		scale = ledger.JournalScale(l)
+	} else {
+		slog.InfoContext(r.Context(), "unable to get ledger scale", "error", err)
+	}
+
+	w.Header().Set("Content-Type", report.XLSXContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.xlsx"`, name))
+	w.WriteHeader(http.StatusOK)
+
+	if err := report.WriteXLSX(w, rep, scale); err != nil {
+		// the status code is already sent, the client sees a truncated body
+		slog.ErrorContext(r.Context(), "unable to write workbook", "error", err)
+	}
+}
+
+// wantsXLSX gives the format param precedence over the Accept header
+func wantsXLSX(r *http.Request, query ReportQuery) bool {
+	if query.Format != "" {
+		return query.Format == "xlsx"
+	}
+	return strings.Contains(r.Header.Get("Accept"), report.XLSXContentType)
+}
@@ -1,47 +1,148 @@
 package server
 
 import (
+	"github.com/j0lvera/go-double-e/internal/auth"
 	"github.com/j0lvera/go-double-e/internal/db"
+	"github.com/j0lvera/go-double-e/internal/events"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/j0lvera/go-double-e/internal/metrics"
+	"github.com/j0lvera/go-double-e/internal/ratelimit"
 	"net/http"
+	"sync/atomic"
+	"time"
 )
 
 type Server struct {
 	client *db.Client
+	broker *events.Broker
+	// ledger owns the business rules the handlers share with the gRPC API
+	ledger *ledger.Service
+	// authenticator resolves the API keys, and the JWTs when there is a
+	// verifier
+	authenticator *auth.Authenticator
+	limiters      ratelimit.Limiters
+	// metrics record the requests and transactions, nil disables them.
+	// They are served by an admin server of their own, not the API.
+	metrics *metrics.Metrics
+	// maxReplicationLag fails the readiness probe when the database is a
+	// replica lagging further behind, zero skips the check
+	maxReplicationLag time.Duration
+	// allowPrivateWebhooks lets the webhooks reach private addresses, for
+	// development only
+	allowPrivateWebhooks bool
+	// draining is set once the server is shutting down
+	draining atomic.Bool
+
+	handler http.Handler
+}
+
+// Options of the server, the zero value accepts API keys only, without
+// rate limits nor metrics
+type Options struct {
+	Verifier auth.TokenVerifier
+	// RateLimiters are the limiters of RateLimits.Limiters, shared with the
+	// gRPC server
+	RateLimiters      ratelimit.Limiters
+	Metrics           *metrics.Metrics
+	MaxReplicationLag time.Duration
+	// AllowPrivateWebhooks accepts the webhook URLs of loopback and private
+	// addresses, the dispatcher must be allowed to reach them as well
+	AllowPrivateWebhooks bool
 }
 
-func NewServer(client *db.Client) http.Handler {
-	// top level HTTP that applies to all routes, e.g.,
-	// CORS, auth middlewares, logging, etc.
+func NewServer(client *db.Client, broker *events.Broker, opts Options) *Server {
+	// top level HTTP that applies to all routes: tracing, request IDs,
+	// access logs, metrics, auth, rate limits and idempotency keys
 
-	srv := Server{
-		client: client,
+	srv := &Server{
+		client:               client,
+		broker:               broker,
+		ledger:               ledger.NewService(client),
+		authenticator:        auth.NewAuthenticator(client, opts.Verifier),
+		limiters:             opts.RateLimiters,
+		metrics:              opts.Metrics,
+		maxReplicationLag:    opts.MaxReplicationLag,
+		allowPrivateWebhooks: opts.AllowPrivateWebhooks,
 	}
 
 	mux := http.NewServeMux()
 	srv.addRoutes(mux)
 
-	// add middlewares here
+	// add middlewares here, the last one runs first
 
 	var handler http.Handler = mux
-	return handler
+	handler = srv.idempotent(handler)
+	// limited after authenticating, so clients are told apart by their
+	// credentials rather than their IPs
+	handler = srv.rateLimit(handler)
+	handler = srv.authenticate(handler)
+	handler = srv.observeRequests(mux, handler)
+	handler = accessLog(handler)
+	handler = requestID(handler)
+	handler = traceRequests(mux, handler)
+	srv.handler = handler
+
+	return srv
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
 }
 
-func (s *Server) addRoutes(mux *http.ServeMux) {
+// routes is the part of http.ServeMux the routes are added to
+type routes interface {
+	Handle(pattern string, handler http.Handler)
+	HandleFunc(pattern string, handler func(http.ResponseWriter, *http.Request))
+}
+
+func (s *Server) addRoutes(mux routes) {
+	// scopes limit what a key can do, roles what the user of the key can
+	// do on each ledger
+	// public
 	mux.HandleFunc("GET /health", s.HandleHealthCheck)
+	mux.HandleFunc("GET /livez", s.HandleLivez)
+	mux.HandleFunc("GET /readyz", s.HandleReadyz)
+	mux.HandleFunc("GET /openapi.json", s.HandleOpenAPI)
+	mux.HandleFunc("GET /docs", s.HandleDocs)
 
 	// ledgers
-	mux.HandleFunc("GET /ledgers", s.HandleListLedgers)
-	mux.HandleFunc("POST /ledgers", s.HandleCreateLedger)
-	mux.HandleFunc("PATCH /ledgers/{id}", s.HandleUpdateLedger)
+	mux.HandleFunc("GET /ledgers", requireScope(auth.ScopeLedgersRead, s.HandleListLedgers))
+	mux.HandleFunc("POST /ledgers", requireScope(auth.ScopeLedgersWrite, s.HandleCreateLedger))
+	mux.HandleFunc("POST /ledgers/import", requireScope(auth.ScopeLedgersWrite, s.HandleImportLedger))
+	mux.HandleFunc("POST /ledgers/restore", requireScope(auth.ScopeLedgersWrite, s.HandleRestoreLedger))
+	mux.HandleFunc("PATCH /ledgers/{id}", requireScope(auth.ScopeLedgersWrite, s.HandleUpdateLedger))
+	mux.HandleFunc("GET /ledgers/{id}/grants", requireScope(auth.ScopeLedgersRead, s.HandleListGrants))
+	mux.HandleFunc("PUT /ledgers/{id}/grants/{user}", requireScope(auth.ScopeLedgersWrite, s.HandlePutGrant))
+	mux.HandleFunc("DELETE /ledgers/{id}/grants/{user}", requireScope(auth.ScopeLedgersWrite, s.HandleDeleteGrant))
+	mux.HandleFunc("GET /ledgers/{id}/dump", requireScope(auth.ScopeLedgersRead, s.HandleDumpLedger))
+	mux.HandleFunc("GET /ledgers/{id}/events", requireScope(auth.ScopeLedgersRead, s.HandleLedgerEvents))
+
+	// reports
+	mux.HandleFunc("GET /ledgers/{id}/reports/trial-balance", requireScope(auth.ScopeReportsRead, s.HandleTrialBalance))
+	mux.HandleFunc("GET /ledgers/{id}/reports/balance-sheet", requireScope(auth.ScopeReportsRead, s.HandleBalanceSheet))
+	mux.HandleFunc("GET /ledgers/{id}/reports/income-statement", requireScope(auth.ScopeReportsRead, s.HandleIncomeStatement))
+	mux.HandleFunc("GET /accounts/{id}/statement", requireScope(auth.ScopeReportsRead, s.HandleAccountStatement))
 
 	// accounts
-	mux.HandleFunc("GET /accounts", s.HandleListAccounts)
-	mux.HandleFunc("POST /accounts", s.HandleCreateAccount)
-	mux.HandleFunc("PATCH /accounts/{id}", s.HandleUpdateAccount)
+	mux.HandleFunc("GET /accounts", requireScope(auth.ScopeAccountsRead, s.HandleListAccounts))
+	mux.HandleFunc("POST /accounts", requireScope(auth.ScopeAccountsWrite, s.HandleCreateAccount))
+	mux.HandleFunc("PATCH /accounts/{id}", requireScope(auth.ScopeAccountsWrite, s.HandleUpdateAccount))
 
 	// transactions
-	mux.HandleFunc("GET /transactions", s.HandleListTransactions)
-	mux.HandleFunc("POST /transactions", s.HandleCreateTransaction)
-	mux.HandleFunc("PATCH /transactions/{uuid}", s.HandleUpdateTransaction)
-	mux.HandleFunc("DELETE /transactions/{uuid}", s.HandleDeleteTransaction)
+	mux.HandleFunc("GET /transactions", requireScope(auth.ScopeTransactionsRead, s.HandleListTransactions))
+	mux.HandleFunc("GET /transactions/export", requireScope(auth.ScopeTransactionsRead, s.HandleExportTransactions))
+	mux.HandleFunc("GET /transactions/{uuid}", requireScope(auth.ScopeTransactionsRead, s.HandleGetTransaction))
+	mux.HandleFunc("POST /transactions", requireScope(auth.ScopeTransactionsWrite, s.HandleCreateTransaction))
+	mux.HandleFunc("PATCH /transactions/{uuid}", requireScope(auth.ScopeTransactionsWrite, s.HandleUpdateTransaction))
+	mux.HandleFunc("DELETE /transactions/{uuid}", requireScope(auth.ScopeTransactionsWrite, s.HandleDeleteTransaction))
+
+	// audit
+	mux.HandleFunc("GET /audit", requireScope(auth.ScopeAuditRead, s.HandleListAuditEvents))
+
+	// webhooks
+	mux.HandleFunc("GET /webhooks", requireScope(auth.ScopeWebhooksRead, tenantOnly(s.HandleListWebhooks)))
+	mux.HandleFunc("POST /webhooks", requireScope(auth.ScopeWebhooksWrite, tenantOnly(s.HandleCreateWebhook)))
+	mux.HandleFunc("DELETE /webhooks/{id}", requireScope(auth.ScopeWebhooksWrite, tenantOnly(s.HandleDeleteWebhook)))
+	mux.HandleFunc("GET /webhooks/{id}/deliveries", requireScope(auth.ScopeWebhooksRead, tenantOnly(s.HandleListWebhookDeliveries)))
+	mux.HandleFunc("POST /webhooks/deliveries/{id}/replay", requireScope(auth.ScopeWebhooksWrite, tenantOnly(s.HandleReplayWebhookDelivery)))
 }
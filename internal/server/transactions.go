@@ -1,10 +1,10 @@
 package server
 
 import (
-	"encoding/json"
 	"github.com/go-playground/form/v4"
 	"github.com/go-playground/validator/v10"
 	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
 	"github.com/jackc/pgx/v5/pgtype"
 	"log/slog"
 	"net/http"
@@ -22,96 +22,52 @@ type CreateTransactionRequest struct {
 	LedgerUUID        string                 `json:"ledger_uuid" validate:"required"`
 }
 
+type CreateTransactionResponse struct {
+	UUID        string      `json:"uuid"`
+	Amount      int64       `json:"amount"`
+	Date        time.Time   `json:"date"`
+	Description pgtype.Text `json:"description"`
+}
+
 func (s *Server) HandleCreateTransaction(w http.ResponseWriter, r *http.Request) {
 	startReqTime := time.Now()
-	slog.Debug("transaction.create.start",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"remoted_add", r.RemoteAddr,
-	)
+	slog.DebugContext(r.Context(), "transaction.create.start")
 
 	// decode the request body
 	req, err := Decode[CreateTransactionRequest](r)
 	if err != nil {
-		slog.Info("unable to decode request body", "error", err)
-		slog.Debug("request body decoding", "body", r.Body)
+		slog.InfoContext(r.Context(), "unable to decode request body", "error", err)
+		slog.DebugContext(r.Context(), "request body decoding", "body", r.Body)
 		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	slog.Debug("body decoding", "body", r.Body, "request", req)
-
-	// validate the request
-	validate := validator.New(validator.WithRequiredStructEnabled())
-	if err = validate.Struct(req); err != nil {
-		validationErrors := ParseValidationErrors(err)
-
-		res := map[string][]ValidationError{
-			"errors": validationErrors,
-		}
-
-		slog.Info("unable to validate request", "error", err)
-		slog.Debug("request validation", "body", r.Body, "validation_errors", res)
+	slog.DebugContext(r.Context(), "body decoding", "body", r.Body, "request", req)
 
-		WriteError(w, res, http.StatusBadRequest)
-		return
-	}
-
-	// cast the date field to a pgtype.Date
-	date := pgtype.Date{
-		Time:  req.Date,
-		Valid: true,
-	}
-
-	//// cast the description field to a pgtype.Text
-	//description := pgtype.Text{
-	//	String: req.Description,
-	//	Valid:  true,
-	//}
-
-	// marshal the metadata field
-	metadataBytes, err := json.Marshal(req.Metadata)
-	if err != nil {
-		slog.Info("unable to marshal metadata", "error", err)
-		slog.Debug("metadata marshalling", "metadata", req.Metadata)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
-		return
-	}
-
-	transactionParams := dbGen.CreateTransactionParams{
+	transaction, err := s.ledger.PostTransaction(r.Context(), ledger.PostTransactionParams{
 		Amount:            req.Amount,
-		Date:              date,
+		Date:              req.Date,
 		Description:       req.Description,
-		Metadata:          metadataBytes,
-		CreditAccountUuid: req.CreditAccountUUID,
-		DebitAccountUuid:  req.DebitAccountUUID,
-		LedgerUuid:        req.LedgerUUID,
-	}
-
-	transaction, err := s.client.Queries.CreateTransaction(r.Context(), transactionParams)
+		Metadata:          req.Metadata,
+		CreditAccountUUID: req.CreditAccountUUID,
+		DebitAccountUUID:  req.DebitAccountUUID,
+		LedgerUUID:        req.LedgerUUID,
+	})
 	if err != nil {
-		slog.Error("unable to create transaction", "error", err)
-		slog.Debug("transaction creation", "params", transactionParams)
-
 		// TODO:
 		// - [ ] handle errors, e.g., "ERROR: Total balance of entries must be 0 (SQLSTATE P0001)" should be invalid request.
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		writeLedgerError(w, r, "unable to create transaction", err)
 		return
 	}
 
-	slog.Debug("transaction creation",
+	s.metrics.TransactionCreated(req.LedgerUUID, transaction.Amount)
+
+	slog.DebugContext(r.Context(), "transaction creation",
 		"transaction", transaction,
 		"query_time", time.Since(startReqTime),
 	)
 
-	detail := struct {
-		UUID        string      `json:"uuid"`
-		Amount      int64       `json:"amount"`
-		Date        time.Time   `json:"date"`
-		Description pgtype.Text `json:"description"`
-	}{
+	detail := CreateTransactionResponse{
 		UUID:        transaction.Uuid,
 		Amount:      transaction.Amount,
 		Date:        transaction.Date.Time,
@@ -121,13 +77,13 @@ func (s *Server) HandleCreateTransaction(w http.ResponseWriter, r *http.Request)
 	res := NewResponse("OK", 1, "OBJ", detail)
 	err = WriteResponse(w, http.StatusCreated, res)
 	if err != nil {
-		slog.Error("unable to write response", "error", err)
-		slog.Debug("response writing", "response", res)
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
 		return
 	}
 
-	slog.Info("transaction created", "uuid", transaction.Uuid, "description", transaction.Description)
-	slog.Debug("transaction.create.complete",
+	slog.InfoContext(r.Context(), "transaction created", "uuid", transaction.Uuid, "description", transaction.Description)
+	slog.DebugContext(r.Context(), "transaction.create.complete",
 		"transaction_uuid", transaction.Uuid,
 		"duration", time.Since(startReqTime),
 	)
@@ -153,11 +109,7 @@ func (s *Server) HandleListTransactions(w http.ResponseWriter, r *http.Request)
 	decoder := form.NewDecoder()
 
 	startReqTime := time.Now()
-	slog.Debug("transaction.list.start",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"remote_add", r.RemoteAddr,
-	)
+	slog.DebugContext(r.Context(), "transaction.list.start")
 
 	// default values
 	query := ListTransactionsQuery{
@@ -167,13 +119,13 @@ func (s *Server) HandleListTransactions(w http.ResponseWriter, r *http.Request)
 
 	// decode the query params
 	if err := decoder.Decode(&query, r.URL.Query()); err != nil {
-		slog.Info("unable to decode query params", "error", err)
-		slog.Debug("query params decoding", "query_params", r.URL.Query())
+		slog.InfoContext(r.Context(), "unable to decode query params", "error", err)
+		slog.DebugContext(r.Context(), "query params decoding", "query_params", r.URL.Query())
 		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	slog.Debug("query params decoding", "query_params", r.URL.Query(), "query", query)
+	slog.DebugContext(r.Context(), "query params decoding", "query_params", r.URL.Query(), "query", query)
 
 	// validate the query params
 	validate := validator.New(validator.WithRequiredStructEnabled())
@@ -182,65 +134,39 @@ func (s *Server) HandleListTransactions(w http.ResponseWriter, r *http.Request)
 		res := map[string][]ValidationError{
 			"errors": validationErrors,
 		}
-		slog.Info("unable to validate query params", "error", err)
-		slog.Debug("query params validation", "query_params", r.URL.Query(), "validation_errors", res)
+		slog.InfoContext(r.Context(), "unable to validate query params", "error", err)
+		slog.DebugContext(r.Context(), "query params validation", "query_params", r.URL.Query(), "validation_errors", res)
 		WriteError(w, res, http.StatusBadRequest)
 		return
 	}
 
 	// parse metadata query param e.g., metadata.user_id=25
-	metadataBytes, err := parseMetadataParam(r)
-	if err != nil {
-		slog.Info("unable to parse metadata query param", "error", err)
-		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
-		return
-	}
-
-	listTransactionsParams := dbGen.ListTransactionsParams{
-		LedgerUuid: query.LedgerUUID,
-		Metadata:   metadataBytes,
-		Limit:      query.Limit,
-		Offset:     query.Offset,
-	}
+	metadata := parseMetadataFilter(r)
 
 	startQueryTime := time.Now()
 
-	// get the transactions from the database
-	transactions, err := s.client.Queries.ListTransactions(r.Context(), listTransactionsParams)
-	if err != nil {
-		slog.Error("unable to list transactions", "error", err)
-
-		deadline, _ := r.Context().Deadline()
-		slog.Debug("transaction listing", "query_timeout", deadline)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
-		return
-	}
-
-	totalCount, err := s.client.Queries.GetTransactionsCount(r.Context(), dbGen.GetTransactionsCountParams{
-		LedgerUuid: query.LedgerUUID,
-		Metadata:   metadataBytes,
+	page, err := s.ledger.ListTransactions(r.Context(), ledger.ListTransactionsParams{
+		LedgerUUID: query.LedgerUUID,
+		Metadata:   metadata,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
 	})
 	if err != nil {
-		slog.Error("unable to get transactions count", "error", err)
-
-		deadline, _ := r.Context().Deadline()
-		slog.Debug("transaction count query", "query_params", listTransactionsParams, "query_timeout", deadline)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		writeLedgerError(w, r, "unable to list transactions", err)
 		return
 	}
 
+	transactions, totalCount := page.Transactions, page.Total
 	transactionsCount := len(transactions)
 
-	slog.Debug("transaction listing",
+	slog.DebugContext(r.Context(), "transaction listing",
 		"transactions_count", transactionsCount,
 		"query_time", time.Since(startQueryTime),
 	)
 
 	if transactionsCount == 0 {
-		slog.Info("no transactions found", "metadata_filter", string(metadataBytes))
-		slog.Debug("transaction.list.complete",
+		slog.InfoContext(r.Context(), "no transactions found", "metadata_filter", metadata)
+		slog.DebugContext(r.Context(), "transaction.list.complete",
 			"transactions_count", transactionsCount,
 			"duration", time.Since(startReqTime),
 		)
@@ -257,21 +183,21 @@ func (s *Server) HandleListTransactions(w http.ResponseWriter, r *http.Request)
 			Offset int32 `json:"offset"`
 		}{
 			Total:  int32(totalCount),
-			Limit:  query.Limit,
-			Offset: query.Offset,
+			Limit:  page.Limit,
+			Offset: page.Offset,
 		},
 	}
 	w.Header().Set("X-Total-Count", strconv.FormatInt(totalCount, 10))
 	res := NewResponse("OK", transactionsCount, "LIST", paginatedResponse)
 	err = WriteResponse(w, http.StatusOK, res)
 	if err != nil {
-		slog.Error("unable to write response", "error", err)
-		slog.Debug("response writing", "response", res)
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
 		return
 	}
 
-	slog.Info("transactions listed", "count", transactionsCount)
-	slog.Debug(
+	slog.InfoContext(r.Context(), "transactions listed", "count", transactionsCount)
+	slog.DebugContext(r.Context(),
 		"transaction.list.complete",
 		"transactions_count", transactionsCount,
 		"duration", time.Since(startReqTime),
@@ -288,142 +214,63 @@ type UpdateTransactionRequest struct {
 	LedgerID          *pgtype.Text            `json:"ledger_uuid,omitempty"`
 }
 
+type UpdateTransactionResponse struct {
+	Uuid        string      `json:"uuid"`
+	Amount      int64       `json:"amount"`
+	Date        pgtype.Date `json:"date"`
+	Description pgtype.Text `json:"description"`
+	//Metadata map[string]interface{} `json:"metadata"`
+}
+
+// TransactionResponse is the snapshot of a transaction, the same the audit
+// log keeps
+type TransactionResponse ledger.Transaction
+
 func (s *Server) HandleUpdateTransaction(w http.ResponseWriter, r *http.Request) {
 	startReqTime := time.Now()
 
-	slog.Debug("transaction.update.start",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"remote_add", r.RemoteAddr,
-	)
+	slog.DebugContext(r.Context(), "transaction.update.start")
 
 	txnUUID := r.PathValue("uuid")
 
 	// decode the request body
 	req, err := Decode[UpdateTransactionRequest](r)
 	if err != nil {
-		slog.Info("unable to decode request body", "error", err)
-		slog.Debug("body decoding", "body", r.Body)
-		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
-		return
-	}
-
-	slog.Debug("body decoding", "request", req)
-
-	// return invalid request on empty body, e.g., {}
-	if isEmptyJSON(req) {
-		slog.Info("empty update request body")
+		slog.InfoContext(r.Context(), "unable to decode request body", "error", err)
+		slog.DebugContext(r.Context(), "body decoding", "body", r.Body)
 		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
-	// validate the request
-	validate := validator.New(validator.WithRequiredStructEnabled())
-	if err = validate.Struct(req); err != nil {
-		validationErrors := ParseValidationErrors(err)
-
-		res := map[string][]ValidationError{
-			"errors": validationErrors,
-		}
-
-		slog.Info("unable to validate request", "errors", res)
-		slog.Debug("request validation", "body", r.Body)
-
-		WriteError(w, res, http.StatusBadRequest)
-		return
-	}
-
-	// marshal the metadata field
-	metadataValue, metadataIsValid := ptrValue(req.Metadata)
-	var metadataBytes []byte
-	if metadataIsValid {
-		metadataBytes, err = json.Marshal(metadataValue)
-		if err != nil {
-			slog.Info("unable to marshal metadata", "error", err)
-			slog.Debug("metadata marshaling", "metadata", metadataValue)
-			WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
-			return
-		}
-	}
-
-	// cast the amount to pgtype.Int8
-	amountValue, amountIsValid := ptrValue(req.Amount)
-	amount := pgtype.Int8{
-		Int64: amountValue,
-		Valid: amountIsValid,
-	}
-
-	// cast the date to pgtype.Date
-	dateValue, dateIsValid := ptrValue(req.Date)
-	date := pgtype.Date{
-		Time:  dateValue,
-		Valid: dateIsValid,
-	}
-
-	// cast the description to pgtype.Text
-	descValue, descIsValid := ptrValue(req.Description)
-	description := pgtype.Text{
-		String: descValue.String,
-		Valid:  descIsValid,
-	}
-
-	// cast the credit account id to pgtype.int8
-	creditIdValue, creditIdIsValid := ptrValue(req.CreditAccountUuid)
-	creditAccountUuid := pgtype.Text{
-		String: creditIdValue.String,
-		Valid:  creditIdIsValid,
-	}
-
-	// cast the debit account id to pgtype.int8
-	debitIdValue, debitIdIsValid := ptrValue(req.DebitAccountUuid)
-	debitAccountUuid := pgtype.Text{
-		String: debitIdValue.String,
-		Valid:  debitIdIsValid,
-	}
+	slog.DebugContext(r.Context(), "body decoding", "request", req)
 
-	// cast the ledger id to pgtype.int8
-	ledgerIdValue, ledgerIdIsValid := ptrValue(req.LedgerID)
-	ledgerUuid := pgtype.Text{
-		String: ledgerIdValue.String,
-		Valid:  ledgerIdIsValid,
+	params := ledger.UpdateTransactionParams{
+		Amount:            req.Amount,
+		Date:              req.Date,
+		Description:       textValue(req.Description),
+		CreditAccountUUID: textValue(req.CreditAccountUuid),
+		DebitAccountUUID:  textValue(req.DebitAccountUuid),
+		LedgerUUID:        textValue(req.LedgerID),
 	}
-
-	txnParams := dbGen.UpdateTransactionParams{
-		Uuid:              txnUUID,
-		Amount:            amount,
-		Date:              date,
-		Description:       description,
-		Metadata:          metadataBytes,
-		CreditAccountUuid: creditAccountUuid,
-		DebitAccountUuid:  debitAccountUuid,
-		LedgerUuid:        ledgerUuid,
+	if req.Metadata != nil {
+		params.Metadata = *req.Metadata
 	}
 
 	startQueryTime := time.Now()
 
-	txn, err := s.client.Queries.UpdateTransaction(r.Context(), txnParams)
+	txn, err := s.ledger.UpdateTransaction(r.Context(), txnUUID, params)
 	if err != nil {
-		slog.Error("unable to update transaction", "error", err)
-		slog.Debug("transaction update", "params", txnParams)
-		deadline, _ := r.Context().Deadline()
-		slog.Debug("transaction update", "query_timeout", deadline)
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		writeLedgerError(w, r, "unable to update transaction", err)
 		return
 	}
 
-	slog.Debug("transaction update",
+	slog.DebugContext(r.Context(), "transaction update",
 		"uuid", txn.Uuid,
 		"query_time", time.Since(startQueryTime),
 	)
 
 	// format response
-	detail := struct {
-		Uuid        string      `json:"uuid"`
-		Amount      int64       `json:"amount"`
-		Date        pgtype.Date `json:"date"`
-		Description pgtype.Text `json:"description"`
-		//Metadata map[string]interface{} `json:"metadata"`
-	}{
+	detail := UpdateTransactionResponse{
 		Uuid:        txn.Uuid,
 		Amount:      txn.Amount,
 		Date:        txn.Date,
@@ -434,48 +281,67 @@ func (s *Server) HandleUpdateTransaction(w http.ResponseWriter, r *http.Request)
 	res := NewResponse("OK", 1, "OBJ", detail)
 	err = WriteResponse(w, http.StatusOK, res)
 	if err != nil {
-		slog.Error("unable to write response", "error", err)
-		slog.Debug("response writing", "response", res)
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
 		return
 	}
-	slog.Info("transaction updated", "uuid", txn.Uuid)
-	slog.Debug(
+	slog.InfoContext(r.Context(), "transaction updated", "uuid", txn.Uuid)
+	slog.DebugContext(r.Context(),
 		"transaction.update.complete",
 		"uuid", txn.Uuid,
 		"duration", time.Since(startReqTime),
 	)
 }
 
-func (s *Server) HandleDeleteTransaction(w http.ResponseWriter, r *http.Request) {
+// HandleGetTransaction returns a transaction with the UUIDs of its
+// accounts and ledger
+func (s *Server) HandleGetTransaction(w http.ResponseWriter, r *http.Request) {
 	startReqTime := time.Now()
-	slog.Debug("transaction.delete.start",
-		"method", r.Method,
-		"path", r.URL.Path,
-		"remote_add", r.RemoteAddr,
+	slog.DebugContext(r.Context(), "transaction.get.start")
+
+	txnUUID := r.PathValue("uuid")
+
+	transaction, err := s.ledger.GetTransaction(r.Context(), txnUUID)
+	if err != nil {
+		writeLedgerError(w, r, "unable to get transaction", err)
+		return
+	}
+
+	detail := TransactionResponse(*transaction)
+
+	res := NewResponse("OK", 1, "OBJ", detail)
+	err = WriteResponse(w, http.StatusOK, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "transaction.get.complete",
+		"transaction_uuid", txnUUID,
+		"duration", time.Since(startReqTime),
 	)
+}
+
+func (s *Server) HandleDeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "transaction.delete.start")
 
 	txnUUID := r.PathValue("uuid")
 	if txnUUID == "" {
-		slog.Info("transaction uuid is required")
+		slog.InfoContext(r.Context(), "transaction uuid is required")
 		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
 		return
 	}
 
 	startQueryTime := time.Now()
 
-	err := s.client.Queries.DeleteTransaction(r.Context(), txnUUID)
+	err := s.ledger.DeleteTransaction(r.Context(), txnUUID)
 	if err != nil {
-		slog.Error("unable to delete transaction", "error", err)
-		slog.Debug("transaction deletion", "uuid", txnUUID)
-
-		deadline, _ := r.Context().Deadline()
-		slog.Debug("transaction deletion", "query_timeout", deadline)
-
-		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		writeLedgerError(w, r, "unable to delete transaction", err)
 		return
 	}
 
-	slog.Debug("transaction deletion",
+	slog.DebugContext(r.Context(), "transaction deletion",
 		"uuid", txnUUID,
 		"query_time", time.Since(startQueryTime),
 	)
@@ -483,15 +349,24 @@ func (s *Server) HandleDeleteTransaction(w http.ResponseWriter, r *http.Request)
 	res := NewResponse("OK", 1, "OBJ", nil)
 	err = WriteResponse(w, http.StatusNoContent, res)
 	if err != nil {
-		slog.Error("unable to write response", "error", err)
-		slog.Debug("response writing", "response", res)
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		slog.DebugContext(r.Context(), "response writing", "response", res)
 		return
 	}
 
-	slog.Info("transaction deleted", "uuid", txnUUID)
-	slog.Debug(
+	slog.InfoContext(r.Context(), "transaction deleted", "uuid", txnUUID)
+	slog.DebugContext(r.Context(),
 		"transaction.delete.complete",
 		"uuid", txnUUID,
 		"duration", time.Since(startReqTime),
 	)
 }
+
+// textValue is the string of a text field of a request, nil when it's
+// missing or null
+func textValue(t *pgtype.Text) *string {
+	if t == nil || !t.Valid {
+		return nil
+	}
+	return &t.String
+}
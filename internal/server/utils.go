@@ -2,6 +2,7 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/schema"
 	"log/slog"
@@ -43,6 +44,17 @@ func Decode[T any](r *http.Request) (T, error) {
 	return v, nil
 }
 
+// maxUploadSize is the largest body of the routes that read whole
+// documents, e.g., imports and restores
+const maxUploadSize = 32 << 20
+
+// isTooLarge tells whether reading the body failed because it's over the
+// limit of http.MaxBytesReader
+func isTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
 func WriteError(w http.ResponseWriter, message interface{}, status int) {
 	res := ErrorResponse{
 		Status:  status,
@@ -57,7 +69,9 @@ func WriteError(w http.ResponseWriter, message interface{}, status int) {
 	}
 }
 
-func parseMetadataParam(r *http.Request) ([]byte, error) {
+// parseMetadataFilter collects the `metadata.` query params, e.g.,
+// `?metadata.user_id=25` is `{"user_id": 25}`
+func parseMetadataFilter(r *http.Request) map[string]interface{} {
 	queryValues := r.URL.Query()
 	prefix := "metadata."
 
@@ -74,17 +88,7 @@ func parseMetadataParam(r *http.Request) ([]byte, error) {
 		}
 	}
 
-	if len(metadataFilter) == 0 {
-		return nil, fmt.Errorf("no metadata parameters found")
-	}
-
-	// convert to JSON for database query
-	metadataBytes, err := json.Marshal(metadataFilter)
-	if err != nil {
-		return nil, fmt.Errorf("parse metadata: %w", err)
-	}
-
-	return metadataBytes, nil
+	return metadataFilter
 }
 
 // MapNonZeroFields
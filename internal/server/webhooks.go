@@ -0,0 +1,352 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"github.com/go-playground/form/v4"
+	"github.com/go-playground/validator/v10"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/webhook"
+	"github.com/jackc/pgx/v5"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type CreateWebhookRequest struct {
+	URL        string   `json:"url" validate:"required,http_url,max=2047"`
+	EventTypes []string `json:"event_types" validate:"dive,required"`
+}
+
+type WebhookResponse struct {
+	UUID       string    `json:"uuid"`
+	CreatedAt  time.Time `json:"created_at"`
+	URL        string    `json:"url"`
+	EventTypes []string  `json:"event_types"`
+	// Secret is only returned when the webhook is created
+	Secret string `json:"secret,omitempty"`
+}
+
+// HandleCreateWebhook registers a webhook URL. An empty list of event
+// types subscribes to every event. The signing secret is generated and
+// only returned in this response.
+func (s *Server) HandleCreateWebhook(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "webhook.create.start")
+
+	// decode the request body
+	req, err := Decode[CreateWebhookRequest](r)
+	if err != nil {
+		slog.InfoContext(r.Context(), "unable to decode request body", "error", err)
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// validate the request
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err = validate.Struct(req); err != nil {
+		validationErrors := ParseValidationErrors(err)
+
+		res := map[string][]ValidationError{
+			"errors": validationErrors,
+		}
+
+		slog.InfoContext(r.Context(), "unable to validate request", "error", err)
+		slog.DebugContext(r.Context(), "request validation", "validation_errors", res)
+
+		WriteError(w, res, http.StatusBadRequest)
+		return
+	}
+
+	// the dispatcher checks the addresses again when it connects, the
+	// host can resolve to others by then
+	if !s.allowPrivateWebhooks {
+		if err := webhook.CheckURL(r.Context(), req.URL); err != nil {
+			slog.InfoContext(r.Context(), "unable to accept webhook url", "error", err)
+
+			res := map[string][]ValidationError{
+				"errors": {{Field: "URL", Message: "must resolve to public addresses"}},
+			}
+			WriteError(w, res, http.StatusBadRequest)
+			return
+		}
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to generate webhook secret", "error", err)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	eventTypes := req.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	startQueryTime := time.Now()
+
+	webhook, err := s.client.Queries.CreateWebhook(r.Context(), dbGen.CreateWebhookParams{
+		Url:        req.URL,
+		Secret:     secret,
+		EventTypes: eventTypes,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to create webhook", "error", err)
+		slog.DebugContext(r.Context(), "webhook creation", "url", req.URL)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	slog.DebugContext(r.Context(), "webhook creation",
+		"uuid", webhook.Uuid,
+		"query_time", time.Since(startQueryTime),
+	)
+
+	detail := newWebhookResponse(webhook)
+	detail.Secret = webhook.Secret
+
+	res := NewResponse("OK", 1, "OBJ", detail)
+	err = WriteResponse(w, http.StatusCreated, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "webhook created", "uuid", webhook.Uuid, "url", webhook.Url)
+	slog.DebugContext(r.Context(), "webhook.create.complete",
+		"uuid", webhook.Uuid,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+func (s *Server) HandleListWebhooks(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "webhook.list.start")
+
+	webhooks, err := s.client.Queries.ListWebhooks(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to list webhooks", "error", err)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	detail := make([]WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		detail = append(detail, newWebhookResponse(webhook))
+	}
+
+	res := NewResponse("OK", len(detail), "LIST", detail)
+	err = WriteResponse(w, http.StatusOK, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "webhooks listed", "count", len(detail))
+	slog.DebugContext(r.Context(), "webhook.list.complete",
+		"webhooks_count", len(detail),
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// HandleDeleteWebhook removes the webhook and its pending deliveries
+func (s *Server) HandleDeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "webhook.delete.start")
+
+	webhookUUID := r.PathValue("id")
+
+	deleted, err := s.client.Queries.DeleteWebhook(r.Context(), webhookUUID)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to delete webhook", "error", err)
+		slog.DebugContext(r.Context(), "webhook deletion", "uuid", webhookUUID)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	if deleted == 0 {
+		slog.InfoContext(r.Context(), "webhook not found", "uuid", webhookUUID)
+		WriteError(w, ErrNotFound, http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	slog.InfoContext(r.Context(), "webhook deleted", "uuid", webhookUUID)
+	slog.DebugContext(r.Context(), "webhook.delete.complete",
+		"uuid", webhookUUID,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+type ListWebhookDeliveriesQuery struct {
+	Status string `form:"status" json:"status" validate:"omitempty,oneof=pending failed delivered dead"`
+	Limit  int32  `form:"limit" json:"limit" validate:"min=1,max=1000"`
+	Offset int32  `form:"offset" json:"offset" validate:"min=0"`
+}
+
+type WebhookDeliveryResponse struct {
+	UUID           string     `json:"uuid"`
+	CreatedAt      time.Time  `json:"created_at"`
+	Status         string     `json:"status"`
+	Attempts       int32      `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"next_attempt_at"`
+	LastStatusCode *int32     `json:"last_status_code"`
+	LastError      *string    `json:"last_error"`
+	DeliveredAt    *time.Time `json:"delivered_at"`
+	EventUUID      string     `json:"event_uuid"`
+	EventType      string     `json:"event_type"`
+}
+
+type ReplayWebhookDeliveryResponse struct {
+	UUID   string `json:"uuid"`
+	Status string `json:"status"`
+}
+
+// HandleListWebhookDeliveries returns the deliveries of a webhook, newest
+// first, e.g., `?status=dead` lists the ones that can be replayed.
+func (s *Server) HandleListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	decoder := form.NewDecoder()
+
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "webhook.deliveries.start")
+
+	// default values
+	query := ListWebhookDeliveriesQuery{
+		Limit:  30,
+		Offset: 0,
+	}
+
+	// decode the query params
+	if err := decoder.Decode(&query, r.URL.Query()); err != nil {
+		slog.InfoContext(r.Context(), "unable to decode query params", "error", err)
+		slog.DebugContext(r.Context(), "query params decoding", "query_params", r.URL.Query())
+		WriteError(w, ErrInvalidRequest, http.StatusBadRequest)
+		return
+	}
+
+	// validate the query params
+	validate := validator.New(validator.WithRequiredStructEnabled())
+	if err := validate.Struct(query); err != nil {
+		validationErrors := ParseValidationErrors(err)
+		res := map[string][]ValidationError{
+			"errors": validationErrors,
+		}
+		slog.InfoContext(r.Context(), "unable to validate query params", "error", err)
+		slog.DebugContext(r.Context(), "query params validation", "query_params", r.URL.Query(), "validation_errors", res)
+		WriteError(w, res, http.StatusBadRequest)
+		return
+	}
+
+	webhookUUID := r.PathValue("id")
+
+	deliveries, err := s.client.Queries.ListWebhookDeliveries(r.Context(), dbGen.ListWebhookDeliveriesParams{
+		Status: dbGen.NullWebhookDeliveryStatus{
+			WebhookDeliveryStatus: dbGen.WebhookDeliveryStatus(query.Status),
+			Valid:                 query.Status != "",
+		},
+		Limit:       query.Limit,
+		Offset:      query.Offset,
+		WebhookUuid: webhookUUID,
+	})
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to list webhook deliveries", "error", err)
+		slog.DebugContext(r.Context(), "webhook deliveries listing", "webhook_uuid", webhookUUID)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	detail := make([]WebhookDeliveryResponse, 0, len(deliveries))
+	for _, d := range deliveries {
+		res := WebhookDeliveryResponse{
+			UUID:          d.Uuid,
+			CreatedAt:     d.CreatedAt.Time,
+			Status:        string(d.Status),
+			Attempts:      d.Attempts,
+			NextAttemptAt: d.NextAttemptAt.Time,
+			LastError:     textPtr(d.LastError),
+			EventUUID:     d.EventUuid,
+			EventType:     d.EventType,
+		}
+		if d.LastStatusCode.Valid {
+			res.LastStatusCode = &d.LastStatusCode.Int32
+		}
+		if d.DeliveredAt.Valid {
+			res.DeliveredAt = &d.DeliveredAt.Time
+		}
+		detail = append(detail, res)
+	}
+
+	res := NewResponse("OK", len(detail), "LIST", detail)
+	err = WriteResponse(w, http.StatusOK, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "webhook deliveries listed", "webhook_uuid", webhookUUID, "count", len(detail))
+	slog.DebugContext(r.Context(), "webhook.deliveries.complete",
+		"deliveries_count", len(detail),
+		"duration", time.Since(startReqTime),
+	)
+}
+
+// HandleReplayWebhookDelivery schedules a delivery again with a fresh
+// attempts count, whatever its status is
+func (s *Server) HandleReplayWebhookDelivery(w http.ResponseWriter, r *http.Request) {
+	startReqTime := time.Now()
+	slog.DebugContext(r.Context(), "webhook.replay.start")
+
+	deliveryUUID := r.PathValue("id")
+
+	delivery, err := s.client.Queries.ReplayWebhookDelivery(r.Context(), deliveryUUID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			slog.InfoContext(r.Context(), "webhook delivery not found", "uuid", deliveryUUID)
+			WriteError(w, ErrNotFound, http.StatusNotFound)
+			return
+		}
+
+		slog.ErrorContext(r.Context(), "unable to replay webhook delivery", "error", err)
+		slog.DebugContext(r.Context(), "webhook delivery replay", "uuid", deliveryUUID)
+		WriteError(w, ErrInternalServerError, http.StatusInternalServerError)
+		return
+	}
+
+	detail := ReplayWebhookDeliveryResponse{
+		UUID:   delivery.Uuid,
+		Status: string(delivery.Status),
+	}
+
+	res := NewResponse("OK", 1, "OBJ", detail)
+	err = WriteResponse(w, http.StatusAccepted, res)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "unable to write response", "error", err)
+		return
+	}
+
+	slog.InfoContext(r.Context(), "webhook delivery replayed", "uuid", delivery.Uuid)
+	slog.DebugContext(r.Context(), "webhook.replay.complete",
+		"uuid", delivery.Uuid,
+		"duration", time.Since(startReqTime),
+	)
+}
+
+func newWebhookResponse(webhook *dbGen.Webhook) WebhookResponse {
+	return WebhookResponse{
+		UUID:       webhook.Uuid,
+		CreatedAt:  webhook.CreatedAt.Time,
+		URL:        webhook.Url,
+		EventTypes: webhook.EventTypes,
+	}
+}
+
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCreateWebhookPrivateAddress(t *testing.T) {
+	is := is_.New(t)
+
+	// rejected before the database is reached
+	s := &Server{}
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://10.0.0.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+	} {
+		w := httptest.NewRecorder()
+		body := `{"url": "` + url + `"}`
+		s.HandleCreateWebhook(w, httptest.NewRequest("POST", "/webhooks", strings.NewReader(body)))
+		is.Equal(w.Code, http.StatusBadRequest) // private address accepted
+		is.True(strings.Contains(w.Body.String(), "must resolve to public addresses"))
+	}
+}
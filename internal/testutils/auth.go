@@ -0,0 +1,149 @@
+package testutils
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/auth"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// bearerTransport adds the API key to every request
+type bearerTransport struct {
+	key  string
+	base http.RoundTripper
+}
+
+func (t *bearerTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	// RoundTrip must not modify the request
+	r = r.Clone(r.Context())
+	if r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "Bearer "+t.key)
+	}
+	return t.base.RoundTrip(r)
+}
+
+// CreateTestTenant stores a new tenant
+func CreateTestTenant(ctx context.Context, pool *pgxpool.Pool, name string) (*dbGen.Tenant, error) {
+	tenant, err := dbGen.New(pool).CreateTenant(ctx, name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// CreateTestUser stores a new user of the tenant
+func CreateTestUser(ctx context.Context, pool *pgxpool.Pool, tenantUUID, email string) (*dbGen.User, error) {
+	user, err := dbGen.New(pool).CreateUser(ctx, dbGen.CreateUserParams{
+		Email:      email,
+		TenantUuid: tenantUUID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// CreateTestAPIKey stores a new API key of the tenant with the scopes and
+// returns it in plain text
+func CreateTestAPIKey(ctx context.Context, pool *pgxpool.Pool, tenantUUID string, scopes ...string) (string, error) {
+	return createTestAPIKey(ctx, pool, tenantUUID, pgtype.Text{}, scopes)
+}
+
+// CreateTestUserAPIKey stores a new API key acting for the user, limited
+// to the user's roles, and returns it in plain text
+func CreateTestUserAPIKey(ctx context.Context, pool *pgxpool.Pool, tenantUUID, userUUID string, scopes ...string) (string, error) {
+	return createTestAPIKey(ctx, pool, tenantUUID, pgtype.Text{String: userUUID, Valid: true}, scopes)
+}
+
+func createTestAPIKey(ctx context.Context, pool *pgxpool.Pool, tenantUUID string, userUUID pgtype.Text, scopes []string) (string, error) {
+	key, err := auth.GenerateKey()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	_, err = dbGen.New(pool).CreateApiKey(ctx, dbGen.CreateApiKeyParams{
+		Name:       "test",
+		Prefix:     key.Prefix,
+		KeyHash:    key.Hash,
+		Scopes:     scopes,
+		TenantUuid: tenantUUID,
+		UserUuid:   userUUID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create key: %w", err)
+	}
+
+	return key.Plain, nil
+}
+
+// The issuer and audience of the JWTs of the fake identity provider
+const (
+	TestIssuer   = "https://id.example.com"
+	TestAudience = "doubleed"
+)
+
+// SetupTestSigningKey generates the key of a fake identity provider and
+// points JWT_KEY_FILE to its public key, it must be called before the
+// server starts
+func SetupTestSigningKey() (crypto.Signer, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal public key: %w", err)
+	}
+
+	path := filepath.Join(os.TempDir(), "doubleed-test-idp.pem")
+	err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write public key: %w", err)
+	}
+
+	env := map[string]string{
+		"JWT_KEY_FILE": path,
+		"JWT_ISSUER":   TestIssuer,
+		"JWT_AUDIENCE": TestAudience,
+	}
+	for name, value := range env {
+		if err := os.Setenv(name, value); err != nil {
+			return nil, fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	return key, nil
+}
+
+// NewTestClient returns a new tenant and a client that sends a key with
+// every scope of the tenant
+func NewTestClient(ctx context.Context, pool *pgxpool.Pool) (*dbGen.Tenant, *http.Client, error) {
+	tenant, err := CreateTestTenant(ctx, pool, "test")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key, err := CreateTestAPIKey(ctx, pool, tenant.Uuid, auth.ScopeAll)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	client := &http.Client{
+		Transport: &bearerTransport{
+			key:  key,
+			base: http.DefaultTransport,
+		},
+	}
+	return tenant, client, nil
+}
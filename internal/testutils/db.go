@@ -4,22 +4,30 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/j0lvera/go-double-e/internal/db"
+	"github.com/j0lvera/go-double-e/internal/db/migrations"
 	"github.com/jackc/pgx/v5/pgxpool"
 	_ "github.com/jackc/pgx/v5/stdlib" // registers the pgx driver
-	"github.com/pressly/goose/v3"
 	"github.com/testcontainers/testcontainers-go"
 	"github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
 	"log"
-	"path/filepath"
-	"runtime"
 	"sync"
 	"time"
 )
 
+// AppRole is the role the test pools connect as, a regular role so the
+// row level security policies apply to it like they do to the service
+const AppRole = "app"
+
 type TestDB struct {
-	Pool    *pgxpool.Pool
-	Cleanup func()
+	// Pool connects as AppRole, the queries run with it are scoped to the
+	// tenant of the context
+	Pool *pgxpool.Pool
+	// AdminPool connects as the superuser that owns the database, for the
+	// fixtures and checks that must see every tenant
+	AdminPool *pgxpool.Pool
+	Cleanup   func()
 }
 
 var (
@@ -33,16 +41,7 @@ var (
 // Callers should ensure they call ResetTestData() between tests if needed.
 func GetTestDB(ctx context.Context) (*TestDB, error) {
 	setupOnce.Do(func() {
-		pool, cleanup, err := SetupTestDB(ctx)
-		if err != nil {
-			setupError = err
-			return
-		}
-
-		testDB = &TestDB{
-			Pool:    pool,
-			Cleanup: cleanup,
-		}
+		testDB, setupError = SetupTestDB(ctx)
 	})
 
 	if setupError != nil {
@@ -52,14 +51,16 @@ func GetTestDB(ctx context.Context) (*TestDB, error) {
 	return testDB, nil
 }
 
-// ResetTestData truncates all tables in the test database.
-// Call this between tests to ensure test isolation.
-func ResetTestData(ctx context.Context, db *pgxpool.Pool) error {
+// ResetTestData truncates all tables in the test database, except the
+// tenants, users and API keys the test clients authenticate with.
+// Call this between tests to ensure test isolation, with the AdminPool.
+func ResetTestData(ctx context.Context, pool *pgxpool.Pool) error {
 	// Get all table names
-	rows, err := db.Query(ctx, `
+	rows, err := pool.Query(ctx, `
 		select tablename
 		from pg_catalog.pg_tables
-		where schemaname = 'public';
+		where schemaname = 'public'
+		  and tablename not in ('tenants', 'users', 'api_keys');
 	`)
 	if err != nil {
 		return fmt.Errorf("failed to get table names: %w", err)
@@ -73,7 +74,7 @@ func ResetTestData(ctx context.Context, db *pgxpool.Pool) error {
 			return fmt.Errorf("failed to scan table name: %w", err)
 		}
 
-		_, err := db.Exec(ctx, fmt.Sprintf("TRUNCATE TABLE %s CASCADE", tableName))
+		_, err := pool.Exec(ctx, fmt.Sprintf("TRUNCATE TABLE %s CASCADE", tableName))
 		if err != nil {
 			return fmt.Errorf("failed to truncate table %s: %w", tableName, err)
 		}
@@ -82,11 +83,9 @@ func ResetTestData(ctx context.Context, db *pgxpool.Pool) error {
 	return rows.Err()
 }
 
-func SetupTestDB(ctx context.Context) (*pgxpool.Pool, func(), error) {
-	// get the project root to find migrations
-	_, b, _, _ := runtime.Caller(0)
-	projectRoot := filepath.Join(filepath.Dir(b), "..", "..")
-
+// SetupTestDB starts a database in a container, applies the migrations
+// and creates AppRole
+func SetupTestDB(ctx context.Context) (*TestDB, error) {
 	// setup postgres container
 	postgresContainer, err := postgres.Run(
 		ctx,
@@ -100,62 +99,63 @@ func SetupTestDB(ctx context.Context) (*pgxpool.Pool, func(), error) {
 				WithStartupTimeout(5*time.Second)),
 	)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to start container: %s", err)
+		return nil, fmt.Errorf("failed to start container: %s", err)
 	}
 
 	// get connection details
 	mappedPort, err := postgresContainer.MappedPort(ctx, "5432")
 	if err != nil {
 		_ = postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to get mapped port: %s", err)
+		return nil, fmt.Errorf("failed to get mapped port: %s", err)
 	}
 
 	host, err := postgresContainer.Host(ctx)
 	if err != nil {
 		_ = postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to get host: %s", err)
+		return nil, fmt.Errorf("failed to get host: %s", err)
 	}
 
-	dsn := fmt.Sprintf("postgres://test:test@%s:%s/test?sslmode=disable", host, mappedPort.Port())
-	log.Printf("Database DSN: %s", dsn)
+	adminDSN := fmt.Sprintf("postgres://test:test@%s:%s/test?sslmode=disable", host, mappedPort.Port())
+	appDSN := fmt.Sprintf("postgres://%s:%s@%s:%s/test?sslmode=disable", AppRole, AppRole, host, mappedPort.Port())
+	log.Printf("Database DSN: %s", appDSN)
 
-	db, err := sql.Open("pgx", dsn)
+	sqlDB, err := sql.Open("pgx", adminDSN)
 	if err != nil {
 		_ = postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to open DB for migrations: %w", err)
+		return nil, fmt.Errorf("failed to open DB for migrations: %w", err)
 	}
 
-	if err := db.Ping(); err != nil {
-		_ = db.Close()
+	if err := sqlDB.Ping(); err != nil {
+		_ = sqlDB.Close()
 		_ = postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to ping DB: %w", err)
+		return nil, fmt.Errorf("failed to ping DB: %w", err)
 	}
 
-	if err := goose.Up(db, filepath.Join(projectRoot, "internal/db/migrations")); err != nil {
-		_ = db.Close()
+	if err := migrations.Up(ctx, sqlDB); err != nil {
+		_ = sqlDB.Close()
 		_ = postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to run migrations: %w", err)
+		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
-	_ = db.Close()
 
-	// Create connection pool for actual usage
-	config, err := pgxpool.ParseConfig(dsn)
-	if err != nil {
-		postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to parse config: %s", err)
+	if err := createAppRole(ctx, sqlDB); err != nil {
+		_ = sqlDB.Close()
+		_ = postgresContainer.Terminate(ctx)
+		return nil, err
 	}
+	_ = sqlDB.Close()
 
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+	// Create connection pools for actual usage
+	adminPool, err := newTestPool(ctx, adminDSN)
 	if err != nil {
-		postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to create pool: %s", err)
+		_ = postgresContainer.Terminate(ctx)
+		return nil, err
 	}
 
-	// Test the pool connection
-	if err := pool.Ping(ctx); err != nil {
-		pool.Close()
-		postgresContainer.Terminate(ctx)
-		return nil, nil, fmt.Errorf("failed to ping database pool: %s", err)
+	pool, err := newTestPool(ctx, appDSN)
+	if err != nil {
+		adminPool.Close()
+		_ = postgresContainer.Terminate(ctx)
+		return nil, err
 	}
 
 	log.Println("Test database setup completed successfully")
@@ -163,10 +163,55 @@ func SetupTestDB(ctx context.Context) (*pgxpool.Pool, func(), error) {
 	cleanup := func() {
 		log.Println("Cleaning up test database...")
 		pool.Close()
+		adminPool.Close()
 		if err := postgresContainer.Terminate(ctx); err != nil {
 			log.Printf("Failed to terminate container during cleanup: %s", err)
 		}
 	}
 
-	return pool, cleanup, nil
+	return &TestDB{
+		Pool:      pool,
+		AdminPool: adminPool,
+		Cleanup:   cleanup,
+	}, nil
+}
+
+// createAppRole creates AppRole with the privileges the service needs on
+// the tables of the migrations, and no more
+func createAppRole(ctx context.Context, sqlDB *sql.DB) error {
+	statements := []string{
+		fmt.Sprintf("create role %s login password '%s' nosuperuser nobypassrls", AppRole, AppRole),
+		fmt.Sprintf("grant usage on schema public to %s", AppRole),
+		fmt.Sprintf("grant select, insert, update, delete on all tables in schema public to %s", AppRole),
+		fmt.Sprintf("grant usage, select on all sequences in schema public to %s", AppRole),
+	}
+	for _, statement := range statements {
+		if _, err := sqlDB.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create app role: %w", err)
+		}
+	}
+	return nil
+}
+
+// newTestPool connects to dsn, scoping the connections to the tenant of
+// the context like the service does
+func newTestPool(ctx context.Context, dsn string) (*pgxpool.Pool, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse config: %s", err)
+	}
+	db.ScopeToTenant(config)
+
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool: %s", err)
+	}
+
+	// Test the pool connection
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database pool: %s", err)
+	}
+
+	return pool, nil
 }
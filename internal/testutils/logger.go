@@ -1,19 +1,17 @@
 package testutils
 
 import (
-	"github.com/charmbracelet/log"
+	"github.com/j0lvera/go-double-e/internal/logging"
 	"log/slog"
 	"os"
 )
 
 func SetupTestLogger() {
-	handler := log.NewWithOptions(os.Stderr, log.Options{
-		ReportCaller:    true,
-		ReportTimestamp: true,
-		Level:           log.DebugLevel,
-	})
+	handler, err := logging.NewHandler(os.Stderr, logging.FormatText, slog.LevelDebug)
+	if err != nil {
+		panic(err)
+	}
 
 	logger := slog.New(handler)
 	slog.SetDefault(logger)
-	handler.SetLevel(log.DebugLevel) // force debug level
 }
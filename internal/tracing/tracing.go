@@ -0,0 +1,135 @@
+// Package tracing sets up OpenTelemetry traces. Requests get a server span
+// and every query of a request a child span named after its sqlc query, so
+// the latency of a request can be broken down by query.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"strings"
+)
+
+// Exporters
+const (
+	ExporterOff    = "off"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+// ServiceName is the default name of the service, OTEL_SERVICE_NAME
+// overrides it
+const ServiceName = "doubleed"
+
+const instrumentation = "github.com/j0lvera/go-double-e"
+
+// Setup installs the global tracer provider of the exporter and returns a
+// function flushing the spans left on shutdown. The OTLP exporter is
+// configured with the standard `OTEL_EXPORTER_OTLP_*` environment
+// variables, the stdout one writes to w. Traces are off by default.
+func Setup(ctx context.Context, exporter string, w io.Writer) (func(context.Context) error, error) {
+	var spanExporter sdktrace.SpanExporter
+	switch exporter {
+	case ExporterOff, "":
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		otlp, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("create otlp exporter: %w", err)
+		}
+		spanExporter = otlp
+	case ExporterStdout:
+		stdout, err := stdouttrace.New(stdouttrace.WithWriter(w))
+		if err != nil {
+			return nil, fmt.Errorf("create stdout exporter: %w", err)
+		}
+		spanExporter = stdout
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q, use %q, %q or %q", exporter, ExporterOTLP, ExporterStdout, ExporterOff)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence
+	res, err = resource.Merge(res, resource.Environment())
+	if err != nil {
+		return nil, fmt.Errorf("create resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(spanExporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	// continue the traces of the clients and proxies
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	return provider.Shutdown, nil
+}
+
+// QueryTracer traces the queries of a pgx connection, set it as the Tracer
+// of the connection config. Spans are named after the sqlc query, e.g.,
+// `CreateTransaction`, or the SQL command of the other queries.
+type QueryTracer struct{}
+
+var _ pgx.QueryTracer = QueryTracer{}
+
+func (QueryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	ctx, _ = otel.Tracer(instrumentation).Start(ctx, QueryName(data.SQL),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			attribute.String("db.statement", data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (QueryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	defer span.End()
+
+	if data.Err != nil {
+		span.RecordError(data.Err)
+		span.SetStatus(codes.Error, data.Err.Error())
+		return
+	}
+	span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+}
+
+// QueryName is the name sqlc gives the query in its `-- name: X :one`
+// comment, or the SQL command, e.g., `select`, for hand written queries
+func QueryName(sql string) string {
+	sql = strings.TrimSpace(sql)
+	if rest, ok := strings.CutPrefix(sql, "-- name: "); ok {
+		if name, _, ok := strings.Cut(rest, " "); ok {
+			return name
+		}
+	}
+
+	command, _, _ := strings.Cut(sql, " ")
+	command = strings.ToLower(strings.TrimSpace(command))
+	if command == "" {
+		return "query"
+	}
+	return command
+}
@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	is_ "github.com/matryer/is"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestQueryName(t *testing.T) {
+	is := is_.New(t)
+
+	is.Equal(QueryName("-- name: CreateTransaction :one\nINSERT INTO transactions"), "CreateTransaction")
+	is.Equal(QueryName("\n  select set_config('app.tenant_id', $1, false)"), "select")
+	is.Equal(QueryName("declare transactions_export no scroll cursor for select 1"), "declare")
+	is.Equal(QueryName(""), "query")
+}
+
+func TestQueryTracer(t *testing.T) {
+	is := is_.New(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "GET /ledgers")
+
+	var tracer QueryTracer
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "-- name: ListLedgers :many\nSELECT 1"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("SELECT 1")})
+
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "-- name: CreateLedger :one\nINSERT"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("boom")})
+	parent.End()
+
+	spans := recorder.Ended()
+	is.Equal(len(spans), 3)
+	is.Equal(spans[0].Name(), "ListLedgers")
+	is.Equal(spans[0].Parent().SpanID(), parent.SpanContext().SpanID()) // child of the request
+	is.Equal(spans[1].Name(), "CreateLedger")
+	is.Equal(spans[1].Status().Code, codes.Error) // failed queries are errors
+}
@@ -0,0 +1,99 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned for the webhook URLs of loopback, private,
+// link-local or otherwise reserved addresses, a tenant must not make the
+// dispatcher reach the hosts of the network it runs in
+var ErrPrivateAddress = errors.New("webhook address is private or reserved")
+
+// reservedPrefixes are the ranges that aren't covered by the checks of
+// netip.Addr and can't be reached on the internet
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// publicAddress tells whether ip can be the address of a webhook
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckURL resolves the host of a webhook URL, it's ErrPrivateAddress when
+// any of its addresses isn't public. The host can resolve to other
+// addresses later on, the client of NewClient checks them again.
+func CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse webhook url: %w", err)
+	}
+
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("resolve webhook host: %w", err)
+	}
+	for _, addr := range addrs {
+		if !publicAddress(addr) {
+			return ErrPrivateAddress
+		}
+	}
+	return nil
+}
+
+// dialControl rejects the connections to addresses that aren't public,
+// it runs after the name resolution so a host resolving to another
+// address than the one checked by CheckURL is rejected as well
+func dialControl(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("parse address: %w", err)
+	}
+	if !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("dial %s: %w", address, ErrPrivateAddress)
+	}
+	return nil
+}
+
+// NewClient returns the client of the deliveries, it only connects to
+// public addresses and never through a proxy, the proxy would be the
+// address checked
+func NewClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/jackc/pgx/v5/pgtype"
+	"time"
+)
+
+// PostgresStore keeps the deliveries in the webhook_deliveries table
+type PostgresStore struct {
+	queries *dbGen.Queries
+}
+
+func NewPostgresStore(queries *dbGen.Queries) *PostgresStore {
+	return &PostgresStore{queries: queries}
+}
+
+func (s *PostgresStore) Claim(ctx context.Context, limit int, leaseUntil time.Time) ([]Delivery, error) {
+	rows, err := s.queries.ClaimWebhookDeliveries(ctx, dbGen.ClaimWebhookDeliveriesParams{
+		LeaseUntil: pgtype.Timestamptz{Time: leaseUntil, Valid: true},
+		BatchSize:  int32(limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]Delivery, 0, len(rows))
+	for _, row := range rows {
+		deliveries = append(deliveries, Delivery{
+			ID:       row.ID,
+			Attempts: int(row.Attempts),
+			URL:      row.Url,
+			Secret:   row.Secret,
+			Event: Event{
+				ID:        row.EventUuid,
+				Type:      row.EventType,
+				CreatedAt: row.EventCreatedAt.Time,
+				Data:      row.Payload,
+			},
+		})
+	}
+
+	return deliveries, nil
+}
+
+func (s *PostgresStore) MarkDelivered(ctx context.Context, id int64, statusCode int) error {
+	return s.queries.MarkWebhookDeliveryDelivered(ctx, dbGen.MarkWebhookDeliveryDeliveredParams{
+		StatusCode: int32(statusCode),
+		ID:         id,
+	})
+}
+
+func (s *PostgresStore) MarkFailed(ctx context.Context, id int64, failure Failure) error {
+	status := dbGen.WebhookDeliveryStatusFailed
+	if failure.Dead {
+		status = dbGen.WebhookDeliveryStatusDead
+	}
+
+	return s.queries.MarkWebhookDeliveryFailed(ctx, dbGen.MarkWebhookDeliveryFailedParams{
+		Status:        status,
+		StatusCode:    pgtype.Int4{Int32: int32(failure.StatusCode), Valid: failure.StatusCode != 0},
+		LastError:     failure.Error,
+		NextAttemptAt: pgtype.Timestamptz{Time: failure.NextAttemptAt, Valid: true},
+		ID:            id,
+	})
+}
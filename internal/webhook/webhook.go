@@ -0,0 +1,263 @@
+// Package webhook delivers the events of the outbox to the registered
+// webhook URLs. Deliveries are signed with the secret of the webhook,
+// retried with exponential backoff and marked as dead after too many
+// failures, dead deliveries can be replayed by hand.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/sync/errgroup"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Headers sent with every delivery
+const (
+	EventIDHeader   = "X-Webhook-ID"
+	EventTypeHeader = "X-Webhook-Event"
+)
+
+// maxErrorLength bounds the error kept for a failed delivery
+const maxErrorLength = 500
+
+// Event is the body of a delivery
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Delivery is an event to deliver to a webhook, Attempts is the number of
+// attempts made before this one
+type Delivery struct {
+	ID       int64
+	Attempts int
+	URL      string
+	Secret   string
+	Event    Event
+}
+
+// Failure describes a failed attempt, StatusCode is 0 when no response was
+// received. A dead delivery isn't retried until it is replayed.
+type Failure struct {
+	StatusCode    int
+	Error         string
+	NextAttemptAt time.Time
+	Dead          bool
+}
+
+// Store is the persistence used by the dispatcher
+type Store interface {
+	// Claim returns up to limit due deliveries and hides them from other
+	// dispatchers until leaseUntil
+	Claim(ctx context.Context, limit int, leaseUntil time.Time) ([]Delivery, error)
+	MarkDelivered(ctx context.Context, id int64, statusCode int) error
+	MarkFailed(ctx context.Context, id int64, failure Failure) error
+}
+
+type Dispatcher struct {
+	Store Store
+	// Client must have a Timeout, the lease of the batches is sized from it.
+	// The one of NewDispatcher only connects to public addresses.
+	Client *http.Client
+
+	// MaxAttempts is the number of failed attempts before a delivery is dead
+	MaxAttempts int
+	// BaseBackoff is the delay after the first failure, it doubles with
+	// every failure up to MaxBackoff
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+
+	BatchSize int
+	// Concurrency is the number of deliveries of a batch sent at a time
+	Concurrency  int
+	PollInterval time.Duration
+	// Lease is how long a claimed delivery stays hidden from other
+	// dispatchers after the batch had the time to be sent, every round of
+	// Concurrency deliveries can take up to the Client.Timeout
+	Lease time.Duration
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{
+		Store:        store,
+		Client:       NewClient(10 * time.Second),
+		MaxAttempts:  10,
+		BaseBackoff:  10 * time.Second,
+		MaxBackoff:   6 * time.Hour,
+		BatchSize:    50,
+		Concurrency:  10,
+		PollInterval: 2 * time.Second,
+		Lease:        time.Minute,
+	}
+}
+
+// Run polls the store until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	slog.Info("Webhook dispatcher started", "poll_interval", d.PollInterval)
+
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+
+		// drain the due deliveries before waiting again
+		for {
+			n, err := d.DispatchOnce(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					slog.Error("unable to dispatch webhooks", "error", err)
+				}
+				break
+			}
+			if n < d.BatchSize {
+				break
+			}
+		}
+	}
+}
+
+// DispatchOnce claims a batch of due deliveries and attempts them, it
+// returns the number of deliveries attempted
+func (d *Dispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	sendTime := d.sendTime()
+	leaseUntil := time.Now().Add(sendTime + d.Lease)
+
+	deliveries, err := d.Store.Claim(ctx, d.BatchSize, leaseUntil)
+	if err != nil {
+		return 0, fmt.Errorf("claim deliveries: %w", err)
+	}
+
+	// the deliveries that are still being sent when the lease is about to
+	// end fail, so another dispatcher never sends them at the same time
+	sendCtx, cancel := context.WithDeadline(ctx, leaseUntil.Add(-d.Lease))
+	defer cancel()
+
+	g := errgroup.Group{}
+	g.SetLimit(d.concurrency())
+	for _, delivery := range deliveries {
+		g.Go(func() error {
+			return d.attempt(ctx, sendCtx, delivery)
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return 0, err
+	}
+
+	return len(deliveries), nil
+}
+
+// sendTime is the longest a batch can take to be sent
+func (d *Dispatcher) sendTime() time.Duration {
+	rounds := (d.BatchSize + d.concurrency() - 1) / d.concurrency()
+	return time.Duration(rounds) * d.Client.Timeout
+}
+
+func (d *Dispatcher) concurrency() int {
+	return max(d.Concurrency, 1)
+}
+
+// attempt sends the delivery with sendCtx and records the outcome with ctx
+func (d *Dispatcher) attempt(ctx, sendCtx context.Context, delivery Delivery) error {
+	startTime := time.Now()
+
+	statusCode, err := d.send(sendCtx, delivery)
+	if err == nil {
+		slog.Debug("webhook delivered",
+			"delivery_id", delivery.ID,
+			"event_id", delivery.Event.ID,
+			"status_code", statusCode,
+			"duration", time.Since(startTime),
+		)
+		if err := d.Store.MarkDelivered(ctx, delivery.ID, statusCode); err != nil {
+			return fmt.Errorf("mark delivery %d as delivered: %w", delivery.ID, err)
+		}
+		return nil
+	}
+
+	attempts := delivery.Attempts + 1
+	failure := Failure{
+		StatusCode:    statusCode,
+		Error:         truncate(err.Error(), maxErrorLength),
+		NextAttemptAt: time.Now().Add(d.backoff(attempts)),
+		Dead:          attempts >= d.MaxAttempts,
+	}
+
+	slog.Info("webhook delivery failed",
+		"delivery_id", delivery.ID,
+		"event_id", delivery.Event.ID,
+		"attempts", attempts,
+		"dead", failure.Dead,
+		"error", err,
+	)
+
+	if err := d.Store.MarkFailed(ctx, delivery.ID, failure); err != nil {
+		return fmt.Errorf("mark delivery %d as failed: %w", delivery.ID, err)
+	}
+
+	return nil
+}
+
+// send posts the signed event, any response other than 2xx is an error
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) (int, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return 0, fmt.Errorf("marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventIDHeader, delivery.Event.ID)
+	req.Header.Set(EventTypeHeader, delivery.Event.Type)
+	req.Header.Set(SignatureHeader, Sign(delivery.Secret, time.Now(), body))
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	// drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt after the given number
+// of failed attempts
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	delay := d.BaseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= d.MaxBackoff {
+			return d.MaxBackoff
+		}
+	}
+	return delay
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n]
+}
@@ -0,0 +1,267 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	is_ "github.com/matryer/is"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryStore keeps deliveries in memory, due deliveries are the ones that
+// are neither delivered nor dead
+type memoryStore struct {
+	mu         sync.Mutex
+	deliveries []*Delivery
+	delivered  map[int64]int
+	failures   map[int64][]Failure
+	// leaseUntil is the lease of the last claim, markedAt the time of the
+	// last outcome recorded for each delivery
+	leaseUntil time.Time
+	markedAt   map[int64]time.Time
+}
+
+func newMemoryStore(deliveries ...*Delivery) *memoryStore {
+	return &memoryStore{
+		deliveries: deliveries,
+		delivered:  map[int64]int{},
+		failures:   map[int64][]Failure{},
+		markedAt:   map[int64]time.Time{},
+	}
+}
+
+func (s *memoryStore) Claim(_ context.Context, limit int, leaseUntil time.Time) ([]Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.leaseUntil = leaseUntil
+
+	var due []Delivery
+	for _, d := range s.deliveries {
+		failures := s.failures[d.ID]
+		if _, ok := s.delivered[d.ID]; ok || (len(failures) > 0 && failures[len(failures)-1].Dead) {
+			continue
+		}
+		due = append(due, *d)
+		if len(due) == limit {
+			break
+		}
+	}
+	return due, nil
+}
+
+func (s *memoryStore) MarkDelivered(_ context.Context, id int64, statusCode int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.delivered[id] = statusCode
+	s.markedAt[id] = time.Now()
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(_ context.Context, id int64, failure Failure) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[id] = append(s.failures[id], failure)
+	s.markedAt[id] = time.Now()
+	for _, d := range s.deliveries {
+		if d.ID == id {
+			d.Attempts++
+		}
+	}
+	return nil
+}
+
+func newDelivery(url string) *Delivery {
+	return &Delivery{
+		ID:     1,
+		URL:    url,
+		Secret: "whsec_test",
+		Event: Event{
+			ID:        "evt1",
+			Type:      "transaction.created",
+			CreatedAt: time.Date(2024, 1, 5, 10, 0, 0, 0, time.UTC),
+			Data:      json.RawMessage(`{"entity_uuid":"txn1"}`),
+		},
+	}
+}
+
+func TestDispatcherDelivers(t *testing.T) {
+	is := is_.New(t)
+
+	var (
+		received  Event
+		verifyErr error
+	)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		verifyErr = Verify("whsec_test", r.Header.Get(SignatureHeader), body, time.Minute)
+		_ = json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(newDelivery(receiver.URL))
+	dispatcher := NewDispatcher(store)
+	// the receiver listens on loopback, which NewClient rejects
+	dispatcher.Client = &http.Client{Timeout: 10 * time.Second}
+
+	n, err := dispatcher.DispatchOnce(context.Background())
+	is.NoErr(err)
+	is.Equal(n, 1)
+
+	is.NoErr(verifyErr) // invalid signature
+	is.Equal(received.ID, "evt1")
+	is.Equal(received.Type, "transaction.created")
+	is.Equal(store.delivered[1], http.StatusNoContent)
+}
+
+func TestDispatcherRetriesUntilDead(t *testing.T) {
+	is := is_.New(t)
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(newDelivery(receiver.URL))
+	dispatcher := NewDispatcher(store)
+	dispatcher.Client = &http.Client{Timeout: 10 * time.Second}
+	dispatcher.MaxAttempts = 3
+
+	for i := 0; i < 5; i++ {
+		_, err := dispatcher.DispatchOnce(context.Background())
+		is.NoErr(err)
+	}
+
+	failures := store.failures[1]
+	is.Equal(len(failures), 3) // dead deliveries aren't retried
+	is.Equal(failures[0].StatusCode, http.StatusInternalServerError)
+	is.True(!failures[1].Dead)
+	is.True(failures[2].Dead)
+}
+
+func TestDispatcherSlowReceiver(t *testing.T) {
+	is := is_.New(t)
+
+	const delay = 200 * time.Millisecond
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	var deliveries []*Delivery
+	for i := int64(1); i <= 4; i++ {
+		d := newDelivery(receiver.URL)
+		d.ID = i
+		deliveries = append(deliveries, d)
+	}
+	store := newMemoryStore(deliveries...)
+
+	dispatcher := NewDispatcher(store)
+	dispatcher.Client = &http.Client{Timeout: time.Second}
+	dispatcher.BatchSize = 4
+	dispatcher.Concurrency = 2
+	dispatcher.Lease = 0
+
+	startTime := time.Now()
+	n, err := dispatcher.DispatchOnce(context.Background())
+	is.NoErr(err)
+	is.Equal(n, 4)
+
+	// two rounds of two deliveries, each of them can take the timeout
+	is.True(store.leaseUntil.Sub(startTime) >= 2*time.Second) // lease shorter than the batch
+	is.True(time.Since(startTime) < 4*delay)                  // deliveries sent one at a time
+	for _, d := range deliveries {
+		is.Equal(store.delivered[d.ID], http.StatusNoContent)
+		is.True(store.markedAt[d.ID].Before(store.leaseUntil)) // delivered after the lease ended
+	}
+}
+
+func TestDispatcherPrivateAddress(t *testing.T) {
+	is := is_.New(t)
+
+	received := false
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer receiver.Close()
+
+	store := newMemoryStore(newDelivery(receiver.URL))
+	dispatcher := NewDispatcher(store)
+
+	_, err := dispatcher.DispatchOnce(context.Background())
+	is.NoErr(err)
+
+	is.True(!received) // loopback reached
+	failures := store.failures[1]
+	is.Equal(len(failures), 1)
+	is.Equal(failures[0].StatusCode, 0)
+	is.True(strings.Contains(failures[0].Error, ErrPrivateAddress.Error()))
+}
+
+func TestPublicAddress(t *testing.T) {
+	is := is_.New(t)
+
+	for addr, public := range map[string]bool{
+		"93.184.215.14":        true,
+		"2606:2800:21f:cb07::": true,
+		"127.0.0.1":            false,
+		"::1":                  false,
+		"10.0.0.1":             false,
+		"172.16.5.4":           false,
+		"192.168.1.1":          false,
+		"169.254.169.254":      false, // cloud metadata
+		"100.64.0.1":           false,
+		"0.0.0.0":              false,
+		"255.255.255.255":      false,
+		"224.0.0.1":            false,
+		"fe80::1":              false,
+		"fd00::1":              false,
+		"::ffff:127.0.0.1":     false,
+		"::ffff:10.0.0.1":      false,
+	} {
+		is.Equal(publicAddress(netip.MustParseAddr(addr)), public) // public address
+	}
+
+	ctx := context.Background()
+	is.True(errors.Is(CheckURL(ctx, "http://127.0.0.1:8080/hook"), ErrPrivateAddress))
+	is.True(errors.Is(CheckURL(ctx, "http://[::1]/hook"), ErrPrivateAddress))
+	is.True(errors.Is(CheckURL(ctx, "http://169.254.169.254/latest/meta-data"), ErrPrivateAddress))
+	is.True(errors.Is(CheckURL(ctx, "http://localhost/hook"), ErrPrivateAddress))
+	is.NoErr(CheckURL(ctx, "https://93.184.215.14/hook"))
+}
+
+func TestBackoff(t *testing.T) {
+	is := is_.New(t)
+
+	dispatcher := NewDispatcher(nil)
+	dispatcher.BaseBackoff = time.Second
+	dispatcher.MaxBackoff = 10 * time.Second
+
+	is.Equal(dispatcher.backoff(1), time.Second)
+	is.Equal(dispatcher.backoff(2), 2*time.Second)
+	is.Equal(dispatcher.backoff(4), 8*time.Second)
+	is.Equal(dispatcher.backoff(5), 10*time.Second)
+}
+
+func TestVerify(t *testing.T) {
+	is := is_.New(t)
+
+	body := []byte(`{"id":"evt1"}`)
+	header := Sign("secret", time.Now(), body)
+
+	is.NoErr(Verify("secret", header, body, time.Minute))
+	is.Equal(Verify("other", header, body, time.Minute), ErrInvalidSignature)
+	is.Equal(Verify("secret", header, []byte(`{}`), time.Minute), ErrInvalidSignature)
+	is.Equal(Verify("secret", "v1=abc", body, 0), ErrMalformedSignature)
+
+	old := Sign("secret", time.Now().Add(-time.Hour), body)
+	is.Equal(Verify("secret", old, body, time.Minute), ErrExpiredSignature)
+}
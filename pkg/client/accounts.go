@@ -0,0 +1,88 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+// Account types
+const (
+	AccountTypeAsset     = "asset"
+	AccountTypeLiability = "liability"
+	AccountTypeEquity    = "equity"
+	AccountTypeRevenue   = "revenue"
+	AccountTypeExpense   = "expense"
+)
+
+type Account struct {
+	UUID       string   `json:"uuid"`
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Metadata   Metadata `json:"metadata"`
+	LedgerUUID string   `json:"ledger_uuid,omitempty"`
+}
+
+type CreateAccountRequest struct {
+	Name       string   `json:"name"`
+	Type       string   `json:"type"`
+	Metadata   Metadata `json:"metadata"`
+	LedgerUUID string   `json:"ledger_uuid"`
+}
+
+// CreateAccount creates an account, the API only sends back its uuid and
+// name so the rest is filled in from the request
+func (c *Client) CreateAccount(ctx context.Context, req CreateAccountRequest) (*Account, error) {
+	if req.Metadata == nil {
+		req.Metadata = Metadata{}
+	}
+
+	var account Account
+	if err := c.Do(ctx, http.MethodPost, "/accounts", nil, req, &account); err != nil {
+		return nil, err
+	}
+	account.Type = req.Type
+	account.Metadata = req.Metadata
+	account.LedgerUUID = req.LedgerUUID
+	return &account, nil
+}
+
+type ListAccountsRequest struct {
+	LedgerUUID string
+	// Metadata filters the accounts whose metadata contains it, the API
+	// requires at least one key
+	Metadata Metadata
+}
+
+// ListAccounts lists the accounts of a ledger matching the metadata
+func (c *Client) ListAccounts(ctx context.Context, req ListAccountsRequest) ([]Account, error) {
+	query := url.Values{"ledger_uuid": {req.LedgerUUID}}
+	req.Metadata.filter(query)
+
+	var accounts []Account
+	err := c.Do(ctx, http.MethodGet, "/accounts", query, nil, &accounts)
+	// the API answers a 404 when none matches
+	if errors.Is(err, ErrNotFound) {
+		return []Account{}, nil
+	}
+	for i := range accounts {
+		accounts[i].LedgerUUID = req.LedgerUUID
+	}
+	return accounts, err
+}
+
+type UpdateAccountRequest struct {
+	Name     string   `json:"name,omitempty"`
+	Type     string   `json:"type,omitempty"`
+	Metadata Metadata `json:"metadata,omitempty"`
+}
+
+// UpdateAccount changes the fields set in the request
+func (c *Client) UpdateAccount(ctx context.Context, uuid string, req UpdateAccountRequest) (*Account, error) {
+	var account Account
+	if err := c.Do(ctx, http.MethodPatch, "/accounts/"+url.PathEscape(uuid), nil, req, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
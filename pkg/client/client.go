@@ -0,0 +1,276 @@
+// Package client is the Go client of the doubleed API. Requests are retried
+// on network errors, rate limits and server errors. POST requests are sent
+// with an `Idempotency-Key`, the same on every attempt, so a retry never
+// creates a ledger, an account or a transaction twice.
+//
+//	c, err := client.New("http://localhost:8080", os.Getenv("DOUBLEED_API_KEY"), client.Options{})
+//	ledger, err := c.CreateLedger(ctx, client.CreateLedgerRequest{Name: "Books"})
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	mathrand "math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultMaxRetries is how many times a request is retried unless the
+	// options say otherwise
+	DefaultMaxRetries = 3
+	// DefaultMinBackoff is the wait before the first retry, it doubles on
+	// every attempt
+	DefaultMinBackoff = 250 * time.Millisecond
+	// DefaultMaxBackoff caps the wait between attempts
+	DefaultMaxBackoff = 10 * time.Second
+)
+
+// idempotencyKeyHeader is the header the server remembers POST requests by
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// Options configure a Client, the zero value uses the defaults
+type Options struct {
+	// HTTPClient sends the requests, a client with a 30s timeout by default
+	HTTPClient *http.Client
+	// MaxRetries is how many times a failed request is retried, zero is
+	// DefaultMaxRetries and a negative value disables retries
+	MaxRetries int
+	// MinBackoff and MaxBackoff bound the wait between attempts
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// UserAgent is sent with every request
+	UserAgent string
+}
+
+// Client talks to the API of a doubleed server. It's safe for concurrent
+// use.
+type Client struct {
+	baseURL    *url.URL
+	apiKey     string
+	http       *http.Client
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	userAgent  string
+}
+
+// New returns a client of the server at baseURL authenticated with the API
+// key, or with a JWT of the identity provider
+func New(baseURL, apiKey string, opts Options) (*Client, error) {
+	u, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("parse base url: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base url %q, use http or https", baseURL)
+	}
+	if apiKey == "" {
+		return nil, errors.New("api key is required")
+	}
+
+	c := &Client{
+		baseURL:    u,
+		apiKey:     apiKey,
+		http:       opts.HTTPClient,
+		maxRetries: opts.MaxRetries,
+		minBackoff: opts.MinBackoff,
+		maxBackoff: opts.MaxBackoff,
+		userAgent:  opts.UserAgent,
+	}
+	if c.http == nil {
+		c.http = &http.Client{Timeout: 30 * time.Second}
+	}
+	if c.maxRetries == 0 {
+		c.maxRetries = DefaultMaxRetries
+	}
+	if c.maxRetries < 0 {
+		c.maxRetries = 0
+	}
+	if c.minBackoff <= 0 {
+		c.minBackoff = DefaultMinBackoff
+	}
+	if c.maxBackoff <= 0 {
+		c.maxBackoff = DefaultMaxBackoff
+	}
+	if c.userAgent == "" {
+		c.userAgent = "doubleed-go"
+	}
+
+	return c, nil
+}
+
+type idempotencyKey struct{}
+
+// WithIdempotencyKey sends the POST requests made with ctx with the key
+// instead of a random one, e.g., to retry a request after a restart
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// envelope is the StandardResponse every successful response is wrapped in
+type envelope struct {
+	Detail json.RawMessage `json:"detail"`
+}
+
+// Do sends a request to the API and decodes the detail of the response
+// into out, which may be nil. Every typed method goes through it, it's
+// exported for the endpoints they don't cover.
+func (c *Client) Do(ctx context.Context, method, path string, query url.Values, body, out any) error {
+	var payload []byte
+	if body != nil {
+		var err error
+		payload, err = json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("marshal request: %w", err)
+		}
+	}
+
+	var key string
+	if method == http.MethodPost {
+		key, _ = ctx.Value(idempotencyKey{}).(string)
+		if key == "" {
+			key = newIdempotencyKey()
+		}
+	}
+
+	u := c.baseURL.JoinPath(path)
+	u.RawQuery = query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, method, u.String(), payload, key)
+		if err == nil {
+			if resp.StatusCode < http.StatusBadRequest {
+				return decode(resp, out)
+			}
+			err = responseError(resp)
+		}
+
+		// the caller gave up, its error is the one to return
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt >= c.maxRetries || !c.retryable(method, key, err) {
+			return err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt, err))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(ctx context.Context, method, u string, payload []byte, key string) (*http.Response, error) {
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+
+	return c.http.Do(req)
+}
+
+func decode(resp *http.Response, out any) error {
+	defer resp.Body.Close()
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		_, _ = io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	var res envelope
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	if err := json.Unmarshal(res.Detail, out); err != nil {
+		return fmt.Errorf("decode response detail: %w", err)
+	}
+	return nil
+}
+
+// retryable tells whether the request can be sent again. Requests other
+// than POST don't create anything, POST requests are only retried with an
+// idempotency key.
+func (c *Client) retryable(method, key string, err error) bool {
+	if method == http.MethodPost && key == "" {
+		return false
+	}
+
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		// the request may not have reached the server
+		return true
+	}
+
+	switch apiErr.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	case http.StatusConflict:
+		// the first attempt is still in progress, or failed
+		return key != "" && apiErr.retryableConflict()
+	default:
+		return false
+	}
+}
+
+// backoff is the wait before the next attempt, the one the server asks for
+// when it rate limits the client, an exponential one with jitter otherwise
+func (c *Client) backoff(attempt int, err error) time.Duration {
+	var apiErr *Error
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	d := float64(c.minBackoff) * math.Pow(2, float64(attempt))
+	if d > float64(c.maxBackoff) {
+		d = float64(c.maxBackoff)
+	}
+	// half of it random, so clients failing together don't retry together
+	return time.Duration(d/2 + mathrand.Float64()*d/2)
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// never happens, see crypto/rand.Read
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// retryAfter reads the `Retry-After` header in seconds
+func retryAfter(h http.Header) time.Duration {
+	seconds, err := strconv.Atoi(h.Get("Retry-After"))
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
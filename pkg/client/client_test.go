@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	is_ "github.com/matryer/is"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// testClient is a client of the handler that retries right away
+func testClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL, "dde_test_secret", Options{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("unable to create client: %v", err)
+	}
+	return c
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func TestRetries(t *testing.T) {
+	is := is_.New(t)
+
+	var attempts int
+	var keys []string
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		is.Equal(r.Header.Get("Authorization"), "Bearer dde_test_secret")
+
+		if attempts < 3 {
+			writeJSON(w, http.StatusServiceUnavailable, map[string]any{"status": 503, "message": "Service Unavailable"})
+			return
+		}
+		writeJSON(w, http.StatusCreated, map[string]any{
+			"msg": "OK", "length": 1, "type": "OBJ",
+			"detail": map[string]any{"uuid": "l1", "name": "Books"},
+		})
+	})
+
+	ledger, err := c.CreateLedger(context.Background(), CreateLedgerRequest{Name: "Books", Metadata: Metadata{"user_id": 24}})
+	is.NoErr(err)
+	is.Equal(ledger.UUID, "l1")
+	is.Equal(ledger.Metadata["user_id"], 24)
+	is.Equal(attempts, 3)
+
+	// every attempt is the same request to the server
+	is.True(keys[0] != "")
+	is.Equal(keys[0], keys[1])
+	is.Equal(keys[1], keys[2])
+}
+
+func TestErrors(t *testing.T) {
+	is := is_.New(t)
+
+	var attempts int
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		switch r.URL.Path {
+		case "/accounts":
+			writeJSON(w, http.StatusBadRequest, map[string]any{
+				"status":  400,
+				"message": map[string]any{"errors": []map[string]string{{"Field": "Name", "Message": "This field is required"}}},
+			})
+		case "/transactions/missing":
+			writeJSON(w, http.StatusNotFound, map[string]any{"status": 404, "message": "Not Found"})
+		case "/ledgers/l1":
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"status": 422, "message": "Unprocessable Entity"})
+		case "/ledgers/l2":
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]any{"status": 422, "message": "Idempotency-Key was used for another request"})
+		case "/ledgers":
+			w.Header().Set("Retry-After", "1")
+			writeJSON(w, http.StatusTooManyRequests, map[string]any{"status": 429, "message": "Too Many Requests"})
+		}
+	})
+
+	_, err := c.CreateAccount(context.Background(), CreateAccountRequest{Type: AccountTypeAsset, LedgerUUID: "l1"})
+	var apiErr *Error
+	is.True(errors.As(err, &apiErr))
+	is.True(errors.Is(err, ErrInvalidRequest))
+	is.Equal(apiErr.ValidationErrors, []ValidationError{{Field: "Name", Message: "This field is required"}})
+	is.Equal(attempts, 1) // client errors aren't retried
+
+	_, err = c.GetTransaction(context.Background(), "missing")
+	is.True(errors.Is(err, ErrNotFound))
+
+	_, err = c.UpdateLedger(context.Background(), "l1", UpdateLedgerRequest{Name: "Books"})
+	is.True(!errors.Is(err, ErrIdempotencyKeyReused)) // any 422 taken for a reused key
+	_, err = c.UpdateLedger(context.Background(), "l2", UpdateLedgerRequest{Name: "Books"})
+	is.True(errors.Is(err, ErrIdempotencyKeyReused))
+
+	// the wait the server asks for is longer than the caller's
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	attempts = 0
+	_, err = c.ListLedgers(ctx, Metadata{"user_id": 24})
+	is.True(errors.Is(err, context.DeadlineExceeded))
+	is.Equal(attempts, 1)
+}
+
+func TestTransactions(t *testing.T) {
+	is := is_.New(t)
+
+	const total = 5
+	c := testClient(t, func(w http.ResponseWriter, r *http.Request) {
+		is.Equal(r.URL.Query().Get("metadata.user_id"), "24")
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		if offset >= total {
+			writeJSON(w, http.StatusNotFound, map[string]any{"status": 404, "message": "Not Found"})
+			return
+		}
+
+		var data []map[string]any
+		for i := offset; i < min(offset+limit, total); i++ {
+			data = append(data, map[string]any{
+				"uuid":        "t" + strconv.Itoa(i),
+				"amount":      100,
+				"date":        "2024-11-24",
+				"description": nil,
+				// the list endpoints encode the metadata
+				"metadata": []byte(`{"user_id":24}`),
+			})
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"msg": "OK", "length": len(data), "type": "LIST",
+			"detail": map[string]any{
+				"data":       data,
+				"pagination": map[string]any{"total": total, "limit": limit, "offset": offset},
+			},
+		})
+	})
+
+	it := c.Transactions(ListTransactionsRequest{LedgerUUID: "l1", Metadata: Metadata{"user_id": 24}, Limit: 2})
+	var uuids []string
+	for it.Next(context.Background()) {
+		tx := it.Transaction()
+		is.Equal(tx.Date.String(), "2024-11-24")
+		is.Equal(tx.Metadata["user_id"], float64(24))
+		is.Equal(tx.LedgerUUID, "l1")
+		uuids = append(uuids, tx.UUID)
+	}
+	is.NoErr(it.Err())
+	is.Equal(uuids, []string{"t0", "t1", "t2", "t3", "t4"})
+
+	page, err := c.ListTransactions(context.Background(), ListTransactionsRequest{LedgerUUID: "l1", Metadata: Metadata{"user_id": 24}, Offset: 10})
+	is.NoErr(err)
+	is.Equal(len(page.Transactions), 0) // an empty page isn't an error
+}
@@ -0,0 +1,125 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Errors the API responds with, match them with errors.Is
+var (
+	ErrInvalidRequest = errors.New("invalid request")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrForbidden      = errors.New("forbidden")
+	ErrNotFound       = errors.New("not found")
+	ErrConflict       = errors.New("conflict")
+	// ErrIdempotencyKeyReused is returned when the key of a request was
+	// already used for another one
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused")
+	ErrRateLimited          = errors.New("rate limited")
+	ErrServer               = errors.New("server error")
+)
+
+// ValidationError is a field the API rejected, the keys of the JSON are
+// the names of the fields
+type ValidationError struct {
+	Field   string `json:"Field"`
+	Message string `json:"Message"`
+}
+
+// Error is an error response of the API, the ErrorResponse of the server
+type Error struct {
+	StatusCode int
+	Message    string
+	// ValidationErrors are the fields of the request the API rejected
+	ValidationErrors []ValidationError
+	// RetryAfter is how long the server asked to wait before retrying
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	message := e.Message
+	if len(e.ValidationErrors) > 0 {
+		fields := make([]string, len(e.ValidationErrors))
+		for i, v := range e.ValidationErrors {
+			fields[i] = v.Field + ": " + v.Message
+		}
+		message = strings.Join(fields, ", ")
+	}
+	if message == "" {
+		message = http.StatusText(e.StatusCode)
+	}
+	return fmt.Sprintf("doubleed: %d %s", e.StatusCode, message)
+}
+
+// Unwrap maps the status to one of the Err* errors
+func (e *Error) Unwrap() error {
+	switch {
+	case e.StatusCode == http.StatusBadRequest:
+		return ErrInvalidRequest
+	case e.StatusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case e.StatusCode == http.StatusForbidden:
+		return ErrForbidden
+	case e.StatusCode == http.StatusNotFound:
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict:
+		return ErrConflict
+	case e.reusedIdempotencyKey():
+		return ErrIdempotencyKeyReused
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimited
+	case e.StatusCode >= http.StatusInternalServerError:
+		return ErrServer
+	default:
+		return nil
+	}
+}
+
+// reusedIdempotencyKey tells apart the 422 of a key used for another
+// request from the ones of the request itself
+func (e *Error) reusedIdempotencyKey() bool {
+	return e.StatusCode == http.StatusUnprocessableEntity && strings.Contains(e.Message, "Idempotency-Key")
+}
+
+// retryableConflict tells apart the conflicts of an idempotency key still
+// in progress, or released, from the ones of the request itself
+func (e *Error) retryableConflict() bool {
+	return e.StatusCode == http.StatusConflict && strings.Contains(e.Message, "Idempotency-Key")
+}
+
+// errorResponse is the ErrorResponse of the server, the message is a
+// string or the validation errors
+type errorResponse struct {
+	Status  int             `json:"status"`
+	Message json.RawMessage `json:"message"`
+}
+
+// responseError reads the error response, the status is enough when the
+// body isn't one, e.g., the response of a proxy
+func responseError(resp *http.Response) error {
+	defer resp.Body.Close()
+
+	apiErr := &Error{
+		StatusCode: resp.StatusCode,
+		RetryAfter: retryAfter(resp.Header),
+	}
+
+	var res errorResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil || len(res.Message) == 0 {
+		return apiErr
+	}
+
+	var validation struct {
+		Errors []ValidationError `json:"errors"`
+	}
+	if err := json.Unmarshal(res.Message, &apiErr.Message); err != nil {
+		if err := json.Unmarshal(res.Message, &validation); err == nil {
+			apiErr.ValidationErrors = validation.Errors
+		}
+	}
+	return apiErr
+}
@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+)
+
+type Ledger struct {
+	UUID        string   `json:"uuid"`
+	Name        string   `json:"name"`
+	Description *string  `json:"description"`
+	Metadata    Metadata `json:"metadata"`
+}
+
+type CreateLedgerRequest struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Metadata    Metadata `json:"metadata"`
+}
+
+// CreateLedger creates a ledger, the API only sends back its uuid and name
+// so the rest is filled in from the request
+func (c *Client) CreateLedger(ctx context.Context, req CreateLedgerRequest) (*Ledger, error) {
+	if req.Metadata == nil {
+		req.Metadata = Metadata{}
+	}
+
+	var ledger Ledger
+	if err := c.Do(ctx, http.MethodPost, "/ledgers", nil, req, &ledger); err != nil {
+		return nil, err
+	}
+	if req.Description != "" {
+		ledger.Description = &req.Description
+	}
+	ledger.Metadata = req.Metadata
+	return &ledger, nil
+}
+
+// ListLedgers lists the ledgers whose metadata contains the filter, the
+// API requires at least one key
+func (c *Client) ListLedgers(ctx context.Context, metadata Metadata) ([]Ledger, error) {
+	query := url.Values{}
+	metadata.filter(query)
+
+	var ledgers []Ledger
+	err := c.Do(ctx, http.MethodGet, "/ledgers", query, nil, &ledgers)
+	// the API answers a 404 when none matches
+	if errors.Is(err, ErrNotFound) {
+		return []Ledger{}, nil
+	}
+	return ledgers, err
+}
+
+type UpdateLedgerRequest struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Metadata    Metadata `json:"metadata,omitempty"`
+}
+
+// UpdateLedger changes the fields set in the request
+func (c *Client) UpdateLedger(ctx context.Context, uuid string, req UpdateLedgerRequest) (*Ledger, error) {
+	var ledger Ledger
+	if err := c.Do(ctx, http.MethodPatch, "/ledgers/"+url.PathEscape(uuid), nil, req, &ledger); err != nil {
+		return nil, err
+	}
+	return &ledger, nil
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+)
+
+// ReportRequest is the inclusive period of a report, `2006-01-02` dates,
+// empty means open
+type ReportRequest struct {
+	From string
+	To   string
+}
+
+func (r ReportRequest) query() url.Values {
+	query := url.Values{}
+	if r.From != "" {
+		query.Set("from", r.From)
+	}
+	if r.To != "" {
+		query.Set("to", r.To)
+	}
+	return query
+}
+
+type Period struct {
+	From string `json:"from,omitempty"`
+	To   string `json:"to,omitempty"`
+}
+
+// Line is an account in a report. Amounts are in minor units, Balance
+// uses the normal sign of the account type.
+type Line struct {
+	AccountUUID string `json:"account_uuid"`
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Depth       int    `json:"depth"`
+	Debit       int64  `json:"debit"`
+	Credit      int64  `json:"credit"`
+	Balance     int64  `json:"balance"`
+}
+
+// Section is a group of accounts of the same type with its total
+type Section struct {
+	Name  string `json:"name"`
+	Lines []Line `json:"lines"`
+	Total int64  `json:"total"`
+}
+
+type TrialBalance struct {
+	Period      Period `json:"period"`
+	Lines       []Line `json:"lines"`
+	TotalDebit  int64  `json:"total_debit"`
+	TotalCredit int64  `json:"total_credit"`
+}
+
+type BalanceSheet struct {
+	Period                    Period  `json:"period"`
+	Assets                    Section `json:"assets"`
+	Liabilities               Section `json:"liabilities"`
+	Equity                    Section `json:"equity"`
+	NetIncome                 int64   `json:"net_income"`
+	TotalLiabilitiesAndEquity int64   `json:"total_liabilities_and_equity"`
+}
+
+type IncomeStatement struct {
+	Period    Period  `json:"period"`
+	Revenue   Section `json:"revenue"`
+	Expenses  Section `json:"expenses"`
+	NetIncome int64   `json:"net_income"`
+}
+
+// Entry is a transaction in an account statement, Balance is the running
+// balance after it
+type Entry struct {
+	TransactionUUID string `json:"transaction_uuid"`
+	Date            string `json:"date"`
+	Description     string `json:"description"`
+	CounterpartUUID string `json:"counterpart_uuid"`
+	CounterpartName string `json:"counterpart_name"`
+	Debit           int64  `json:"debit"`
+	Credit          int64  `json:"credit"`
+	Balance         int64  `json:"balance"`
+}
+
+type AccountStatement struct {
+	Account struct {
+		UUID       string `json:"uuid"`
+		Name       string `json:"name"`
+		Type       string `json:"type"`
+		LedgerUUID string `json:"ledger_uuid"`
+	} `json:"account"`
+	Period         Period  `json:"period"`
+	OpeningBalance int64   `json:"opening_balance"`
+	Entries        []Entry `json:"entries"`
+	ClosingBalance int64   `json:"closing_balance"`
+}
+
+// TrialBalance lists the debit and credit totals of every account of the
+// ledger
+func (c *Client) TrialBalance(ctx context.Context, ledgerUUID string, req ReportRequest) (*TrialBalance, error) {
+	var report TrialBalance
+	if err := c.Do(ctx, http.MethodGet, "/ledgers/"+url.PathEscape(ledgerUUID)+"/reports/trial-balance", req.query(), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// BalanceSheet reports the assets, liabilities and equity of the ledger as
+// of the end of the period
+func (c *Client) BalanceSheet(ctx context.Context, ledgerUUID string, req ReportRequest) (*BalanceSheet, error) {
+	var report BalanceSheet
+	if err := c.Do(ctx, http.MethodGet, "/ledgers/"+url.PathEscape(ledgerUUID)+"/reports/balance-sheet", req.query(), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// IncomeStatement reports the revenue and expenses of the ledger in the
+// period
+func (c *Client) IncomeStatement(ctx context.Context, ledgerUUID string, req ReportRequest) (*IncomeStatement, error) {
+	var report IncomeStatement
+	if err := c.Do(ctx, http.MethodGet, "/ledgers/"+url.PathEscape(ledgerUUID)+"/reports/income-statement", req.query(), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// AccountStatement lists the entries of the account with their running
+// balance
+func (c *Client) AccountStatement(ctx context.Context, accountUUID string, req ReportRequest) (*AccountStatement, error) {
+	var report AccountStatement
+	if err := c.Do(ctx, http.MethodGet, "/accounts/"+url.PathEscape(accountUUID)+"/statement", req.query(), nil, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
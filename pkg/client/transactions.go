@@ -0,0 +1,216 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// DefaultPageSize is the number of transactions of a page unless the
+// request says otherwise, the one of the API
+const DefaultPageSize = 30
+
+type Transaction struct {
+	UUID              string    `json:"uuid"`
+	Amount            int64     `json:"amount"`
+	Date              Date      `json:"date"`
+	Description       *string   `json:"description"`
+	Metadata          Metadata  `json:"metadata"`
+	CreditAccountUUID string    `json:"credit_account_uuid,omitempty"`
+	DebitAccountUUID  string    `json:"debit_account_uuid,omitempty"`
+	LedgerUUID        string    `json:"ledger_uuid,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// CreateTransactionRequest debits the amount, in minor units, to one
+// account and credits it to the other
+type CreateTransactionRequest struct {
+	Amount            int64     `json:"amount"`
+	Date              time.Time `json:"date"`
+	Description       string    `json:"description,omitempty"`
+	Metadata          Metadata  `json:"metadata"`
+	CreditAccountUUID string    `json:"credit_account_uuid"`
+	DebitAccountUUID  string    `json:"debit_account_uuid"`
+	LedgerUUID        string    `json:"ledger_uuid"`
+}
+
+// CreateTransaction creates a transaction, the accounts, the ledger and
+// the metadata are filled in from the request
+func (c *Client) CreateTransaction(ctx context.Context, req CreateTransactionRequest) (*Transaction, error) {
+	if req.Metadata == nil {
+		req.Metadata = Metadata{}
+	}
+
+	var tx Transaction
+	if err := c.Do(ctx, http.MethodPost, "/transactions", nil, req, &tx); err != nil {
+		return nil, err
+	}
+	tx.Metadata = req.Metadata
+	tx.CreditAccountUUID = req.CreditAccountUUID
+	tx.DebitAccountUUID = req.DebitAccountUUID
+	tx.LedgerUUID = req.LedgerUUID
+	return &tx, nil
+}
+
+// GetTransaction returns the transaction with all its fields
+func (c *Client) GetTransaction(ctx context.Context, uuid string) (*Transaction, error) {
+	var tx Transaction
+	if err := c.Do(ctx, http.MethodGet, "/transactions/"+url.PathEscape(uuid), nil, nil, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// UpdateTransactionRequest changes the fields that are set
+type UpdateTransactionRequest struct {
+	Amount            *int64     `json:"amount,omitempty"`
+	Date              *time.Time `json:"date,omitempty"`
+	Description       *string    `json:"description,omitempty"`
+	Metadata          Metadata   `json:"metadata,omitempty"`
+	CreditAccountUUID *string    `json:"credit_account_uuid,omitempty"`
+	DebitAccountUUID  *string    `json:"debit_account_uuid,omitempty"`
+}
+
+// UpdateTransaction changes the fields set in the request, the API sends
+// back the uuid, amount, date and description
+func (c *Client) UpdateTransaction(ctx context.Context, uuid string, req UpdateTransactionRequest) (*Transaction, error) {
+	var tx Transaction
+	if err := c.Do(ctx, http.MethodPatch, "/transactions/"+url.PathEscape(uuid), nil, req, &tx); err != nil {
+		return nil, err
+	}
+	return &tx, nil
+}
+
+// DeleteTransaction deletes the transaction
+func (c *Client) DeleteTransaction(ctx context.Context, uuid string) error {
+	return c.Do(ctx, http.MethodDelete, "/transactions/"+url.PathEscape(uuid), nil, nil, nil)
+}
+
+type ListTransactionsRequest struct {
+	LedgerUUID string
+	// Metadata filters the transactions whose metadata contains it, the
+	// API requires at least one key
+	Metadata Metadata
+	// Limit is the size of the page, DefaultPageSize when zero
+	Limit  int32
+	Offset int32
+}
+
+// TransactionPage is a page of transactions, Total counts the
+// transactions of every page
+type TransactionPage struct {
+	Transactions []Transaction
+	Total        int32
+	Limit        int32
+	Offset       int32
+}
+
+// ListTransactions returns a page of the transactions of a ledger, newest
+// first. Use Transactions to go through all of them.
+func (c *Client) ListTransactions(ctx context.Context, req ListTransactionsRequest) (*TransactionPage, error) {
+	if req.Limit == 0 {
+		req.Limit = DefaultPageSize
+	}
+
+	query := url.Values{
+		"ledger_uuid": {req.LedgerUUID},
+		"limit":       {strconv.FormatInt(int64(req.Limit), 10)},
+		"offset":      {strconv.FormatInt(int64(req.Offset), 10)},
+	}
+	req.Metadata.filter(query)
+
+	var res struct {
+		Data       []Transaction `json:"data"`
+		Pagination struct {
+			Total  int32 `json:"total"`
+			Limit  int32 `json:"limit"`
+			Offset int32 `json:"offset"`
+		} `json:"pagination"`
+	}
+	err := c.Do(ctx, http.MethodGet, "/transactions", query, nil, &res)
+	// the API answers a 404 when the page is empty
+	if errors.Is(err, ErrNotFound) {
+		return &TransactionPage{Transactions: []Transaction{}, Limit: req.Limit, Offset: req.Offset}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range res.Data {
+		res.Data[i].LedgerUUID = req.LedgerUUID
+	}
+	return &TransactionPage{
+		Transactions: res.Data,
+		Total:        res.Pagination.Total,
+		Limit:        res.Pagination.Limit,
+		Offset:       res.Pagination.Offset,
+	}, nil
+}
+
+// TransactionIterator goes through the transactions of every page, it
+// fetches a page when the previous one is done:
+//
+//	it := c.Transactions(client.ListTransactionsRequest{LedgerUUID: uuid, Metadata: filter})
+//	for it.Next(ctx) {
+//		tx := it.Transaction()
+//	}
+//	if err := it.Err(); err != nil {
+//		return err
+//	}
+type TransactionIterator struct {
+	client *Client
+	req    ListTransactionsRequest
+	page   []Transaction
+	i      int
+	done   bool
+	err    error
+}
+
+// Transactions iterates over the transactions matching the request,
+// starting at its offset
+func (c *Client) Transactions(req ListTransactionsRequest) *TransactionIterator {
+	return &TransactionIterator{client: c, req: req, i: -1}
+}
+
+// Next moves to the next transaction, it returns false when there are no
+// more or a page couldn't be fetched
+func (it *TransactionIterator) Next(ctx context.Context) bool {
+	if it.err != nil {
+		return false
+	}
+
+	it.i++
+	if it.i < len(it.page) {
+		return true
+	}
+	if it.done {
+		return false
+	}
+
+	page, err := it.client.ListTransactions(ctx, it.req)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.page = page.Transactions
+	it.i = 0
+	it.req.Offset += int32(len(page.Transactions))
+	// a short page is the last one
+	it.done = len(page.Transactions) < int(page.Limit) || it.req.Offset >= page.Total
+	return len(it.page) > 0
+}
+
+// Transaction is the current transaction
+func (it *TransactionIterator) Transaction() Transaction {
+	return it.page[it.i]
+}
+
+// Err is the error that stopped the iteration, if any
+func (it *TransactionIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+)
+
+// Metadata is the JSON object attached to ledgers, accounts and
+// transactions
+type Metadata map[string]any
+
+// UnmarshalJSON reads an object, or the base64 encoded object the list
+// endpoints send
+func (m *Metadata) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		*m = nil
+		return nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var raw []byte
+		if err := json.Unmarshal(b, &raw); err != nil {
+			return fmt.Errorf("decode metadata: %w", err)
+		}
+		b = raw
+	}
+	return json.Unmarshal(b, (*map[string]any)(m))
+}
+
+// filter adds the metadata to the query as `metadata.KEY=VALUE` params,
+// the filters of the list endpoints
+func (m Metadata) filter(query url.Values) {
+	for key, value := range m {
+		query.Set("metadata."+key, fmt.Sprint(value))
+	}
+}
+
+// Date is a calendar date, the API sends it as `2006-01-02` or as a
+// timestamp
+type Date struct {
+	time.Time
+}
+
+func (d *Date) UnmarshalJSON(b []byte) error {
+	if bytes.Equal(b, []byte("null")) {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		if t, err = time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Errorf("invalid date %q", s)
+		}
+	}
+	d.Time = t
+	return nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.Format(time.DateOnly))
+}
+
+func (d Date) String() string {
+	return d.Format(time.DateOnly)
+}
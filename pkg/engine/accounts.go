@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+)
+
+// CreateAccount creates an account, it's ErrInvalidReference when the
+// ledger doesn't exist
+func (e *Engine) CreateAccount(ctx context.Context, params CreateAccountParams) (*Account, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Account, error) {
+		return svc.CreateAccount(ctx, params)
+	})
+}
+
+// ListAccounts lists the accounts of a ledger whose metadata contains the
+// filter
+func (e *Engine) ListAccounts(ctx context.Context, ledgerUUID string, metadata map[string]any) ([]*AccountRow, error) {
+	return run(ctx, e, func(svc *ledger.Service) ([]*AccountRow, error) {
+		return svc.ListAccounts(ctx, ledgerUUID, metadata)
+	})
+}
+
+func (e *Engine) UpdateAccount(ctx context.Context, uuid string, params UpdateAccountParams) (*Account, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Account, error) {
+		return svc.UpdateAccount(ctx, uuid, params)
+	})
+}
+
+// Balance returns the current debit, credit and balance of an account
+func (e *Engine) Balance(ctx context.Context, accountUUID string) (*Line, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Line, error) {
+		return svc.Balance(ctx, accountUUID)
+	})
+}
+
+// Balances returns the current balances of the accounts ordered by name,
+// unknown accounts are left out
+func (e *Engine) Balances(ctx context.Context, accountUUIDs ...string) ([]Line, error) {
+	return run(ctx, e, func(svc *ledger.Service) ([]Line, error) {
+		return svc.Balances(ctx, accountUUIDs...)
+	})
+}
@@ -0,0 +1,222 @@
+// Package engine embeds the ledger engine in another Go program. It runs on
+// the program's own pool, optionally in a schema of its own, and calls the
+// same service the HTTP and gRPC APIs use, without a server in between.
+//
+//	eng, err := engine.Open(ctx, pool, engine.Options{Schema: "ledger"})
+//	err = eng.Migrate(ctx)
+//	tenant, err := eng.CreateTenant(ctx, "acme")
+//	ctx = engine.WithTenant(ctx, tenant.ID)
+//	books, err := eng.CreateLedger(ctx, engine.CreateLedgerParams{Name: "Books"})
+//
+// WithTx joins a transaction of the program, so postings commit or roll
+// back along with its own writes.
+package engine
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/db"
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/db/migrations"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+	"log/slog"
+	"strconv"
+)
+
+// ErrTenantRequired is returned when the context isn't scoped to a tenant
+// with WithTenant
+var ErrTenantRequired = errors.New("a tenant is required")
+
+type Engine struct {
+	pool *pgxpool.Pool
+	// tx is the transaction of the caller set by WithTx, the operations
+	// run in savepoints of it
+	tx pgx.Tx
+	// schema is empty when the tables are in the search path of the
+	// connections
+	schema string
+	ledger *ledger.Service
+}
+
+// Options of the engine, the zero value uses the search path of the
+// connections
+type Options struct {
+	// Schema holds the tables of the engine, it's created by Migrate.
+	// public stays in the search path after it for the extensions.
+	Schema string
+}
+
+// Open returns an engine using pool, the pool stays owned by the caller
+func Open(ctx context.Context, pool *pgxpool.Pool, opts Options) (*Engine, error) {
+	slog.DebugContext(ctx, "engine.open.start", "schema", opts.Schema)
+
+	if pool == nil {
+		return nil, errors.New("a pool is required")
+	}
+
+	e := &Engine{
+		pool:   pool,
+		schema: opts.Schema,
+		ledger: ledger.NewService(nil),
+	}
+
+	slog.DebugContext(ctx, "engine.open.complete", "schema", opts.Schema)
+	return e, nil
+}
+
+// WithTenant scopes the operations run with ctx to the tenant
+func WithTenant(ctx context.Context, tenantID int64) context.Context {
+	return db.WithTenant(ctx, tenantID)
+}
+
+// WithTx returns a copy of the engine that runs in tx. The caller commits
+// or rolls back tx, the changes of the engine are part of it. An operation
+// that fails is rolled back to a savepoint, so tx can still be used.
+func (e *Engine) WithTx(tx pgx.Tx) *Engine {
+	return &Engine{
+		pool:   e.pool,
+		tx:     tx,
+		schema: e.schema,
+		ledger: e.ledger,
+	}
+}
+
+// Migrate applies the pending migrations, creating the schema first
+func (e *Engine) Migrate(ctx context.Context) error {
+	slog.DebugContext(ctx, "engine.migrate.start")
+
+	var sqlDB *sql.DB
+	if e.schema == "" {
+		sqlDB = stdlib.OpenDBFromPool(e.pool)
+	} else {
+		if _, err := e.pool.Exec(ctx, "create schema if not exists "+pgx.Identifier{e.schema}.Sanitize()); err != nil {
+			return fmt.Errorf("create schema: %w", err)
+		}
+
+		// the migrations create their tables, functions and the goose
+		// table in the first schema of the search path
+		connConfig := e.pool.Config().ConnConfig
+		connConfig.RuntimeParams["search_path"] = e.searchPath()
+		sqlDB = stdlib.OpenDB(*connConfig)
+	}
+	defer sqlDB.Close()
+
+	if err := migrations.Up(ctx, sqlDB); err != nil {
+		return err
+	}
+
+	slog.DebugContext(ctx, "engine.migrate.complete")
+	return nil
+}
+
+// CreateTenant stores a new tenant, its ID goes to WithTenant
+func (e *Engine) CreateTenant(ctx context.Context, name string) (*Tenant, error) {
+	var tenant *Tenant
+	noTenant := ""
+	err := e.scoped(ctx, &noTenant, func(tx pgx.Tx) error {
+		var err error
+		tenant, err = dbGen.New(tx).CreateTenant(ctx, name)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create tenant: %w", err)
+	}
+	return tenant, nil
+}
+
+// run calls fn with the ledger service bound to a transaction scoped to
+// the tenant of ctx
+func run[T any](ctx context.Context, e *Engine, fn func(svc *ledger.Service) (T, error)) (T, error) {
+	var result T
+
+	tenantID, ok := db.TenantFromContext(ctx)
+	if !ok {
+		return result, ErrTenantRequired
+	}
+
+	tenant := strconv.FormatInt(tenantID, 10)
+	err := e.scoped(ctx, &tenant, func(tx pgx.Tx) error {
+		var err error
+		result, err = fn(e.ledger.WithClient(db.NewTxClient(tx)))
+		return err
+	})
+	return result, err
+}
+
+// scoped runs fn in a transaction, or a savepoint of the transaction of
+// the caller, where `app.tenant_id` is tenant and the search path the one
+// of the engine. The pool isn't configured by the engine, so the settings
+// are local to the transaction instead of set on every connection.
+func (e *Engine) scoped(ctx context.Context, tenant *string, fn func(tx pgx.Tx) error) error {
+	var tx pgx.Tx
+	var err error
+	if e.tx != nil {
+		tx, err = e.tx.Begin(ctx)
+	} else {
+		tx, err = e.pool.Begin(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	// rollback is a no-op once the transaction has been committed
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	// local settings outlive a released savepoint, the ones of the caller
+	// are put back before releasing it. The tenant is null when the caller
+	// didn't set it, and reset instead of set to an empty string.
+	var previousSearchPath string
+	var previousTenant *string
+	if e.tx != nil {
+		err = tx.QueryRow(ctx, "select current_setting('search_path'), current_setting('app.tenant_id', true)").
+			Scan(&previousSearchPath, &previousTenant)
+		if err != nil {
+			return fmt.Errorf("read settings: %w", err)
+		}
+	}
+
+	if err := setScope(ctx, tx, tenant, e.searchPath()); err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if e.tx != nil {
+		if err := setScope(ctx, tx, previousTenant, previousSearchPath); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+	return nil
+}
+
+// searchPath puts the schema of the engine first, empty without a schema
+func (e *Engine) searchPath() string {
+	if e.schema == "" {
+		return ""
+	}
+	return pgx.Identifier{e.schema}.Sanitize() + ", public"
+}
+
+// setScope sets the tenant and the search path until the end of the
+// transaction, an empty search path keeps the current one. A nil tenant
+// resets it to the value of the session, set_config resets on null.
+func setScope(ctx context.Context, tx pgx.Tx, tenant *string, searchPath string) error {
+	_, err := tx.Exec(ctx, `
+select set_config('app.tenant_id', $1, true),
+       set_config('search_path', coalesce(nullif($2, ''), current_setting('search_path')), true)`,
+		tenant, searchPath)
+	if err != nil {
+		return fmt.Errorf("set scope: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/j0lvera/go-double-e/internal/testutils"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	is_ "github.com/matryer/is"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestOpen(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	_, err := Open(ctx, nil, Options{})
+	is.True(err != nil) // a pool is required
+
+	// the pool connects lazily, nothing below reaches the database
+	pool, err := pgxpool.New(ctx, "postgres://localhost:1/doubleed")
+	is.NoErr(err)
+	t.Cleanup(pool.Close)
+
+	eng, err := Open(ctx, pool, Options{Schema: `Books "2024"`})
+	is.NoErr(err)
+	is.Equal(eng.searchPath(), `"Books ""2024""", public`)
+
+	_, err = eng.CreateLedger(ctx, CreateLedgerParams{Name: "Books"})
+	is.True(errors.Is(err, ErrTenantRequired))
+
+	eng, err = Open(ctx, pool, Options{})
+	is.NoErr(err)
+	is.Equal(eng.searchPath(), "") // keeps the search path of the connections
+}
+
+func TestEngine(t *testing.T) {
+	is := is_.New(t)
+	ctx := context.Background()
+
+	testDb, err := testutils.SetupTestDB(ctx)
+	if err != nil {
+		t.Fatalf("unable to setup test database: %v", err)
+	}
+	t.Cleanup(testDb.Cleanup)
+
+	// the schema and the tenants are set up by the owner of the database,
+	// the engine runs as the app role under row level security
+	admin, err := Open(ctx, testDb.AdminPool, Options{Schema: "embedded"})
+	is.NoErr(err)
+	is.NoErr(admin.Migrate(ctx))
+	is.NoErr(admin.Migrate(ctx)) // nothing left to apply
+
+	tenant, err := admin.CreateTenant(ctx, "embedded")
+	is.NoErr(err)
+	tenantCtx := WithTenant(ctx, tenant.ID)
+
+	_, err = testDb.AdminPool.Exec(ctx, fmt.Sprintf(`
+		grant usage on schema embedded to %[1]s;
+		grant select, insert, update, delete on all tables in schema embedded to %[1]s;
+		grant usage, select on all sequences in schema embedded to %[1]s`, testutils.AppRole))
+	is.NoErr(err)
+
+	eng, err := Open(ctx, testDb.Pool, Options{Schema: "embedded"})
+	is.NoErr(err)
+
+	books, err := eng.CreateLedger(tenantCtx, CreateLedgerParams{Name: "Books"})
+	is.NoErr(err)
+	cash, err := eng.CreateAccount(tenantCtx, CreateAccountParams{LedgerUUID: books.Uuid, Name: "Cash", Type: "asset"})
+	is.NoErr(err)
+	sales, err := eng.CreateAccount(tenantCtx, CreateAccountParams{LedgerUUID: books.Uuid, Name: "Sales", Type: "revenue"})
+	is.NoErr(err)
+
+	var inSchema int
+	is.NoErr(testDb.AdminPool.QueryRow(ctx, "select count(*) from embedded.ledgers where uuid = $1", books.Uuid).Scan(&inSchema))
+	is.Equal(inSchema, 1) // ledger stored in the schema
+
+	// the orders of the program, in a schema of its own, written along
+	// with the postings
+	_, err = testDb.AdminPool.Exec(ctx, fmt.Sprintf(`
+		create schema program;
+		create table program.orders (id bigint primary key);
+		grant usage on schema program to %[1]s;
+		grant select, insert on program.orders to %[1]s`, testutils.AppRole))
+	is.NoErr(err)
+	t.Cleanup(func() {
+		_, _ = testDb.AdminPool.Exec(context.Background(), "drop schema program cascade")
+	})
+
+	sale := PostTransactionParams{
+		Amount:            1000,
+		Date:              time.Date(2024, 11, 24, 0, 0, 0, 0, time.UTC),
+		DebitAccountUUID:  cash.Uuid,
+		CreditAccountUUID: sales.Uuid,
+		LedgerUUID:        books.Uuid,
+	}
+
+	placeOrder := func(id int64) (*Transaction, pgx.Tx) {
+		tx, err := testDb.Pool.Begin(ctx)
+		is.NoErr(err)
+
+		var searchPath string
+		is.NoErr(tx.QueryRow(ctx, "show search_path").Scan(&searchPath))
+
+		_, err = tx.Exec(ctx, "insert into program.orders (id) values ($1)", id)
+		is.NoErr(err)
+
+		posted, err := eng.WithTx(tx).PostTransaction(tenantCtx, sale)
+		is.NoErr(err)
+
+		// a failed posting leaves the transaction usable
+		_, err = eng.WithTx(tx).PostTransaction(tenantCtx, PostTransactionParams{
+			Amount:            1,
+			Date:              sale.Date,
+			DebitAccountUUID:  "unkn0wn",
+			CreditAccountUUID: sales.Uuid,
+			LedgerUUID:        books.Uuid,
+		})
+		is.True(errors.Is(err, ErrInvalidReference))
+
+		var after string
+		var currentTenant *int64
+		is.NoErr(tx.QueryRow(ctx, "select current_setting('search_path'), current_tenant_id()").Scan(&after, &currentTenant))
+		is.Equal(after, searchPath)  // search path of the caller restored
+		is.Equal(currentTenant, nil) // tenant of the engine not left behind
+		return posted, tx
+	}
+
+	posted, tx := placeOrder(1)
+	is.NoErr(tx.Rollback(ctx))
+	_, err = eng.GetTransaction(tenantCtx, posted.Uuid)
+	is.True(errors.Is(err, ErrNotFound)) // posting rolled back with the order
+
+	posted, tx = placeOrder(2)
+	is.NoErr(tx.Commit(ctx))
+	_, err = eng.GetTransaction(tenantCtx, posted.Uuid)
+	is.NoErr(err)
+
+	var orders int
+	is.NoErr(testDb.AdminPool.QueryRow(ctx, "select count(*) from program.orders").Scan(&orders))
+	is.Equal(orders, 1) // only the committed order
+
+	balance, err := eng.Balance(tenantCtx, cash.Uuid)
+	is.NoErr(err)
+	is.Equal(balance.Balance, int64(1000))
+
+	t.Run("should scope the operations to the tenant", func(t *testing.T) {
+		is := is_.New(t)
+
+		other, err := admin.CreateTenant(ctx, "other")
+		is.NoErr(err)
+		otherCtx := WithTenant(ctx, other.ID)
+
+		// the policies hide the rows of the tenant even without a filter
+		tx, err := testDb.Pool.Begin(ctx)
+		is.NoErr(err)
+		t.Cleanup(func() { _ = tx.Rollback(context.Background()) })
+		_, err = tx.Exec(ctx, "select set_config('app.tenant_id', $1, true)", strconv.FormatInt(other.ID, 10))
+		is.NoErr(err)
+		var visible int
+		is.NoErr(tx.QueryRow(ctx, "select count(*) from embedded.ledgers where uuid = $1", books.Uuid).Scan(&visible))
+		is.Equal(visible, 0) // ledger of another tenant read
+		is.NoErr(tx.QueryRow(ctx, "select count(*) from embedded.transactions").Scan(&visible))
+		is.Equal(visible, 0) // transactions of another tenant read
+
+		_, err = eng.GetLedger(otherCtx, books.Uuid)
+		is.True(errors.Is(err, ErrNotFound)) // ledger of another tenant
+
+		_, err = eng.Balance(otherCtx, cash.Uuid)
+		is.True(errors.Is(err, ErrNotFound)) // account of another tenant
+
+		_, err = eng.PostTransaction(otherCtx, sale)
+		is.True(errors.Is(err, ErrInvalidReference)) // accounts of another tenant
+	})
+
+	t.Run("should reset a tenant the caller didn't set", func(t *testing.T) {
+		is := is_.New(t)
+
+		// a connection of its own, where the setting was never defined
+		conn, err := pgx.ConnectConfig(ctx, testDb.Pool.Config().ConnConfig)
+		is.NoErr(err)
+		t.Cleanup(func() { _ = conn.Close(context.Background()) })
+
+		tx, err := conn.Begin(ctx)
+		is.NoErr(err)
+		t.Cleanup(func() { _ = tx.Rollback(context.Background()) })
+
+		_, err = eng.WithTx(tx).GetLedger(tenantCtx, books.Uuid)
+		is.NoErr(err)
+
+		// the setting stays defined in the session once set, reset it
+		// reads empty instead of the tenant of the engine
+		var tenantID string
+		var currentTenant *int64
+		is.NoErr(tx.QueryRow(ctx, "select current_setting('app.tenant_id'), current_tenant_id()").Scan(&tenantID, &currentTenant))
+		is.Equal(tenantID, "")
+		is.Equal(currentTenant, nil) // no tenant
+
+		is.NoErr(tx.Commit(ctx))
+		is.NoErr(conn.QueryRow(ctx, "select current_setting('app.tenant_id')").Scan(&tenantID))
+		is.Equal(tenantID, "") // nothing left in the session
+	})
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+)
+
+// CreateLedger creates a ledger of the tenant
+func (e *Engine) CreateLedger(ctx context.Context, params CreateLedgerParams) (*Ledger, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Ledger, error) {
+		return svc.CreateLedger(ctx, params)
+	})
+}
+
+func (e *Engine) GetLedger(ctx context.Context, uuid string) (*Ledger, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Ledger, error) {
+		return svc.GetLedger(ctx, uuid)
+	})
+}
+
+// ListLedgers lists the ledgers whose metadata contains the filter, the
+// filter is required
+func (e *Engine) ListLedgers(ctx context.Context, metadata map[string]any) ([]*LedgerRow, error) {
+	return run(ctx, e, func(svc *ledger.Service) ([]*LedgerRow, error) {
+		return svc.ListLedgers(ctx, metadata)
+	})
+}
+
+func (e *Engine) UpdateLedger(ctx context.Context, uuid string, params UpdateLedgerParams) (*Ledger, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Ledger, error) {
+		return svc.UpdateLedger(ctx, uuid, params)
+	})
+}
+
+// Dump returns the ledger, its accounts and its transactions as a single
+// document that Restore accepts
+func (e *Engine) Dump(ctx context.Context, ledgerUUID string) (*LedgerDump, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*LedgerDump, error) {
+		return svc.Dump(ctx, ledgerUUID)
+	})
+}
+
+// Restore recreates a ledger from a dump, an existing ledger is
+// ErrConflict unless replace is true
+func (e *Engine) Restore(ctx context.Context, dump LedgerDump, replace bool) error {
+	_, err := run(ctx, e, func(svc *ledger.Service) (struct{}, error) {
+		return struct{}{}, svc.Restore(ctx, dump, replace)
+	})
+	return err
+}
@@ -0,0 +1,30 @@
+package engine
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+)
+
+func (e *Engine) TrialBalance(ctx context.Context, ledgerUUID string, period Period) (*TrialBalance, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*TrialBalance, error) {
+		return svc.TrialBalance(ctx, ledgerUUID, period)
+	})
+}
+
+func (e *Engine) BalanceSheet(ctx context.Context, ledgerUUID string, period Period) (*BalanceSheet, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*BalanceSheet, error) {
+		return svc.BalanceSheet(ctx, ledgerUUID, period)
+	})
+}
+
+func (e *Engine) IncomeStatement(ctx context.Context, ledgerUUID string, period Period) (*IncomeStatement, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*IncomeStatement, error) {
+		return svc.IncomeStatement(ctx, ledgerUUID, period)
+	})
+}
+
+func (e *Engine) AccountStatement(ctx context.Context, accountUUID string, period Period) (*AccountStatement, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*AccountStatement, error) {
+		return svc.AccountStatement(ctx, accountUUID, period)
+	})
+}
@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"context"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+)
+
+// PostTransaction records a transaction, it's ErrInvalidReference when the
+// ledger or the accounts don't exist
+func (e *Engine) PostTransaction(ctx context.Context, params PostTransactionParams) (*Transaction, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Transaction, error) {
+		return svc.PostTransaction(ctx, params)
+	})
+}
+
+func (e *Engine) GetTransaction(ctx context.Context, uuid string) (*TransactionDetail, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*TransactionDetail, error) {
+		return svc.GetTransaction(ctx, uuid)
+	})
+}
+
+// ListTransactions returns a page of the transactions of a ledger, newest
+// first
+func (e *Engine) ListTransactions(ctx context.Context, params ListTransactionsParams) (*TransactionPage, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*TransactionPage, error) {
+		return svc.ListTransactions(ctx, params)
+	})
+}
+
+// StreamTransactions calls fn with the transactions of a ledger in
+// batches, in the order they were recorded. The batch slice is reused
+// between calls.
+func (e *Engine) StreamTransactions(
+	ctx context.Context,
+	ledgerUUID string,
+	metadata map[string]any,
+	fn func(transactions []*TransactionRow) error,
+) error {
+	_, err := run(ctx, e, func(svc *ledger.Service) (struct{}, error) {
+		return struct{}{}, svc.StreamTransactions(ctx, ledgerUUID, metadata, fn)
+	})
+	return err
+}
+
+func (e *Engine) UpdateTransaction(ctx context.Context, uuid string, params UpdateTransactionParams) (*Transaction, error) {
+	return run(ctx, e, func(svc *ledger.Service) (*Transaction, error) {
+		return svc.UpdateTransaction(ctx, uuid, params)
+	})
+}
+
+func (e *Engine) DeleteTransaction(ctx context.Context, uuid string) error {
+	_, err := run(ctx, e, func(svc *ledger.Service) (struct{}, error) {
+		return struct{}{}, svc.DeleteTransaction(ctx, uuid)
+	})
+	return err
+}
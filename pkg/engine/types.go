@@ -0,0 +1,53 @@
+package engine
+
+import (
+	dbGen "github.com/j0lvera/go-double-e/internal/db/generated"
+	"github.com/j0lvera/go-double-e/internal/ledger"
+	"github.com/j0lvera/go-double-e/internal/report"
+)
+
+// The records and params are the ones of the ledger service, aliased so
+// callers outside the module can name them.
+type (
+	Tenant      = dbGen.Tenant
+	Ledger      = dbGen.Ledger
+	LedgerRow   = dbGen.ListLedgersRow
+	Account     = dbGen.Account
+	AccountRow  = dbGen.ListAccountsRow
+	Transaction = dbGen.Transaction
+	// TransactionDetail is a transaction with the uuids of its accounts
+	// and ledger
+	TransactionDetail = ledger.Transaction
+	TransactionPage   = ledger.TransactionPage
+	TransactionRow    = dbGen.ListTransactionsByLedgerRow
+
+	CreateLedgerParams      = ledger.CreateLedgerParams
+	UpdateLedgerParams      = ledger.UpdateLedgerParams
+	CreateAccountParams     = ledger.CreateAccountParams
+	UpdateAccountParams     = ledger.UpdateAccountParams
+	PostTransactionParams   = ledger.PostTransactionParams
+	UpdateTransactionParams = ledger.UpdateTransactionParams
+	ListTransactionsParams  = ledger.ListTransactionsParams
+
+	LedgerDump = ledger.LedgerDump
+
+	Period           = report.Period
+	Line             = report.Line
+	TrialBalance     = report.TrialBalance
+	BalanceSheet     = report.BalanceSheet
+	IncomeStatement  = report.IncomeStatement
+	AccountStatement = report.AccountStatement
+
+	ValidationError  = ledger.ValidationError
+	ValidationErrors = ledger.ValidationErrors
+)
+
+// The errors of the ledger service, compare them with errors.Is
+var (
+	ErrNotFound         = ledger.ErrNotFound
+	ErrInvalidReference = ledger.ErrInvalidReference
+	ErrLedgerRequired   = ledger.ErrLedgerRequired
+	ErrMetadataRequired = ledger.ErrMetadataRequired
+	ErrNoChanges        = ledger.ErrNoChanges
+	ErrConflict         = ledger.ErrConflict
+)